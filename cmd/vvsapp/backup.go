@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+)
+
+const backupUsage = `usage: vvsapp backup [FILE]
+
+Writes a consistent copy of the database to FILE (default
+backup/vvsapp-YYYYMMDD-HHMMSS.db). Safe to run while the server is up.
+SQLite only; back up Postgres with pg_dump.
+
+Flags:
+`
+
+const restoreUsage = `usage: vvsapp restore FILE
+
+Replaces the configured database with the backup in FILE after checking that
+it is an intact vvsapp database no newer than this build. Stop the server
+first. The current database is kept next to it as <db>.pre-restore-<time>,
+and pending migrations are applied to the restored copy. SQLite only;
+restore Postgres with pg_restore.
+
+Flags:
+`
+
+func runBackup(c *cli, args []string) int {
+	fs := c.flags("backup", backupUsage)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		return usageError(fs, "expected at most one file")
+	}
+	out := fs.Arg(0)
+	if out == "" {
+		out = filepath.Join("backup", "vvsapp-"+time.Now().Format("20060102-150405")+".db")
+	}
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return fail("backup", err)
+	}
+	if err := requireSQLite(cfg.Database, "pg_dump"); err != nil {
+		return fail("backup", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		return fail("backup", fmt.Errorf("%s already exists", out))
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return fail("backup", err)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	e, err := c.open(ctx, false)
+	if err != nil {
+		return fail("backup", err)
+	}
+	defer e.Close()
+
+	// VACUUM INTO reads inside one transaction, so the copy is consistent
+	// even while the server keeps writing.
+	if _, err := e.db.ExecContext(ctx, `VACUUM INTO ?`, out); err != nil {
+		return fail("backup", fmt.Errorf("write %s: %w", out, err))
+	}
+	e.logger.Info("backup_written", map[string]any{"path": out})
+	fmt.Println(out)
+	return exitOK
+}
+
+func runRestore(c *cli, args []string) int {
+	fs := c.flags("restore", restoreUsage)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected one backup file")
+	}
+	src := fs.Arg(0)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return fail("restore", err)
+	}
+	if err := requireSQLite(cfg.Database, "pg_restore"); err != nil {
+		return fail("restore", err)
+	}
+	if err := checkBackup(ctx, src); err != nil {
+		return fail("restore", err)
+	}
+
+	target := cfg.Database.Path
+	if same, _ := sameFile(src, target); same {
+		return fail("restore", fmt.Errorf("%s is the configured database", src))
+	}
+	kept := ""
+	if _, err := os.Stat(target); err == nil {
+		kept = target + ".pre-restore-" + time.Now().Format("20060102-150405")
+		if err := copyFile(target, kept); err != nil {
+			return fail("restore", fmt.Errorf("keep current database: %w", err))
+		}
+	}
+
+	// Copy next to the target and rename over it so a failed copy never
+	// leaves a half-written database behind.
+	tmp := target + ".restoring"
+	if err := copyFile(src, tmp); err != nil {
+		os.Remove(tmp)
+		return fail("restore", err)
+	}
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(target + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return fail("restore", err)
+		}
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fail("restore", err)
+	}
+
+	e, err := c.open(ctx, true)
+	if err != nil {
+		return fail("restore", err)
+	}
+	defer e.Close()
+	e.logger.Info("backup_restored", map[string]any{"path": src, "kept": kept})
+
+	fmt.Printf("restored %s from %s\n", target, src)
+	if kept != "" {
+		fmt.Printf("previous database kept at %s\n", kept)
+	}
+	return exitOK
+}
+
+// requireSQLite rejects file-level backup and restore of a Postgres
+// database, naming the tool to use instead.
+func requireSQLite(cfg config.DatabaseConfig, tool string) error {
+	if cfg.Driver == db.DriverPostgres {
+		return fmt.Errorf("the database is Postgres; use %s instead", tool)
+	}
+	return nil
+}
+
+// checkBackup opens path read-only and verifies it is an intact vvsapp
+// database whose schema this build can run.
+func checkBackup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	conn, err := db.Open(config.DatabaseConfig{Path: "file:" + path + "?mode=ro"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var integrity string
+	if err := conn.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&integrity); err != nil {
+		return fmt.Errorf("%s is not a SQLite database: %w", path, err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("%s failed the integrity check: %s", path, integrity)
+	}
+	var version sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("%s is not a vvsapp database: %w", path, err)
+	}
+	if latest := db.LatestVersion(); int(version.Int64) > latest {
+		return fmt.Errorf("%s is at schema version %d but this build only knows %d", path, version.Int64, latest)
+	}
+	return nil
+}
+
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/importer"
+)
+
+const importUsage = `usage: vvsapp import [flags] FILE...
+
+Loads Google Sheets exports (.xlsx workbooks or per-sheet .csv files) into the
+database. Each sheet is recognised by its name as one of: %s.
+Re-running an import only applies what changed. Exits 3 when rows were
+rejected.
+
+Flags:
+`
+
+// runImport implements "vvsapp import". It exits with exitPartial when the
+// import ran but rejected rows.
+func runImport(c *cli, args []string) int {
+	fs := c.flags("import", "")
+	dryRun := fs.Bool("dry-run", false, "show what would change without writing")
+	sheet := fs.String("sheet", "", "import only the sheet with this name")
+	kind := fs.String("kind", "", "treat every sheet as this kind ("+strings.Join(importer.Kinds(), ", ")+")")
+	rejects := fs.String("rejects", "import-rejects.csv", "where to write rejected rows (only written when there are any)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), importUsage, strings.Join(importer.Kinds(), ", "))
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "expected at least one file")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	e, err := c.open(ctx, true)
+	if err != nil {
+		return fail("import", err)
+	}
+	defer e.Close()
+	loc, err := time.LoadLocation(e.cfg.Jobs.Timezone)
+	if err != nil {
+		return fail("import", fmt.Errorf("jobs timezone: %w", err))
+	}
+
+	report, err := importer.NewService(e.db, e.logger).Run(ctx, importer.Options{
+		Files:    fs.Args(),
+		Sheet:    *sheet,
+		Kind:     *kind,
+		DryRun:   *dryRun,
+		Location: loc,
+	})
+	if err != nil {
+		return fail("import", err)
+	}
+
+	if *dryRun {
+		if err := report.WriteDiff(os.Stdout); err != nil {
+			return fail("import", err)
+		}
+	}
+	if err := report.WriteSummary(os.Stdout); err != nil {
+		return fail("import", err)
+	}
+	if !*dryRun {
+		if err := e.record(ctx, activity.Entry{Action: "import", EntityType: "import", Summary: report.Summary(),
+			Path: "import " + strings.Join(fs.Args(), " ")}); err != nil {
+			return fail("import", err)
+		}
+	}
+	if len(report.Rejects) == 0 {
+		return exitOK
+	}
+	f, err := os.Create(*rejects)
+	if err != nil {
+		return fail("import", fmt.Errorf("write rejects: %w", err))
+	}
+	if err := report.WriteRejects(f); err != nil {
+		f.Close()
+		return fail("import", fmt.Errorf("write rejects: %w", err))
+	}
+	if err := f.Close(); err != nil {
+		return fail("import", fmt.Errorf("write rejects: %w", err))
+	}
+	fmt.Printf("%d rejected rows written to %s\n", len(report.Rejects), *rejects)
+	return exitPartial
+}
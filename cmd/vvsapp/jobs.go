@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/jobs"
+)
+
+const jobsUsage = `usage: vvsapp jobs list|run NAME
+
+  list      show registered jobs with their schedule and last run
+  run NAME  run one job now and wait for it; exits 1 if the run fails
+
+Runs are recorded in the job history exactly like scheduled ones.
+
+Flags:
+`
+
+func runJobs(c *cli, args []string) int {
+	fs := c.flags("jobs", jobsUsage)
+	sub, code, ok := parseSub(fs, args)
+	if !ok {
+		return code
+	}
+	switch {
+	case sub == "list" && fs.NArg() == 0:
+	case sub == "run" && fs.NArg() == 1:
+	default:
+		return usageError(fs, "expected list or run NAME")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	e, err := c.open(ctx, true)
+	if err != nil {
+		return fail("jobs", err)
+	}
+	defer e.Close()
+
+	services, err := newServices(e.cfg, e.logger, e.db)
+	if err != nil {
+		return fail("jobs", err)
+	}
+	runner := services.Jobs
+
+	if sub == "list" {
+		infos, err := runner.Jobs(ctx)
+		if err != nil {
+			return fail("jobs", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSCHEDULE\tNEXT RUN\tLAST RUN\tSTATUS")
+		for _, info := range infos {
+			last, status := "-", "-"
+			if info.LastRun != nil {
+				last, status = info.LastRun.StartedAt, info.LastRun.Status
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", info.Name, orDash(info.Schedule), orDash(info.NextRunAt), last, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return fail("jobs", err)
+		}
+		return exitOK
+	}
+
+	name := fs.Arg(0)
+	run, err := runner.RunSync(ctx, name, cliActor)
+	if errors.Is(err, jobs.ErrUnknownJob) {
+		return usageError(fs, fmt.Sprintf("unknown job %q", name))
+	}
+	if err != nil {
+		return fail("jobs", err)
+	}
+	if err := e.record(ctx, activity.Entry{Action: "run", EntityType: "job", EntityID: name,
+		Summary: fmt.Sprintf("run %d %s", run.ID, run.Status), Path: "jobs run " + name}); err != nil {
+		return fail("jobs", err)
+	}
+	if run.Status != jobs.StatusSucceeded {
+		return fail("jobs", fmt.Errorf("%s run %d %s: %s", run.JobName, run.ID, run.Status, run.Error))
+	}
+	fmt.Printf("%s run %d %s\n", run.JobName, run.ID, run.Status)
+	return exitOK
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,88 +1,236 @@
+// Command vvsapp runs the VVS local app server and the maintenance commands
+// used to manage an offline install.
 package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"net/http"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
+	_ "time/tzdata"
 
-	"github.com/google/uuid"
-
-	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/internal/activity"
 	"github.com/example/vvsapp/internal/config"
 	"github.com/example/vvsapp/internal/db"
 	"github.com/example/vvsapp/internal/logging"
-	"github.com/example/vvsapp/internal/server"
 )
 
+// Exit codes shared by every command so scripts can tell a bad invocation
+// from a failed operation.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	// exitPartial means the command finished but left something undone,
+	// such as rejected import rows.
+	exitPartial = 3
+)
+
+const defaultConfigPath = "config/app.yaml"
+
+const mainUsage = `usage: vvsapp [--config FILE] <command> [args]
+
+Commands:
+  serve                               run the HTTP server and job scheduler (default)
+  migrate up|down|status              apply, roll back or list schema migrations
+  seed                                create the configured admin account if missing
+  user create|disable|reset-password  manage staff accounts
+  backup [FILE]                       write a consistent copy of the database
+  restore FILE                        replace the database with a backup
+  import FILE...                      load Google Sheets exports
+  jobs list|run NAME                  list or run background jobs
+
+Run "vvsapp <command> -h" for the flags of a command.
+Exit codes: 0 success, 1 failure, 2 usage error, 3 finished with rejected rows.
+
+Flags:
+`
+
 func main() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+	os.Exit(run(os.Args[1:]))
+}
 
-	cfg, err := config.Load("config/app.yaml")
-	if err != nil {
-		panic(err)
+func run(args []string) int {
+	c := &cli{config: configFlag{path: defaultConfigPath}}
+	fs := flag.NewFlagSet("vvsapp", flag.ContinueOnError)
+	c.configVar(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), mainUsage)
+		fs.PrintDefaults()
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
 	}
 
-	baseLogger := logging.New(cfg.Logging.Level)
-	logger := baseLogger.With(map[string]any{"request_id": uuid.NewString()})
-	logger.Info("config_loaded", map[string]any{"config": cfg.Summary()})
+	args = fs.Args()
+	if len(args) == 0 {
+		return runServe(c, nil)
+	}
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "serve":
+		return runServe(c, rest)
+	case "migrate":
+		return runMigrate(c, rest)
+	case "seed":
+		return runSeed(c, rest)
+	case "user":
+		return runUser(c, rest)
+	case "backup":
+		return runBackup(c, rest)
+	case "restore":
+		return runRestore(c, rest)
+	case "import":
+		return runImport(c, rest)
+	case "jobs":
+		return runJobs(c, rest)
+	case "help":
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "vvsapp: unknown command %q\n\n", cmd)
+		fs.Usage()
+		return exitUsage
+	}
+}
 
-	database, err := db.Open(cfg.Database)
-	if err != nil {
-		logger.Error("database_open_failed", map[string]any{"error": err.Error()})
-		os.Exit(1)
+// cli carries the global flags into each command.
+type cli struct {
+	config configFlag
+}
+
+// configFlag remembers whether --config was given so an explicit path that
+// does not exist is an error rather than a silent fall back to defaults.
+type configFlag struct {
+	path string
+	set  bool
+}
+
+func (f *configFlag) String() string { return f.path }
+
+func (f *configFlag) Set(v string) error {
+	f.path, f.set = v, true
+	return nil
+}
+
+// configVar registers --config on fs; every command accepts it before or
+// after the command name.
+func (c *cli) configVar(fs *flag.FlagSet) {
+	fs.Var(&c.config, "config", "path to the YAML config file")
+}
+
+// flags returns a flag set for a command that prints usage followed by the
+// command's flags.
+func (c *cli) flags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	c.configVar(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
 	}
-	defer database.Close()
+	return fs
+}
 
-	if applied, err := db.RunMigrations(ctx, database); err != nil {
-		logger.Error("migrations_failed", map[string]any{"error": err.Error()})
-		os.Exit(1)
-	} else if len(applied) > 0 {
-		logger.Info("migrations_applied", map[string]any{"count": len(applied)})
+// parseFlags parses args and reports whether the command should go on; when
+// it should not, code is the exit code to return.
+func parseFlags(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
 	}
+	return exitOK, true
+}
 
-	if err := auth.SeedAdmin(ctx, database, cfg.Seed, logger); err != nil {
-		logger.Error("seed_admin_failed", map[string]any{"error": err.Error()})
-		os.Exit(1)
+// parseSub parses flags on both sides of a leading subcommand word, so
+// "migrate --config x status" and "migrate status --config x" both work. sub
+// is empty when no word was given; fs.Args() holds what follows it.
+func parseSub(fs *flag.FlagSet, args []string) (sub string, code int, ok bool) {
+	if code, ok := parseFlags(fs, args); !ok {
+		return "", code, false
+	}
+	if fs.NArg() == 0 {
+		return "", exitOK, true
+	}
+	sub = fs.Arg(0)
+	if code, ok := parseFlags(fs, fs.Args()[1:]); !ok {
+		return "", code, false
 	}
+	return sub, exitOK, true
+}
 
-	authSvc, err := auth.NewService(database, cfg.Auth, logger)
-	if err != nil {
-		logger.Error("auth_service_init_failed", map[string]any{"error": err.Error()})
-		os.Exit(1)
+// usageError prints msg and the command's usage and returns exitUsage.
+func usageError(fs *flag.FlagSet, msg string) int {
+	fmt.Fprintf(fs.Output(), "vvsapp %s: %s\n\n", fs.Name(), msg)
+	fs.Usage()
+	return exitUsage
+}
+
+// fail prints err for command name and returns exitFailure.
+func fail(name string, err error) int {
+	fmt.Fprintf(os.Stderr, "vvsapp %s: %v\n", name, err)
+	return exitFailure
+}
+
+func (c *cli) loadConfig() (*config.Config, error) {
+	if c.config.set {
+		if _, err := os.Stat(c.config.path); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
 	}
+	return config.Load(c.config.path)
+}
 
-	srv := server.New(cfg, logger, database, authSvc)
-	httpServer := &http.Server{
-		Addr:    cfg.Server.Address,
-		Handler: srv,
+// env is what most maintenance commands need: config, a logger that keeps
+// stdout free for the command's own output, and an open database.
+type env struct {
+	cfg    *config.Config
+	logger *logging.Logger
+	db     *sql.DB
+}
+
+// open loads config and opens the database, applying pending migrations
+// first when migrate is set.
+func (c *cli) open(ctx context.Context, migrate bool) (*env, error) {
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return nil, err
 	}
+	logger := logging.NewWriter(cfg.Logging.Level, os.Stderr)
+	database, err := db.Open(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if !migrate {
+		return &env{cfg: cfg, logger: logger, db: database}, nil
+	}
+	if applied, err := db.RunMigrations(ctx, database); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	} else if len(applied) > 0 {
+		logger.Info("migrations_applied", map[string]any{"count": len(applied)})
+	}
+	return &env{cfg: cfg, logger: logger, db: database}, nil
+}
 
-	serverErr := make(chan error, 1)
-	go func() {
-		logger.Info("server_listening", map[string]any{"address": cfg.Server.Address})
-		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			serverErr <- err
-		}
-		close(serverErr)
-	}()
-
-	select {
-	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := httpServer.Shutdown(shutdownCtx); err != nil {
-			logger.Error("server_shutdown_failed", map[string]any{"error": err.Error()})
-		}
-	case err := <-serverErr:
-		if err != nil {
-			logger.Error("server_listen_failed", map[string]any{"error": err.Error()})
-		}
+func (e *env) Close() error { return e.db.Close() }
+
+// record adds a change made from the shell to the activity log, next to the
+// API's: the actor is cliActor, the method "CLI" and the path the command.
+func (e *env) record(ctx context.Context, entry activity.Entry) error {
+	entry.Actor, entry.Method = cliActor, "CLI"
+	if err := activity.NewService(e.db, e.logger).Record(ctx, entry); err != nil {
+		return fmt.Errorf("saved, but not recorded in the activity log: %w", err)
 	}
+	return nil
+}
 
-	logger.Info("server_stopped", map[string]any{})
+// signalContext is cancelled on Ctrl-C or SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/example/vvsapp/internal/db"
+)
+
+const migrateUsage = `usage: vvsapp migrate up|down|status [flags]
+
+  up      apply pending schema migrations
+  down    roll back the newest applied migration, or every one above --to
+  status  list every migration and whether it has been applied; exits 1
+          when an applied migration changed or is unknown to this build
+
+Flags:
+`
+
+func runMigrate(c *cli, args []string) int {
+	fs := c.flags("migrate", migrateUsage)
+	to := fs.Int("to", -1, "down: roll back to this version (0 empties the schema)")
+	sub, code, ok := parseSub(fs, args)
+	if !ok {
+		return code
+	}
+	if sub != "up" && sub != "down" && sub != "status" || fs.NArg() > 0 {
+		return usageError(fs, "expected up, down or status")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	e, err := c.open(ctx, false)
+	if err != nil {
+		return fail("migrate", err)
+	}
+	defer e.Close()
+
+	switch sub {
+	case "status":
+		st, err := db.Status(ctx, e.db)
+		if err != nil {
+			return fail("migrate", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, m := range st.Migrations {
+			state := "applied"
+			switch {
+			case m.Unknown:
+				state = "unknown"
+			case !m.Applied:
+				state = "pending"
+			case m.Modified:
+				state = "modified"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", m.Version, m.Name, state, orDash(m.AppliedAt))
+		}
+		if err := tw.Flush(); err != nil {
+			return fail("migrate", err)
+		}
+		fmt.Printf("schema %s: version %d of %d, %d pending\n", st.State, st.Current, st.Latest, st.Pending)
+		if st.State == db.SchemaModified || st.State == db.SchemaUnknown {
+			return exitFailure
+		}
+		return exitOK
+
+	case "down":
+		target := *to
+		if target < 0 {
+			st, err := db.Status(ctx, e.db)
+			if err != nil {
+				return fail("migrate", err)
+			}
+			target = previousVersion(st)
+		}
+		rolledBack, err := db.MigrateDown(ctx, e.db, target)
+		for _, m := range rolledBack {
+			fmt.Printf("rolled back %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return fail("migrate", err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+		return exitOK
+	}
+
+	applied, err := db.RunMigrations(ctx, e.db)
+	for _, m := range applied {
+		fmt.Printf("applied %d %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return fail("migrate", err)
+	}
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+	return exitOK
+}
+
+// previousVersion is the highest applied version below the current one, so
+// a bare "migrate down" undoes exactly one migration.
+func previousVersion(st *db.SchemaStatus) int {
+	prev := 0
+	for _, m := range st.Migrations {
+		if m.Applied && m.Version < st.Current {
+			prev = m.Version
+		}
+	}
+	return prev
+}
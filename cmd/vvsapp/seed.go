@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/example/vvsapp/internal/auth"
+)
+
+const seedUsage = `usage: vvsapp seed
+
+Applies pending migrations and creates the admin account from the seed
+section of the config (or VVSAPP_ADMIN_* variables) if it does not exist.
+An existing account is left untouched.
+
+Flags:
+`
+
+func runSeed(c *cli, args []string) int {
+	fs := c.flags("seed", seedUsage)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	e, err := c.open(ctx, true)
+	if err != nil {
+		return fail("seed", err)
+	}
+	defer e.Close()
+
+	if err := auth.SeedAdmin(ctx, e.db, e.cfg.Seed, e.logger); err != nil {
+		return fail("seed", err)
+	}
+	return exitOK
+}
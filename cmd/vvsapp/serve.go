@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/server"
+)
+
+const serveUsage = `usage: vvsapp serve
+
+Loads config, applies pending migrations, seeds the admin account and runs
+the HTTP server and job scheduler until interrupted. This is also what
+vvsapp does when no command is given.
+
+Flags:
+`
+
+func runServe(c *cli, args []string) int {
+	fs := c.flags("serve", serveUsage)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments")
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return fail("serve", err)
+	}
+
+	baseLogger := logging.New(cfg.Logging.Level)
+	logger := baseLogger.With(map[string]any{"request_id": uuid.NewString()})
+	logger.Info("config_loaded", map[string]any{"config": cfg.Summary()})
+
+	database, err := db.Open(cfg.Database)
+	if err != nil {
+		logger.Error("database_open_failed", map[string]any{"error": err.Error()})
+		return exitFailure
+	}
+	defer database.Close()
+
+	if applied, err := db.RunMigrations(ctx, database); err != nil {
+		logger.Error("migrations_failed", map[string]any{"error": err.Error()})
+		return exitFailure
+	} else if len(applied) > 0 {
+		logger.Info("migrations_applied", map[string]any{"count": len(applied)})
+	}
+
+	if err := auth.SeedAdmin(ctx, database, cfg.Seed, logger); err != nil {
+		logger.Error("seed_admin_failed", map[string]any{"error": err.Error()})
+		return exitFailure
+	}
+
+	services, err := newServices(cfg, logger, database)
+	if err != nil {
+		logger.Error("services_init_failed", map[string]any{"error": err.Error()})
+		return exitFailure
+	}
+	runner := services.Jobs
+
+	srv := server.New(cfg, logger, database, services)
+	httpServer := &http.Server{
+		Addr:    cfg.Server.Address,
+		Handler: srv,
+	}
+
+	if cfg.Jobs.Enabled {
+		if err := runner.Start(ctx); err != nil {
+			logger.Error("jobs_start_failed", map[string]any{"error": err.Error()})
+			return exitFailure
+		}
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server_listening", map[string]any{"address": cfg.Server.Address})
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	code := exitOK
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("server_shutdown_failed", map[string]any{"error": err.Error()})
+		}
+	case err := <-serverErr:
+		if err != nil {
+			logger.Error("server_listen_failed", map[string]any{"error": err.Error()})
+			code = exitFailure
+		}
+	}
+
+	stop()
+	runner.Wait()
+
+	logger.Info("server_stopped", map[string]any{})
+	return code
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/audit"
+	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/internal/clientstatus"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/deadlines"
+	"github.com/example/vvsapp/internal/design3d"
+	"github.com/example/vvsapp/internal/diamonds"
+	"github.com/example/vvsapp/internal/jobs"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/payments"
+	"github.com/example/vvsapp/internal/reminders"
+	"github.com/example/vvsapp/internal/reports"
+	"github.com/example/vvsapp/internal/reps"
+	"github.com/example/vvsapp/internal/search"
+	"github.com/example/vvsapp/internal/server"
+	"github.com/example/vvsapp/internal/wax"
+)
+
+// newServices wires every service the server exposes, with the background
+// jobs registered on the runner. The jobs command shares it with serve so a
+// job run from the shell behaves exactly like a scheduled one.
+func newServices(cfg *config.Config, logger *logging.Logger, database *sql.DB) (server.Services, error) {
+	authSvc, err := auth.NewService(database, cfg.Auth, logger)
+	if err != nil {
+		return server.Services{}, fmt.Errorf("auth service: %w", err)
+	}
+
+	remindersLoc, err := time.LoadLocation(cfg.Reminders.Timezone)
+	if err != nil {
+		return server.Services{}, fmt.Errorf("reminders timezone: %w", err)
+	}
+	var notifier reminders.Notifier
+	if cfg.Reminders.WebhookURL != "" {
+		notifier = reminders.NewWebhookNotifier(cfg.Reminders.WebhookURL)
+	}
+	remindersSvc, err := reminders.NewService(database, remindersLoc, cfg.Reminders.DailyAt, notifier, logger)
+	if err != nil {
+		return server.Services{}, fmt.Errorf("reminders service: %w", err)
+	}
+
+	jobsLoc, err := time.LoadLocation(cfg.Jobs.Timezone)
+	if err != nil {
+		return server.Services{}, fmt.Errorf("jobs timezone: %w", err)
+	}
+	auditSvc := audit.NewService(database, cfg.Audit, logger)
+	repsSvc := reps.NewService(database, logger)
+	reportsSvc := reports.NewService(database, cfg.Reports, jobsLoc, repsSvc, logger)
+	appointmentsSvc := appointments.NewService(database, logger)
+	ordersSvc := orders.NewService(database, appointmentsSvc, logger)
+	deadlinesSvc, err := deadlines.NewService(database, ordersSvc, remindersSvc, cfg.Deadlines, jobsLoc, logger)
+	if err != nil {
+		return server.Services{}, fmt.Errorf("deadlines service: %w", err)
+	}
+	runner := jobs.NewRunner(database, jobsLoc, logger)
+	if err := registerJobs(runner, cfg, remindersSvc, auditSvc, reportsSvc, deadlinesSvc); err != nil {
+		return server.Services{}, fmt.Errorf("register jobs: %w", err)
+	}
+
+	waxSvc, err := wax.NewService(database, appointmentsSvc, ordersSvc, cfg.Wax, jobsLoc, logger)
+	if err != nil {
+		return server.Services{}, fmt.Errorf("wax service: %w", err)
+	}
+	return server.Services{
+		Auth:         authSvc,
+		Appointments: appointmentsSvc,
+		Orders:       ordersSvc,
+		Queues:       orders.NewQueues(database, cfg.Queues, cfg.Reports.ProductionStatuses, jobsLoc, logger),
+		Payments:     payments.NewService(database, appointmentsSvc, ordersSvc, cfg.Payments, logger),
+		Diamonds:     diamonds.NewService(database, appointmentsSvc, jobsLoc, logger),
+		Wax:          waxSvc,
+		Design3D:     design3d.NewService(database, appointmentsSvc, ordersSvc, jobsLoc, logger),
+		Deadlines:    deadlinesSvc,
+		ClientStatus: clientstatus.NewService(database, appointmentsSvc, jobsLoc, logger),
+		Reminders:    remindersSvc,
+		Jobs:         runner,
+		Reps:         repsSvc,
+		Audit:        auditSvc,
+		Activity:     activity.NewService(database, logger),
+		Reports:      reportsSvc,
+		Search:       search.NewService(database, logger),
+	}, nil
+}
+
+// registerJobs adds the background jobs. Schedules come from jobs.schedules,
+// falling back to each job's built-in default.
+//
+// The sheet's hourly dashboard rebuild and 30-minute Clients by Stage
+// refresh have no job here: they re-materialized tabs that /api/reports now
+// computes on each request. The 8:30 morning snapshot fed the ACK flow,
+// which was not ported.
+func registerJobs(runner *jobs.Runner, cfg *config.Config, remindersSvc *reminders.Service, auditSvc *audit.Service, reportsSvc *reports.Service, deadlinesSvc *deadlines.Service) error {
+	remindersSchedule := ""
+	if cfg.Reminders.Enabled {
+		remindersSchedule = remindersSvc.DailySchedule()
+	}
+	return errors.Join(
+		runner.Register(jobs.Job{
+			Name:        "reminders_daily",
+			Description: "Send due reminders and roll unconfirmed ones to the next day",
+			Schedule:    scheduleFor(cfg.Jobs, "reminders_daily", remindersSchedule),
+			Run: func(ctx context.Context) error {
+				_, err := remindersSvc.RunDaily(ctx)
+				return err
+			},
+		}),
+		runner.Register(jobs.Job{
+			Name:        "audit_master",
+			Description: "Run the Master data-quality audit and resolve fixed findings",
+			Schedule:    scheduleFor(cfg.Jobs, "audit_master", "0 6 * * *"),
+			Run: func(ctx context.Context) error {
+				_, err := auditSvc.Run(ctx)
+				return err
+			},
+		}),
+		runner.Register(jobs.Job{
+			Name:        "kpi_snapshot",
+			Description: "Record the trailing seven-day KPIs for sparklines and deltas",
+			Schedule:    scheduleFor(cfg.Jobs, "kpi_snapshot", "55 23 * * *"),
+			Run: func(ctx context.Context) error {
+				_, err := reportsSvc.Snapshot(ctx)
+				return err
+			},
+		}),
+		runner.Register(jobs.Job{
+			Name:        "deadline_reminders",
+			Description: "Queue reminders for overdue and at-risk 3D and Production Deadlines",
+			Schedule:    scheduleFor(cfg.Jobs, "deadline_reminders", "0 7 * * *"),
+			Run: func(ctx context.Context) error {
+				_, err := deadlinesSvc.QueueReminders(ctx)
+				return err
+			},
+		}),
+	)
+}
+
+func scheduleFor(cfg config.JobsConfig, name, def string) string {
+	if v, ok := cfg.Schedules[name]; ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/auth"
+)
+
+// cliActor is recorded as the actor for changes made from the shell.
+const cliActor = "cli"
+
+const userUsage = `usage: vvsapp user <subcommand> [flags]
+
+  create --email EMAIL [--role ROLE] [--name NAME] [--rep REP] [--password-stdin]
+  disable EMAIL
+  reset-password [--password-stdin] EMAIL
+
+Without --password-stdin, create invites the user and reset-password issues a
+single-use reset token; the token is printed for redemption via
+POST /api/auth/reset. With it, the first line of stdin becomes the password.
+
+Flags:
+`
+
+func runUser(c *cli, args []string) int {
+	fs := c.flags("user", userUsage)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "expected create, disable or reset-password")
+	}
+	// Each subcommand parses its own flags, which also accept --config.
+	sub, rest := fs.Arg(0), fs.Args()[1:]
+
+	var run func(context.Context, *env, *auth.Service) int
+	switch sub {
+	case "create":
+		sfs := c.flags("user create", userUsage)
+		email := sfs.String("email", "", "login email (required)")
+		role := sfs.String("role", auth.RoleRep, "one of "+strings.Join(auth.Roles, ", "))
+		name := sfs.String("name", "", "display name")
+		rep := sfs.String("rep", "", "roster name the account acts as (Assigned Rep)")
+		fromStdin := sfs.Bool("password-stdin", false, "read the password from stdin instead of inviting")
+		if code, ok := parseFlags(sfs, rest); !ok {
+			return code
+		}
+		if *email == "" || sfs.NArg() > 0 {
+			return usageError(sfs, "--email is required and no arguments are accepted")
+		}
+		run = func(ctx context.Context, e *env, svc *auth.Service) int {
+			in := auth.CreateUserInput{Email: *email, Role: *role, DisplayName: *name, RepName: *rep}
+			if *fromStdin {
+				password, err := readPassword(os.Stdin)
+				if err != nil {
+					return fail("user", err)
+				}
+				in.Password = password
+			}
+			u, token, err := svc.CreateUser(ctx, in, cliActor)
+			if err != nil {
+				return fail("user", err)
+			}
+			fmt.Printf("created user %d %s (%s)\n", u.ID, u.Email, u.Role)
+			printResetToken(token)
+			return recordUser(ctx, e, "user create", activity.ActionCreate, u, activity.Diff(nil, u))
+		}
+
+	case "disable", "reset-password":
+		sfs := c.flags("user "+sub, userUsage)
+		fromStdin := false
+		if sub == "reset-password" {
+			sfs.BoolVar(&fromStdin, "password-stdin", false, "read the new password from stdin instead of issuing a reset token")
+		}
+		if code, ok := parseFlags(sfs, rest); !ok {
+			return code
+		}
+		if sfs.NArg() != 1 {
+			return usageError(sfs, "expected one email")
+		}
+		email := sfs.Arg(0)
+		run = func(ctx context.Context, e *env, svc *auth.Service) int {
+			u, err := svc.FindUserByEmail(ctx, email)
+			if err != nil {
+				return fail("user", fmt.Errorf("%s: %w", email, err))
+			}
+			if sub == "disable" {
+				active := false
+				updated, err := svc.UpdateUser(ctx, u.ID, auth.UpdateUserInput{Active: &active}, cliActor)
+				if err != nil {
+					return fail("user", err)
+				}
+				fmt.Printf("disabled user %d %s\n", u.ID, u.Email)
+				return recordUser(ctx, e, "user disable", activity.ActionUpdate, u, activity.Diff(u, updated))
+			}
+			if fromStdin {
+				password, err := readPassword(os.Stdin)
+				if err != nil {
+					return fail("user", err)
+				}
+				if err := svc.SetPassword(ctx, u.ID, password, cliActor); err != nil {
+					return fail("user", err)
+				}
+				fmt.Printf("password set for user %d %s\n", u.ID, u.Email)
+				return recordUser(ctx, e, "user reset-password", "set_password", u, nil)
+			}
+			token, err := svc.IssueResetToken(ctx, u.ID, cliActor)
+			if err != nil {
+				return fail("user", err)
+			}
+			printResetToken(token)
+			return recordUser(ctx, e, "user reset-password", "issue_password_reset", u, nil)
+		}
+
+	default:
+		return usageError(fs, fmt.Sprintf("unknown subcommand %q", sub))
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	e, err := c.open(ctx, true)
+	if err != nil {
+		return fail("user", err)
+	}
+	defer e.Close()
+
+	svc, err := auth.NewService(e.db, e.cfg.Auth, e.logger)
+	if err != nil {
+		return fail("user", err)
+	}
+	return run(ctx, e, svc)
+}
+
+// recordUser logs a change to u in the activity log. The command's output is
+// printed first, so an invite token is never lost to a logging failure.
+func recordUser(ctx context.Context, e *env, path, action string, u *auth.User, changes []activity.Change) int {
+	err := e.record(ctx, activity.Entry{Action: action, EntityType: "user", EntityID: strconv.FormatInt(u.ID, 10),
+		Summary: u.Email, Changes: changes, Path: path})
+	if err != nil {
+		return fail("user", err)
+	}
+	return exitOK
+}
+
+// readPassword reads the first line of r, so both piped input and a
+// here-string work without echoing the password in the process list.
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func printResetToken(token *auth.ResetToken) {
+	if token == nil {
+		return
+	}
+	fmt.Printf("reset token: %s\nexpires: %s\n", token.Token, token.ExpiresAt)
+}
@@ -0,0 +1,259 @@
+// Package activity records who changed what, feeding the dashboard's
+// Recent Activity feed and per-entity history.
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// Actions recorded when a handler does not name a more specific one.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// ignoredFields are bookkeeping columns left out of diffs.
+var ignoredFields = map[string]bool{"updatedAt": true, "createdAt": true}
+
+// Change is one field's before/after value.
+type Change struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// Entry is one recorded mutation.
+type Entry struct {
+	ID         int64    `json:"id"`
+	At         string   `json:"at"`
+	Actor      string   `json:"actor"`
+	ActorRole  string   `json:"actorRole"`
+	Action     string   `json:"action"`
+	EntityType string   `json:"entityType"`
+	EntityID   string   `json:"entityId"`
+	RootApptID string   `json:"rootApptId"`
+	Summary    string   `json:"summary"`
+	Changes    []Change `json:"changes"`
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Status     int      `json:"status"`
+	// Link is the UI deep link for the entity, when it has a page.
+	Link string `json:"link"`
+}
+
+// Filter narrows List results. Cursor is the NextCursor of a previous page.
+type Filter struct {
+	Actor      string
+	EntityType string
+	EntityID   string
+	RootApptID string
+	Cursor     string
+	Limit      int
+}
+
+// Page is one slice of the feed, newest first.
+type Page struct {
+	Entries    []Entry `json:"entries"`
+	NextCursor string  `json:"nextCursor"`
+}
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 200
+)
+
+// links maps entity types to UI routes; the entity id is appended.
+var links = map[string]string{
+	"appointment": "/appointments/",
+	"order":       "/orders/",
+	"payment":     "/payments/",
+	"reminder":    "/reminders/",
+	"user":        "/users/",
+	"job":         "/jobs/",
+}
+
+// Service stores and queries the activity log.
+type Service struct {
+	db     *sql.DB
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs an activity service.
+func NewService(db *sql.DB, logger *logging.Logger) *Service {
+	return &Service{db: db, logger: logger, now: time.Now}
+}
+
+// Record appends an entry. At defaults to now.
+func (s *Service) Record(ctx context.Context, e Entry) error {
+	if e.At == "" {
+		e.At = s.now().UTC().Format(time.RFC3339)
+	}
+	changes := "[]"
+	if len(e.Changes) > 0 {
+		raw, err := json.Marshal(e.Changes)
+		if err != nil {
+			return fmt.Errorf("encode changes: %w", err)
+		}
+		changes = string(raw)
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO activity_log(at, actor, actor_role, action, entity_type, entity_id,
+        root_appt_id, summary, changes, method, path, status)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.At, e.Actor, e.ActorRole, e.Action, e.EntityType, e.EntityID, e.RootApptID, e.Summary, changes, e.Method, e.Path, e.Status)
+	if err != nil {
+		return fmt.Errorf("insert activity: %w", err)
+	}
+	return nil
+}
+
+// List returns a page of entries, newest first.
+func (s *Service) List(ctx context.Context, f Filter) (*Page, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if c := strings.TrimSpace(f.Cursor); c != "" {
+		before, err := strconv.ParseInt(c, 10, 64)
+		if err != nil || before <= 0 {
+			return nil, &ValidationError{Field: "cursor", Message: "is invalid"}
+		}
+		where = append(where, "id < ?")
+		args = append(args, before)
+	}
+	eq := func(col, val string) {
+		if v := strings.TrimSpace(val); v != "" {
+			where = append(where, "LOWER("+col+") = LOWER(?)")
+			args = append(args, v)
+		}
+	}
+	eq("actor", f.Actor)
+	eq("entity_type", f.EntityType)
+	eq("entity_id", f.EntityID)
+	eq("root_appt_id", f.RootApptID)
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	query := `SELECT id, at, actor, actor_role, action, entity_type, entity_id, root_appt_id, summary, changes,
+        method, path, status FROM activity_log`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list activity: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page{Entries: []Entry{}}
+	for rows.Next() {
+		var (
+			e       Entry
+			changes string
+		)
+		if err := rows.Scan(&e.ID, &e.At, &e.Actor, &e.ActorRole, &e.Action, &e.EntityType, &e.EntityID,
+			&e.RootApptID, &e.Summary, &changes, &e.Method, &e.Path, &e.Status); err != nil {
+			return nil, fmt.Errorf("scan activity: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+			return nil, fmt.Errorf("decode activity changes: %w", err)
+		}
+		if prefix, ok := links[e.EntityType]; ok && e.EntityID != "" {
+			e.Link = prefix + e.EntityID
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate activity: %w", err)
+	}
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		page.NextCursor = strconv.FormatInt(page.Entries[limit-1].ID, 10)
+	}
+	return page, nil
+}
+
+// Diff compares the JSON forms of before and after and returns the fields
+// that differ, sorted by name. A nil side yields a create or delete diff.
+func Diff(before, after any) []Change {
+	b, a := toMap(before), toMap(after)
+	keys := map[string]bool{}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range a {
+		keys[k] = true
+	}
+	var out []Change
+	for k := range keys {
+		if ignoredFields[k] {
+			continue
+		}
+		bv, av := b[k], a[k]
+		if reflect.DeepEqual(bv, av) || (isZero(bv) && isZero(av)) {
+			continue
+		}
+		out = append(out, Change{Field: k, Before: bv, After: av})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+func toMap(v any) map[string]any {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func isZero(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case float64:
+		return t == 0
+	case bool:
+		return !t
+	}
+	return false
+}
+
+// ValidationError reports a filter that cannot be applied.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
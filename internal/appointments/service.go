@@ -0,0 +1,383 @@
+package appointments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// ErrNotFound is returned when an appointment does not exist.
+var ErrNotFound = errors.New("appointment not found")
+
+// ValidationError reports input that cannot be stored.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Brands accepted in the Brand column of 00_Master Appointments.
+const (
+	BrandVVS   = "VVS"
+	BrandHPUSA = "HPUSA"
+)
+
+// Appointment mirrors one row of the "00_Master Appointments" sheet using the
+// canonical headers from 00_Canon.js.
+type Appointment struct {
+	ApptID                 string `json:"apptId"`
+	RootApptID             string `json:"rootApptId"`
+	Brand                  string `json:"brand"`
+	CustomerName           string `json:"customerName"`
+	EmailLower             string `json:"emailLower"`
+	PhoneNorm              string `json:"phoneNorm"`
+	VisitDate              string `json:"visitDate"`
+	VisitTime              string `json:"visitTime"`
+	VisitType              string `json:"visitType"`
+	VisitNumber            int    `json:"visitNumber"`
+	AssignedRep            string `json:"assignedRep"`
+	AssistedRep            string `json:"assistedRep"`
+	SalesStage             string `json:"salesStage"`
+	ConversionStatus       string `json:"conversionStatus"`
+	CustomOrderStatus      string `json:"customOrderStatus"`
+	CenterStoneOrderStatus string `json:"centerStoneOrderStatus"`
+	NextSteps              string `json:"nextSteps"`
+	// SO#, Odoo SO URL and SO Linked At are owned by the orders registry and
+	// ignored on create/update here.
+	SONumber   string `json:"so"`
+	OdooSOURL  string `json:"odooSoUrl"`
+	SOLinkedAt string `json:"soLinkedAt"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+// Filter narrows List results. Empty fields are ignored.
+type Filter struct {
+	RootApptID       string
+	Brand            string
+	AssignedRep      string
+	Rep              string // matches a name in Assigned Rep or Assisted Rep
+	VisitType        string
+	SalesStage       string
+	ConversionStatus string
+	From             string // inclusive Visit Date, YYYY-MM-DD
+	To               string // inclusive Visit Date, YYYY-MM-DD
+	Query            string // matches customer name, email or phone
+	Limit            int
+	Offset           int
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service stores appointments in SQLite.
+type Service struct {
+	db     *sql.DB
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs an appointments service.
+func NewService(db *sql.DB, logger *logging.Logger) *Service {
+	return &Service{db: db, logger: logger, now: time.Now}
+}
+
+const selectColumns = `appt_id, root_appt_id, brand, customer_name, email_lower, phone_norm,
+        visit_date, visit_time, visit_type, visit_number, assigned_rep, assisted_rep,
+        sales_stage, conversion_status, custom_order_status, center_stone_order_status,
+        next_steps, so_number, odoo_so_url, so_linked_at, created_at, updated_at`
+
+// Create inserts a new appointment. A missing APPT_ID is generated, and a
+// missing RootApptID defaults to the appointment's own ID (first visit). The
+// SO fields start empty and the Center Stone Order Status is copied from the
+// client's other appointments; the orders and diamonds services own them.
+func (s *Service) Create(ctx context.Context, appt Appointment) (*Appointment, error) {
+	if strings.TrimSpace(appt.ApptID) == "" {
+		appt.ApptID = uuid.NewString()
+	}
+	if err := normalize(&appt); err != nil {
+		return nil, err
+	}
+	appt.SONumber, appt.OdooSOURL, appt.SOLinkedAt = "", "", ""
+	err := s.db.QueryRowContext(ctx, `SELECT center_stone_order_status FROM appointments
+        WHERE root_appt_id = ? LIMIT 1`, appt.RootApptID).Scan(&appt.CenterStoneOrderStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		appt.CenterStoneOrderStatus = ""
+	} else if err != nil {
+		return nil, fmt.Errorf("select center stone order status: %w", err)
+	}
+	stamp := s.now().UTC().Format(time.RFC3339)
+	appt.CreatedAt = stamp
+	appt.UpdatedAt = stamp
+
+	const insert = `INSERT INTO appointments(` + selectColumns + `)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.ExecContext(ctx, insert,
+		appt.ApptID, appt.RootApptID, appt.Brand, appt.CustomerName, appt.EmailLower, appt.PhoneNorm,
+		appt.VisitDate, appt.VisitTime, appt.VisitType, appt.VisitNumber, appt.AssignedRep, appt.AssistedRep,
+		appt.SalesStage, appt.ConversionStatus, appt.CustomOrderStatus, appt.CenterStoneOrderStatus,
+		appt.NextSteps, appt.SONumber, appt.OdooSOURL, appt.SOLinkedAt, appt.CreatedAt, appt.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, &ValidationError{Field: "apptId", Message: "already exists"}
+		}
+		return nil, fmt.Errorf("insert appointment: %w", err)
+	}
+
+	s.logger.Info("appointment_created", map[string]any{"appt_id": appt.ApptID, "root_appt_id": appt.RootApptID})
+	return &appt, nil
+}
+
+// Get returns the appointment with the given APPT_ID.
+func (s *Service) Get(ctx context.Context, apptID string) (*Appointment, error) {
+	return get(ctx, s.db, apptID)
+}
+
+// GetTx returns the appointment with the given APPT_ID as seen inside tx,
+// for writers that update it in the same transaction.
+func GetTx(ctx context.Context, tx *sql.Tx, apptID string) (*Appointment, error) {
+	return get(ctx, tx, apptID)
+}
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func get(ctx context.Context, q rowQueryer, apptID string) (*Appointment, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+selectColumns+` FROM appointments WHERE appt_id = ?`, strings.TrimSpace(apptID))
+	appt, err := scanAppointment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select appointment: %w", err)
+	}
+	return appt, nil
+}
+
+// Update replaces the editable columns of an existing appointment. The SO
+// fields and the Center Stone Order Status are kept as stored.
+func (s *Service) Update(ctx context.Context, apptID string, appt Appointment) (*Appointment, error) {
+	existing, err := s.Get(ctx, apptID)
+	if err != nil {
+		return nil, err
+	}
+	appt.ApptID = existing.ApptID
+	if err := normalize(&appt); err != nil {
+		return nil, err
+	}
+	appt.SONumber, appt.OdooSOURL, appt.SOLinkedAt = existing.SONumber, existing.OdooSOURL, existing.SOLinkedAt
+	appt.CenterStoneOrderStatus = existing.CenterStoneOrderStatus
+	appt.CreatedAt = existing.CreatedAt
+	appt.UpdatedAt = s.now().UTC().Format(time.RFC3339)
+
+	const update = `UPDATE appointments SET
+        root_appt_id = ?, brand = ?, customer_name = ?, email_lower = ?, phone_norm = ?,
+        visit_date = ?, visit_time = ?, visit_type = ?, visit_number = ?, assigned_rep = ?, assisted_rep = ?,
+        sales_stage = ?, conversion_status = ?, custom_order_status = ?, next_steps = ?, updated_at = ?
+        WHERE appt_id = ?`
+	_, err = s.db.ExecContext(ctx, update,
+		appt.RootApptID, appt.Brand, appt.CustomerName, appt.EmailLower, appt.PhoneNorm,
+		appt.VisitDate, appt.VisitTime, appt.VisitType, appt.VisitNumber, appt.AssignedRep, appt.AssistedRep,
+		appt.SalesStage, appt.ConversionStatus, appt.CustomOrderStatus, appt.NextSteps, appt.UpdatedAt, appt.ApptID)
+	if err != nil {
+		return nil, fmt.Errorf("update appointment: %w", err)
+	}
+
+	s.logger.Info("appointment_updated", map[string]any{"appt_id": appt.ApptID})
+	return &appt, nil
+}
+
+// Delete removes an appointment.
+func (s *Service) Delete(ctx context.Context, apptID string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM appointments WHERE appt_id = ?`, strings.TrimSpace(apptID))
+	if err != nil {
+		return fmt.Errorf("delete appointment: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	s.logger.Info("appointment_deleted", map[string]any{"appt_id": apptID})
+	return nil
+}
+
+// List returns appointments matching the filter, newest visit first.
+func (s *Service) List(ctx context.Context, f Filter) ([]Appointment, error) {
+	var (
+		where []string
+		args  []any
+	)
+	eq := func(col, val string) {
+		if v := strings.TrimSpace(val); v != "" {
+			where = append(where, "LOWER("+col+") = LOWER(?)")
+			args = append(args, v)
+		}
+	}
+	eq("root_appt_id", f.RootApptID)
+	eq("brand", f.Brand)
+	eq("assigned_rep", f.AssignedRep)
+	if v := strings.TrimSpace(f.Rep); v != "" {
+		where = append(where, "("+reps.MatchSQL("assigned_rep")+" OR "+reps.MatchSQL("assisted_rep")+")")
+		args = append(args, reps.MatchArg(v), reps.MatchArg(v))
+	}
+	eq("visit_type", f.VisitType)
+	eq("sales_stage", f.SalesStage)
+	eq("conversion_status", f.ConversionStatus)
+	if f.From != "" {
+		where = append(where, "visit_date >= ?")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "visit_date <= ?")
+		args = append(args, f.To)
+	}
+	if q := strings.TrimSpace(f.Query); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		where = append(where, "(LOWER(customer_name) LIKE ? OR email_lower LIKE ? OR phone_norm LIKE ?)")
+		args = append(args, like, like, like)
+	}
+
+	query := `SELECT ` + selectColumns + ` FROM appointments`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY visit_date DESC, visit_time DESC, appt_id LIMIT ? OFFSET ?`
+	args = append(args, clampLimit(f.Limit), max(f.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list appointments: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Appointment{}
+	for rows.Next() {
+		appt, err := scanAppointment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan appointment: %w", err)
+		}
+		out = append(out, *appt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate appointments: %w", err)
+	}
+	return out, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAppointment(row scanner) (*Appointment, error) {
+	var a Appointment
+	err := row.Scan(&a.ApptID, &a.RootApptID, &a.Brand, &a.CustomerName, &a.EmailLower, &a.PhoneNorm,
+		&a.VisitDate, &a.VisitTime, &a.VisitType, &a.VisitNumber, &a.AssignedRep, &a.AssistedRep,
+		&a.SalesStage, &a.ConversionStatus, &a.CustomOrderStatus, &a.CenterStoneOrderStatus,
+		&a.NextSteps, &a.SONumber, &a.OdooSOURL, &a.SOLinkedAt, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+var visitTimePattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// normalize trims input, applies the sheet's EmailLower/PhoneNorm rules and
+// validates required columns.
+func normalize(a *Appointment) error {
+	a.ApptID = strings.TrimSpace(a.ApptID)
+	a.RootApptID = strings.TrimSpace(a.RootApptID)
+	if a.RootApptID == "" {
+		a.RootApptID = a.ApptID
+	}
+	a.Brand = strings.ToUpper(strings.TrimSpace(a.Brand))
+	a.CustomerName = strings.TrimSpace(a.CustomerName)
+	a.EmailLower = NormEmail(a.EmailLower)
+	a.PhoneNorm = NormPhone(a.PhoneNorm)
+	a.VisitDate = strings.TrimSpace(a.VisitDate)
+	a.VisitTime = strings.TrimSpace(a.VisitTime)
+	a.VisitType = strings.TrimSpace(a.VisitType)
+	a.AssignedRep = strings.TrimSpace(a.AssignedRep)
+	a.AssistedRep = strings.TrimSpace(a.AssistedRep)
+	a.SalesStage = strings.TrimSpace(a.SalesStage)
+	a.ConversionStatus = strings.TrimSpace(a.ConversionStatus)
+	a.CustomOrderStatus = strings.TrimSpace(a.CustomOrderStatus)
+	a.CenterStoneOrderStatus = strings.TrimSpace(a.CenterStoneOrderStatus)
+	a.NextSteps = strings.TrimSpace(a.NextSteps)
+
+	switch a.Brand {
+	case BrandVVS, BrandHPUSA:
+	default:
+		return &ValidationError{Field: "brand", Message: "must be VVS or HPUSA"}
+	}
+	if a.CustomerName == "" {
+		return &ValidationError{Field: "customerName", Message: "is required"}
+	}
+	if a.VisitDate == "" {
+		return &ValidationError{Field: "visitDate", Message: "is required"}
+	}
+	if _, err := time.Parse("2006-01-02", a.VisitDate); err != nil {
+		return &ValidationError{Field: "visitDate", Message: "must be YYYY-MM-DD"}
+	}
+	if a.VisitTime != "" && !visitTimePattern.MatchString(a.VisitTime) {
+		return &ValidationError{Field: "visitTime", Message: "must be HH:MM (24h)"}
+	}
+	if a.VisitNumber < 0 {
+		return &ValidationError{Field: "visitNumber", Message: "must not be negative"}
+	}
+	return nil
+}
+
+// NormEmail mirrors normEmail_ in Resolver.js.
+func NormEmail(v string) string {
+	return strings.ToLower(strings.TrimSpace(v))
+}
+
+// NormPhone mirrors normPhone_ in Resolver.js: digits only, rendered as E.164
+// with a +1 default for 10-digit US numbers.
+func NormPhone(v string) string {
+	var b strings.Builder
+	for _, r := range v {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	d := b.String()
+	switch {
+	case d == "":
+		return ""
+	case len(d) == 10:
+		return "+1" + d
+	default:
+		return "+" + d
+	}
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
+
+func isUniqueViolation(err error) bool {
+	return db.IsUniqueViolation(err)
+}
@@ -0,0 +1,131 @@
+package appointments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+func TestNormPhone(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"n/a":              "",
+		"(555) 010-2030":   "+15550102030",
+		"555.010.2030":     "+15550102030",
+		"+1 555 010 2030":  "+15550102030",
+		"+44 20 7946 0958": "+442079460958",
+	}
+	for in, want := range tests {
+		if got := NormPhone(in); got != want {
+			t.Errorf("NormPhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	valid := func() Appointment {
+		return Appointment{ApptID: " A1 ", Brand: " vvs ", CustomerName: " Jamie ", EmailLower: " Jamie@Example.COM ",
+			VisitDate: "2024-04-02", VisitTime: "09:30"}
+	}
+
+	a := valid()
+	if err := normalize(&a); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if a.ApptID != "A1" || a.RootApptID != "A1" || a.Brand != BrandVVS || a.CustomerName != "Jamie" || a.EmailLower != "jamie@example.com" {
+		t.Errorf("normalize = %+v", a)
+	}
+
+	tests := []struct {
+		field  string
+		mutate func(*Appointment)
+	}{
+		{"brand", func(a *Appointment) { a.Brand = "Other" }},
+		{"customerName", func(a *Appointment) { a.CustomerName = "  " }},
+		{"visitDate", func(a *Appointment) { a.VisitDate = "" }},
+		{"visitDate", func(a *Appointment) { a.VisitDate = "04/02/2024" }},
+		{"visitTime", func(a *Appointment) { a.VisitTime = "9:30am" }},
+		{"visitTime", func(a *Appointment) { a.VisitTime = "24:00" }},
+		{"visitNumber", func(a *Appointment) { a.VisitNumber = -1 }},
+	}
+	for _, tt := range tests {
+		a := valid()
+		tt.mutate(&a)
+		var verr *ValidationError
+		if err := normalize(&a); !errors.As(err, &verr) || verr.Field != tt.field {
+			t.Errorf("normalize(%+v) = %v, want a %s validation error", a, err, tt.field)
+		}
+	}
+}
+
+func TestUpdateKeepsOrderFields(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	svc := NewService(conn, logging.NewWriter("error", io.Discard))
+	svc.now = func() time.Time { return time.Date(2024, 4, 2, 15, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, Appointment{ApptID: "A1", Brand: BrandVVS, CustomerName: "Jamie",
+		VisitDate: "2024-04-02", SONumber: "12.3456", CenterStoneOrderStatus: "Stone Received"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.SONumber != "" || created.CenterStoneOrderStatus != "" {
+		t.Errorf("create kept SO %q, CSOS %q from input", created.SONumber, created.CenterStoneOrderStatus)
+	}
+	linkSO(t, conn, "A1", "12.3456")
+	if _, err := conn.Exec(`UPDATE appointments SET center_stone_order_status = 'Stone Ordered' WHERE appt_id = 'A1'`); err != nil {
+		t.Fatalf("set csos: %v", err)
+	}
+
+	in := *created
+	in.SONumber, in.OdooSOURL, in.NextSteps = "99.9999", "evil.com", "Call back"
+	in.CenterStoneOrderStatus = "Stone Received"
+	updated, err := svc.Update(ctx, "A1", in)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := svc.Get(ctx, "A1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if updated.SONumber != "12.3456" || got.SONumber != "12.3456" || got.OdooSOURL != "odoo.example.com" {
+		t.Errorf("update changed SO fields: returned %q, stored %q %q", updated.SONumber, got.SONumber, got.OdooSOURL)
+	}
+	if updated.CenterStoneOrderStatus != "Stone Ordered" || got.CenterStoneOrderStatus != "Stone Ordered" {
+		t.Errorf("update changed CSOS: returned %q, stored %q", updated.CenterStoneOrderStatus, got.CenterStoneOrderStatus)
+	}
+	if got.NextSteps != "Call back" {
+		t.Errorf("next steps = %q", got.NextSteps)
+	}
+
+	// A later visit shares the client's status.
+	visit, err := svc.Create(ctx, Appointment{ApptID: "A2", RootApptID: "A1", Brand: BrandVVS, CustomerName: "Jamie",
+		VisitDate: "2024-05-02"})
+	if err != nil {
+		t.Fatalf("create visit: %v", err)
+	}
+	if visit.CenterStoneOrderStatus != "Stone Ordered" {
+		t.Errorf("visit CSOS = %q, want the root's", visit.CenterStoneOrderStatus)
+	}
+
+	if _, err := svc.Update(ctx, "missing", in); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing = %v, want ErrNotFound", err)
+	}
+}
+
+// linkSO stands in for the orders registry, which owns the SO columns.
+func linkSO(t *testing.T, conn *sql.DB, apptID, so string) {
+	t.Helper()
+	_, err := conn.Exec(`UPDATE appointments SET so_number = ?, odoo_so_url = ?, so_linked_at = ? WHERE appt_id = ?`,
+		so, "odoo.example.com", "2024-04-02T15:00:00Z", apptID)
+	if err != nil {
+		t.Fatalf("link SO: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package audit runs the Master data-quality checks ported from Auditv1 and
+// tracks their findings over time.
+package audit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/vvsapp/internal/config"
+)
+
+// Severities, as written to the Severity column of "Audit Findings (v1)".
+const (
+	SeverityCritical = "Critical"
+	SeverityWarning  = "Warning"
+)
+
+// Rule identifiers stored with each finding.
+const (
+	RuleMissingOrderTotal     = "missing_order_total"
+	RuleDepositMissingPayment = "deposit_missing_payment"
+	RuleCompletedBalanceDue   = "completed_balance_due"
+	RuleMissingSO             = "missing_so"
+	RulePaymentMathMismatch   = "payment_math_mismatch"
+	RuleOverpayment           = "overpayment"
+	RuleDepositBelowPolicy    = "deposit_below_policy"
+	RuleMissingAssignedRep    = "missing_assigned_rep"
+	RuleFollowUpNoNextSteps   = "followup_missing_next_steps"
+
+	RuleMissing3DDeadline         = "missing_3d_deadline"
+	RuleMissingProductionDeadline = "missing_production_deadline"
+)
+
+// Conversion Status values the rules key on.
+const (
+	convDepositPaid    = "Deposit Paid"
+	convOrderCompleted = "Order Completed"
+	convFollowUpPrefix = "Follow-Up"
+)
+
+// Row is one appointment joined with its root's payment balance. Money is in cents.
+type Row struct {
+	ApptID            string
+	RootApptID        string
+	SONumber          string
+	CustomerName      string
+	SalesStage        string
+	ConversionStatus  string
+	CustomOrderStatus string
+	AssignedRep       string
+	AssistedRep       string
+	NextSteps         string
+	Brand             string
+
+	// Has3DDeadline and HasProductionDeadline report whether the row's SO
+	// has ever had that deadline recorded in deadline_history.
+	Has3DDeadline         bool
+	HasProductionDeadline bool
+
+	HasBalance    bool
+	OrderTotal    int64
+	PaidToDate    int64
+	Credits       int64
+	Remaining     int64
+	LastPaymentAt string
+}
+
+// Check is one rule violation found on a row.
+type Check struct {
+	Rule     string
+	Issue    string
+	Action   string
+	Severity string
+	// Field names the Master column to fix.
+	Field string
+}
+
+// Rules evaluates rows against the configured policy.
+type Rules struct {
+	minDeposit        float64
+	requireFinancials map[string]bool
+	exclude           map[string]bool
+	require3D         map[string]bool
+	requireProduction map[string]bool
+}
+
+// NewRules builds a rule set from config.
+func NewRules(cfg config.AuditConfig) *Rules {
+	r := &Rules{
+		minDeposit:        cfg.MinDepositPercent,
+		requireFinancials: map[string]bool{},
+		exclude:           map[string]bool{},
+		require3D:         map[string]bool{},
+		requireProduction: map[string]bool{},
+	}
+	for _, v := range cfg.RequireFinancials {
+		r.requireFinancials[strings.TrimSpace(v)] = true
+	}
+	for _, v := range cfg.ExcludeStatuses {
+		r.exclude[strings.TrimSpace(v)] = true
+	}
+	for _, v := range cfg.Require3DDeadline {
+		r.require3D[strings.TrimSpace(v)] = true
+	}
+	for _, v := range cfg.RequireProductionDeadline {
+		r.requireProduction[strings.TrimSpace(v)] = true
+	}
+	return r
+}
+
+// Evaluate returns every rule the row violates, in Auditv1 order.
+func (r *Rules) Evaluate(row Row) []Check {
+	conv := strings.TrimSpace(row.ConversionStatus)
+	cos := strings.TrimSpace(row.CustomOrderStatus)
+	if r.exclude[conv] || r.exclude[cos] {
+		return nil
+	}
+
+	var out []Check
+	add := func(rule, issue, action, severity, field string) {
+		out = append(out, Check{Rule: rule, Issue: issue, Action: action, Severity: severity, Field: field})
+	}
+
+	if r.requireFinancials[conv] {
+		hasTotal := row.HasBalance && row.OrderTotal > 0
+		hasPaid := row.HasBalance && row.PaidToDate > 0
+
+		if !hasTotal {
+			add(RuleMissingOrderTotal, "Missing Order Total",
+				"Enter Order Total via Record Payments.", SeverityCritical, "Order Total")
+		}
+
+		if conv == convDepositPaid && (!hasPaid || row.LastPaymentAt == "") {
+			var missing []string
+			field := "Last Payment Date"
+			if !hasPaid {
+				missing = append(missing, "Paid-to-Date")
+				field = "Paid-to-Date"
+			}
+			if row.LastPaymentAt == "" {
+				missing = append(missing, "Last Payment Date")
+			}
+			add(RuleDepositMissingPayment, "Deposit Paid but missing: "+strings.Join(missing, ", "),
+				"Record the deposit amount (Paid-to-Date) and set Last Payment Date.", SeverityCritical, field)
+		}
+
+		if conv == convOrderCompleted && row.HasBalance && row.Remaining > 0 {
+			add(RuleCompletedBalanceDue, fmt.Sprintf("Order Completed but Remaining Balance > 0 (%s)", money(row.Remaining)),
+				"Collect final payment or correct Paid-to-Date / Remaining Balance.", SeverityCritical, "Remaining Balance")
+		}
+
+		if strings.TrimSpace(row.SONumber) == "" {
+			add(RuleMissingSO, "Missing SO#",
+				"Add the Sales Order number (SO#) to link payments and production.", SeverityCritical, "SO#")
+		}
+
+		if hasTotal {
+			if expected := max(row.OrderTotal-row.Credits-row.PaidToDate, 0); expected != row.Remaining {
+				add(RulePaymentMathMismatch, "Payment math mismatch (Order Total - Paid-to-Date - Remaining ≠ 0)",
+					"Correct Order Total / Paid-to-Date / Remaining Balance so they reconcile.", SeverityWarning, "Remaining Balance")
+			}
+			if row.PaidToDate > row.OrderTotal {
+				add(RuleOverpayment, "Overpayment: Paid-to-Date > Order Total",
+					"Verify totals or correct Order Total.", SeverityWarning, "Paid-to-Date")
+			}
+			minRequired := int64(float64(row.OrderTotal)*r.minDeposit + 0.5)
+			if row.PaidToDate < minRequired {
+				add(RuleDepositBelowPolicy, fmt.Sprintf("Deposit below policy (%s < %s)", money(row.PaidToDate), money(minRequired)),
+					fmt.Sprintf("Collect additional deposit (≥ %d%% of Order Total) or update totals if misrecorded.", int(r.minDeposit*100+0.5)),
+					SeverityCritical, "Paid-to-Date")
+			}
+		}
+	}
+
+	if strings.TrimSpace(row.AssignedRep) == "" {
+		add(RuleMissingAssignedRep, "Missing Assigned Rep",
+			"Assign a rep so the client has an owner.", SeverityWarning, "Assigned Rep")
+	}
+
+	if strings.HasPrefix(conv, convFollowUpPrefix) && strings.TrimSpace(row.NextSteps) == "" {
+		add(RuleFollowUpNoNextSteps, "Follow-up status but Next Steps missing",
+			"Fill Next Steps so anyone can pick up the thread.", SeverityWarning, "Next Steps")
+	}
+
+	if r.require3D[cos] && !row.Has3DDeadline {
+		add(RuleMissing3DDeadline, "Missing 3D Deadline for Custom Order Status "+cos,
+			"Record the 3D Deadline for this order.", SeverityWarning, "3D Deadline")
+	}
+	if r.requireProduction[cos] && !row.HasProductionDeadline {
+		add(RuleMissingProductionDeadline, "Missing Production Deadline for Custom Order Status "+cos,
+			"Record the Production Deadline for this order.", SeverityWarning, "Production Deadline")
+	}
+
+	return out
+}
+
+func money(cents int64) string {
+	return fmt.Sprintf("$%.2f", float64(cents)/100)
+}
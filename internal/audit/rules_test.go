@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+var testConfig = config.AuditConfig{
+	MinDepositPercent:         0.20,
+	RequireFinancials:         []string{"Deposit Paid", "Order Completed"},
+	ExcludeStatuses:           []string{"Canceled"},
+	Require3DDeadline:         []string{"3D Requested"},
+	RequireProductionDeadline: []string{"In Production"},
+}
+
+func rulesOf(checks []Check) map[string]bool {
+	out := map[string]bool{}
+	for _, c := range checks {
+		out[c.Rule] = true
+	}
+	return out
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := NewRules(testConfig)
+	base := Row{ApptID: "A1", AssignedRep: "Jamie", SONumber: "12.3456"}
+	paid := func(r Row, total, paid int64) Row {
+		r.HasBalance, r.OrderTotal, r.PaidToDate = true, total, paid
+		r.Remaining = max(total-paid, 0)
+		r.LastPaymentAt = "2024-05-01T00:00:00Z"
+		return r
+	}
+	with := func(mutate func(*Row)) Row {
+		r := base
+		mutate(&r)
+		return r
+	}
+
+	tests := []struct {
+		name string
+		row  Row
+		want []string
+	}{
+		{"clean lead", base, nil},
+		{"no rep", with(func(r *Row) { r.AssignedRep = "" }), []string{RuleMissingAssignedRep}},
+		{"follow-up without next steps", with(func(r *Row) { r.ConversionStatus = "Follow-Up Required" }),
+			[]string{RuleFollowUpNoNextSteps}},
+		{"deposit without money", with(func(r *Row) { r.ConversionStatus = "Deposit Paid" }),
+			[]string{RuleMissingOrderTotal, RuleDepositMissingPayment}},
+		{"deposit below policy", paid(with(func(r *Row) { r.ConversionStatus = "Deposit Paid" }), 100000, 10000),
+			[]string{RuleDepositBelowPolicy}},
+		{"completed with balance", paid(with(func(r *Row) { r.ConversionStatus = "Order Completed" }), 100000, 50000),
+			[]string{RuleCompletedBalanceDue}},
+		{"overpaid, no SO", paid(with(func(r *Row) { r.ConversionStatus = "Deposit Paid"; r.SONumber = "" }), 100000, 120000),
+			[]string{RuleMissingSO, RuleOverpayment}},
+		{"math mismatch", with(func(r *Row) {
+			*r = paid(*r, 100000, 50000)
+			r.ConversionStatus, r.Remaining = "Deposit Paid", 1
+		}), []string{RulePaymentMathMismatch}},
+		{"3D requested, none recorded", with(func(r *Row) { r.CustomOrderStatus = " 3D Requested " }),
+			[]string{RuleMissing3DDeadline}},
+		{"3D requested, recorded", with(func(r *Row) { r.CustomOrderStatus = "3D Requested"; r.Has3DDeadline = true }), nil},
+		{"in production, only 3D recorded", with(func(r *Row) { r.CustomOrderStatus = "In Production"; r.Has3DDeadline = true }),
+			[]string{RuleMissingProductionDeadline}},
+		{"in production, recorded", with(func(r *Row) { r.CustomOrderStatus = "In Production"; r.HasProductionDeadline = true }), nil},
+		{"excluded", with(func(r *Row) { r.CustomOrderStatus = "Canceled"; r.AssignedRep = "" }), nil},
+	}
+	for _, tt := range tests {
+		got := rulesOf(rules.Evaluate(tt.row))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: rules = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for _, rule := range tt.want {
+			if !got[rule] {
+				t.Errorf("%s: rules = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestRunMissingDeadlines(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	logger := logging.NewWriter("error", io.Discard)
+	svc := NewService(conn, testConfig, logger)
+	svc.now = func() time.Time { return time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	appts := appointments.NewService(conn, logger)
+	for _, id := range []string{"A1", "A2"} {
+		if _, err := appts.Create(ctx, appointments.Appointment{ApptID: id, Brand: appointments.BrandVVS,
+			CustomerName: "Client " + id, VisitDate: "2024-04-02", AssignedRep: "Jamie"}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	exec(`UPDATE appointments SET custom_order_status = 'In Production', so_number = CASE appt_id
+        WHEN 'A1' THEN '11.0001' ELSE '22.0002' END`)
+	exec(`INSERT INTO deadline_history(brand, so_number, so_key, deadline_type, action, deadline_date, created_at)
+        VALUES('VVS', '11.0001', '110001', 'production', 'met', '2024-04-20', '2024-04-01T00:00:00Z')`)
+
+	res, err := svc.Run(ctx)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res.Open != 1 {
+		t.Fatalf("run = %+v, want one finding", res)
+	}
+	found, err := svc.List(ctx, Filter{Rule: RuleMissingProductionDeadline})
+	if err != nil || len(found) != 1 || found[0].ApptID != "A2" || found[0].Field != "Production Deadline" {
+		t.Fatalf("findings = %+v, %v", found, err)
+	}
+
+	exec(`INSERT INTO deadline_history(brand, so_number, so_key, deadline_type, action, deadline_date, created_at)
+        VALUES('VVS', '22.0002', '220002', 'production', 'set', '2024-06-01', '2024-05-01T00:00:00Z')`)
+	if res, err = svc.Run(ctx); err != nil || res.Resolved != 1 || res.Open != 0 {
+		t.Errorf("rerun = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,311 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/deadlines"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// Finding statuses accepted by Filter.Status.
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+	StatusAll      = "all"
+)
+
+// Finding is a persisted rule violation. A finding stays open while the rule
+// keeps firing for the appointment and is resolved on the first run it doesn't.
+type Finding struct {
+	ID                int64  `json:"id"`
+	Rule              string `json:"rule"`
+	ApptID            string `json:"apptId"`
+	RootApptID        string `json:"rootApptId"`
+	SONumber          string `json:"soNumber"`
+	CustomerName      string `json:"customerName"`
+	SalesStage        string `json:"salesStage"`
+	ConversionStatus  string `json:"conversionStatus"`
+	CustomOrderStatus string `json:"customOrderStatus"`
+	AssignedRep       string `json:"assignedRep"`
+	AssistedRep       string `json:"assistedRep"`
+	Issue             string `json:"issue"`
+	Action            string `json:"action"`
+	Severity          string `json:"severity"`
+	Field             string `json:"field"`
+	FirstSeenAt       string `json:"firstSeenAt"`
+	LastSeenAt        string `json:"lastSeenAt"`
+	ResolvedAt        string `json:"resolvedAt"`
+}
+
+// Filter narrows List results. Empty fields are ignored; Status defaults to open.
+type Filter struct {
+	Severity   string
+	Rep        string // matches a name in Assigned Rep or Assisted Rep
+	Rule       string
+	RootApptID string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+// ValidationError reports a filter that cannot be applied.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// RunResult summarizes one audit pass.
+type RunResult struct {
+	Checked  int `json:"checked"`
+	Open     int `json:"open"`
+	New      int `json:"new"`
+	Resolved int `json:"resolved"`
+}
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// Service runs the rules and stores findings.
+type Service struct {
+	db     *sql.DB
+	rules  *Rules
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs an audit service.
+func NewService(db *sql.DB, cfg config.AuditConfig, logger *logging.Logger) *Service {
+	return &Service{db: db, rules: NewRules(cfg), logger: logger, now: time.Now}
+}
+
+// Run evaluates every appointment, opens new findings, refreshes ones that
+// still apply and resolves ones that no longer do.
+func (s *Service) Run(ctx context.Context) (*RunResult, error) {
+	rows, err := s.loadRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stamp := s.now().UTC().Format(time.RFC3339)
+	res := &RunResult{Checked: len(rows)}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin audit run: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	open := map[string]bool{}
+	existing, err := tx.QueryContext(ctx, `SELECT finding_key FROM audit_findings WHERE resolved_at = ''`)
+	if err != nil {
+		return nil, fmt.Errorf("select open findings: %w", err)
+	}
+	for existing.Next() {
+		var key string
+		if err := existing.Scan(&key); err != nil {
+			existing.Close()
+			return nil, fmt.Errorf("scan open finding: %w", err)
+		}
+		open[key] = true
+	}
+	existing.Close()
+	if err := existing.Err(); err != nil {
+		return nil, fmt.Errorf("iterate open findings: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, c := range s.rules.Evaluate(row) {
+			key := row.ApptID + "|" + c.Rule
+			seen[key] = true
+			if !open[key] {
+				res.New++
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO audit_findings(finding_key, rule, appt_id, root_appt_id, so_number,
+                customer_name, sales_stage, conversion_status, custom_order_status, assigned_rep, assisted_rep,
+                issue, action, severity, field, first_seen_at, last_seen_at)
+                VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+                ON CONFLICT(finding_key) DO UPDATE SET
+                    root_appt_id = excluded.root_appt_id, so_number = excluded.so_number,
+                    customer_name = excluded.customer_name, sales_stage = excluded.sales_stage,
+                    conversion_status = excluded.conversion_status, custom_order_status = excluded.custom_order_status,
+                    assigned_rep = excluded.assigned_rep, assisted_rep = excluded.assisted_rep,
+                    issue = excluded.issue, action = excluded.action, severity = excluded.severity, field = excluded.field,
+                    first_seen_at = CASE WHEN audit_findings.resolved_at <> '' THEN excluded.first_seen_at ELSE audit_findings.first_seen_at END,
+                    last_seen_at = excluded.last_seen_at, resolved_at = ''`,
+				key, c.Rule, row.ApptID, row.RootApptID, row.SONumber, row.CustomerName, row.SalesStage,
+				row.ConversionStatus, row.CustomOrderStatus, row.AssignedRep, row.AssistedRep,
+				c.Issue, c.Action, c.Severity, c.Field, stamp, stamp); err != nil {
+				return nil, fmt.Errorf("upsert finding %s: %w", key, err)
+			}
+		}
+	}
+	res.Open = len(seen)
+
+	for key := range open {
+		if seen[key] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE audit_findings SET resolved_at = ? WHERE finding_key = ?`, stamp, key); err != nil {
+			return nil, fmt.Errorf("resolve finding %s: %w", key, err)
+		}
+		res.Resolved++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit audit run: %w", err)
+	}
+	s.logger.Info("audit_run_complete", map[string]any{"checked": res.Checked, "open": res.Open, "new": res.New, "resolved": res.Resolved})
+	return res, nil
+}
+
+// List returns findings, critical first, then most recently first seen.
+func (s *Service) List(ctx context.Context, f Filter) ([]Finding, error) {
+	var (
+		where []string
+		args  []any
+	)
+	switch strings.ToLower(strings.TrimSpace(f.Status)) {
+	case "", StatusOpen:
+		where = append(where, "resolved_at = ''")
+	case StatusResolved:
+		where = append(where, "resolved_at <> ''")
+	case StatusAll:
+	default:
+		return nil, &ValidationError{Field: "status", Message: "must be open, resolved or all"}
+	}
+	if v := strings.TrimSpace(f.Severity); v != "" {
+		where = append(where, "LOWER(severity) = LOWER(?)")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.Rule); v != "" {
+		where = append(where, "rule = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.RootApptID); v != "" {
+		where = append(where, "root_appt_id = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.Rep); v != "" {
+		where = append(where, "("+reps.MatchSQL("assigned_rep")+" OR "+reps.MatchSQL("assisted_rep")+")")
+		args = append(args, reps.MatchArg(v), reps.MatchArg(v))
+	}
+
+	query := `SELECT id, rule, appt_id, root_appt_id, so_number, customer_name, sales_stage, conversion_status,
+        custom_order_status, assigned_rep, assisted_rep, issue, action, severity, field,
+        first_seen_at, last_seen_at, resolved_at FROM audit_findings`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY CASE severity WHEN '` + SeverityCritical + `' THEN 0 ELSE 1 END, first_seen_at DESC, id LIMIT ? OFFSET ?`
+	args = append(args, clampLimit(f.Limit), max(f.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list findings: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Finding{}
+	for rows.Next() {
+		var fd Finding
+		if err := rows.Scan(&fd.ID, &fd.Rule, &fd.ApptID, &fd.RootApptID, &fd.SONumber, &fd.CustomerName, &fd.SalesStage,
+			&fd.ConversionStatus, &fd.CustomOrderStatus, &fd.AssignedRep, &fd.AssistedRep, &fd.Issue, &fd.Action,
+			&fd.Severity, &fd.Field, &fd.FirstSeenAt, &fd.LastSeenAt, &fd.ResolvedAt); err != nil {
+			return nil, fmt.Errorf("scan finding: %w", err)
+		}
+		out = append(out, fd)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate findings: %w", err)
+	}
+	return out, nil
+}
+
+// loadRows joins each appointment with its client's payment balance, summed
+// over the client's SOs, and notes which deadlines its SO has on record.
+func (s *Service) loadRows(ctx context.Context) ([]Row, error) {
+	recorded, err := s.recordedDeadlines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT a.appt_id, a.root_appt_id, a.so_number, a.customer_name, a.sales_stage,
+        a.conversion_status, a.custom_order_status, a.assigned_rep, a.assisted_rep, a.next_steps, a.brand,
+        b.root_appt_id IS NOT NULL, COALESCE(b.order_total_cents, 0), COALESCE(b.paid_to_date_cents, 0),
+        COALESCE(b.credits_cents, 0), COALESCE(b.remaining_balance_cents, 0), COALESCE(b.last_payment_at, '')
+        FROM appointments a
+        LEFT JOIN root_payment_balances b ON b.root_appt_id = a.root_appt_id
+        ORDER BY a.appt_id`)
+	if err != nil {
+		return nil, fmt.Errorf("select audit rows: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Row
+	for rows.Next() {
+		var r Row
+		if err := rows.Scan(&r.ApptID, &r.RootApptID, &r.SONumber, &r.CustomerName, &r.SalesStage,
+			&r.ConversionStatus, &r.CustomOrderStatus, &r.AssignedRep, &r.AssistedRep, &r.NextSteps, &r.Brand,
+			&r.HasBalance, &r.OrderTotal, &r.PaidToDate, &r.Credits, &r.Remaining, &r.LastPaymentAt); err != nil {
+			return nil, fmt.Errorf("scan audit row: %w", err)
+		}
+		if so := orders.SOKey(r.SONumber); so != "" {
+			key := r.Brand + "|" + so + "|"
+			r.Has3DDeadline = recorded[key+deadlines.Type3D]
+			r.HasProductionDeadline = recorded[key+deadlines.TypeProduction]
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit rows: %w", err)
+	}
+	return out, nil
+}
+
+// recordedDeadlines returns the "brand|so_key|type" of every deadline ever
+// set, moved or met.
+func (s *Service) recordedDeadlines(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT brand, so_key, deadline_type FROM deadline_history`)
+	if err != nil {
+		return nil, fmt.Errorf("select recorded deadlines: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string]bool{}
+	for rows.Next() {
+		var brand, soKey, kind string
+		if err := rows.Scan(&brand, &soKey, &kind); err != nil {
+			return nil, fmt.Errorf("scan recorded deadline: %w", err)
+		}
+		out[brand+"|"+soKey+"|"+kind] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate recorded deadlines: %w", err)
+	}
+	return out, nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
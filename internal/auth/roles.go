@@ -0,0 +1,30 @@
+package auth
+
+import "strings"
+
+// Roles stored in users.role and carried in Claims.Role.
+const (
+	RoleAdmin    = "admin"
+	RoleManager  = "manager"
+	RoleStaff    = "staff"
+	RoleRep      = "rep"
+	RoleReadOnly = "readonly"
+)
+
+// Roles lists every known role, most privileged first.
+var Roles = []string{RoleAdmin, RoleManager, RoleStaff, RoleRep, RoleReadOnly}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeRole lowercases and trims a role name for comparison.
+func NormalizeRole(role string) string {
+	return strings.ToLower(strings.TrimSpace(role))
+}
@@ -17,19 +17,28 @@ import (
 
 // Service provides authentication helpers for login and token validation.
 type Service struct {
-	db       *sql.DB
-	secret   []byte
-	tokenTTL time.Duration
-	logger   *logging.Logger
+	db         *sql.DB
+	secret     []byte
+	tokenTTL   time.Duration
+	refreshTTL time.Duration
+	logger     *logging.Logger
 }
 
 // Claims describes the JWT payload returned to clients.
 type Claims struct {
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
+// Tokens is the pair handed to clients on login and refresh.
+type Tokens struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
 // NewService constructs an auth service from config values.
 func NewService(db *sql.DB, cfg config.AuthConfig, logger *logging.Logger) (*Service, error) {
 	if len(cfg.JWTSecret) < 16 {
@@ -39,42 +48,62 @@ func NewService(db *sql.DB, cfg config.AuthConfig, logger *logging.Logger) (*Ser
 	if ttl <= 0 {
 		ttl = 15 * time.Minute
 	}
+	refreshTTL := time.Duration(cfg.RefreshTTLHours) * time.Hour
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
 	return &Service{
-		db:       db,
-		secret:   []byte(cfg.JWTSecret),
-		tokenTTL: ttl,
-		logger:   logger,
+		db:         db,
+		secret:     []byte(cfg.JWTSecret),
+		tokenTTL:   ttl,
+		refreshTTL: refreshTTL,
+		logger:     logger,
 	}, nil
 }
 
-// Authenticate verifies credentials and returns a signed JWT.
-func (s *Service) Authenticate(ctx context.Context, email, password string) (string, *Claims, error) {
+// Authenticate verifies credentials, opens a session and returns its tokens.
+func (s *Service) Authenticate(ctx context.Context, email, password string) (*Tokens, *Claims, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
-	const query = `SELECT id, password_hash, role FROM users WHERE email = ?`
+	const query = `SELECT id, password_hash, role, active FROM users WHERE email = ?`
 	var (
 		userID       int64
 		passwordHash string
 		role         string
+		active       bool
 	)
-	err := s.db.QueryRowContext(ctx, query, email).Scan(&userID, &passwordHash, &role)
+	err := s.db.QueryRowContext(ctx, query, email).Scan(&userID, &passwordHash, &role, &active)
 	if errors.Is(err, sql.ErrNoRows) {
-		return "", nil, fmt.Errorf("invalid credentials")
+		return nil, nil, fmt.Errorf("invalid credentials")
 	}
 	if err != nil {
-		return "", nil, fmt.Errorf("query user: %w", err)
+		return nil, nil, fmt.Errorf("query user: %w", err)
 	}
 
 	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) != nil {
-		return "", nil, fmt.Errorf("invalid credentials")
+		return nil, nil, fmt.Errorf("invalid credentials")
+	}
+	if !active {
+		return nil, nil, ErrUserDisabled
 	}
 
+	if _, err := s.db.ExecContext(ctx, `UPDATE users SET last_login_at = ? WHERE id = ?`,
+		time.Now().UTC().Format(time.RFC3339), userID); err != nil {
+		return nil, nil, fmt.Errorf("record login: %w", err)
+	}
+
+	return s.openSession(ctx, userID, email, role)
+}
+
+// signAccessToken issues a short-lived JWT bound to sessionID.
+func (s *Service) signAccessToken(userID int64, email, role, sessionID string, now time.Time) (string, *Claims, error) {
 	claims := &Claims{
-		Email: email,
-		Role:  role,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   fmt.Sprint(userID),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.tokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -87,8 +116,9 @@ func (s *Service) Authenticate(ctx context.Context, email, password string) (str
 	return signed, claims, nil
 }
 
-// ParseToken validates the JWT and returns claims when valid.
-func (s *Service) ParseToken(tokenStr string) (*Claims, error) {
+// ParseToken validates the JWT and returns claims when valid and the
+// session it belongs to is still active.
+func (s *Service) ParseToken(ctx context.Context, tokenStr string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (any, error) {
 		if token.Method != jwt.SigningMethodHS256 {
 			return nil, fmt.Errorf("unexpected signing method")
@@ -102,6 +132,9 @@ func (s *Service) ParseToken(tokenStr string) (*Claims, error) {
 	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token claims")
 	}
+	if err := s.checkSession(ctx, claims.SessionID); err != nil {
+		return nil, err
+	}
 	return claims, nil
 }
 
@@ -133,7 +166,7 @@ func SeedAdmin(ctx context.Context, db *sql.DB, seedCfg config.SeedConfig, logge
 	}
 
 	if seedCfg.AdminRole == "" {
-		seedCfg.AdminRole = "admin"
+		seedCfg.AdminRole = RoleAdmin
 	}
 
 	const insert = `INSERT INTO users(email, password_hash, role) VALUES(?, ?, ?)`
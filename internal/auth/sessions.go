@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidRefreshToken is returned for unknown or malformed refresh tokens.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused is returned when an already-rotated refresh token is
+// presented again; the whole session is revoked because the token leaked.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// ErrSessionRevoked is returned when a session was logged out, revoked or expired.
+var ErrSessionRevoked = errors.New("session revoked or expired")
+
+// ErrUserNotFound is returned when revoking sessions for an unknown user.
+var ErrUserNotFound = errors.New("user not found")
+
+// Revocation reasons stored in sessions.revoked_reason.
+const (
+	RevokeLogout = "logout"
+	RevokeAdmin  = "admin"
+	RevokeReuse  = "refresh_reuse"
+
+	RevokePasswordChange = "password_change"
+	RevokePasswordReset  = "password_reset"
+	RevokeRoleChange     = "role_change"
+)
+
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// openSession creates a session row with its first refresh token and signs
+// the matching access token.
+func (s *Service) openSession(ctx context.Context, userID int64, email, role string) (*Tokens, *Claims, error) {
+	now := time.Now().UTC()
+	sessionID := uuid.NewString()
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin session: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stamp := now.Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `INSERT INTO sessions(id, user_id, created_at, last_seen_at, expires_at)
+        VALUES(?, ?, ?, ?, ?)`, sessionID, userID, stamp, stamp, now.Add(s.refreshTTL).Format(time.RFC3339)); err != nil {
+		return nil, nil, fmt.Errorf("insert session: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO refresh_tokens(token_hash, session_id, created_at) VALUES(?, ?, ?)`,
+		hash, sessionID, stamp); err != nil {
+		return nil, nil, fmt.Errorf("insert refresh token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("commit session: %w", err)
+	}
+
+	access, claims, err := s.signAccessToken(userID, email, role, sessionID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Tokens{AccessToken: access, RefreshToken: refresh, ExpiresAt: claims.ExpiresAt.Time}, claims, nil
+}
+
+// Refresh rotates a refresh token: the presented token is marked used and a
+// new pair is issued. Presenting a used token revokes the session.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Tokens, *Claims, error) {
+	refreshToken = strings.TrimSpace(refreshToken)
+	if refreshToken == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	hash := hashToken(refreshToken)
+	now := time.Now().UTC()
+	stamp := now.Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin refresh: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		sessionID, usedAt, revokedAt, expiresAt string
+		userID                                  int64
+		email, role                             string
+		active                                  bool
+	)
+	err = tx.QueryRowContext(ctx, `SELECT rt.session_id, rt.used_at, s.revoked_at, s.expires_at, u.id, u.email, u.role, u.active
+        FROM refresh_tokens rt
+        JOIN sessions s ON s.id = rt.session_id
+        JOIN users u ON u.id = s.user_id
+        WHERE rt.token_hash = ?`, hash).Scan(&sessionID, &usedAt, &revokedAt, &expiresAt, &userID, &email, &role, &active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("lookup refresh token: %w", err)
+	}
+
+	if usedAt != "" {
+		return nil, nil, s.revokeReused(ctx, tx, sessionID, email, revokedAt == "", stamp)
+	}
+	if revokedAt != "" || expiresAt <= stamp || !active {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	refresh, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only one of two concurrent refreshes with the same token can flip
+	// used_at; the loser is treated as reuse.
+	res, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at = ''`, stamp, hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mark refresh token used: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("mark refresh token used: %w", err)
+	}
+	if n != 1 {
+		return nil, nil, s.revokeReused(ctx, tx, sessionID, email, true, stamp)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO refresh_tokens(token_hash, session_id, created_at) VALUES(?, ?, ?)`,
+		newHash, sessionID, stamp); err != nil {
+		return nil, nil, fmt.Errorf("insert refresh token: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?`,
+		stamp, now.Add(s.refreshTTL).Format(time.RFC3339), sessionID); err != nil {
+		return nil, nil, fmt.Errorf("extend session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("commit refresh: %w", err)
+	}
+
+	access, claims, err := s.signAccessToken(userID, email, role, sessionID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Tokens{AccessToken: access, RefreshToken: refresh, ExpiresAt: claims.ExpiresAt.Time}, claims, nil
+}
+
+// revokeReused revokes the session of a reused refresh token, when revoke is
+// set, and returns ErrRefreshTokenReused.
+func (s *Service) revokeReused(ctx context.Context, tx *sql.Tx, sessionID, email string, revoke bool, stamp string) error {
+	if !revoke {
+		return ErrRefreshTokenReused
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = ?, revoked_reason = ? WHERE id = ? AND revoked_at = ''`,
+		stamp, RevokeReuse, sessionID); err != nil {
+		return fmt.Errorf("revoke reused session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit reuse revocation: %w", err)
+	}
+	s.logger.Info("refresh_token_reuse", map[string]any{"session_id": sessionID, "email": email})
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes a single session.
+func (s *Service) Logout(ctx context.Context, sessionID string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = ?, revoked_reason = ? WHERE id = ? AND revoked_at = ''`,
+		time.Now().UTC().Format(time.RFC3339), RevokeLogout, sessionID)
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session for email and returns how
+// many were revoked. Outstanding access tokens stop working immediately.
+func (s *Service) RevokeUserSessions(ctx context.Context, email string) (int64, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE email = ?`, email).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query user: %w", err)
+	}
+
+	n, err := revokeSessions(ctx, s.db, userID, RevokeAdmin)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("sessions_revoked", map[string]any{"email": email, "count": n})
+	return n, nil
+}
+
+func revokeSessions(ctx context.Context, q execer, userID int64, reason string) (int64, error) {
+	res, err := q.ExecContext(ctx, `UPDATE sessions SET revoked_at = ?, revoked_reason = ? WHERE user_id = ? AND revoked_at = ''`,
+		time.Now().UTC().Format(time.RFC3339), reason, userID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke user sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("revoke user sessions: %w", err)
+	}
+	return n, nil
+}
+
+// checkSession rejects access tokens whose session is missing, revoked or expired.
+func (s *Service) checkSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return ErrSessionRevoked
+	}
+	var revokedAt, expiresAt string
+	err := s.db.QueryRowContext(ctx, `SELECT revoked_at, expires_at FROM sessions WHERE id = ?`, sessionID).Scan(&revokedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return fmt.Errorf("lookup session: %w", err)
+	}
+	if revokedAt != "" || expiresAt <= time.Now().UTC().Format(time.RFC3339) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// newRefreshToken returns an opaque token and the hash stored for it.
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+func newTestService(t *testing.T, conn *sql.DB) *Service {
+	t.Helper()
+	svc, err := NewService(conn, config.AuthConfig{JWTSecret: "test-secret-0123456789"}, logging.NewWriter("error", io.Discard))
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	return svc
+}
+
+// login creates an account and signs it in.
+func login(t *testing.T, svc *Service, email, role string) (*User, *Tokens) {
+	t.Helper()
+	ctx := context.Background()
+	u, _, err := svc.CreateUser(ctx, CreateUserInput{Email: email, Password: "correct horse", Role: role}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	tokens, _, err := svc.Authenticate(ctx, email, "correct horse")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	return u, tokens
+}
+
+func TestRefreshRotation(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		svc := newTestService(t, conn)
+		ctx := context.Background()
+		_, first := login(t, svc, "rep@example.com", RoleRep)
+
+		second, claims, err := svc.Refresh(ctx, first.RefreshToken)
+		if err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		if second.RefreshToken == first.RefreshToken || claims.Email != "rep@example.com" {
+			t.Fatalf("refresh = %+v, %+v", second, claims)
+		}
+		third, _, err := svc.Refresh(ctx, second.RefreshToken)
+		if err != nil {
+			t.Fatalf("refresh rotated token: %v", err)
+		}
+
+		// Replaying a rotated token revokes the whole session.
+		if _, _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("replay = %v, want ErrRefreshTokenReused", err)
+		}
+		if _, _, err := svc.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+			t.Errorf("latest token after reuse = %v, want ErrSessionRevoked", err)
+		}
+		if _, err := svc.ParseToken(ctx, third.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+			t.Errorf("access token after reuse = %v, want ErrSessionRevoked", err)
+		}
+		var reason string
+		if err := conn.QueryRowContext(ctx, `SELECT revoked_reason FROM sessions`).Scan(&reason); err != nil || reason != RevokeReuse {
+			t.Errorf("revoked reason = %q, %v", reason, err)
+		}
+
+		for _, bad := range []string{"", "  ", "not-a-token"} {
+			if _, _, err := svc.Refresh(ctx, bad); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("Refresh(%q) = %v, want ErrInvalidRefreshToken", bad, err)
+			}
+		}
+	})
+}
+
+// TestConcurrentRefresh presents one token from several clients at once:
+// exactly one may rotate it and the rest must be treated as reuse.
+func TestConcurrentRefresh(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		svc := newTestService(t, conn)
+		_, tokens := login(t, svc, "rep@example.com", RoleRep)
+
+		const clients = 4
+		errs := make([]error, clients)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, _, errs[i] = svc.Refresh(context.Background(), tokens.RefreshToken)
+			}(i)
+		}
+		wg.Wait()
+
+		rotated := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				rotated++
+			case errors.Is(err, ErrRefreshTokenReused), errors.Is(err, ErrSessionRevoked):
+			default:
+				t.Errorf("refresh: %v", err)
+			}
+		}
+		if rotated != 1 {
+			t.Fatalf("%d clients rotated the same token, want 1", rotated)
+		}
+		var used int
+		if err := conn.QueryRow(`SELECT COUNT(*) FROM refresh_tokens WHERE used_at <> ''`).Scan(&used); err != nil || used != rotated {
+			t.Errorf("used tokens = %d, %v; want %d", used, err, rotated)
+		}
+	})
+}
@@ -0,0 +1,421 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUserDisabled is returned when a deactivated account tries to log in.
+var ErrUserDisabled = errors.New("user is disabled")
+
+// ErrEmailTaken is returned when creating a user whose email already exists.
+var ErrEmailTaken = errors.New("email already in use")
+
+// ErrInvalidResetToken is returned for unknown, used or expired reset tokens.
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// ErrWrongPassword is returned when a password change supplies the wrong current password.
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+// ResetTokenTTL is how long an admin-issued password reset token stays valid.
+const ResetTokenTTL = 72 * time.Hour
+
+// MinPasswordLength is the shortest password accepted on create, change or reset.
+const MinPasswordLength = 8
+
+// ValidationError reports user input that cannot be saved.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// User is a staff account as exposed to admins.
+type User struct {
+	ID          int64  `json:"id"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	DisplayName string `json:"displayName"`
+	// RepName is the "Assigned Rep"/"Assisted Rep" roster name this account acts as.
+	RepName     string `json:"repName"`
+	Active      bool   `json:"active"`
+	LastLoginAt string `json:"lastLoginAt"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// CreateUserInput describes a new account. An empty Password creates an
+// invited account that can only log in after redeeming the returned reset token.
+type CreateUserInput struct {
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	Role        string `json:"role"`
+	DisplayName string `json:"displayName"`
+	RepName     string `json:"repName"`
+}
+
+// UpdateUserInput carries the fields an admin may change; nil leaves a field as is.
+type UpdateUserInput struct {
+	Role        *string `json:"role"`
+	DisplayName *string `json:"displayName"`
+	RepName     *string `json:"repName"`
+	Active      *bool   `json:"active"`
+}
+
+// ResetToken is an admin-issued, single-use password reset credential.
+type ResetToken struct {
+	Token     string `json:"resetToken"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+const userColumns = `id, email, role, display_name, rep_name, active, last_login_at, created_at, updated_at`
+
+// ListUsers returns every account ordered by email.
+func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY email`)
+	if err != nil {
+		return nil, fmt.Errorf("select users: %w", err)
+	}
+	defer rows.Close()
+
+	out := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate users: %w", err)
+	}
+	return out, nil
+}
+
+// GetUser loads one account by id.
+func (s *Service) GetUser(ctx context.Context, id int64) (*User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+// FindUserByEmail loads one account by its (case-insensitive) email.
+func (s *Service) FindUserByEmail(ctx context.Context, email string) (*User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	u, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return u, err
+}
+
+// CreateUser adds an account. When no password is supplied the user is
+// invited: a reset token is returned for the admin to hand over.
+func (s *Service) CreateUser(ctx context.Context, in CreateUserInput, actor string) (*User, *ResetToken, error) {
+	email := strings.ToLower(strings.TrimSpace(in.Email))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, nil, &ValidationError{Field: "email", Message: "must be a valid address"}
+	}
+	role := NormalizeRole(in.Role)
+	if role == "" {
+		role = RoleRep
+	}
+	if !ValidRole(role) {
+		return nil, nil, &ValidationError{Field: "role", Message: "must be one of " + strings.Join(Roles, ", ")}
+	}
+
+	password := in.Password
+	invite := password == ""
+	if invite {
+		// Unusable until the invite token is redeemed.
+		random, _, err := newRefreshToken()
+		if err != nil {
+			return nil, nil, err
+		}
+		password = random
+	} else if err := checkPassword(password); err != nil {
+		return nil, nil, err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hash password: %w", err)
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE email = ?`, email).Scan(&exists)
+	if err == nil {
+		return nil, nil, ErrEmailTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("check email: %w", err)
+	}
+
+	stamp := time.Now().UTC().Format(time.RFC3339)
+	var id int64
+	if err := s.db.QueryRowContext(ctx, `INSERT INTO users(email, password_hash, role, display_name, rep_name, active, updated_at)
+        VALUES(?, ?, ?, ?, ?, 1, ?) RETURNING id`, email, string(hashed), role, strings.TrimSpace(in.DisplayName), strings.TrimSpace(in.RepName), stamp).Scan(&id); err != nil {
+		return nil, nil, fmt.Errorf("insert user: %w", err)
+	}
+	s.logger.Info("user_created", map[string]any{"email": email, "role": role, "actor": actor, "invite": invite})
+
+	var reset *ResetToken
+	if invite {
+		if reset, err = s.IssueResetToken(ctx, id, actor); err != nil {
+			return nil, nil, err
+		}
+	}
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, reset, nil
+}
+
+// UpdateUser changes role, names or the active flag. Deactivating an account
+// or changing its role revokes its sessions, so outstanding tokens (which
+// carry the role) stop working immediately.
+func (s *Service) UpdateUser(ctx context.Context, id int64, in UpdateUserInput, actor string) (*User, error) {
+	u, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	roleChanged := false
+	if in.Role != nil {
+		role := NormalizeRole(*in.Role)
+		if !ValidRole(role) {
+			return nil, &ValidationError{Field: "role", Message: "must be one of " + strings.Join(Roles, ", ")}
+		}
+		roleChanged = role != u.Role
+		u.Role = role
+	}
+	if in.DisplayName != nil {
+		u.DisplayName = strings.TrimSpace(*in.DisplayName)
+	}
+	if in.RepName != nil {
+		u.RepName = strings.TrimSpace(*in.RepName)
+	}
+	deactivated := false
+	if in.Active != nil {
+		deactivated = u.Active && !*in.Active
+		u.Active = *in.Active
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin user update: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stamp := time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET role = ?, display_name = ?, rep_name = ?, active = ?, updated_at = ? WHERE id = ?`,
+		u.Role, u.DisplayName, u.RepName, u.Active, stamp, id); err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	switch {
+	case deactivated:
+		if _, err := revokeSessions(ctx, tx, id, RevokeAdmin); err != nil {
+			return nil, err
+		}
+	case roleChanged:
+		if _, err := revokeSessions(ctx, tx, id, RevokeRoleChange); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit user update: %w", err)
+	}
+	s.logger.Info("user_updated", map[string]any{"email": u.Email, "role": u.Role, "active": u.Active, "actor": actor})
+	return s.GetUser(ctx, id)
+}
+
+// ChangePassword lets a user replace their own password. Every other session
+// for the user is revoked in the same transaction; keepSessionID stays
+// signed in.
+func (s *Service) ChangePassword(ctx context.Context, userID int64, current, next, keepSessionID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin change password: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	var passwordHash string
+	err = tx.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("query user: %w", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(current)) != nil {
+		return ErrWrongPassword
+	}
+	if err := checkPassword(next); err != nil {
+		return err
+	}
+	if err := setPassword(ctx, tx, userID, next); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = ?, revoked_reason = ? WHERE user_id = ? AND id <> ? AND revoked_at = ''`,
+		time.Now().UTC().Format(time.RFC3339), RevokePasswordChange, userID, keepSessionID)
+	if err != nil {
+		return fmt.Errorf("revoke other sessions: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit change password: %w", err)
+	}
+	return nil
+}
+
+// IssueResetToken creates a single-use password reset token for userID.
+func (s *Service) IssueResetToken(ctx context.Context, userID int64, actor string) (*ResetToken, error) {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	expires := now.Add(ResetTokenTTL).Format(time.RFC3339)
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO password_resets(token_hash, user_id, created_by, created_at, expires_at)
+        VALUES(?, ?, ?, ?, ?)`, hash, userID, actor, now.Format(time.RFC3339), expires); err != nil {
+		return nil, fmt.Errorf("insert password reset: %w", err)
+	}
+	s.logger.Info("password_reset_issued", map[string]any{"user_id": userID, "actor": actor})
+	return &ResetToken{Token: token, ExpiresAt: expires}, nil
+}
+
+// SetPassword replaces userID's password without a reset token and revokes
+// all of the user's sessions. It is meant for operators with shell access.
+func (s *Service) SetPassword(ctx context.Context, userID int64, password, actor string) error {
+	if err := checkPassword(password); err != nil {
+		return err
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin set password: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if err := setPassword(ctx, tx, userID, password); err != nil {
+		return err
+	}
+	if _, err := revokeSessions(ctx, tx, userID, RevokePasswordReset); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit set password: %w", err)
+	}
+	s.logger.Info("password_set", map[string]any{"user_id": userID, "actor": actor})
+	return nil
+}
+
+// ResetPassword redeems a reset token, sets the new password and revokes all
+// of the user's sessions in one transaction, so a failure leaves the token
+// usable and the old password in place. It returns the account reset.
+func (s *Service) ResetPassword(ctx context.Context, token, next string) (*User, error) {
+	if err := checkPassword(next); err != nil {
+		return nil, err
+	}
+	hash := hashToken(strings.TrimSpace(token))
+	stamp := time.Now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin password reset: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		userID    int64
+		expiresAt string
+		usedAt    string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT user_id, expires_at, used_at FROM password_resets WHERE token_hash = ?`, hash).
+		Scan(&userID, &expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidResetToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup password reset: %w", err)
+	}
+	if usedAt != "" || expiresAt <= stamp {
+		return nil, ErrInvalidResetToken
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE password_resets SET used_at = ? WHERE token_hash = ? AND used_at = ''`, stamp, hash)
+	if err != nil {
+		return nil, fmt.Errorf("consume password reset: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrInvalidResetToken
+	}
+	if err := setPassword(ctx, tx, userID, next); err != nil {
+		return nil, err
+	}
+	if _, err := revokeSessions(ctx, tx, userID, RevokePasswordReset); err != nil {
+		return nil, err
+	}
+	u, err := scanUser(tx.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, userID))
+	if err != nil {
+		return nil, fmt.Errorf("load reset user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit password reset: %w", err)
+	}
+	return u, nil
+}
+
+func setPassword(ctx context.Context, q execer, userID int64, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`,
+		string(hashed), time.Now().UTC().Format(time.RFC3339), userID); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	return nil
+}
+
+func checkPassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return &ValidationError{Field: "password", Message: fmt.Sprintf("must be at least %d characters", MinPasswordLength)}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	if err := row.Scan(&u.ID, &u.Email, &u.Role, &u.DisplayName, &u.RepName, &u.Active, &u.LastLoginAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan user: %w", err)
+	}
+	return &u, nil
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+)
+
+func TestUpdateUserRevokesSessions(t *testing.T) {
+	svc := newTestService(t, dbtest.Open(t, db.DriverSQLite))
+	ctx := context.Background()
+	u, tokens := login(t, svc, "rep@example.com", RoleRep)
+
+	name := "Jamie"
+	if _, err := svc.UpdateUser(ctx, u.ID, UpdateUserInput{DisplayName: &name}, "admin@example.com"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	same := " REP "
+	if _, err := svc.UpdateUser(ctx, u.ID, UpdateUserInput{Role: &same}, "admin@example.com"); err != nil {
+		t.Fatalf("same role: %v", err)
+	}
+	if _, err := svc.ParseToken(ctx, tokens.AccessToken); err != nil {
+		t.Fatalf("token after rename = %v, want valid", err)
+	}
+
+	bad := "owner"
+	var verr *ValidationError
+	if _, err := svc.UpdateUser(ctx, u.ID, UpdateUserInput{Role: &bad}, "admin@example.com"); !errors.As(err, &verr) || verr.Field != "role" {
+		t.Errorf("bad role = %v, want a role validation error", err)
+	}
+
+	role := RoleManager
+	updated, err := svc.UpdateUser(ctx, u.ID, UpdateUserInput{Role: &role}, "admin@example.com")
+	if err != nil || updated.Role != RoleManager {
+		t.Fatalf("promote = %+v, %v", updated, err)
+	}
+	// The old token still claims "rep"; it must not outlive the change.
+	if _, err := svc.ParseToken(ctx, tokens.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("token after role change = %v, want ErrSessionRevoked", err)
+	}
+	if _, _, err := svc.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("refresh after role change = %v, want ErrSessionRevoked", err)
+	}
+	fresh, claims, err := svc.Authenticate(ctx, "rep@example.com", "correct horse")
+	if err != nil || claims.Role != RoleManager {
+		t.Fatalf("login after role change = %+v, %v", claims, err)
+	}
+
+	off := false
+	if _, err := svc.UpdateUser(ctx, u.ID, UpdateUserInput{Active: &off}, "admin@example.com"); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if _, err := svc.ParseToken(ctx, fresh.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("token after deactivation = %v, want ErrSessionRevoked", err)
+	}
+	if _, _, err := svc.Authenticate(ctx, "rep@example.com", "correct horse"); !errors.Is(err, ErrUserDisabled) {
+		t.Errorf("login while disabled = %v, want ErrUserDisabled", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	svc := newTestService(t, conn)
+	ctx := context.Background()
+	u, tokens := login(t, svc, "rep@example.com", RoleRep)
+
+	reset, err := svc.IssueResetToken(ctx, u.ID, "admin@example.com")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	var verr *ValidationError
+	if _, err := svc.ResetPassword(ctx, reset.Token, "short"); !errors.As(err, &verr) {
+		t.Fatalf("short password = %v, want a validation error", err)
+	}
+	if _, err := svc.ResetPassword(ctx, "unknown", "new password 1"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("unknown token = %v, want ErrInvalidResetToken", err)
+	}
+
+	if got, err := svc.ResetPassword(ctx, " "+reset.Token+" ", "new password 1"); err != nil || got.ID != u.ID {
+		t.Fatalf("reset = %+v, %v", got, err)
+	}
+	if _, err := svc.ParseToken(ctx, tokens.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("token after reset = %v, want ErrSessionRevoked", err)
+	}
+	if _, _, err := svc.Authenticate(ctx, "rep@example.com", "correct horse"); err == nil {
+		t.Error("old password still works")
+	}
+	if _, _, err := svc.Authenticate(ctx, "rep@example.com", "new password 1"); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+	if _, err := svc.ResetPassword(ctx, reset.Token, "new password 2"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("second redeem = %v, want ErrInvalidResetToken", err)
+	}
+
+	expired, err := svc.IssueResetToken(ctx, u.ID, "admin@example.com")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if _, err := conn.Exec(`UPDATE password_resets SET expires_at = '2000-01-01T00:00:00Z' WHERE used_at = ''`); err != nil {
+		t.Fatalf("expire token: %v", err)
+	}
+	if _, err := svc.ResetPassword(ctx, expired.Token, "new password 3"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expired token = %v, want ErrInvalidResetToken", err)
+	}
+	if _, _, err := svc.Authenticate(ctx, "rep@example.com", "new password 1"); err != nil {
+		t.Errorf("password changed by a rejected reset: %v", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	svc := newTestService(t, conn)
+	ctx := context.Background()
+	u, kept := login(t, svc, "rep@example.com", RoleRep)
+	other, _, err := svc.Authenticate(ctx, "rep@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	claims, err := svc.ParseToken(ctx, kept.AccessToken)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if err := svc.ChangePassword(ctx, u.ID, "wrong", "new password 1", claims.SessionID); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong current = %v, want ErrWrongPassword", err)
+	}
+
+	// A failed revoke rolls the password back with it.
+	if _, err := conn.Exec(`CREATE TRIGGER fail_revoke BEFORE UPDATE ON sessions BEGIN SELECT RAISE(ABORT, 'boom'); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+	if err := svc.ChangePassword(ctx, u.ID, "correct horse", "new password 1", claims.SessionID); err == nil {
+		t.Fatal("change password succeeded with a failing revoke")
+	}
+	if _, _, err := svc.Authenticate(ctx, "rep@example.com", "correct horse"); err != nil {
+		t.Errorf("old password after failed change: %v", err)
+	}
+	if _, err := conn.Exec(`DROP TRIGGER fail_revoke`); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+
+	if err := svc.ChangePassword(ctx, u.ID, "correct horse", "new password 1", claims.SessionID); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+	if _, err := svc.ParseToken(ctx, kept.AccessToken); err != nil {
+		t.Errorf("kept session = %v, want signed in", err)
+	}
+	if _, err := svc.ParseToken(ctx, other.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("other session = %v, want ErrSessionRevoked", err)
+	}
+	if _, _, err := svc.Authenticate(ctx, "rep@example.com", "new password 1"); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	svc := newTestService(t, dbtest.Open(t, db.DriverSQLite))
+	ctx := context.Background()
+
+	u, reset, err := svc.CreateUser(ctx, CreateUserInput{Email: " New@Example.com "}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("invite: %v", err)
+	}
+	if u.Email != "new@example.com" || u.Role != RoleRep || reset == nil {
+		t.Fatalf("invite = %+v, %+v", u, reset)
+	}
+	if _, err := svc.ResetPassword(ctx, reset.Token, "first password"); err != nil {
+		t.Fatalf("redeem invite: %v", err)
+	}
+	if _, _, err := svc.Authenticate(ctx, "NEW@example.com", "first password"); err != nil {
+		t.Errorf("login after invite: %v", err)
+	}
+
+	if _, _, err := svc.CreateUser(ctx, CreateUserInput{Email: "new@example.com", Password: "whatever1"}, "admin@example.com"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("duplicate = %v, want ErrEmailTaken", err)
+	}
+	for _, in := range []CreateUserInput{
+		{Email: "nope"},
+		{Email: "x@example.com", Role: "owner"},
+		{Email: "y@example.com", Password: "short"},
+	} {
+		var verr *ValidationError
+		if _, _, err := svc.CreateUser(ctx, in, "admin@example.com"); !errors.As(err, &verr) {
+			t.Errorf("CreateUser(%+v) = %v, want a validation error", in, err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package clientstatus posts status updates to appointments and keeps the
+// client status history behind 03_Client_Status_Log (ClientStatus_v1.js).
+package clientstatus
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// ValidationError reports input that cannot be recorded.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Entry is one row of the client status log.
+type Entry struct {
+	ID                     int64  `json:"id"`
+	ApptID                 string `json:"apptId"`
+	LogDate                string `json:"logDate"`
+	SalesStage             string `json:"salesStage"`
+	ConversionStatus       string `json:"conversionStatus"`
+	CustomOrderStatus      string `json:"customOrderStatus"`
+	InProductionStatus     string `json:"inProductionStatus"`
+	CenterStoneOrderStatus string `json:"centerStoneOrderStatus"`
+	NextSteps              string `json:"nextSteps"`
+	AssistedRep            string `json:"assistedRep"`
+	DeadlineType           string `json:"deadlineType"`
+	DeadlineDate           string `json:"deadlineDate"`
+	MoveCount              int    `json:"moveCount"`
+	UpdatedBy              string `json:"updatedBy"`
+	UpdatedAt              string `json:"updatedAt"`
+	AppliedTo              string `json:"appliedTo"`
+}
+
+// UpdateInput is the status dialog. Nil fields are left unchanged; at least
+// one must be given. InProductionStatus is recorded in the log only, as the
+// Master has no column for it.
+type UpdateInput struct {
+	ApptID             string  `json:"apptId"`
+	SalesStage         *string `json:"salesStage"`
+	ConversionStatus   *string `json:"conversionStatus"`
+	CustomOrderStatus  *string `json:"customOrderStatus"`
+	InProductionStatus *string `json:"inProductionStatus"`
+	NextSteps          *string `json:"nextSteps"`
+}
+
+// Result is the updated appointment and the log row written with it.
+type Result struct {
+	Appointment *appointments.Appointment `json:"appointment"`
+	Entry       *Entry                    `json:"entry"`
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service writes status updates and reads the status history.
+type Service struct {
+	db     *sql.DB
+	appts  *appointments.Service
+	loc    *time.Location
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs a client status service. Log Date is today in loc.
+func NewService(db *sql.DB, appts *appointments.Service, loc *time.Location, logger *logging.Logger) *Service {
+	return &Service{db: db, appts: appts, loc: loc, logger: logger, now: time.Now}
+}
+
+const entryColumns = `id, appt_id, log_date, sales_stage, conversion_status, custom_order_status,
+        in_production_status, center_stone_order_status, next_steps, assisted_rep, deadline_type,
+        deadline_date, move_count, updated_by, updated_at, applied_to`
+
+// Post applies a status update to one appointment and appends the log row
+// for its client in the same transaction, so the two never disagree. Only
+// the supplied columns are written, so concurrent posts that touch different
+// fields both stick; the log row snapshots the appointment after the update.
+func (s *Service) Post(ctx context.Context, in UpdateInput, actor string) (*Result, error) {
+	if in.SalesStage == nil && in.ConversionStatus == nil && in.CustomOrderStatus == nil &&
+		in.InProductionStatus == nil && in.NextSteps == nil {
+		return nil, &ValidationError{Field: "status", Message: "nothing to update"}
+	}
+	var production string
+	if in.InProductionStatus != nil {
+		production = strings.TrimSpace(*in.InProductionStatus)
+	}
+	now := s.now()
+	stamp := now.UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin status update: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE appointments SET
+            sales_stage = COALESCE(?, sales_stage), conversion_status = COALESCE(?, conversion_status),
+            custom_order_status = COALESCE(?, custom_order_status), next_steps = COALESCE(?, next_steps),
+            updated_at = ?
+        WHERE appt_id = ?`,
+		optional(in.SalesStage), optional(in.ConversionStatus), optional(in.CustomOrderStatus), optional(in.NextSteps),
+		stamp, strings.TrimSpace(in.ApptID))
+	if err != nil {
+		return nil, fmt.Errorf("update appointment status: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, appointments.ErrNotFound
+	}
+	// The update holds the row until commit, so this read can't interleave
+	// with another post.
+	appt, err := appointments.GetTx(ctx, tx, in.ApptID)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{
+		ApptID:                 appt.RootApptID,
+		LogDate:                now.In(s.loc).Format("2006-01-02"),
+		SalesStage:             appt.SalesStage,
+		ConversionStatus:       appt.ConversionStatus,
+		CustomOrderStatus:      appt.CustomOrderStatus,
+		InProductionStatus:     production,
+		CenterStoneOrderStatus: appt.CenterStoneOrderStatus,
+		NextSteps:              appt.NextSteps,
+		AssistedRep:            appt.AssistedRep,
+		UpdatedBy:              actor,
+		UpdatedAt:              stamp,
+		AppliedTo:              appt.ApptID,
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO client_status_log(appt_id, log_date, sales_stage, conversion_status,
+            custom_order_status, in_production_status, center_stone_order_status, next_steps, assisted_rep,
+            updated_by, updated_at, applied_to, created_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		e.ApptID, e.LogDate, e.SalesStage, e.ConversionStatus, e.CustomOrderStatus, e.InProductionStatus,
+		e.CenterStoneOrderStatus, e.NextSteps, e.AssistedRep, e.UpdatedBy, e.UpdatedAt, e.AppliedTo, e.UpdatedAt).
+		Scan(&e.ID)
+	if err != nil {
+		return nil, fmt.Errorf("append client status log: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit status update: %w", err)
+	}
+
+	s.logger.Info("client_status_posted", map[string]any{
+		"appt_id":      appt.ApptID,
+		"root_appt_id": appt.RootApptID,
+		"sales_stage":  appt.SalesStage,
+	})
+	return &Result{Appointment: appt, Entry: e}, nil
+}
+
+// optional binds a supplied field trimmed, or NULL so COALESCE keeps the
+// stored value.
+func optional(v *string) any {
+	if v == nil {
+		return nil
+	}
+	return strings.TrimSpace(*v)
+}
+
+// History returns a client's status log, oldest first. Rows logged against
+// any appointment under the root are included.
+func (s *Service) History(ctx context.Context, rootApptID string, limit, offset int) ([]Entry, error) {
+	rootApptID = strings.TrimSpace(rootApptID)
+	if rootApptID == "" {
+		return nil, &ValidationError{Field: "rootApptId", Message: "is required"}
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT `+entryColumns+` FROM client_status_log
+        WHERE appt_id = ? OR appt_id IN (SELECT appt_id FROM appointments WHERE root_appt_id = ?)
+        ORDER BY log_date, updated_at, id LIMIT ? OFFSET ?`,
+		rootApptID, rootApptID, clampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("list client status log: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.ApptID, &e.LogDate, &e.SalesStage, &e.ConversionStatus, &e.CustomOrderStatus,
+			&e.InProductionStatus, &e.CenterStoneOrderStatus, &e.NextSteps, &e.AssistedRep, &e.DeadlineType,
+			&e.DeadlineDate, &e.MoveCount, &e.UpdatedBy, &e.UpdatedAt, &e.AppliedTo); err != nil {
+			return nil, fmt.Errorf("scan client status log: %w", err)
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate client status log: %w", err)
+	}
+	return out, nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
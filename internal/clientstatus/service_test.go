@@ -0,0 +1,106 @@
+package clientstatus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// newTestService returns a service over conn with client R1 (first visit R1,
+// later visit A2) and today 2024-05-10.
+func newTestService(t *testing.T, conn *sql.DB) *Service {
+	t.Helper()
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	ctx := context.Background()
+	for _, a := range []appointments.Appointment{
+		{ApptID: "R1", Brand: appointments.BrandVVS, CustomerName: "Jamie", VisitDate: "2024-04-02",
+			SalesStage: "Consult", AssistedRep: "Bea"},
+		{ApptID: "A2", RootApptID: "R1", Brand: appointments.BrandVVS, CustomerName: "Jamie", VisitDate: "2024-05-01",
+			SalesStage: "Consult", NextSteps: "Send sketches"},
+	} {
+		if _, err := appts.Create(ctx, a); err != nil {
+			t.Fatalf("create %s: %v", a.ApptID, err)
+		}
+	}
+	svc := NewService(conn, appts, time.UTC, logger)
+	svc.now = func() time.Time { return time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func ptr(v string) *string {
+	return &v
+}
+
+func TestPost(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		svc := newTestService(t, conn)
+		ctx := context.Background()
+
+		res, err := svc.Post(ctx, UpdateInput{ApptID: "A2", SalesStage: ptr(" Deposit Paid "),
+			InProductionStatus: ptr("Casting")}, "alice")
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		a := res.Appointment
+		if a.SalesStage != "Deposit Paid" || a.NextSteps != "Send sketches" || a.UpdatedAt != "2024-05-10T15:00:00Z" {
+			t.Errorf("appointment = %+v", a)
+		}
+		e := res.Entry
+		if e.ID == 0 || e.ApptID != "R1" || e.AppliedTo != "A2" || e.LogDate != "2024-05-10" ||
+			e.SalesStage != "Deposit Paid" || e.NextSteps != "Send sketches" || e.InProductionStatus != "Casting" ||
+			e.UpdatedBy != "alice" {
+			t.Errorf("entry = %+v", e)
+		}
+
+		second, err := svc.Post(ctx, UpdateInput{ApptID: "R1", NextSteps: ptr("Call back")}, "bob")
+		if err != nil {
+			t.Fatalf("second post: %v", err)
+		}
+		if second.Entry.ID <= e.ID || second.Entry.SalesStage != "Consult" || second.Entry.AssistedRep != "Bea" {
+			t.Errorf("second entry = %+v", second.Entry)
+		}
+
+		history, err := svc.History(ctx, "R1", 0, 0)
+		if err != nil {
+			t.Fatalf("history: %v", err)
+		}
+		if len(history) != 2 || history[0].ID != e.ID || history[1].ID != second.Entry.ID ||
+			history[1].NextSteps != "Call back" {
+			t.Errorf("history = %+v", history)
+		}
+		stored, err := svc.appts.Get(ctx, "A2")
+		if err != nil || stored.SalesStage != "Deposit Paid" || stored.NextSteps != "Send sketches" {
+			t.Errorf("stored A2 = %+v, %v", stored, err)
+		}
+	})
+}
+
+func TestPostErrors(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		svc := newTestService(t, conn)
+		ctx := context.Background()
+
+		var verr *ValidationError
+		if _, err := svc.Post(ctx, UpdateInput{ApptID: "A2"}, "alice"); !errors.As(err, &verr) {
+			t.Errorf("empty post = %v, want a validation error", err)
+		}
+		if _, err := svc.Post(ctx, UpdateInput{ApptID: "missing", NextSteps: ptr("x")}, "alice"); !errors.Is(err, appointments.ErrNotFound) {
+			t.Errorf("missing appointment = %v, want ErrNotFound", err)
+		}
+		if _, err := svc.History(ctx, " ", 0, 0); !errors.As(err, &verr) {
+			t.Errorf("history without root = %v, want a validation error", err)
+		}
+		var n int
+		if err := conn.QueryRow(`SELECT COUNT(*) FROM client_status_log`).Scan(&n); err != nil || n != 0 {
+			t.Errorf("log rows after failed posts = %d, %v", n, err)
+		}
+	})
+}
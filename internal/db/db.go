@@ -3,16 +3,37 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/example/vvsapp/internal/config"
 
+	"github.com/lib/pq"
 	_ "modernc.org/sqlite"
 )
 
-// Open establishes a SQLite connection using the provided configuration.
+// Supported values of config.DatabaseConfig.Driver.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Open establishes a database connection using the provided configuration.
 func Open(cfg config.DatabaseConfig) (*sql.DB, error) {
+	switch cfg.Driver {
+	case "", DriverSQLite:
+		return openSQLite(cfg)
+	case DriverPostgres:
+		return openPostgres(cfg)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q (expected %s or %s)", cfg.Driver, DriverSQLite, DriverPostgres)
+	}
+}
+
+func openSQLite(cfg config.DatabaseConfig) (*sql.DB, error) {
 	if cfg.Path == "" {
 		return nil, fmt.Errorf("database path is required")
 	}
@@ -37,6 +58,55 @@ func Open(cfg config.DatabaseConfig) (*sql.DB, error) {
 	return conn, nil
 }
 
+func openPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("database dsn is required for postgres")
+	}
+	connector, err := pq.NewConnector(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	conn := sql.OpenDB(pgConnector{inner: connector})
+
+	maxOpen := cfg.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = 10
+	}
+	conn.SetMaxOpenConns(maxOpen)
+	conn.SetMaxIdleConns(maxOpen)
+	conn.SetConnMaxIdleTime(5 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ping postgres: %w", err)
+	}
+
+	return conn, nil
+}
+
+// DriverOf reports which driver opened conn: DriverPostgres for connections
+// from Open with the postgres driver, DriverSQLite otherwise.
+func DriverOf(conn any) string {
+	if d, ok := conn.(interface{ Driver() driver.Driver }); ok {
+		if _, ok := d.Driver().(pgDriver); ok {
+			return DriverPostgres
+		}
+	}
+	return DriverSQLite
+}
+
+// IsUniqueViolation reports whether err is a unique constraint failure from
+// either driver.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
 // Ping verifies the database connection is alive.
 type pinger interface {
 	PingContext(ctx context.Context) error
@@ -0,0 +1,137 @@
+// Package dbtest opens migrated databases for tests on every supported
+// driver. SQLite always runs, against a file in the test's temp directory.
+// Postgres runs when VVSAPP_TEST_POSTGRES_DSN is set; each test gets its own
+// schema, dropped on cleanup, so tests never see each other's rows. Under CI
+// (CI set, as every hosted runner does) a missing DSN fails the Postgres
+// subtests instead of skipping them, so that leg can't silently go untested.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+)
+
+// PostgresDSNEnv names the environment variable holding the Postgres DSN.
+const PostgresDSNEnv = "VVSAPP_TEST_POSTGRES_DSN"
+
+// requirePostgres reports whether this run must include Postgres.
+func requirePostgres() bool {
+	return os.Getenv("CI") != ""
+}
+
+// Drivers lists the drivers this test run covers.
+func Drivers() []string {
+	if os.Getenv(PostgresDSNEnv) != "" || requirePostgres() {
+		return []string{db.DriverSQLite, db.DriverPostgres}
+	}
+	return []string{db.DriverSQLite}
+}
+
+// Run calls fn once per available driver, each in its own subtest with a
+// freshly migrated database.
+func Run(t *testing.T, fn func(t *testing.T, conn *sql.DB)) {
+	t.Helper()
+	for _, driver := range Drivers() {
+		driver := driver
+		t.Run(driver, func(t *testing.T) {
+			fn(t, Open(t, driver))
+		})
+	}
+}
+
+// Open returns a migrated database on driver, closed when t ends.
+func Open(t *testing.T, driver string) *sql.DB {
+	t.Helper()
+	conn := OpenEmpty(t, driver)
+	if _, err := db.RunMigrations(context.Background(), conn); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	return conn
+}
+
+// OpenEmpty returns an unmigrated database on driver, closed when t ends.
+func OpenEmpty(t *testing.T, driver string) *sql.DB {
+	t.Helper()
+	switch driver {
+	case db.DriverSQLite:
+		conn, err := db.Open(config.DatabaseConfig{
+			Driver: db.DriverSQLite,
+			Path:   filepath.Join(t.TempDir(), "test.db"),
+		})
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	case db.DriverPostgres:
+		return openPostgres(t)
+	default:
+		t.Fatalf("unknown driver %q", driver)
+		return nil
+	}
+}
+
+// openPostgres creates a throwaway schema and connects with it as the
+// search_path, so unqualified table names resolve inside it.
+func openPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		if requirePostgres() {
+			t.Fatalf("%s is not set; CI must run the Postgres tests", PostgresDSNEnv)
+		}
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+	admin, err := db.Open(config.DatabaseConfig{Driver: db.DriverPostgres, DSN: dsn, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	var buf [6]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		t.Fatalf("schema name: %v", err)
+	}
+	schema := "vvsapp_test_" + hex.EncodeToString(buf[:])
+	ctx := context.Background()
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.ExecContext(context.Background(), `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	conn, err := db.Open(config.DatabaseConfig{Driver: db.DriverPostgres, DSN: withSearchPath(dsn, schema), MaxOpenConns: 4})
+	if err != nil {
+		t.Fatalf("open postgres schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// withSearchPath adds search_path to a URL or key=value DSN. lib/pq sends
+// unknown parameters to the server as session settings.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
@@ -2,64 +2,181 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// ErrChecksumMismatch is returned when the SQL of an applied migration no
+// longer matches what was recorded when it ran.
+var ErrChecksumMismatch = errors.New("applied migration has changed")
+
+// ErrIrreversible is returned when rolling back a migration without down SQL.
+var ErrIrreversible = errors.New("migration has no down SQL")
+
+// Migration is one schema step, loaded from migrations/NNNN_name.up.sql and
+// the optional NNNN_name.down.sql next to it. A NNNN_name.postgres.up.sql
+// (or .sqlite.) file replaces the shared one for that driver.
 type Migration struct {
 	Version int
 	Name    string
 	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up, recorded when the migration is applied.
+	Checksum string
 }
 
-var migrations = []Migration{
-	{
-		Version: 1,
-		Name:    "create_users",
-		Up: `CREATE TABLE IF NOT EXISTS users (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            email TEXT NOT NULL UNIQUE,
-            password_hash TEXT NOT NULL,
-            role TEXT NOT NULL,
-            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-        );`,
-	},
-	{
-		Version: 2,
-		Name:    "create_customers",
-		Up: `CREATE TABLE IF NOT EXISTS customers (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            business_name TEXT NOT NULL,
-            contact_name TEXT,
-            phone TEXT,
-            email TEXT,
-            city TEXT,
-            state TEXT,
-            zip TEXT,
-            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-        );`,
-	},
-}
-
-// RunMigrations applies pending migrations and returns the versions that were newly applied.
-func RunMigrations(ctx context.Context, conn *sql.DB) ([]Migration, error) {
-	if err := ensureSchemaTable(ctx, conn); err != nil {
-		return nil, err
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationSets holds the migrations of each driver, sorted by version and
+// never modified after load. Every set has the same versions and names.
+var migrationSets = mustLoadMigrationSets(migrationFiles, "migrations")
+
+var migrationFileRE = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)(?:\.(sqlite|postgres))?\.(up|down)\.sql$`)
+
+func mustLoadMigrationSets(fsys fs.FS, dir string) map[string][]Migration {
+	sets := make(map[string][]Migration)
+	for _, driver := range []string{DriverSQLite, DriverPostgres} {
+		ms, err := loadMigrations(fsys, dir, driver)
+		if err != nil {
+			panic(err)
+		}
+		sets[driver] = ms
 	}
+	base := sets[DriverSQLite]
+	for driver, ms := range sets {
+		if len(ms) != len(base) {
+			panic(fmt.Sprintf("%s migrations: %d files, want %d", driver, len(ms), len(base)))
+		}
+		for i := range ms {
+			if ms[i].Version != base[i].Version || ms[i].Name != base[i].Name {
+				panic(fmt.Sprintf("%s migration %d %s does not match %d %s", driver, ms[i].Version, ms[i].Name, base[i].Version, base[i].Name))
+			}
+		}
+	}
+	return sets
+}
 
-	applied, err := fetchApplied(ctx, conn)
+// loadMigrations reads every NNNN_name.up.sql / .down.sql pair in dir,
+// preferring the files specific to driver.
+func loadMigrations(fsys fs.FS, dir, driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	byVersion := make(map[int]*Migration)
+	// specific records which steps came from a driver-specific file, so a
+	// shared file never overrides one regardless of directory order.
+	specific := make(map[string]bool)
+	for _, entry := range entries {
+		match := migrationFileRE.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s: name must look like 0001_create_things.up.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		dialect, step := match[3], match[4]
+		key := match[1] + step
+		if dialect != "" && dialect != driver || dialect == "" && specific[key] {
+			continue
+		}
+		specific[key] = dialect != ""
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+		if step == "up" {
+			m.Up = string(data)
+			m.Checksum = checksum(m.Up)
+		} else {
+			m.Down = string(data)
+		}
 	}
 
-	sort.Slice(migrations, func(i, j int) bool {
-		return migrations[i].Version < migrations[j].Version
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d %s has no up SQL", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Version < out[j].Version
 	})
+	return out, nil
+}
+
+// checksum hashes migration SQL with line endings normalised, so a checkout
+// with CRLF endings does not look like an edit.
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(sql, "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrations returns the known migrations for driver in version order.
+func Migrations(driver string) []Migration {
+	return append([]Migration(nil), migrationsFor(driver)...)
+}
+
+// migrationsFor returns the shared slice for driver; SQLite's for unknown ones.
+func migrationsFor(driver string) []Migration {
+	if ms, ok := migrationSets[driver]; ok {
+		return ms
+	}
+	return migrationSets[DriverSQLite]
+}
+
+// LatestVersion is the highest migration version this build knows about.
+func LatestVersion() int {
+	ms := migrationSets[DriverSQLite]
+	if len(ms) == 0 {
+		return 0
+	}
+	return ms[len(ms)-1].Version
+}
+
+// appliedMigration is one row of schema_migrations.
+type appliedMigration struct {
+	Name      string
+	Checksum  string
+	AppliedAt string
+}
+
+// RunMigrations applies pending migrations and returns the versions that were
+// newly applied. It refuses to run when an applied migration's SQL changed or
+// the database has migrations this build does not know.
+func RunMigrations(ctx context.Context, conn *sql.DB) ([]Migration, error) {
+	migrations := migrationsFor(DriverOf(conn))
+	applied, err := prepareSchemaTable(ctx, conn, migrations)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyApplied(applied, migrations); err != nil {
+		return nil, err
+	}
 
 	var newlyApplied []Migration
 	for _, m := range migrations {
-		if applied[m.Version] {
+		if _, ok := applied[m.Version]; ok {
 			continue
 		}
 		if err := applyMigration(ctx, conn, m); err != nil {
@@ -71,30 +188,159 @@ func RunMigrations(ctx context.Context, conn *sql.DB) ([]Migration, error) {
 	return newlyApplied, nil
 }
 
-func ensureSchemaTable(ctx context.Context, conn *sql.DB) error {
-	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
-        version INTEGER PRIMARY KEY
-    );`)
+// MigrateDown rolls back applied migrations newer than target, newest first,
+// and returns the ones that were rolled back. It stops at the first migration
+// without down SQL.
+func MigrateDown(ctx context.Context, conn *sql.DB, target int) ([]Migration, error) {
+	if target < 0 {
+		return nil, fmt.Errorf("target version must not be negative")
+	}
+	migrations := migrationsFor(DriverOf(conn))
+	applied, err := prepareSchemaTable(ctx, conn, migrations)
 	if err != nil {
-		return fmt.Errorf("ensure schema_migrations: %w", err)
+		return nil, err
+	}
+	if err := verifyApplied(applied, migrations); err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			return rolledBack, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, ErrIrreversible)
+		}
+		if err := revertMigration(ctx, conn, m); err != nil {
+			return rolledBack, err
+		}
+		rolledBack = append(rolledBack, m)
+	}
+	return rolledBack, nil
+}
+
+// verifyApplied checks every applied migration against the known ones.
+func verifyApplied(applied map[int]appliedMigration, migrations []Migration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	for _, v := range versions {
+		m, ok := known[v]
+		if !ok {
+			return fmt.Errorf("database has migration %d which this build does not know; run a newer build", v)
+		}
+		if applied[v].Checksum != m.Checksum {
+			return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, ErrChecksumMismatch)
+		}
 	}
 	return nil
 }
 
-func fetchApplied(ctx context.Context, conn *sql.DB) (map[int]bool, error) {
-	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+// prepareSchemaTable creates schema_migrations, upgrades the version-only
+// table of older databases, and adopts rows that predate checksums by
+// recording the current name and checksum for them.
+func prepareSchemaTable(ctx context.Context, conn *sql.DB, migrations []Migration) (map[int]appliedMigration, error) {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL DEFAULT '',
+        checksum TEXT NOT NULL DEFAULT '',
+        applied_at TEXT NOT NULL DEFAULT ''
+    );`); err != nil {
+		return nil, fmt.Errorf("ensure schema_migrations: %w", err)
+	}
+	cols, err := schemaColumns(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range []string{"name", "checksum", "applied_at"} {
+		if cols[col] {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, `ALTER TABLE schema_migrations ADD COLUMN `+col+` TEXT NOT NULL DEFAULT ''`); err != nil {
+			return nil, fmt.Errorf("upgrade schema_migrations: %w", err)
+		}
+	}
+
+	applied, err := fetchApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		row, ok := applied[m.Version]
+		if !ok || row.Checksum != "" {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, `UPDATE schema_migrations SET name = ?, checksum = ? WHERE version = ?`,
+			m.Name, m.Checksum, m.Version); err != nil {
+			return nil, fmt.Errorf("adopt migration %d: %w", m.Version, err)
+		}
+		row.Name, row.Checksum = m.Name, m.Checksum
+		applied[m.Version] = row
+	}
+	return applied, nil
+}
+
+// querier is the read-only subset of *sql.DB used by Status.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// schemaColumns returns the columns of schema_migrations; none when the
+// table does not exist yet.
+func schemaColumns(ctx context.Context, q querier) (map[string]bool, error) {
+	query := `SELECT name FROM pragma_table_info('schema_migrations')`
+	if DriverOf(q) == DriverPostgres {
+		query = `SELECT column_name FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	}
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("inspect schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("inspect schema_migrations: %w", err)
+		}
+		cols[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("inspect schema_migrations: %w", err)
+	}
+	return cols, nil
+}
+
+func fetchApplied(ctx context.Context, q querier) (map[int]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
 	if err != nil {
 		return nil, fmt.Errorf("select schema_migrations: %w", err)
 	}
 	defer rows.Close()
 
-	applied := make(map[int]bool)
+	applied := make(map[int]appliedMigration)
 	for rows.Next() {
-		var version int
-		if err := rows.Scan(&version); err != nil {
+		var (
+			version int
+			row     appliedMigration
+		)
+		if err := rows.Scan(&version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
 			return nil, fmt.Errorf("scan schema_migrations: %w", err)
 		}
-		applied[version] = true
+		applied[version] = row
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate schema_migrations: %w", err)
@@ -115,7 +361,8 @@ func applyMigration(ctx context.Context, conn *sql.DB, m Migration) error {
 		return fmt.Errorf("exec migration %d: %w", m.Version, err)
 	}
 
-	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations(version) VALUES(?)`, m.Version); err != nil {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES(?, ?, ?, ?)`,
+		m.Version, m.Name, m.Checksum, time.Now().UTC().Format(time.RFC3339)); err != nil {
 		return fmt.Errorf("record migration %d: %w", m.Version, err)
 	}
 
@@ -125,3 +372,24 @@ func applyMigration(ctx context.Context, conn *sql.DB, m Migration) error {
 
 	return nil
 }
+
+func revertMigration(ctx context.Context, conn *sql.DB, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin rollback %d: %w", m.Version, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+		return fmt.Errorf("exec rollback %d: %w", m.Version, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+		return fmt.Errorf("unrecord migration %d: %w", m.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit rollback %d: %w", m.Version, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS appointments (
+    appt_id TEXT PRIMARY KEY,
+    root_appt_id TEXT NOT NULL,
+    brand TEXT NOT NULL,
+    customer_name TEXT NOT NULL,
+    email_lower TEXT NOT NULL DEFAULT '',
+    phone_norm TEXT NOT NULL DEFAULT '',
+    visit_date TEXT NOT NULL,
+    visit_time TEXT NOT NULL DEFAULT '',
+    visit_type TEXT NOT NULL DEFAULT '',
+    visit_number INTEGER NOT NULL DEFAULT 0,
+    assigned_rep TEXT NOT NULL DEFAULT '',
+    assisted_rep TEXT NOT NULL DEFAULT '',
+    sales_stage TEXT NOT NULL DEFAULT '',
+    conversion_status TEXT NOT NULL DEFAULT '',
+    custom_order_status TEXT NOT NULL DEFAULT '',
+    center_stone_order_status TEXT NOT NULL DEFAULT '',
+    next_steps TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_appointments_root ON appointments(root_appt_id);
+CREATE INDEX IF NOT EXISTS idx_appointments_visit_date ON appointments(visit_date);
+CREATE INDEX IF NOT EXISTS idx_appointments_assigned_rep ON appointments(assigned_rep);
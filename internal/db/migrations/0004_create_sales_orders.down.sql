@@ -0,0 +1,4 @@
+ALTER TABLE appointments DROP COLUMN so_linked_at;
+ALTER TABLE appointments DROP COLUMN odoo_so_url;
+ALTER TABLE appointments DROP COLUMN so_number;
+DROP TABLE IF EXISTS sales_orders;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS sales_orders (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    brand TEXT NOT NULL,
+    so_key TEXT NOT NULL,
+    so_pretty TEXT NOT NULL,
+    root_appt_id TEXT NOT NULL,
+    odoo_url TEXT NOT NULL DEFAULT '',
+    linked_at TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    UNIQUE(brand, so_key)
+);
+CREATE INDEX IF NOT EXISTS idx_sales_orders_root ON sales_orders(root_appt_id);
+ALTER TABLE appointments ADD COLUMN so_number TEXT NOT NULL DEFAULT '';
+ALTER TABLE appointments ADD COLUMN odoo_so_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE appointments ADD COLUMN so_linked_at TEXT NOT NULL DEFAULT '';
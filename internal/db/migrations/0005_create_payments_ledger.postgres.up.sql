@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id TEXT PRIMARY KEY,
+    brand TEXT NOT NULL,
+    root_appt_id TEXT NOT NULL,
+    so_number TEXT NOT NULL DEFAULT '',
+    anchor_type TEXT NOT NULL,
+    basket_id TEXT NOT NULL,
+    customer_name TEXT NOT NULL DEFAULT '',
+    doc_type TEXT NOT NULL,
+    doc_kind TEXT NOT NULL,
+    doc_role TEXT NOT NULL DEFAULT '',
+    doc_status TEXT NOT NULL DEFAULT 'DRAFT',
+    payment_date_time TEXT NOT NULL DEFAULT '',
+    method TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    lines_json TEXT NOT NULL,
+    subtotal_cents BIGINT NOT NULL,
+    amount_gross_cents BIGINT NOT NULL DEFAULT 0,
+    fee_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fee_amount_cents BIGINT NOT NULL DEFAULT 0,
+    amount_net_cents BIGINT NOT NULL DEFAULT 0,
+    allocated_to_so_cents BIGINT NOT NULL DEFAULT 0,
+    requested_amount_cents BIGINT NOT NULL DEFAULT 0,
+    order_total_cents BIGINT NOT NULL DEFAULT 0,
+    paid_before_cents BIGINT NOT NULL DEFAULT 0,
+    balance_before_cents BIGINT NOT NULL DEFAULT 0,
+    paid_after_cents BIGINT NOT NULL DEFAULT 0,
+    balance_after_cents BIGINT NOT NULL DEFAULT 0,
+    submitted_by TEXT NOT NULL DEFAULT '',
+    submitted_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_payments_root ON payments(root_appt_id);
+CREATE INDEX IF NOT EXISTS idx_payments_so ON payments(brand, so_number);
+CREATE INDEX IF NOT EXISTS idx_payments_submitted_at ON payments(submitted_at);
+CREATE TABLE IF NOT EXISTS payment_balances (
+    root_appt_id TEXT PRIMARY KEY,
+    brand TEXT NOT NULL,
+    so_number TEXT NOT NULL DEFAULT '',
+    order_total_cents BIGINT NOT NULL DEFAULT 0,
+    paid_to_date_cents BIGINT NOT NULL DEFAULT 0,
+    credits_cents BIGINT NOT NULL DEFAULT 0,
+    remaining_balance_cents BIGINT NOT NULL DEFAULT 0,
+    last_payment_at TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL
+);
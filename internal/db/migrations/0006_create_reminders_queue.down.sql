@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reminders_log;
+DROP TABLE IF EXISTS reminders_queue;
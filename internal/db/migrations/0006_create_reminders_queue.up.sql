@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS reminders_queue (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    so_number TEXT NOT NULL DEFAULT '',
+    root_appt_id TEXT NOT NULL DEFAULT '',
+    customer_name TEXT NOT NULL DEFAULT '',
+    next_steps TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    assigned_rep TEXT NOT NULL DEFAULT '',
+    assisted_rep TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    first_due_date TEXT NOT NULL,
+    next_due_at TEXT NOT NULL,
+    snooze_until TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_sent_at TEXT NOT NULL DEFAULT '',
+    confirmed_at TEXT NOT NULL DEFAULT '',
+    confirmed_by TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    created_by TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_so ON reminders_queue(so_number);
+CREATE INDEX IF NOT EXISTS idx_reminders_due ON reminders_queue(status, next_due_at);
+CREATE TABLE IF NOT EXISTS reminders_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reminder_id TEXT NOT NULL,
+    so_number TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_log_reminder ON reminders_log(reminder_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS job_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_name TEXT NOT NULL,
+    trigger TEXT NOT NULL,
+    triggered_by TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TEXT NOT NULL,
+    finished_at TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job ON job_runs(job_name, id);
+CREATE INDEX IF NOT EXISTS idx_job_runs_status ON job_runs(status);
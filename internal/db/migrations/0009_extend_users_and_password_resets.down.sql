@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS password_resets;
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN last_login_at;
+ALTER TABLE users DROP COLUMN active;
+ALTER TABLE users DROP COLUMN rep_name;
+ALTER TABLE users DROP COLUMN display_name;
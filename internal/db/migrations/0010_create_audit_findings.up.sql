@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS audit_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    finding_key TEXT NOT NULL UNIQUE,
+    rule TEXT NOT NULL,
+    appt_id TEXT NOT NULL,
+    root_appt_id TEXT NOT NULL DEFAULT '',
+    so_number TEXT NOT NULL DEFAULT '',
+    customer_name TEXT NOT NULL DEFAULT '',
+    sales_stage TEXT NOT NULL DEFAULT '',
+    conversion_status TEXT NOT NULL DEFAULT '',
+    custom_order_status TEXT NOT NULL DEFAULT '',
+    assigned_rep TEXT NOT NULL DEFAULT '',
+    assisted_rep TEXT NOT NULL DEFAULT '',
+    issue TEXT NOT NULL,
+    action TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    field TEXT NOT NULL DEFAULT '',
+    first_seen_at TEXT NOT NULL,
+    last_seen_at TEXT NOT NULL,
+    resolved_at TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_audit_findings_open ON audit_findings(resolved_at, severity);
+CREATE INDEX IF NOT EXISTS idx_audit_findings_appt ON audit_findings(appt_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS kpi_history (
+    as_of TEXT NOT NULL,
+    brand TEXT NOT NULL DEFAULT '',
+    rep TEXT NOT NULL DEFAULT '',
+    kpi TEXT NOT NULL,
+    value REAL NOT NULL,
+    window_start TEXT NOT NULL,
+    window_end TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (brand, rep, kpi, as_of)
+);
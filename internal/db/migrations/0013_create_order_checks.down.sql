@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payment_balances_remaining;
+DROP INDEX IF EXISTS idx_appointments_custom_order_status;
+DROP INDEX IF EXISTS idx_appointments_root_visit;
+DROP INDEX IF EXISTS idx_sales_orders_linked_at;
+DROP TABLE IF EXISTS order_checks;
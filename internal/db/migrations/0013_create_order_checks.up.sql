@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS order_checks (
+    check_type TEXT NOT NULL,
+    brand TEXT NOT NULL,
+    so_key TEXT NOT NULL,
+    reviewed_at TEXT NOT NULL DEFAULT '',
+    reviewed_by TEXT NOT NULL DEFAULT '',
+    snooze_until TEXT NOT NULL DEFAULT '',
+    snoozed_by TEXT NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (check_type, brand, so_key)
+);
+CREATE INDEX IF NOT EXISTS idx_sales_orders_linked_at ON sales_orders(linked_at);
+CREATE INDEX IF NOT EXISTS idx_appointments_root_visit ON appointments(root_appt_id, visit_date, visit_time, appt_id);
+CREATE INDEX IF NOT EXISTS idx_appointments_custom_order_status ON appointments(custom_order_status);
+CREATE INDEX IF NOT EXISTS idx_payment_balances_remaining ON payment_balances(remaining_balance_cents);
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS search_payments_au;
+DROP TRIGGER IF EXISTS search_payments_ad;
+DROP TRIGGER IF EXISTS search_payments_ai;
+DROP TRIGGER IF EXISTS search_sales_orders_au;
+DROP TRIGGER IF EXISTS search_sales_orders_ad;
+DROP TRIGGER IF EXISTS search_sales_orders_ai;
+DROP TRIGGER IF EXISTS search_appointments_au;
+DROP TRIGGER IF EXISTS search_appointments_ad;
+DROP TRIGGER IF EXISTS search_appointments_ai;
+DROP TABLE IF EXISTS search_payments;
+DROP TABLE IF EXISTS search_orders;
+DROP TABLE IF EXISTS search_customers;
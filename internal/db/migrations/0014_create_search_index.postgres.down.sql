@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS search_payments_sync ON payments;
+DROP TRIGGER IF EXISTS search_sales_orders_sync ON sales_orders;
+DROP TRIGGER IF EXISTS search_appointments_sync ON appointments;
+DROP FUNCTION IF EXISTS search_payments_sync();
+DROP FUNCTION IF EXISTS search_sales_orders_sync();
+DROP FUNCTION IF EXISTS search_appointments_sync();
+DROP TABLE IF EXISTS search_payments;
+DROP TABLE IF EXISTS search_orders;
+DROP TABLE IF EXISTS search_customers;
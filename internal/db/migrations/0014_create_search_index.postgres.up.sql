@@ -0,0 +1,103 @@
+-- Postgres keeps the search documents in plain tables keyed by the source
+-- row, with a generated tsvector over the text folded to letters and digits
+-- so words split the same way the query builder splits them. Phones are
+-- indexed whole and by their last 10, 7 and 4 digits, and SOs by both the
+-- dotted form and the 6-digit key, so partial input matches.
+CREATE TABLE IF NOT EXISTS search_customers (
+    appt_id TEXT PRIMARY KEY,
+    root_appt_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    contact TEXT NOT NULL DEFAULT '',
+    so TEXT NOT NULL DEFAULT '',
+    doc tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', regexp_replace(lower(name || ' ' || contact || ' ' || so), '[^[:alnum:]]+', ' ', 'g'))
+    ) STORED
+);
+CREATE INDEX IF NOT EXISTS idx_search_customers_doc ON search_customers USING GIN (doc);
+CREATE TABLE IF NOT EXISTS search_orders (
+    order_id BIGINT PRIMARY KEY,
+    root_appt_id TEXT NOT NULL,
+    so TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    doc tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', regexp_replace(lower(so || ' ' || name), '[^[:alnum:]]+', ' ', 'g'))
+    ) STORED
+);
+CREATE INDEX IF NOT EXISTS idx_search_orders_doc ON search_orders USING GIN (doc);
+CREATE INDEX IF NOT EXISTS idx_search_orders_root ON search_orders(root_appt_id);
+CREATE TABLE IF NOT EXISTS search_payments (
+    payment_id TEXT PRIMARY KEY,
+    root_appt_id TEXT NOT NULL,
+    ref TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    doc_text TEXT NOT NULL DEFAULT '',
+    doc tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', regexp_replace(lower(ref || ' ' || name || ' ' || doc_text), '[^[:alnum:]]+', ' ', 'g'))
+    ) STORED
+);
+CREATE INDEX IF NOT EXISTS idx_search_payments_doc ON search_payments USING GIN (doc);
+
+CREATE OR REPLACE FUNCTION search_appointments_sync() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        DELETE FROM search_customers WHERE appt_id = OLD.appt_id;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        INSERT INTO search_customers(appt_id, root_appt_id, name, contact, so) VALUES (NEW.appt_id, NEW.root_appt_id, NEW.customer_name,
+            NEW.email_lower || ' ' || substr(NEW.phone_norm, 2) || ' ' || right(NEW.phone_norm, 10) || ' ' || right(NEW.phone_norm, 7) || ' ' || right(NEW.phone_norm, 4),
+            NEW.so_number || ' ' || replace(NEW.so_number, '.', ''));
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.customer_name <> NEW.customer_name THEN
+        UPDATE search_orders SET name = NEW.customer_name WHERE root_appt_id = NEW.root_appt_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER search_appointments_sync AFTER INSERT OR UPDATE OR DELETE ON appointments
+    FOR EACH ROW EXECUTE FUNCTION search_appointments_sync();
+
+CREATE OR REPLACE FUNCTION search_sales_orders_sync() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        DELETE FROM search_orders WHERE order_id = OLD.id;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        INSERT INTO search_orders(order_id, root_appt_id, so, name) VALUES (NEW.id, NEW.root_appt_id,
+            NEW.brand || ' ' || NEW.so_pretty || ' ' || NEW.so_key,
+            COALESCE((SELECT customer_name FROM appointments WHERE root_appt_id = NEW.root_appt_id ORDER BY visit_date DESC LIMIT 1), ''));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER search_sales_orders_sync AFTER INSERT OR UPDATE OR DELETE ON sales_orders
+    FOR EACH ROW EXECUTE FUNCTION search_sales_orders_sync();
+
+CREATE OR REPLACE FUNCTION search_payments_sync() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        DELETE FROM search_payments WHERE payment_id = OLD.payment_id;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        INSERT INTO search_payments(payment_id, root_appt_id, ref, name, doc_text) VALUES (NEW.payment_id, NEW.root_appt_id,
+            NEW.payment_id || ' ' || NEW.reference || ' ' || NEW.so_number || ' ' || replace(NEW.so_number, '.', ''),
+            NEW.customer_name, NEW.doc_type || ' ' || NEW.method);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER search_payments_sync AFTER INSERT OR UPDATE OR DELETE ON payments
+    FOR EACH ROW EXECUTE FUNCTION search_payments_sync();
+
+INSERT INTO search_customers(appt_id, root_appt_id, name, contact, so)
+    SELECT appt_id, root_appt_id, customer_name,
+        email_lower || ' ' || substr(phone_norm, 2) || ' ' || right(phone_norm, 10) || ' ' || right(phone_norm, 7) || ' ' || right(phone_norm, 4),
+        so_number || ' ' || replace(so_number, '.', '')
+    FROM appointments;
+INSERT INTO search_orders(order_id, root_appt_id, so, name)
+    SELECT o.id, o.root_appt_id, o.brand || ' ' || o.so_pretty || ' ' || o.so_key,
+        COALESCE((SELECT customer_name FROM appointments WHERE root_appt_id = o.root_appt_id ORDER BY visit_date DESC LIMIT 1), '')
+    FROM sales_orders o;
+INSERT INTO search_payments(payment_id, root_appt_id, ref, name, doc_text)
+    SELECT payment_id, root_appt_id, payment_id || ' ' || reference || ' ' || so_number || ' ' || replace(so_number, '.', ''),
+        customer_name, doc_type || ' ' || method
+    FROM payments;
@@ -0,0 +1,76 @@
+-- Each FTS table shares its rowid with the source row. Phones are indexed
+-- whole and by their last 10, 7 and 4 digits, and SOs by both the dotted form
+-- and the 6-digit key, so partial input matches.
+CREATE VIRTUAL TABLE IF NOT EXISTS search_customers USING fts5(
+    root_appt_id UNINDEXED, name, contact, so,
+    tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3 4'
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS search_orders USING fts5(
+    root_appt_id UNINDEXED, so, name,
+    tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3 4'
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS search_payments USING fts5(
+    root_appt_id UNINDEXED, ref, name, doc,
+    tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3 4'
+);
+
+CREATE TRIGGER IF NOT EXISTS search_appointments_ai AFTER INSERT ON appointments BEGIN
+    INSERT INTO search_customers(rowid, root_appt_id, name, contact, so) VALUES (new.rowid, new.root_appt_id, new.customer_name,
+        new.email_lower || ' ' || SUBSTR(new.phone_norm, 2) || ' ' || SUBSTR(new.phone_norm, -10) || ' ' || SUBSTR(new.phone_norm, -7) || ' ' || SUBSTR(new.phone_norm, -4),
+        new.so_number || ' ' || REPLACE(new.so_number, '.', ''));
+END;
+CREATE TRIGGER IF NOT EXISTS search_appointments_ad AFTER DELETE ON appointments BEGIN
+    DELETE FROM search_customers WHERE rowid = old.rowid;
+END;
+CREATE TRIGGER IF NOT EXISTS search_appointments_au AFTER UPDATE ON appointments BEGIN
+    DELETE FROM search_customers WHERE rowid = old.rowid;
+    INSERT INTO search_customers(rowid, root_appt_id, name, contact, so) VALUES (new.rowid, new.root_appt_id, new.customer_name,
+        new.email_lower || ' ' || SUBSTR(new.phone_norm, 2) || ' ' || SUBSTR(new.phone_norm, -10) || ' ' || SUBSTR(new.phone_norm, -7) || ' ' || SUBSTR(new.phone_norm, -4),
+        new.so_number || ' ' || REPLACE(new.so_number, '.', ''));
+    UPDATE search_orders SET name = new.customer_name
+        WHERE root_appt_id = new.root_appt_id AND old.customer_name <> new.customer_name;
+END;
+
+CREATE TRIGGER IF NOT EXISTS search_sales_orders_ai AFTER INSERT ON sales_orders BEGIN
+    INSERT INTO search_orders(rowid, root_appt_id, so, name) VALUES (new.id, new.root_appt_id,
+        new.brand || ' ' || new.so_pretty || ' ' || new.so_key,
+        COALESCE((SELECT customer_name FROM appointments WHERE root_appt_id = new.root_appt_id ORDER BY visit_date DESC LIMIT 1), ''));
+END;
+CREATE TRIGGER IF NOT EXISTS search_sales_orders_ad AFTER DELETE ON sales_orders BEGIN
+    DELETE FROM search_orders WHERE rowid = old.id;
+END;
+CREATE TRIGGER IF NOT EXISTS search_sales_orders_au AFTER UPDATE ON sales_orders BEGIN
+    DELETE FROM search_orders WHERE rowid = old.id;
+    INSERT INTO search_orders(rowid, root_appt_id, so, name) VALUES (new.id, new.root_appt_id,
+        new.brand || ' ' || new.so_pretty || ' ' || new.so_key,
+        COALESCE((SELECT customer_name FROM appointments WHERE root_appt_id = new.root_appt_id ORDER BY visit_date DESC LIMIT 1), ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS search_payments_ai AFTER INSERT ON payments BEGIN
+    INSERT INTO search_payments(rowid, root_appt_id, ref, name, doc) VALUES (new.rowid, new.root_appt_id,
+        new.payment_id || ' ' || new.reference || ' ' || new.so_number || ' ' || REPLACE(new.so_number, '.', ''),
+        new.customer_name, new.doc_type || ' ' || new.method);
+END;
+CREATE TRIGGER IF NOT EXISTS search_payments_ad AFTER DELETE ON payments BEGIN
+    DELETE FROM search_payments WHERE rowid = old.rowid;
+END;
+CREATE TRIGGER IF NOT EXISTS search_payments_au AFTER UPDATE ON payments BEGIN
+    DELETE FROM search_payments WHERE rowid = old.rowid;
+    INSERT INTO search_payments(rowid, root_appt_id, ref, name, doc) VALUES (new.rowid, new.root_appt_id,
+        new.payment_id || ' ' || new.reference || ' ' || new.so_number || ' ' || REPLACE(new.so_number, '.', ''),
+        new.customer_name, new.doc_type || ' ' || new.method);
+END;
+
+INSERT INTO search_customers(rowid, root_appt_id, name, contact, so)
+    SELECT rowid, root_appt_id, customer_name,
+        email_lower || ' ' || SUBSTR(phone_norm, 2) || ' ' || SUBSTR(phone_norm, -10) || ' ' || SUBSTR(phone_norm, -7) || ' ' || SUBSTR(phone_norm, -4),
+        so_number || ' ' || REPLACE(so_number, '.', '')
+    FROM appointments;
+INSERT INTO search_orders(rowid, root_appt_id, so, name)
+    SELECT o.id, o.root_appt_id, o.brand || ' ' || o.so_pretty || ' ' || o.so_key,
+        COALESCE((SELECT customer_name FROM appointments WHERE root_appt_id = o.root_appt_id ORDER BY visit_date DESC LIMIT 1), '')
+    FROM sales_orders o;
+INSERT INTO search_payments(rowid, root_appt_id, ref, name, doc)
+    SELECT rowid, root_appt_id, payment_id || ' ' || reference || ' ' || so_number || ' ' || REPLACE(so_number, '.', ''),
+        customer_name, doc_type || ' ' || method
+    FROM payments;
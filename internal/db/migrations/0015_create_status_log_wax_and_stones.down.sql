@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stones;
+DROP TABLE IF EXISTS wax_requests;
+DROP TABLE IF EXISTS client_status_log;
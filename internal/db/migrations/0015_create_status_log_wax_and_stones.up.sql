@@ -0,0 +1,78 @@
+-- Landing tables for the sheets that had no table yet, so the Sheets importer
+-- can load them. source_key holds the importer's row key.
+CREATE TABLE IF NOT EXISTS client_status_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    appt_id TEXT NOT NULL,
+    log_date TEXT NOT NULL DEFAULT '',
+    sales_stage TEXT NOT NULL DEFAULT '',
+    conversion_status TEXT NOT NULL DEFAULT '',
+    custom_order_status TEXT NOT NULL DEFAULT '',
+    in_production_status TEXT NOT NULL DEFAULT '',
+    center_stone_order_status TEXT NOT NULL DEFAULT '',
+    next_steps TEXT NOT NULL DEFAULT '',
+    assisted_rep TEXT NOT NULL DEFAULT '',
+    updated_by TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL DEFAULT '',
+    applied_to TEXT NOT NULL DEFAULT '',
+    source_key TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_client_status_log_appt ON client_status_log(appt_id, id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_client_status_log_source ON client_status_log(source_key) WHERE source_key <> '';
+CREATE TABLE IF NOT EXISTS wax_requests (
+    wax_request_id TEXT PRIMARY KEY,
+    root_appt_id TEXT NOT NULL DEFAULT '',
+    so_number TEXT NOT NULL DEFAULT '',
+    brand TEXT NOT NULL DEFAULT '',
+    customer_name TEXT NOT NULL DEFAULT '',
+    assigned_rep TEXT NOT NULL DEFAULT '',
+    assisted_rep TEXT NOT NULL DEFAULT '',
+    requested_by TEXT NOT NULL DEFAULT '',
+    priority TEXT NOT NULL DEFAULT '',
+    requested_date TEXT NOT NULL DEFAULT '',
+    needed_by TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT '',
+    admin_deadline TEXT NOT NULL DEFAULT '',
+    estimated_print_date TEXT NOT NULL DEFAULT '',
+    completed_print_date TEXT NOT NULL DEFAULT '',
+    status_notes TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_by TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_wax_requests_so ON wax_requests(brand, so_number);
+CREATE INDEX IF NOT EXISTS idx_wax_requests_root ON wax_requests(root_appt_id);
+CREATE TABLE IF NOT EXISTS stones (
+    stone_id TEXT PRIMARY KEY,
+    root_appt_id TEXT NOT NULL DEFAULT '',
+    brand TEXT NOT NULL DEFAULT '',
+    customer_name TEXT NOT NULL DEFAULT '',
+    assigned_rep TEXT NOT NULL DEFAULT '',
+    appt_time TEXT NOT NULL DEFAULT '',
+    stone_status TEXT NOT NULL DEFAULT '',
+    order_status TEXT NOT NULL DEFAULT '',
+    decision TEXT NOT NULL DEFAULT '',
+    requested_by TEXT NOT NULL DEFAULT '',
+    request_date TEXT NOT NULL DEFAULT '',
+    ordered_by TEXT NOT NULL DEFAULT '',
+    ordered_date TEXT NOT NULL DEFAULT '',
+    vendor TEXT NOT NULL DEFAULT '',
+    stone_type TEXT NOT NULL DEFAULT '',
+    shape TEXT NOT NULL DEFAULT '',
+    carat REAL NOT NULL DEFAULT 0,
+    color TEXT NOT NULL DEFAULT '',
+    clarity TEXT NOT NULL DEFAULT '',
+    cut TEXT NOT NULL DEFAULT '',
+    polish TEXT NOT NULL DEFAULT '',
+    symmetry TEXT NOT NULL DEFAULT '',
+    fluor_intensity TEXT NOT NULL DEFAULT '',
+    fluor_color TEXT NOT NULL DEFAULT '',
+    measurements TEXT NOT NULL DEFAULT '',
+    lw_ratio TEXT NOT NULL DEFAULT '',
+    lab TEXT NOT NULL DEFAULT '',
+    certificate_no TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_stones_root ON stones(root_appt_id);
+CREATE INDEX IF NOT EXISTS idx_stones_certificate ON stones(certificate_no);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_stones_order_status;
+ALTER TABLE stones DROP COLUMN updated_by;
+ALTER TABLE stones DROP COLUMN return_due_date;
+ALTER TABLE stones DROP COLUMN memo_date;
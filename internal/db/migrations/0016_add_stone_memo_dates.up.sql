@@ -0,0 +1,6 @@
+-- Memo/ Invoice Date and Return DUE DATE from Confirm Delivery, plus who
+-- last moved the stone through its lifecycle.
+ALTER TABLE stones ADD COLUMN memo_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE stones ADD COLUMN return_due_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE stones ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_stones_order_status ON stones(order_status);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_wax_requests_status;
+ALTER TABLE sales_orders DROP COLUMN wax_deadline;
+ALTER TABLE sales_orders DROP COLUMN wax_status;
+ALTER TABLE sales_orders DROP COLUMN wax_request_id;
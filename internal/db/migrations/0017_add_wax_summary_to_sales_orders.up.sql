@@ -0,0 +1,6 @@
+-- Wax Print Status, Wax Deadline (Admin) and the latest request, mirrored
+-- onto the order by the wax service (the Master's Wax Request URL columns).
+ALTER TABLE sales_orders ADD COLUMN wax_request_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales_orders ADD COLUMN wax_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales_orders ADD COLUMN wax_deadline TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_wax_requests_status ON wax_requests(status);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS design_revisions;
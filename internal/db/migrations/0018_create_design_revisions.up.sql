@@ -0,0 +1,23 @@
+-- The 3D Tracker: one row per Start 3D (Revision # 0) or revision request
+-- on an SO. spec and attachments hold JSON; design_request is the rendered
+-- Design Request text as it was submitted.
+CREATE TABLE IF NOT EXISTS design_revisions (
+    design_id TEXT PRIMARY KEY,
+    brand TEXT NOT NULL,
+    so_number TEXT NOT NULL,
+    so_key TEXT NOT NULL,
+    root_appt_id TEXT NOT NULL DEFAULT '',
+    revision_no INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    customer_name TEXT NOT NULL DEFAULT '',
+    assigned_rep TEXT NOT NULL DEFAULT '',
+    assisted_rep TEXT NOT NULL DEFAULT '',
+    requested_by TEXT NOT NULL DEFAULT '',
+    spec TEXT NOT NULL DEFAULT '{}',
+    design_request TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    attachments TEXT NOT NULL DEFAULT '[]',
+    created_at TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_design_revisions_so_rev ON design_revisions(brand, so_key, revision_no);
+CREATE INDEX IF NOT EXISTS idx_design_revisions_root ON design_revisions(root_appt_id);
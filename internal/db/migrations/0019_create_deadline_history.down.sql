@@ -0,0 +1,4 @@
+ALTER TABLE client_status_log DROP COLUMN move_count;
+ALTER TABLE client_status_log DROP COLUMN deadline_date;
+ALTER TABLE client_status_log DROP COLUMN deadline_type;
+DROP TABLE IF EXISTS deadline_history;
@@ -0,0 +1,23 @@
+-- Every set, move or completion of an order's 3D or Production Deadline.
+-- The current deadline is the latest row per type; "# of Times Moved" is
+-- the number of moved rows.
+CREATE TABLE IF NOT EXISTS deadline_history (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    brand TEXT NOT NULL,
+    so_number TEXT NOT NULL,
+    so_key TEXT NOT NULL,
+    root_appt_id TEXT NOT NULL DEFAULT '',
+    deadline_type TEXT NOT NULL,
+    action TEXT NOT NULL,
+    deadline_date TEXT NOT NULL DEFAULT '',
+    previous_date TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    updated_by TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_deadline_history_so ON deadline_history(brand, so_key, deadline_type, id);
+CREATE INDEX IF NOT EXISTS idx_deadline_history_date ON deadline_history(deadline_date);
+-- Deadline rows in the client status report (Deadlines_v1.js).
+ALTER TABLE client_status_log ADD COLUMN deadline_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE client_status_log ADD COLUMN deadline_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE client_status_log ADD COLUMN move_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,21 @@
+DROP VIEW IF EXISTS root_payment_balances;
+CREATE TABLE IF NOT EXISTS payment_root_balances (
+    root_appt_id TEXT PRIMARY KEY,
+    brand TEXT NOT NULL,
+    so_number TEXT NOT NULL DEFAULT '',
+    order_total_cents INTEGER NOT NULL DEFAULT 0,
+    paid_to_date_cents INTEGER NOT NULL DEFAULT 0,
+    credits_cents INTEGER NOT NULL DEFAULT 0,
+    remaining_balance_cents INTEGER NOT NULL DEFAULT 0,
+    last_payment_at TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL
+);
+INSERT INTO payment_root_balances(root_appt_id, brand, so_number, order_total_cents, paid_to_date_cents,
+    credits_cents, remaining_balance_cents, last_payment_at, updated_at)
+SELECT root_appt_id, MAX(brand), MAX(so_number), SUM(order_total_cents), SUM(paid_to_date_cents),
+    SUM(credits_cents), SUM(remaining_balance_cents), MAX(last_payment_at), MAX(updated_at)
+FROM payment_balances
+GROUP BY root_appt_id;
+DROP TABLE payment_balances;
+ALTER TABLE payment_root_balances RENAME TO payment_balances;
+CREATE INDEX IF NOT EXISTS idx_payment_balances_remaining ON payment_balances(remaining_balance_cents);
@@ -0,0 +1,64 @@
+CREATE TABLE IF NOT EXISTS payment_anchor_balances (
+    anchor_key TEXT PRIMARY KEY,
+    brand TEXT NOT NULL,
+    so_key TEXT NOT NULL DEFAULT '',
+    so_number TEXT NOT NULL DEFAULT '',
+    root_appt_id TEXT NOT NULL,
+    order_total_cents BIGINT NOT NULL DEFAULT 0,
+    paid_to_date_cents BIGINT NOT NULL DEFAULT 0,
+    credits_cents BIGINT NOT NULL DEFAULT 0,
+    remaining_balance_cents BIGINT NOT NULL DEFAULT 0,
+    last_payment_at TEXT NOT NULL DEFAULT '',
+    updated_at TEXT NOT NULL
+);
+-- One balance per SO, or per root appointment for documents recorded
+-- before the client had an SO. VOID, REPLACED and DRAFT documents don't count.
+INSERT INTO payment_anchor_balances(anchor_key, brand, so_key, so_number, root_appt_id,
+    paid_to_date_cents, credits_cents, last_payment_at, updated_at)
+SELECT k.anchor_key, MAX(k.brand), MAX(k.so_key), MAX(k.so_number), MAX(k.root_appt_id),
+    COALESCE(SUM(CASE WHEN k.counted = 1 AND k.doc_kind = 'receipt' THEN k.allocated_to_so_cents ELSE 0 END), 0),
+    COALESCE(SUM(CASE WHEN k.counted = 1 AND k.doc_kind = 'credit' THEN k.subtotal_cents ELSE 0 END), 0),
+    COALESCE(MAX(CASE WHEN k.counted = 1 AND k.doc_kind = 'receipt' THEN k.submitted_at END), ''),
+    MAX(k.submitted_at)
+FROM (
+    SELECT p.*,
+        CASE WHEN p.so_number <> '' THEN 'SO|' || p.brand || '|' || REPLACE(p.so_number, '.', '')
+            ELSE 'APPT|' || p.root_appt_id END AS anchor_key,
+        REPLACE(p.so_number, '.', '') AS so_key,
+        CASE WHEN p.doc_status IN ('VOID', 'REPLACED', 'DRAFT') THEN 0 ELSE 1 END AS counted
+    FROM payments p
+) k
+GROUP BY k.anchor_key;
+-- A client with a single balance keeps its stored Order Total.
+UPDATE payment_anchor_balances SET order_total_cents = COALESCE(
+        (SELECT b.order_total_cents FROM payment_balances b WHERE b.root_appt_id = payment_anchor_balances.root_appt_id), 0)
+WHERE (SELECT COUNT(*) FROM payment_anchor_balances x WHERE x.root_appt_id = payment_anchor_balances.root_appt_id) = 1;
+-- Otherwise the stored total mixed several orders, so each starts over from
+-- its first counted document's Lines Subtotal, as a fresh anchor would.
+UPDATE payment_anchor_balances SET order_total_cents = COALESCE(
+        (SELECT p.subtotal_cents FROM payments p
+        WHERE CASE WHEN p.so_number <> '' THEN 'SO|' || p.brand || '|' || REPLACE(p.so_number, '.', '')
+                ELSE 'APPT|' || p.root_appt_id END = payment_anchor_balances.anchor_key
+            AND p.doc_status NOT IN ('VOID', 'REPLACED', 'DRAFT')
+        ORDER BY p.submitted_at, p.payment_id LIMIT 1), 0)
+WHERE (SELECT COUNT(*) FROM payment_anchor_balances x WHERE x.root_appt_id = payment_anchor_balances.root_appt_id) > 1;
+UPDATE payment_anchor_balances SET root_appt_id = COALESCE(
+        (SELECT o.root_appt_id FROM sales_orders o
+        WHERE o.brand = payment_anchor_balances.brand AND o.so_key = payment_anchor_balances.so_key), root_appt_id)
+WHERE so_key <> '';
+UPDATE payment_anchor_balances SET remaining_balance_cents = CASE
+    WHEN order_total_cents - credits_cents - paid_to_date_cents > 0 THEN order_total_cents - credits_cents - paid_to_date_cents
+    ELSE 0 END;
+DROP TABLE payment_balances;
+ALTER TABLE payment_anchor_balances RENAME TO payment_balances;
+CREATE INDEX IF NOT EXISTS idx_payment_balances_so ON payment_balances(brand, so_key);
+CREATE INDEX IF NOT EXISTS idx_payment_balances_root ON payment_balances(root_appt_id);
+CREATE INDEX IF NOT EXISTS idx_payment_balances_remaining ON payment_balances(remaining_balance_cents);
+-- Client-level totals across every SO, for the reports and the audit.
+CREATE VIEW root_payment_balances AS
+SELECT root_appt_id, MAX(brand) AS brand, MAX(so_number) AS so_number,
+    CAST(SUM(order_total_cents) AS BIGINT) AS order_total_cents, CAST(SUM(paid_to_date_cents) AS BIGINT) AS paid_to_date_cents,
+    CAST(SUM(credits_cents) AS BIGINT) AS credits_cents, CAST(SUM(remaining_balance_cents) AS BIGINT) AS remaining_balance_cents,
+    MAX(last_payment_at) AS last_payment_at, MAX(updated_at) AS updated_at
+FROM payment_balances
+GROUP BY root_appt_id;
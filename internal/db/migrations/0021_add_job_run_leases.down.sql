@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_job_runs_one_running;
+ALTER TABLE job_runs DROP COLUMN heartbeat_at;
@@ -0,0 +1,9 @@
+-- A running job_runs row is a lease: the process holding it bumps
+-- heartbeat_at while the job runs, and the partial unique index lets only
+-- one process hold a job at a time.
+ALTER TABLE job_runs ADD COLUMN heartbeat_at TEXT NOT NULL DEFAULT '';
+UPDATE job_runs SET status = 'failed', error = 'superseded by a later run', finished_at = started_at
+WHERE status = 'running'
+  AND id < (SELECT MAX(r.id) FROM job_runs r WHERE r.job_name = job_runs.job_name AND r.status = 'running');
+UPDATE job_runs SET heartbeat_at = started_at WHERE status = 'running';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_one_running ON job_runs(job_name) WHERE status = 'running';
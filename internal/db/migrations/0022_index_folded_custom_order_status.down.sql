@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_appointments_custom_order_status_folded;
+CREATE INDEX IF NOT EXISTS idx_appointments_custom_order_status ON appointments(custom_order_status);
@@ -0,0 +1,6 @@
+-- The order queues match Custom Order Status case-, space- and
+-- dash-insensitively (orders.statusSQL); index that expression rather than
+-- the raw column so the match can use it.
+DROP INDEX IF EXISTS idx_appointments_custom_order_status;
+CREATE INDEX IF NOT EXISTS idx_appointments_custom_order_status_folded
+    ON appointments ((REPLACE(LOWER(TRIM(custom_order_status)), '–', '-')), root_appt_id);
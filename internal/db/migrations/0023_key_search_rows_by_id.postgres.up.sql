@@ -0,0 +1,2 @@
+-- Postgres search tables are already keyed by appt_id and payment_id.
+SELECT 1;
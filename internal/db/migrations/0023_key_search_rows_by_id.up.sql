@@ -0,0 +1,64 @@
+-- appointments and payments have TEXT primary keys, so their rowids are
+-- not stable (VACUUM may renumber them). Key their search rows by appt_id
+-- and payment_id instead. sales_orders keeps sharing its rowid, which is
+-- its INTEGER PRIMARY KEY.
+DROP TRIGGER IF EXISTS search_payments_au;
+DROP TRIGGER IF EXISTS search_payments_ad;
+DROP TRIGGER IF EXISTS search_payments_ai;
+DROP TRIGGER IF EXISTS search_appointments_au;
+DROP TRIGGER IF EXISTS search_appointments_ad;
+DROP TRIGGER IF EXISTS search_appointments_ai;
+DROP TABLE IF EXISTS search_payments;
+DROP TABLE IF EXISTS search_customers;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS search_customers USING fts5(
+    appt_id UNINDEXED, root_appt_id UNINDEXED, name, contact, so,
+    tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3 4'
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS search_payments USING fts5(
+    payment_id UNINDEXED, root_appt_id UNINDEXED, ref, name, doc,
+    tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3 4'
+);
+
+CREATE TRIGGER IF NOT EXISTS search_appointments_ai AFTER INSERT ON appointments BEGIN
+    INSERT INTO search_customers(appt_id, root_appt_id, name, contact, so) VALUES (new.appt_id, new.root_appt_id, new.customer_name,
+        new.email_lower || ' ' || SUBSTR(new.phone_norm, 2) || ' ' || SUBSTR(new.phone_norm, -10) || ' ' || SUBSTR(new.phone_norm, -7) || ' ' || SUBSTR(new.phone_norm, -4),
+        new.so_number || ' ' || REPLACE(new.so_number, '.', ''));
+END;
+CREATE TRIGGER IF NOT EXISTS search_appointments_ad AFTER DELETE ON appointments BEGIN
+    DELETE FROM search_customers WHERE appt_id = old.appt_id;
+END;
+CREATE TRIGGER IF NOT EXISTS search_appointments_au AFTER UPDATE ON appointments BEGIN
+    DELETE FROM search_customers WHERE appt_id = old.appt_id;
+    INSERT INTO search_customers(appt_id, root_appt_id, name, contact, so) VALUES (new.appt_id, new.root_appt_id, new.customer_name,
+        new.email_lower || ' ' || SUBSTR(new.phone_norm, 2) || ' ' || SUBSTR(new.phone_norm, -10) || ' ' || SUBSTR(new.phone_norm, -7) || ' ' || SUBSTR(new.phone_norm, -4),
+        new.so_number || ' ' || REPLACE(new.so_number, '.', ''));
+    UPDATE search_orders SET name = new.customer_name
+        WHERE root_appt_id = new.root_appt_id AND old.customer_name <> new.customer_name;
+END;
+
+CREATE TRIGGER IF NOT EXISTS search_payments_ai AFTER INSERT ON payments BEGIN
+    INSERT INTO search_payments(payment_id, root_appt_id, ref, name, doc) VALUES (new.payment_id, new.root_appt_id,
+        new.payment_id || ' ' || new.reference || ' ' || new.so_number || ' ' || REPLACE(new.so_number, '.', ''),
+        new.customer_name, new.doc_type || ' ' || new.method);
+END;
+CREATE TRIGGER IF NOT EXISTS search_payments_ad AFTER DELETE ON payments BEGIN
+    DELETE FROM search_payments WHERE payment_id = old.payment_id;
+END;
+CREATE TRIGGER IF NOT EXISTS search_payments_au AFTER UPDATE ON payments BEGIN
+    DELETE FROM search_payments WHERE payment_id = old.payment_id;
+    INSERT INTO search_payments(payment_id, root_appt_id, ref, name, doc) VALUES (new.payment_id, new.root_appt_id,
+        new.payment_id || ' ' || new.reference || ' ' || new.so_number || ' ' || REPLACE(new.so_number, '.', ''),
+        new.customer_name, new.doc_type || ' ' || new.method);
+END;
+
+INSERT INTO search_customers(appt_id, root_appt_id, name, contact, so)
+    SELECT appt_id, root_appt_id, customer_name,
+        email_lower || ' ' || SUBSTR(phone_norm, 2) || ' ' || SUBSTR(phone_norm, -10) || ' ' || SUBSTR(phone_norm, -7) || ' ' || SUBSTR(phone_norm, -4),
+        so_number || ' ' || REPLACE(so_number, '.', '')
+    FROM appointments;
+INSERT INTO search_payments(payment_id, root_appt_id, ref, name, doc)
+    SELECT payment_id, root_appt_id,
+        payment_id || ' ' || reference || ' ' || so_number || ' ' || REPLACE(so_number, '.', ''),
+        customer_name, doc_type || ' ' || method
+    FROM payments;
@@ -0,0 +1,7 @@
+-- The SO-style keys can't be rebuilt in SQL; fall back to the DV form,
+-- which the old code also produced for roots without digits.
+UPDATE reminders_log SET reminder_id = 'DV|' || SUBSTR(reminder_id, 6)
+WHERE reminder_id LIKE 'APPT|%';
+
+UPDATE reminders_queue SET id = 'DV|' || SUBSTR(id, 6)
+WHERE id LIKE 'APPT|%';
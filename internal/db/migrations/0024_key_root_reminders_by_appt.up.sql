@@ -0,0 +1,14 @@
+-- Reminders anchored to a root appointment were keyed as if the APPT_ID
+-- were an SO# (SO|<last six digits>|<type>), or as DV|<root>|<type> when it
+-- had no digits. Re-key them APPT|<root>|<type> (reminders.RootID), along
+-- with their log rows.
+UPDATE reminders_log SET reminder_id = (
+    SELECT 'APPT|' || q.root_appt_id || '|' || q.type FROM reminders_queue q WHERE q.id = reminders_log.reminder_id)
+WHERE reminder_id IN (
+    SELECT id FROM reminders_queue
+    WHERE so_number = '' AND root_appt_id <> '' AND SUBSTR(type, 1, 3) <> 'DV_'
+        AND (id LIKE 'SO|%' OR id = 'DV|' || root_appt_id || '|' || type));
+
+UPDATE reminders_queue SET id = 'APPT|' || root_appt_id || '|' || type
+WHERE so_number = '' AND root_appt_id <> '' AND SUBSTR(type, 1, 3) <> 'DV_'
+    AND (id LIKE 'SO|%' OR id = 'DV|' || root_appt_id || '|' || type);
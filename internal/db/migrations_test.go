@@ -0,0 +1,257 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+)
+
+func TestMigrationsUpDownUp(t *testing.T) {
+	for _, driver := range dbtest.Drivers() {
+		t.Run(driver, func(t *testing.T) {
+			ctx := context.Background()
+			conn := dbtest.OpenEmpty(t, driver)
+
+			applied, err := db.RunMigrations(ctx, conn)
+			if err != nil {
+				t.Fatalf("migrate up: %v", err)
+			}
+			if len(applied) != len(db.Migrations(driver)) {
+				t.Fatalf("applied %d migrations, want %d", len(applied), len(db.Migrations(driver)))
+			}
+			assertSchema(t, conn, db.LatestVersion(), db.SchemaOK)
+
+			again, err := db.RunMigrations(ctx, conn)
+			if err != nil || len(again) != 0 {
+				t.Fatalf("second migrate up = %d applied, %v; want none", len(again), err)
+			}
+
+			rolledBack, err := db.MigrateDown(ctx, conn, 0)
+			if err != nil {
+				t.Fatalf("migrate down: %v", err)
+			}
+			if len(rolledBack) != len(applied) {
+				t.Fatalf("rolled back %d migrations, want %d", len(rolledBack), len(applied))
+			}
+			assertSchema(t, conn, 0, db.SchemaPending)
+
+			if _, err := db.RunMigrations(ctx, conn); err != nil {
+				t.Fatalf("migrate up after down: %v", err)
+			}
+			assertSchema(t, conn, db.LatestVersion(), db.SchemaOK)
+		})
+	}
+}
+
+func TestMigrateDownStep(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		ctx := context.Background()
+		latest := db.LatestVersion()
+		rolledBack, err := db.MigrateDown(ctx, conn, latest-1)
+		if err != nil {
+			t.Fatalf("migrate down one: %v", err)
+		}
+		if len(rolledBack) != 1 || rolledBack[0].Version != latest {
+			t.Fatalf("rolled back %+v, want only %d", rolledBack, latest)
+		}
+		assertSchema(t, conn, latest-1, db.SchemaPending)
+		if _, err := db.RunMigrations(ctx, conn); err != nil {
+			t.Fatalf("reapply: %v", err)
+		}
+		assertSchema(t, conn, latest, db.SchemaOK)
+	})
+}
+
+// TestSharedMigrations pins the versions whose one SQL file serves both
+// drivers, so adding a Postgres variant (or needing one) is deliberate.
+// TestMigrationsUpDownUp runs them on each backend.
+func TestSharedMigrations(t *testing.T) {
+	want := map[int]bool{3: true, 8: true, 9: true, 13: true, 16: true, 17: true, 18: true, 21: true, 22: true, 24: true}
+	sqlite := db.Migrations(db.DriverSQLite)
+	postgres := db.Migrations(db.DriverPostgres)
+	for i, m := range sqlite {
+		shared := m.Up == postgres[i].Up
+		if shared != want[m.Version] {
+			t.Errorf("migration %d %s: shared = %v, want %v", m.Version, m.Name, shared, want[m.Version])
+		}
+		if shared && m.Down != postgres[i].Down {
+			t.Errorf("migration %d %s: shared up but driver-specific down", m.Version, m.Name)
+		}
+	}
+}
+
+// TestBoundValues checks that flags and timestamps bound as Go values are
+// stored the way the schema expects on every driver.
+func TestBoundValues(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		ctx := context.Background()
+		at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.FixedZone("EDT", -4*60*60))
+		_, err := conn.ExecContext(ctx, `INSERT INTO users(email, password_hash, role, active, created_at, updated_at)
+            VALUES(?, ?, ?, ?, ?, ?)`, "flags@example.com", "x", "rep", false, at, at)
+		if err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		var (
+			active    int
+			createdAt string
+		)
+		err = conn.QueryRowContext(ctx, `SELECT active, created_at FROM users WHERE email = ? AND active = ?`,
+			"flags@example.com", false).Scan(&active, &createdAt)
+		if err != nil {
+			t.Fatalf("select user: %v", err)
+		}
+		if active != 0 {
+			t.Errorf("active = %d, want 0", active)
+		}
+		// The SQLite driver formats times itself; only Postgres goes through
+		// the connection's RFC 3339 conversion.
+		if want := "2024-05-01T13:30:00Z"; db.DriverOf(conn) == db.DriverPostgres && createdAt != want {
+			t.Errorf("created_at = %q, want %q", createdAt, want)
+		}
+	})
+}
+
+func assertSchema(t *testing.T, conn *sql.DB, current int, state string) {
+	t.Helper()
+	st, err := db.Status(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("schema status: %v", err)
+	}
+	if st.Current != current || st.State != state {
+		t.Fatalf("schema = version %d %s, want %d %s", st.Current, st.State, current, state)
+	}
+}
+
+// TestPaymentBalancesBySO migrates root-keyed balances to one per SO.
+func TestPaymentBalancesBySO(t *testing.T) {
+	for _, driver := range dbtest.Drivers() {
+		t.Run(driver, func(t *testing.T) {
+			ctx := context.Background()
+			conn := dbtest.Open(t, driver)
+			if _, err := db.MigrateDown(ctx, conn, 19); err != nil {
+				t.Fatalf("migrate down to 19: %v", err)
+			}
+			exec := func(query string, args ...any) {
+				t.Helper()
+				if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+					t.Fatalf("%s: %v", query, err)
+				}
+			}
+			pay := func(id, root, so, kind, status string, subtotal, alloc int64, at string) {
+				exec(`INSERT INTO payments(payment_id, brand, root_appt_id, so_number, anchor_type, basket_id, doc_type,
+                    doc_kind, doc_status, lines_json, subtotal_cents, allocated_to_so_cents, submitted_at)
+                    VALUES(?, 'VVS', ?, ?, 'SO', '', ?, ?, ?, '[]', ?, ?, ?)`, id, root, so, kind, kind, status, subtotal, alloc, at)
+			}
+			// R1 has one SO and keeps its stored total; R2 had two SOs merged.
+			pay("P1", "R1", "11.0001", "receipt", "ISSUED", 500000, 100000, "2024-01-01T00:00:00Z")
+			pay("P2", "R1", "11.0001", "receipt", "DRAFT", 1000, 50000, "2024-01-02T00:00:00Z")
+			pay("P3", "R2", "22.0001", "receipt", "ISSUED", 300000, 100000, "2024-01-01T00:00:00Z")
+			pay("P4", "R2", "22.0002", "receipt", "ISSUED", 80000, 20000, "2024-01-03T00:00:00Z")
+			pay("P5", "R2", "22.0002", "credit", "ISSUED", 5000, 0, "2024-01-04T00:00:00Z")
+			exec(`INSERT INTO payment_balances(root_appt_id, brand, so_number, order_total_cents, paid_to_date_cents,
+                remaining_balance_cents, updated_at) VALUES('R1', 'VVS', '11.0001', 600000, 150000, 450000, 'x'),
+                ('R2', 'VVS', '22.0002', 300000, 120000, 180000, 'x')`)
+
+			if _, err := db.RunMigrations(ctx, conn); err != nil {
+				t.Fatalf("migrate up: %v", err)
+			}
+			want := map[string][4]int64{
+				"SO|VVS|110001": {600000, 100000, 0, 500000},
+				"SO|VVS|220001": {300000, 100000, 0, 200000},
+				"SO|VVS|220002": {80000, 20000, 5000, 55000},
+			}
+			rows, err := conn.QueryContext(ctx, `SELECT anchor_key, order_total_cents, paid_to_date_cents, credits_cents,
+                remaining_balance_cents FROM payment_balances`)
+			if err != nil {
+				t.Fatalf("select balances: %v", err)
+			}
+			defer rows.Close()
+			got := map[string][4]int64{}
+			for rows.Next() {
+				var (
+					key string
+					v   [4]int64
+				)
+				if err := rows.Scan(&key, &v[0], &v[1], &v[2], &v[3]); err != nil {
+					t.Fatalf("scan: %v", err)
+				}
+				got[key] = v
+			}
+			if len(got) != len(want) {
+				t.Errorf("balances = %v, want %v", got, want)
+			}
+			for key, w := range want {
+				if got[key] != w {
+					t.Errorf("%s = %v, want %v", key, got[key], w)
+				}
+			}
+
+			var total, remaining int64
+			err = conn.QueryRowContext(ctx, `SELECT order_total_cents, remaining_balance_cents FROM root_payment_balances
+                WHERE root_appt_id = 'R2'`).Scan(&total, &remaining)
+			if err != nil || total != 380000 || remaining != 255000 {
+				t.Errorf("R2 totals = %d, %d, %v", total, remaining, err)
+			}
+		})
+	}
+}
+
+// TestRootReminderKeys re-keys root-anchored reminders and their log rows.
+func TestRootReminderKeys(t *testing.T) {
+	for _, driver := range dbtest.Drivers() {
+		t.Run(driver, func(t *testing.T) {
+			ctx := context.Background()
+			conn := dbtest.Open(t, driver)
+			if _, err := db.MigrateDown(ctx, conn, 23); err != nil {
+				t.Fatalf("migrate down to 23: %v", err)
+			}
+			exec := func(query string, args ...any) {
+				t.Helper()
+				if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+					t.Fatalf("%s: %v", query, err)
+				}
+			}
+			rem := func(id, typ, so, root string) {
+				exec(`INSERT INTO reminders_queue(id, type, so_number, root_appt_id, status, first_due_date, next_due_at,
+                    created_at, updated_at) VALUES(?, ?, ?, ?, 'PENDING', 'x', 'x', 'x', 'x')`, id, typ, so, root)
+				exec(`INSERT INTO reminders_log(reminder_id, action, created_at) VALUES(?, 'CREATED', 'x')`, id)
+			}
+			rem("SO|101001|FOLLOWUP", "FOLLOWUP", "", "AP-20250101-001")
+			rem("DV|APPT-X|FOLLOWUP", "FOLLOWUP", "", "APPT-X")
+			rem("SO|123456|START3D", "START3D", "12.3456", "R1")
+			rem("DV|APPT-X|DV_PROPOSE_NUDGE", "DV_PROPOSE_NUDGE", "", "APPT-X")
+
+			if _, err := db.RunMigrations(ctx, conn); err != nil {
+				t.Fatalf("migrate up: %v", err)
+			}
+			for _, table := range []string{"reminders_queue", "reminders_log"} {
+				col := "id"
+				if table == "reminders_log" {
+					col = "reminder_id"
+				}
+				rows, err := conn.QueryContext(ctx, `SELECT `+col+` FROM `+table+` ORDER BY `+col)
+				if err != nil {
+					t.Fatalf("select %s: %v", table, err)
+				}
+				var ids []string
+				for rows.Next() {
+					var id string
+					if err := rows.Scan(&id); err != nil {
+						t.Fatalf("scan: %v", err)
+					}
+					ids = append(ids, id)
+				}
+				rows.Close()
+				want := "APPT|AP-20250101-001|FOLLOWUP,APPT|APPT-X|FOLLOWUP,DV|APPT-X|DV_PROPOSE_NUDGE,SO|123456|START3D"
+				if got := strings.Join(ids, ","); got != want {
+					t.Errorf("%s ids = %s, want %s", table, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// pgConnector opens lib/pq connections wrapped so the SQLite-style queries
+// used throughout the services run unchanged on Postgres.
+type pgConnector struct {
+	inner *pq.Connector
+}
+
+func (c pgConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc, ok := conn.(pqConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("postgres driver connection %T lacks context support", conn)
+	}
+	return &pgConn{conn: pc}, nil
+}
+
+func (c pgConnector) Driver() driver.Driver {
+	return pgDriver{}
+}
+
+// pgDriver marks a *sql.DB as Postgres for DriverOf. Connections are only
+// made through pgConnector.
+type pgDriver struct{}
+
+func (pgDriver) Open(name string) (driver.Conn, error) {
+	c, err := pq.NewConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return pgConnector{inner: c}.Connect(context.Background())
+}
+
+// pqConn is the set of optional interfaces lib/pq connections implement.
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// pgConn rewrites ? placeholders to $n and converts bound values the way
+// the SQLite driver stores them.
+type pgConn struct {
+	conn pqConn
+}
+
+func (c *pgConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(rebind(query))
+}
+
+func (c *pgConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.conn.PrepareContext(ctx, rebind(query))
+}
+
+func (c *pgConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *pgConn) Begin() (driver.Tx, error) {
+	return c.conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *pgConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.conn.BeginTx(ctx, opts)
+}
+
+func (c *pgConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.conn.ExecContext(ctx, rebind(query), args)
+}
+
+func (c *pgConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.conn.QueryContext(ctx, rebind(query), args)
+}
+
+func (c *pgConn) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
+func (c *pgConn) ResetSession(ctx context.Context) error {
+	return c.conn.ResetSession(ctx)
+}
+
+func (c *pgConn) IsValid() bool {
+	return c.conn.IsValid()
+}
+
+// CheckNamedValue stores booleans as 1/0 and times as RFC 3339 text, matching
+// the INTEGER flags and TEXT timestamps of the schema.
+func (c *pgConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case bool:
+		if v {
+			nv.Value = int64(1)
+		} else {
+			nv.Value = int64(0)
+		}
+		return nil
+	case time.Time:
+		nv.Value = v.UTC().Format(time.RFC3339)
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+// rebind rewrites ? placeholders to Postgres' $1, $2, ... Question marks
+// inside string literals, quoted identifiers, comments and dollar-quoted
+// bodies are left alone.
+func rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); {
+		switch ch := query[i]; {
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			i++
+		case ch == '\'' || ch == '"':
+			end := strings.IndexByte(query[i+1:], ch)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			// A doubled quote is an escaped one; the next pass resumes
+			// scanning the literal from there.
+			b.WriteString(query[i : i+end+2])
+			i += end + 2
+		case ch == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+1])
+			i += end + 1
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 4
+		case ch == '$':
+			tag := dollarTag(query[i:])
+			if tag == "" {
+				b.WriteByte(ch)
+				i++
+				continue
+			}
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+2*len(tag)+end])
+			i += 2*len(tag) + end
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// dollarTag returns the opening $tag$ at the start of s, or "" when s does
+// not start a dollar-quoted string.
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '$':
+			return s[:j+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"no placeholders", `SELECT 1`, `SELECT 1`},
+		{"numbered in order", `SELECT * FROM t WHERE a = ? AND b IN (?, ?)`, `SELECT * FROM t WHERE a = $1 AND b IN ($2, $3)`},
+		{"string literal", `SELECT '?' , ? FROM t`, `SELECT '?' , $1 FROM t`},
+		{"escaped quote", `SELECT 'it''s ?', ?`, `SELECT 'it''s ?', $1`},
+		{"quoted identifier", `SELECT "a?b" FROM t WHERE x = ?`, `SELECT "a?b" FROM t WHERE x = $1`},
+		{"line comment", "SELECT ? -- why?\nFROM t WHERE y = ?", "SELECT $1 -- why?\nFROM t WHERE y = $2"},
+		{"block comment", `SELECT /* a ? b */ ?`, `SELECT /* a ? b */ $1`},
+		{"dollar quoted", `DO $$ SELECT '?' $$; SELECT ?`, `DO $$ SELECT '?' $$; SELECT $1`},
+		{"tagged dollar quote", `SELECT $fn$ ? $fn$, ?`, `SELECT $fn$ ? $fn$, $1`},
+		{"positional left alone", `SELECT $1, ?`, `SELECT $1, $1`},
+		{"unterminated literal", `SELECT ?, 'oops ?`, `SELECT $1, 'oops ?`},
+		{"unterminated comment", `SELECT ? /* ?`, `SELECT $1 /* ?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rebind(tt.query); got != tt.want {
+				t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDollarTag(t *testing.T) {
+	tests := map[string]string{
+		"$$ body":     "$$",
+		"$tag$ body":  "$tag$",
+		"$_x1$":       "$_x1$",
+		"$1":          "",
+		"$1$":         "",
+		"$a b$":       "",
+		"$unfinished": "",
+	}
+	for in, want := range tests {
+		if got := dollarTag(in); got != want {
+			t.Errorf("dollarTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheckNamedValue(t *testing.T) {
+	var c pgConn
+	loc := time.FixedZone("EDT", -4*60*60)
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"true", true, int64(1)},
+		{"false", false, int64(0)},
+		{"time in UTC", time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC), "2024-03-09T10:30:00Z"},
+		{"time converted to UTC", time.Date(2024, 3, 9, 22, 15, 5, 999, loc), "2024-03-10T02:15:05Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nv := &driver.NamedValue{Ordinal: 1, Value: tt.in}
+			if err := c.CheckNamedValue(nv); err != nil {
+				t.Fatalf("CheckNamedValue: %v", err)
+			}
+			if nv.Value != tt.want {
+				t.Errorf("value = %#v, want %#v", nv.Value, tt.want)
+			}
+		})
+	}
+
+	for _, v := range []any{"text", int64(7), 1.5, []byte("x"), nil} {
+		nv := &driver.NamedValue{Ordinal: 1, Value: v}
+		if err := c.CheckNamedValue(nv); !errors.Is(err, driver.ErrSkip) {
+			t.Errorf("CheckNamedValue(%#v) = %v, want driver.ErrSkip", v, err)
+		}
+	}
+}
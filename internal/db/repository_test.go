@@ -0,0 +1,340 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/payments"
+	"github.com/example/vvsapp/internal/reminders"
+	"github.com/example/vvsapp/internal/search"
+)
+
+// repos wires the services the suite exercises against one database.
+type repos struct {
+	appts     *appointments.Service
+	orders    *orders.Service
+	payments  *payments.Service
+	reminders *reminders.Service
+	auth      *auth.Service
+	search    *search.Service
+	activity  *activity.Service
+}
+
+func newRepos(t *testing.T, conn *sql.DB) *repos {
+	t.Helper()
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	ordersSvc := orders.NewService(conn, appts, logger)
+	remindersSvc, err := reminders.NewService(conn, time.UTC, "09:00", nil, logger)
+	if err != nil {
+		t.Fatalf("reminders service: %v", err)
+	}
+	authSvc, err := auth.NewService(conn, config.AuthConfig{JWTSecret: "repository-suite-secret", TokenTTLMinutes: 15, RefreshTTLHours: 24}, logger)
+	if err != nil {
+		t.Fatalf("auth service: %v", err)
+	}
+	return &repos{
+		appts:     appts,
+		orders:    ordersSvc,
+		payments:  payments.NewService(conn, appts, ordersSvc, config.PaymentsConfig{FeePercent: map[string]float64{"Card": 0.03}}, logger),
+		reminders: remindersSvc,
+		auth:      authSvc,
+		search:    search.NewService(conn, logger),
+		activity:  activity.NewService(conn, logger),
+	}
+}
+
+// seedClient creates a first visit and a follow-up under it.
+func seedClient(t *testing.T, r *repos, id, name string) (root, followUp *appointments.Appointment) {
+	t.Helper()
+	ctx := context.Background()
+	root, err := r.appts.Create(ctx, appointments.Appointment{
+		ApptID: id, Brand: appointments.BrandVVS, CustomerName: name, EmailLower: id + "@example.com",
+		PhoneNorm: "5550001111", VisitDate: "2024-04-02", VisitTime: "10:00", VisitType: "Appointment",
+		AssignedRep: "Alex", SalesStage: "Appointment", CustomOrderStatus: "3D Requested",
+	})
+	if err != nil {
+		t.Fatalf("create root appointment: %v", err)
+	}
+	followUp, err = r.appts.Create(ctx, appointments.Appointment{
+		ApptID: id + "-2", RootApptID: root.ApptID, Brand: appointments.BrandVVS, CustomerName: name,
+		EmailLower: root.EmailLower, PhoneNorm: root.PhoneNorm, VisitDate: "2024-04-09", VisitTime: "11:30",
+		VisitType: "Follow-Up", VisitNumber: 2, AssignedRep: "Alex",
+	})
+	if err != nil {
+		t.Fatalf("create follow-up: %v", err)
+	}
+	return root, followUp
+}
+
+func TestRepositories(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		t.Run("appointments", func(t *testing.T) { testAppointments(t, newRepos(t, conn)) })
+		t.Run("orders", func(t *testing.T) { testOrders(t, newRepos(t, conn)) })
+		t.Run("payments", func(t *testing.T) { testPayments(t, newRepos(t, conn)) })
+		t.Run("reminders", func(t *testing.T) { testReminders(t, newRepos(t, conn)) })
+		t.Run("sessions", func(t *testing.T) { testSessions(t, newRepos(t, conn)) })
+		t.Run("search", func(t *testing.T) { testSearch(t, newRepos(t, conn)) })
+		t.Run("activity", func(t *testing.T) { testActivity(t, newRepos(t, conn)) })
+	})
+}
+
+func testAppointments(t *testing.T, r *repos) {
+	ctx := context.Background()
+	root, _ := seedClient(t, r, "APPT-A1", "Jamie Rivera")
+
+	got, err := r.appts.Get(ctx, root.ApptID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.RootApptID != root.ApptID || got.CustomerName != "Jamie Rivera" {
+		t.Fatalf("get = %+v", got)
+	}
+
+	got.NextSteps = "Send 3D"
+	got.SONumber = "12.3456"
+	updated, err := r.appts.Update(ctx, root.ApptID, *got)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.NextSteps != "Send 3D" || updated.SONumber != "" {
+		t.Errorf("update = next steps %q, SO %q; want SO untouched", updated.NextSteps, updated.SONumber)
+	}
+
+	list, err := r.appts.List(ctx, appointments.Filter{RootApptID: root.ApptID})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("list by root = %d rows, want 2", len(list))
+	}
+
+	if _, err := r.appts.Create(ctx, *root); err == nil {
+		t.Error("duplicate APPT_ID was accepted")
+	}
+	if err := r.appts.Delete(ctx, root.ApptID+"-2"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := r.appts.Get(ctx, root.ApptID+"-2"); !errors.Is(err, appointments.ErrNotFound) {
+		t.Errorf("get deleted = %v, want ErrNotFound", err)
+	}
+}
+
+func testOrders(t *testing.T, r *repos) {
+	ctx := context.Background()
+	root, followUp := seedClient(t, r, "APPT-O1", "Morgan Lee")
+	other, _ := seedClient(t, r, "APPT-O2", "Sam Park")
+
+	res, err := r.orders.Assign(ctx, orders.AssignInput{ApptID: followUp.ApptID, Brand: "vvs", SO: "123456", OdooURL: "odoo.example.com/so/1"})
+	if err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if res.Order.SOPretty != "12.3456" || res.Order.RootApptID != root.ApptID {
+		t.Fatalf("assign = %+v", res.Order)
+	}
+	if len(res.Propagation.Updated) != 1 || res.Propagation.Updated[0] != root.ApptID {
+		t.Errorf("propagation = %+v, want the root updated", res.Propagation)
+	}
+	if got, _ := r.appts.Get(ctx, root.ApptID); got.SONumber != "12.3456" {
+		t.Errorf("root SO = %q, want 12.3456", got.SONumber)
+	}
+
+	order, err := r.orders.Lookup(ctx, "VVS", "SO12.3456")
+	if err != nil || order.RootApptID != root.ApptID {
+		t.Fatalf("lookup = %+v, %v", order, err)
+	}
+
+	_, err = r.orders.Assign(ctx, orders.AssignInput{ApptID: other.ApptID, Brand: "VVS", SO: "123456", OdooURL: "odoo.example.com/so/1"})
+	var conflict *orders.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("assign to another root = %v, want ConflictError", err)
+	}
+
+	byRoot, err := r.orders.ListByRoot(ctx, root.ApptID)
+	if err != nil || len(byRoot) != 1 {
+		t.Fatalf("list by root = %+v, %v", byRoot, err)
+	}
+}
+
+func testPayments(t *testing.T, r *repos) {
+	ctx := context.Background()
+	root, _ := seedClient(t, r, "APPT-P1", "Casey Nguyen")
+	if _, err := r.orders.Assign(ctx, orders.AssignInput{ApptID: root.ApptID, Brand: "VVS", SO: "223344", OdooURL: "odoo.example.com"}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	lines := []payments.Line{{Desc: "Ring", Qty: 1, Amt: 5000}}
+
+	deposit, err := r.payments.Record(ctx, payments.RecordInput{
+		AnchorType: payments.AnchorSO, Brand: "VVS", SO: "22.3344", DocType: "Deposit Receipt", DocStatus: "ISSUED",
+		Lines: lines, Payment: payments.PaymentDetails{Amount: 1000, Method: "Card"},
+	}, "alex@example.com")
+	if err != nil {
+		t.Fatalf("record deposit: %v", err)
+	}
+	if deposit.OrderTotal != 5000 || deposit.BalanceAfter != 4000 || deposit.FeeAmount != 30 {
+		t.Errorf("deposit = total %v, balance %v, fee %v", deposit.OrderTotal, deposit.BalanceAfter, deposit.FeeAmount)
+	}
+
+	if _, err := r.payments.Record(ctx, payments.RecordInput{
+		AnchorType: payments.AnchorSO, Brand: "VVS", SO: "223344", DocType: "Credit Memo", DocStatus: "ISSUED",
+		Lines: []payments.Line{{Desc: "Adjustment", Qty: 1, Amt: 250}},
+	}, "alex@example.com"); err != nil {
+		t.Fatalf("record credit: %v", err)
+	}
+
+	bal, err := r.payments.BalanceForSO(ctx, "VVS", "223344")
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if bal.OrderTotal != 5000 || bal.PaidToDate != 1000 || bal.Credits != 250 || bal.RemainingBalance != 3750 {
+		t.Errorf("balance = %+v", bal)
+	}
+
+	got, err := r.payments.Get(ctx, deposit.PaymentID)
+	if err != nil || len(got.Lines) != 1 || got.AmountNet != 970 {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+	list, err := r.payments.List(ctx, payments.Filter{SO: "223344", DocKind: payments.KindReceipt})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list receipts = %d, %v", len(list), err)
+	}
+}
+
+func testReminders(t *testing.T, r *repos) {
+	ctx := context.Background()
+	due := time.Now().Add(-time.Hour).UTC()
+	rem, err := r.reminders.Upsert(ctx, reminders.UpsertInput{
+		Type: reminders.TypeFollowUp, SO: "33.4455", RootApptID: "APPT-R1", DueAt: due, CustomerName: "Riley Chen",
+	}, "alex@example.com")
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if rem.ID != "SO|334455|FOLLOWUP" || rem.Status != reminders.StatusPending {
+		t.Fatalf("upsert = %+v", rem)
+	}
+
+	again, err := r.reminders.Upsert(ctx, reminders.UpsertInput{
+		Type: reminders.TypeFollowUp, SO: "334455", DueAt: due.Add(48 * time.Hour), Notes: "call back",
+	}, "alex@example.com")
+	if err != nil {
+		t.Fatalf("second upsert: %v", err)
+	}
+	if again.NextDueAt != rem.NextDueAt {
+		t.Errorf("next due = %q, want the earlier %q kept", again.NextDueAt, rem.NextDueAt)
+	}
+
+	dueList, err := r.reminders.ListDue(ctx)
+	if err != nil || len(dueList) != 1 {
+		t.Fatalf("list due = %d, %v", len(dueList), err)
+	}
+	ok, err := r.reminders.Cancel(ctx, rem.ID, "alex@example.com", "done")
+	if err != nil || !ok {
+		t.Fatalf("cancel = %v, %v", ok, err)
+	}
+	got, err := r.reminders.Get(ctx, rem.ID)
+	if err != nil || got.Status != reminders.StatusCancelled {
+		t.Fatalf("get cancelled = %+v, %v", got, err)
+	}
+}
+
+func testSessions(t *testing.T, r *repos) {
+	ctx := context.Background()
+	user, _, err := r.auth.CreateUser(ctx, auth.CreateUserInput{
+		Email: "suite@example.com", Password: "correct horse", Role: auth.RoleRep, RepName: "Alex",
+	}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if !user.Active {
+		t.Errorf("new user inactive")
+	}
+
+	tokens, claims, err := r.auth.Authenticate(ctx, "Suite@Example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := r.auth.ParseToken(ctx, tokens.AccessToken); err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+
+	rotated, _, err := r.auth.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if rotated.RefreshToken == tokens.RefreshToken {
+		t.Error("refresh token was not rotated")
+	}
+
+	if err := r.auth.Logout(ctx, claims.SessionID); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := r.auth.ParseToken(ctx, rotated.AccessToken); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("parse after logout = %v, want ErrSessionRevoked", err)
+	}
+}
+
+func testSearch(t *testing.T, r *repos) {
+	ctx := context.Background()
+	root, _ := seedClient(t, r, "APPT-S1", "Jordan Whitfield")
+	seedClient(t, r, "APPT-S2", "Taylor Brooks")
+	if _, err := r.orders.Assign(ctx, orders.AssignInput{ApptID: root.ApptID, Brand: "VVS", SO: "445566", OdooURL: "odoo.example.com"}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if _, err := r.payments.Record(ctx, payments.RecordInput{
+		AnchorType: payments.AnchorSO, Brand: "VVS", SO: "445566", DocType: "Deposit Receipt", DocStatus: "ISSUED",
+		Lines: []payments.Line{{Desc: "Ring", Qty: 1, Amt: 800}}, Payment: payments.PaymentDetails{Amount: 200, Reference: "CHK-9981"},
+	}, "alex@example.com"); err != nil {
+		t.Fatalf("record payment: %v", err)
+	}
+
+	res, err := r.search.Search(ctx, search.Query{Q: "whitf"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	customers := res.Groups[search.TypeCustomers]
+	if len(customers) != 1 || customers[0].RootApptID != root.ApptID {
+		t.Errorf("customers = %+v, want only %s", customers, root.ApptID)
+	}
+	if len(res.Groups[search.TypeOrders]) != 1 {
+		t.Errorf("orders = %+v, want the linked SO", res.Groups[search.TypeOrders])
+	}
+
+	res, err = r.search.Search(ctx, search.Query{Q: "CHK-9981", Types: []string{search.TypePayments}})
+	if err != nil {
+		t.Fatalf("search payments: %v", err)
+	}
+	if hits := res.Groups[search.TypePayments]; len(hits) != 1 || hits[0].RootApptID != root.ApptID {
+		t.Errorf("payments = %+v", hits)
+	}
+}
+
+func testActivity(t *testing.T, r *repos) {
+	ctx := context.Background()
+	for i, action := range []string{"create", "update"} {
+		err := r.activity.Record(ctx, activity.Entry{
+			Actor: "alex@example.com", Action: action, EntityType: "appointment", EntityID: "APPT-L1",
+			RootApptID: "APPT-L1", Summary: action, At: time.Date(2024, 4, 1, 12, i, 0, 0, time.UTC).Format(time.RFC3339),
+		})
+		if err != nil {
+			t.Fatalf("record %s: %v", action, err)
+		}
+	}
+	page, err := r.activity.List(ctx, activity.Filter{RootApptID: "APPT-L1"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(page.Entries) != 2 || page.Entries[0].Action != "update" {
+		t.Errorf("activity = %+v, want newest first", page.Entries)
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema states reported by SchemaStatus.State, worst first.
+const (
+	SchemaUnknown  = "unknown"
+	SchemaModified = "modified"
+	SchemaPending  = "pending"
+	SchemaOK       = "ok"
+)
+
+// MigrationStatus describes one known or recorded migration.
+type MigrationStatus struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"appliedAt"`
+	// Modified is set when the SQL changed after the migration was applied.
+	Modified bool `json:"modified"`
+	// Unknown is set for versions recorded in the database that this build
+	// does not have.
+	Unknown    bool `json:"unknown"`
+	Reversible bool `json:"reversible"`
+}
+
+// SchemaStatus summarises the schema for the health page and "migrate status".
+type SchemaStatus struct {
+	State string `json:"state"`
+	// Current is the highest applied version.
+	Current    int               `json:"current"`
+	Latest     int               `json:"latest"`
+	Pending    int               `json:"pending"`
+	Modified   int               `json:"modified"`
+	Unknown    int               `json:"unknown"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// Status reports every known migration, and any unknown applied one, in
+// version order. It only reads, so it is safe to call from the health page
+// and against a database that has never been migrated.
+func Status(ctx context.Context, q querier) (*SchemaStatus, error) {
+	cols, err := schemaColumns(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int]appliedMigration{}
+	switch {
+	case cols["checksum"]:
+		if applied, err = fetchApplied(ctx, q); err != nil {
+			return nil, err
+		}
+	case cols["version"]:
+		// Databases from before checksums were recorded have only versions;
+		// their rows are adopted on the next migrate.
+		if applied, err = fetchVersions(ctx, q); err != nil {
+			return nil, err
+		}
+	}
+
+	migrations := migrationsFor(DriverOf(q))
+	st := &SchemaStatus{Latest: LatestVersion(), Migrations: []MigrationStatus{}}
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		ms := MigrationStatus{Version: m.Version, Name: m.Name, Reversible: strings.TrimSpace(m.Down) != ""}
+		if row, ok := applied[m.Version]; ok {
+			ms.Applied, ms.AppliedAt = true, row.AppliedAt
+			ms.Modified = row.Checksum != "" && row.Checksum != m.Checksum
+		}
+		st.Migrations = append(st.Migrations, ms)
+	}
+	for v, row := range applied {
+		if !known[v] {
+			st.Migrations = append(st.Migrations, MigrationStatus{Version: v, Name: row.Name, Applied: true, AppliedAt: row.AppliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(st.Migrations, func(i, j int) bool {
+		return st.Migrations[i].Version < st.Migrations[j].Version
+	})
+
+	for _, ms := range st.Migrations {
+		switch {
+		case ms.Unknown:
+			st.Unknown++
+		case !ms.Applied:
+			st.Pending++
+		case ms.Modified:
+			st.Modified++
+		}
+		if ms.Applied {
+			st.Current = max(st.Current, ms.Version)
+		}
+	}
+	switch {
+	case st.Unknown > 0:
+		st.State = SchemaUnknown
+	case st.Modified > 0:
+		st.State = SchemaModified
+	case st.Pending > 0:
+		st.State = SchemaPending
+	default:
+		st.State = SchemaOK
+	}
+	return st, nil
+}
+
+func fetchVersions(ctx context.Context, q querier) (map[int]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("select schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedMigration{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate schema_migrations: %w", err)
+	}
+	return applied, nil
+}
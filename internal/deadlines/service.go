@@ -0,0 +1,565 @@
+// Package deadlines tracks each order's 3D and Production Deadlines as a
+// history of sets and moves, replacing Deadlines_v1.js.
+package deadlines
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/reminders"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// ValidationError reports input that cannot be recorded.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Deadline types, with the labels the Master and status report use.
+const (
+	Type3D         = "3d"
+	TypeProduction = "production"
+)
+
+var labels = map[string]string{
+	Type3D:         "3D Deadline",
+	TypeProduction: "Production Deadline",
+}
+
+var reminderTypes = map[string]string{
+	Type3D:         reminders.TypeDeadline3D,
+	TypeProduction: reminders.TypeDeadlineProduction,
+}
+
+// History actions.
+const (
+	ActionSet   = "set"
+	ActionMoved = "moved"
+	ActionMet   = "met"
+)
+
+// Slipping states.
+const (
+	StateOverdue = "overdue"
+	StateAtRisk  = "at_risk"
+)
+
+// Event is one row of deadline_history.
+type Event struct {
+	ID           int64  `json:"id"`
+	Type         string `json:"type"`
+	Action       string `json:"action"`
+	DeadlineDate string `json:"deadlineDate"`
+	PreviousDate string `json:"previousDate"`
+	Reason       string `json:"reason"`
+	UpdatedBy    string `json:"updatedBy"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// Deadline is an order's current 3D or Production Deadline. MoveCount is
+// "# of Times ... Moved", counted from the history.
+type Deadline struct {
+	Type      string `json:"type"`
+	Label     string `json:"label"`
+	Date      string `json:"date"`
+	MoveCount int    `json:"moveCount"`
+	Met       bool   `json:"met"`
+	UpdatedBy string `json:"updatedBy"`
+	UpdatedAt string `json:"updatedAt"`
+	// DaysUntil and State are relative to today while the deadline is open.
+	DaysUntil *int   `json:"daysUntil"`
+	State     string `json:"state"`
+}
+
+// OrderDeadlines is everything recorded for one SO.
+type OrderDeadlines struct {
+	Brand      string     `json:"brand"`
+	SONumber   string     `json:"so"`
+	RootApptID string     `json:"rootApptId"`
+	Deadlines  []Deadline `json:"deadlines"`
+	History    []Event    `json:"history"`
+}
+
+// SetInput sets or moves a deadline. Setting the date it already has is
+// rejected so the move count only grows on real moves.
+type SetInput struct {
+	Brand  string `json:"brand"`
+	SO     string `json:"so"`
+	Type   string `json:"type"`
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+// MetInput closes a deadline.
+type MetInput struct {
+	Brand string `json:"brand"`
+	SO    string `json:"so"`
+	Type  string `json:"type"`
+}
+
+// Item is one slipping deadline on the dashboard queue.
+type Item struct {
+	Brand        string `json:"brand"`
+	SONumber     string `json:"so"`
+	RootApptID   string `json:"rootApptId"`
+	CustomerName string `json:"customerName"`
+	AssignedRep  string `json:"assignedRep"`
+	AssistedRep  string `json:"assistedRep"`
+	Type         string `json:"type"`
+	Label        string `json:"label"`
+	DeadlineDate string `json:"deadlineDate"`
+	MoveCount    int    `json:"moveCount"`
+	DaysUntil    int    `json:"daysUntil"`
+	State        string `json:"state"`
+	Link         string `json:"link"`
+}
+
+// SlippingFilter narrows Slipping. State is overdue, at_risk or empty for both.
+type SlippingFilter struct {
+	Brand string
+	Rep   string
+	Type  string
+	State string
+	Limit int
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service records deadlines, logs them to the client status report and
+// queues reminders for the ones slipping.
+type Service struct {
+	db         *sql.DB
+	orders     *orders.Service
+	reminders  *reminders.Service
+	atRiskDays int
+	loc        *time.Location
+	logger     *logging.Logger
+	now        func() time.Time
+}
+
+// NewService constructs a deadlines service. Today is taken in loc.
+func NewService(db *sql.DB, ordersSvc *orders.Service, remindersSvc *reminders.Service, cfg config.DeadlinesConfig, loc *time.Location, logger *logging.Logger) (*Service, error) {
+	if cfg.AtRiskDays < 0 {
+		return nil, errors.New("deadlines.at_risk_days must not be negative")
+	}
+	return &Service{
+		db:         db,
+		orders:     ordersSvc,
+		reminders:  remindersSvc,
+		atRiskDays: cfg.AtRiskDays,
+		loc:        loc,
+		logger:     logger,
+		now:        time.Now,
+	}, nil
+}
+
+const eventColumns = `id, deadline_type, action, deadline_date, previous_date, reason, updated_by, created_at`
+
+// Set records a new deadline or a move of the current one, and appends the
+// row Deadlines_v1.js wrote to the client's status report.
+func (s *Service) Set(ctx context.Context, in SetInput, actor string) (*Deadline, error) {
+	kind, err := parseType(in.Type)
+	if err != nil {
+		return nil, err
+	}
+	date, err := parseDate(in.Date)
+	if err != nil {
+		return nil, err
+	}
+	if date == "" {
+		return nil, &ValidationError{Field: "date", Message: "is required"}
+	}
+	return s.record(ctx, in.Brand, in.SO, kind, date, strings.TrimSpace(in.Reason), actor)
+}
+
+// Met closes the current deadline so it no longer counts as slipping.
+func (s *Service) Met(ctx context.Context, in MetInput, actor string) (*Deadline, error) {
+	kind, err := parseType(in.Type)
+	if err != nil {
+		return nil, err
+	}
+	return s.record(ctx, in.Brand, in.SO, kind, "", "", actor)
+}
+
+// record appends one history row; an empty date marks the deadline met.
+func (s *Service) record(ctx context.Context, brand, so, kind, date, reason, actor string) (*Deadline, error) {
+	order, err := s.orders.Lookup(ctx, brand, so)
+	if err != nil {
+		return nil, err
+	}
+	var salesStage, assistedRep string
+	err = s.db.QueryRowContext(ctx, `SELECT sales_stage, assisted_rep FROM appointments WHERE root_appt_id = ?
+        ORDER BY visit_date DESC, visit_time DESC, appt_id DESC LIMIT 1`, order.RootApptID).Scan(&salesStage, &assistedRep)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("select client for %s: %w", order.RootApptID, err)
+	}
+
+	now := s.now()
+	stamp := now.UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin record deadline: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	current, err := currentDeadline(ctx, tx, order.Brand, order.SOKey, kind)
+	if err != nil {
+		return nil, err
+	}
+	ev := Event{Type: kind, DeadlineDate: date, Reason: reason, UpdatedBy: actor, CreatedAt: stamp}
+	switch {
+	case date == "":
+		if current == nil || current.Met {
+			return nil, &ValidationError{Field: "type", Message: fmt.Sprintf("SO %s has no open %s", order.SOPretty, labels[kind])}
+		}
+		ev.Action, ev.DeadlineDate, ev.PreviousDate = ActionMet, current.Date, current.Date
+	case current == nil:
+		ev.Action = ActionSet
+	case current.Date == date && !current.Met:
+		return nil, &ValidationError{Field: "date", Message: fmt.Sprintf("%s is already %s", labels[kind], date)}
+	case current.Met:
+		// A new deadline after the last one was met starts over rather
+		// than counting as a move.
+		ev.Action, ev.PreviousDate = ActionSet, current.Date
+	default:
+		ev.Action, ev.PreviousDate = ActionMoved, current.Date
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO deadline_history(brand, so_number, so_key, root_appt_id,
+            deadline_type, action, deadline_date, previous_date, reason, updated_by, created_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.Brand, order.SOPretty, order.SOKey, order.RootApptID,
+		ev.Type, ev.Action, ev.DeadlineDate, ev.PreviousDate, ev.Reason, ev.UpdatedBy, ev.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert deadline history: %w", err)
+	}
+	updated, err := currentDeadline(ctx, tx, order.Brand, order.SOKey, kind)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO client_status_log(appt_id, log_date, sales_stage, assisted_rep,
+            deadline_type, deadline_date, move_count, updated_by, updated_at, created_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.RootApptID, now.In(s.loc).Format("2006-01-02"), salesStage, assistedRep,
+		labels[kind], ev.DeadlineDate, updated.MoveCount, actor, stamp, stamp)
+	if err != nil {
+		return nil, fmt.Errorf("append client status log: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit record deadline: %w", err)
+	}
+
+	s.logger.Info("deadline_recorded", map[string]any{
+		"so":         order.SOPretty,
+		"type":       kind,
+		"action":     ev.Action,
+		"date":       ev.DeadlineDate,
+		"move_count": updated.MoveCount,
+	})
+	if ev.PreviousDate != "" {
+		// The reminder for the old date no longer applies; a new one is
+		// queued by the next run if the new date is slipping too.
+		id := reminderID(order.SOPretty, kind, ev.PreviousDate)
+		if _, err := s.reminders.Cancel(ctx, id, actor, "Deadline "+ev.Action); err != nil {
+			s.logger.Error("deadline_reminder_cancel_failed", map[string]any{"id": id, "error": err.Error()})
+		}
+	}
+	s.withState(updated)
+	return updated, nil
+}
+
+// ForOrder returns an SO's current deadlines and full history, oldest first.
+func (s *Service) ForOrder(ctx context.Context, brand, so string) (*OrderDeadlines, error) {
+	order, err := s.orders.Lookup(ctx, brand, so)
+	if err != nil {
+		return nil, err
+	}
+	out := &OrderDeadlines{
+		Brand:      order.Brand,
+		SONumber:   order.SOPretty,
+		RootApptID: order.RootApptID,
+		Deadlines:  []Deadline{},
+	}
+	for _, kind := range []string{Type3D, TypeProduction} {
+		d, err := currentDeadline(ctx, s.db, order.Brand, order.SOKey, kind)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			d = &Deadline{Type: kind, Label: labels[kind]}
+		}
+		s.withState(d)
+		out.Deadlines = append(out.Deadlines, *d)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+` FROM deadline_history
+        WHERE brand = ? AND so_key = ? ORDER BY id`, order.Brand, order.SOKey)
+	if err != nil {
+		return nil, fmt.Errorf("select deadline history: %w", err)
+	}
+	defer rows.Close()
+	out.History = []Event{}
+	for rows.Next() {
+		ev, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan deadline history: %w", err)
+		}
+		out.History = append(out.History, *ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate deadline history: %w", err)
+	}
+	return out, nil
+}
+
+// Slipping lists open deadlines that are overdue or due within the at-risk
+// window, soonest first.
+func (s *Service) Slipping(ctx context.Context, f SlippingFilter) ([]Item, error) {
+	today := s.today()
+	cutoff := dayOf(today).AddDate(0, 0, s.atRiskDays).Format("2006-01-02")
+	where := []string{
+		`h.id = (SELECT MAX(x.id) FROM deadline_history x
+            WHERE x.brand = h.brand AND x.so_key = h.so_key AND x.deadline_type = h.deadline_type)`,
+		`h.action <> ?`,
+		`h.deadline_date <> ''`,
+		`h.deadline_date <= ?`,
+	}
+	args := []any{ActionMet, cutoff}
+	switch strings.TrimSpace(f.State) {
+	case "":
+	case StateOverdue:
+		where = append(where, `h.deadline_date < ?`)
+		args = append(args, today)
+	case StateAtRisk:
+		where = append(where, `h.deadline_date >= ?`)
+		args = append(args, today)
+	default:
+		return nil, &ValidationError{Field: "state", Message: "must be overdue or at_risk"}
+	}
+	if v := strings.TrimSpace(f.Type); v != "" {
+		kind, err := parseType(v)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, `h.deadline_type = ?`)
+		args = append(args, kind)
+	}
+	if v := strings.ToUpper(strings.TrimSpace(f.Brand)); v != "" {
+		where = append(where, `h.brand = ?`)
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.Rep); v != "" {
+		where = append(where, "("+reps.MatchSQL("COALESCE(a.assigned_rep, '')")+" OR "+reps.MatchSQL("COALESCE(a.assisted_rep, '')")+")")
+		args = append(args, reps.MatchArg(v), reps.MatchArg(v))
+	}
+	args = append(args, clampLimit(f.Limit))
+
+	rows, err := s.db.QueryContext(ctx, `SELECT h.brand, h.so_number, h.root_appt_id, h.deadline_type, h.deadline_date,
+            (SELECT COUNT(*) FROM deadline_history m WHERE m.brand = h.brand AND m.so_key = h.so_key
+                AND m.deadline_type = h.deadline_type AND m.action = 'moved'),
+            COALESCE(a.customer_name, ''), COALESCE(a.assigned_rep, ''), COALESCE(a.assisted_rep, '')
+        FROM deadline_history h
+        LEFT JOIN appointments a ON a.appt_id = h.root_appt_id
+        WHERE `+strings.Join(where, " AND ")+`
+        ORDER BY h.deadline_date, h.so_key, h.deadline_type LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select slipping deadlines: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Item{}
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(&it.Brand, &it.SONumber, &it.RootApptID, &it.Type, &it.DeadlineDate, &it.MoveCount,
+			&it.CustomerName, &it.AssignedRep, &it.AssistedRep); err != nil {
+			return nil, fmt.Errorf("scan slipping deadline: %w", err)
+		}
+		it.Label = labels[it.Type]
+		it.DaysUntil = daysBetween(dayOf(today), dayOf(it.DeadlineDate))
+		it.State = StateAtRisk
+		if it.DaysUntil < 0 {
+			it.State = StateOverdue
+		}
+		it.Link = "/orders/" + it.SONumber
+		out = append(out, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate slipping deadlines: %w", err)
+	}
+	return out, nil
+}
+
+// QueueResult summarizes one QueueReminders run.
+type QueueResult struct {
+	Queued int `json:"queued"`
+}
+
+// QueueReminders files a reminder for every slipping deadline, one per SO,
+// type and date, so a moved deadline that slips again alerts again.
+func (s *Service) QueueReminders(ctx context.Context) (*QueueResult, error) {
+	items, err := s.Slipping(ctx, SlippingFilter{Limit: maxListLimit})
+	if err != nil {
+		return nil, err
+	}
+	res := &QueueResult{}
+	now := s.now()
+	for _, it := range items {
+		note := fmt.Sprintf("%s %s", it.Label, it.DeadlineDate)
+		if it.State == StateOverdue {
+			note = fmt.Sprintf("%s overdue since %s", it.Label, it.DeadlineDate)
+		}
+		if it.MoveCount > 0 {
+			note += fmt.Sprintf(" (moved %d time(s))", it.MoveCount)
+		}
+		_, err := s.reminders.Upsert(ctx, reminders.UpsertInput{
+			ID:           reminderID(it.SONumber, it.Type, it.DeadlineDate),
+			Type:         reminderTypes[it.Type],
+			SO:           it.SONumber,
+			RootApptID:   it.RootApptID,
+			DueAt:        now,
+			CustomerName: it.CustomerName,
+			NextSteps:    "Confirm or move the " + it.Label,
+			Notes:        note,
+			AssignedRep:  it.AssignedRep,
+			AssistedRep:  it.AssistedRep,
+		}, "system:deadlines")
+		if err != nil {
+			return nil, fmt.Errorf("queue reminder for %s: %w", it.SONumber, err)
+		}
+		res.Queued++
+	}
+	s.logger.Info("deadline_reminders_queued", map[string]any{"queued": res.Queued})
+	return res, nil
+}
+
+// withState fills DaysUntil and State for an open deadline.
+func (s *Service) withState(d *Deadline) {
+	d.DaysUntil, d.State = nil, ""
+	if d.Met || d.Date == "" {
+		return
+	}
+	days := daysBetween(dayOf(s.today()), dayOf(d.Date))
+	d.DaysUntil = &days
+	switch {
+	case days < 0:
+		d.State = StateOverdue
+	case days <= s.atRiskDays:
+		d.State = StateAtRisk
+	}
+}
+
+func (s *Service) today() string {
+	return s.now().In(s.loc).Format("2006-01-02")
+}
+
+func reminderID(so, kind, date string) string {
+	return reminders.ID(so, reminderTypes[kind], "") + "|" + date
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// currentDeadline derives the current deadline from the latest history row
+// and the number of moves; nil when none was ever set.
+func currentDeadline(ctx context.Context, q queryer, brand, soKey, kind string) (*Deadline, error) {
+	var d Deadline
+	var action string
+	err := q.QueryRowContext(ctx, `SELECT action, deadline_date, updated_by, created_at FROM deadline_history
+        WHERE brand = ? AND so_key = ? AND deadline_type = ? ORDER BY id DESC LIMIT 1`,
+		brand, soKey, kind).Scan(&action, &d.Date, &d.UpdatedBy, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select current deadline: %w", err)
+	}
+	err = q.QueryRowContext(ctx, `SELECT COUNT(*) FROM deadline_history
+        WHERE brand = ? AND so_key = ? AND deadline_type = ? AND action = ?`,
+		brand, soKey, kind, ActionMoved).Scan(&d.MoveCount)
+	if err != nil {
+		return nil, fmt.Errorf("count deadline moves: %w", err)
+	}
+	d.Type, d.Label, d.Met = kind, labels[kind], action == ActionMet
+	return &d, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row scanner) (*Event, error) {
+	var ev Event
+	if err := row.Scan(&ev.ID, &ev.Type, &ev.Action, &ev.DeadlineDate, &ev.PreviousDate, &ev.Reason,
+		&ev.UpdatedBy, &ev.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// parseType accepts the type key or its label, ignoring case.
+func parseType(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	for kind, label := range labels {
+		if strings.EqualFold(v, kind) || strings.EqualFold(v, label) {
+			return kind, nil
+		}
+	}
+	return "", &ValidationError{Field: "type", Message: "must be 3d or production"}
+}
+
+// parseDate accepts YYYY-MM-DD or MM/DD/YYYY; blank stays blank.
+func parseDate(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	for _, layout := range []string{"2006-01-02", "1/2/2006"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", &ValidationError{Field: "date", Message: "must be YYYY-MM-DD"}
+}
+
+func dayOf(v string) time.Time {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(v))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
@@ -0,0 +1,183 @@
+package deadlines
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/reminders"
+)
+
+type fixture struct {
+	t         *testing.T
+	conn      *sql.DB
+	svc       *Service
+	reminders *reminders.Service
+}
+
+// newFixture sets up deadlines on SO 66.0001 for client R1, with today
+// 2024-05-10 and a three-day at-risk window.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	conn := dbtest.Open(t, db.DriverSQLite)
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	ordersSvc := orders.NewService(conn, appts, logger)
+	remindersSvc, err := reminders.NewService(conn, time.UTC, "09:00", nil, logger)
+	if err != nil {
+		t.Fatalf("reminders: %v", err)
+	}
+	svc, err := NewService(conn, ordersSvc, remindersSvc, config.DeadlinesConfig{AtRiskDays: 3}, time.UTC, logger)
+	if err != nil {
+		t.Fatalf("deadlines: %v", err)
+	}
+	svc.now = func() time.Time { return time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC) }
+
+	ctx := context.Background()
+	if _, err := appts.Create(ctx, appointments.Appointment{ApptID: "R1", Brand: appointments.BrandVVS,
+		CustomerName: "Jamie", VisitDate: "2024-04-02", AssignedRep: "Alice"}); err != nil {
+		t.Fatalf("create appointment: %v", err)
+	}
+	if _, err := ordersSvc.Assign(ctx, orders.AssignInput{ApptID: "R1", Brand: "VVS", SO: "660001", OdooURL: "odoo.example.com"}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	return &fixture{t: t, conn: conn, svc: svc, reminders: remindersSvc}
+}
+
+func (f *fixture) set(kind, date string) *Deadline {
+	f.t.Helper()
+	d, err := f.svc.Set(context.Background(), SetInput{Brand: "VVS", SO: "66.0001", Type: kind, Date: date}, "alice")
+	if err != nil {
+		f.t.Fatalf("set %s %s: %v", kind, date, err)
+	}
+	return d
+}
+
+func TestMoveCount(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		kind, date string
+		want       int
+	}{
+		{Type3D, "2024-05-20", 0},
+		{Type3D, "5/25/2024", 1},
+		{TypeProduction, "2024-06-30", 0}, // each type counts its own moves
+		{Type3D, "2024-05-12", 2},
+	}
+	for _, st := range steps {
+		if d := f.set(st.kind, st.date); d.MoveCount != st.want {
+			t.Errorf("set %s %s: move count = %d, want %d", st.kind, st.date, d.MoveCount, st.want)
+		}
+	}
+
+	// Setting the date it already has is not a move.
+	var verr *ValidationError
+	if _, err := f.svc.Set(ctx, SetInput{Brand: "VVS", SO: "66.0001", Type: "3D Deadline", Date: "2024-05-12"}, "alice"); !errors.As(err, &verr) {
+		t.Errorf("same date = %v, want a validation error", err)
+	}
+
+	met, err := f.svc.Met(ctx, MetInput{Brand: "VVS", SO: "66.0001", Type: Type3D}, "alice")
+	if err != nil {
+		t.Fatalf("met: %v", err)
+	}
+	if !met.Met || met.MoveCount != 2 || met.State != "" {
+		t.Errorf("met = %+v, want met with 2 moves", met)
+	}
+	if _, err := f.svc.Met(ctx, MetInput{Brand: "VVS", SO: "66.0001", Type: Type3D}, "alice"); !errors.As(err, &verr) {
+		t.Errorf("met twice = %v, want a validation error", err)
+	}
+
+	// A new deadline after the last one was met is set, not moved.
+	if d := f.set(Type3D, "2024-07-01"); d.MoveCount != 2 || d.Met {
+		t.Errorf("set after met = %+v, want 2 moves", d)
+	}
+
+	od, err := f.svc.ForOrder(ctx, "VVS", "660001")
+	if err != nil {
+		t.Fatalf("for order: %v", err)
+	}
+	var actions []string
+	for _, ev := range od.History {
+		if ev.Type == Type3D {
+			actions = append(actions, ev.Action+" "+ev.PreviousDate)
+		}
+	}
+	want := "set ,moved 2024-05-20,moved 2024-05-25,met 2024-05-12,set 2024-05-12"
+	if got := strings.Join(actions, ","); got != want {
+		t.Errorf("3D history = %s, want %s", got, want)
+	}
+
+	// Every change is logged to the client status report with its count.
+	rows, err := f.conn.Query(`SELECT move_count FROM client_status_log
+        WHERE appt_id = 'R1' AND deadline_type = '3D Deadline' ORDER BY id`)
+	if err != nil {
+		t.Fatalf("select status log: %v", err)
+	}
+	defer rows.Close()
+	var counts []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			t.Fatalf("scan status log: %v", err)
+		}
+		counts = append(counts, n)
+	}
+	if len(counts) != 5 || counts[0] != 0 || counts[1] != 1 || counts[2] != 2 || counts[3] != 2 || counts[4] != 2 {
+		t.Errorf("status log move counts = %v, want [0 1 2 2 2]", counts)
+	}
+}
+
+func TestSlippingMoveCount(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.set(Type3D, "2024-05-30")
+	f.set(Type3D, "2024-05-20")
+	f.set(Type3D, "2024-05-12")
+	f.set(TypeProduction, "2024-05-01")
+
+	items, err := f.svc.Slipping(ctx, SlippingFilter{})
+	if err != nil {
+		t.Fatalf("slipping: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("slipping = %+v, want 2 items", items)
+	}
+	if it := items[0]; it.Type != TypeProduction || it.State != StateOverdue || it.MoveCount != 0 || it.DaysUntil != -9 {
+		t.Errorf("first item = %+v, want the overdue production deadline", it)
+	}
+	if it := items[1]; it.Type != Type3D || it.State != StateAtRisk || it.MoveCount != 2 || it.CustomerName != "Jamie" {
+		t.Errorf("second item = %+v, want the at-risk 3D deadline moved twice", it)
+	}
+
+	res, err := f.svc.QueueReminders(ctx)
+	if err != nil || res.Queued != 2 {
+		t.Fatalf("queue reminders = %+v, %v", res, err)
+	}
+	r, err := f.reminders.Get(ctx, reminderID("66.0001", Type3D, "2024-05-12"))
+	if err != nil {
+		t.Fatalf("get reminder: %v", err)
+	}
+	if !strings.Contains(r.Notes, "(moved 2 time(s))") {
+		t.Errorf("reminder notes = %q, want the move count", r.Notes)
+	}
+
+	// Moving again cancels the old date's reminder and bumps the count.
+	if d := f.set(Type3D, "2024-05-11"); d.MoveCount != 3 || d.State != StateAtRisk {
+		t.Errorf("moved again = %+v", d)
+	}
+	if old, err := f.reminders.Get(ctx, reminderID("66.0001", Type3D, "2024-05-12")); err != nil || old.Status != reminders.StatusCancelled {
+		t.Errorf("old reminder = %+v, %v", old, err)
+	}
+}
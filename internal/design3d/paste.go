@@ -0,0 +1,101 @@
+package design3d
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// specFields lists the Design Request lines in the order the tracker and
+// the Odoo paste print them.
+func specFields(sp *Spec) []struct {
+	label string
+	value *string
+} {
+	return []struct {
+		label string
+		value *string
+	}{
+		{"Accent Type", &sp.AccentType},
+		{"Ring Style", &sp.RingStyle},
+		{"Metal", &sp.Metal},
+		{"US Size", &sp.USSize},
+		{"Band Width (mm)", &sp.BandWidth},
+		{"Center Type", &sp.CenterType},
+		{"Shape", &sp.Shape},
+		{"Diamond Dimension", &sp.DiamondDimension},
+		{"Design Notes", &sp.DesignNotes},
+	}
+}
+
+func trimSpec(sp Spec) Spec {
+	for _, f := range specFields(&sp) {
+		*f.value = strings.TrimSpace(*f.value)
+	}
+	return sp
+}
+
+// mergeSpec overlays next's non-blank fields on prev, so a revision only
+// has to name what changes.
+func mergeSpec(prev, next Spec) Spec {
+	out := prev
+	nextFields := specFields(&next)
+	for i, f := range specFields(&out) {
+		if v := *nextFields[i].value; v != "" {
+			*f.value = v
+		}
+	}
+	return out
+}
+
+// designRequest renders the tracker's Design Request cell: one
+// "Label: value" line per filled field.
+func designRequest(sp Spec) string {
+	var lines []string
+	for _, f := range specFields(&sp) {
+		if *f.value != "" {
+			lines = append(lines, f.label+": "+*f.value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// odooPaste builds the "Copy into Odoo" block (buildOdooPaste_).
+func (s *Service) odooPaste(e *Entry) string {
+	var b strings.Builder
+	if e.Kind == KindStart {
+		fmt.Fprintf(&b, "3D Request – %s SO %s\n", e.Brand, e.SONumber)
+	} else {
+		fmt.Fprintf(&b, "3D Revision #%d – %s SO %s\n", e.RevisionNo, e.Brand, e.SONumber)
+	}
+	fmt.Fprintf(&b, "Customer: %s\n", e.CustomerName)
+	requested := e.RequestedBy
+	if t, err := time.Parse(time.RFC3339, e.CreatedAt); err == nil {
+		requested += " on " + t.In(s.loc).Format("01/02/2006")
+	}
+	fmt.Fprintf(&b, "Requested by: %s\n", requested)
+	reps := e.AssignedRep
+	if e.AssistedRep != "" {
+		reps += " / " + e.AssistedRep
+	}
+	if reps != "" {
+		fmt.Fprintf(&b, "Rep: %s\n", reps)
+	}
+	if e.DesignRequest != "" {
+		b.WriteString("\nDesign Request:\n")
+		b.WriteString(e.DesignRequest)
+		b.WriteString("\n")
+	}
+	if e.Notes != "" {
+		b.WriteString("\nNotes:\n")
+		b.WriteString(e.Notes)
+		b.WriteString("\n")
+	}
+	if len(e.Attachments) > 0 {
+		b.WriteString("\nAttachments:\n")
+		for _, a := range e.Attachments {
+			fmt.Fprintf(&b, "- %s\n", a)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
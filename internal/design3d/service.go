@@ -0,0 +1,461 @@
+package design3d
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// ErrNotFound is returned when a 3D tracker entry does not exist.
+var ErrNotFound = errors.New("3d design entry not found")
+
+// ValidationError reports input that cannot be recorded.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Entry kinds. Start 3D is always Revision # 0; revisions count up from 1.
+const (
+	KindStart    = "start"
+	KindRevision = "revision"
+)
+
+// StatusRevisionRequested is written to Custom Order Status when a revision
+// is requested; Start 3D writes orders.StatusThreeDRequested.
+const StatusRevisionRequested = "3D Revision Requested"
+
+// Spec is the design form shared by Start 3D and the revision dialog.
+type Spec struct {
+	AccentType       string `json:"accentType"`
+	RingStyle        string `json:"ringStyle"`
+	Metal            string `json:"metal"`
+	USSize           string `json:"usSize"`
+	BandWidth        string `json:"bandWidth"`
+	CenterType       string `json:"centerType"`
+	Shape            string `json:"shape"`
+	DiamondDimension string `json:"diamondDimension"`
+	DesignNotes      string `json:"designNotes"`
+}
+
+// Entry is one row of the 3D Tracker log.
+type Entry struct {
+	DesignID      string   `json:"designId"`
+	Brand         string   `json:"brand"`
+	SONumber      string   `json:"so"`
+	RootApptID    string   `json:"rootApptId"`
+	RevisionNo    int      `json:"revisionNo"`
+	Kind          string   `json:"kind"`
+	CustomerName  string   `json:"customerName"`
+	AssignedRep   string   `json:"assignedRep"`
+	AssistedRep   string   `json:"assistedRep"`
+	RequestedBy   string   `json:"requestedBy"`
+	Spec          Spec     `json:"spec"`
+	DesignRequest string   `json:"designRequest"`
+	Notes         string   `json:"notes"`
+	Attachments   []string `json:"attachments"`
+	CreatedAt     string   `json:"createdAt"`
+	// OdooPaste is the "Copy into Odoo" block, rendered when read.
+	OdooPaste string `json:"odooPaste"`
+}
+
+// Input starts 3D or requests a revision on an SO in the registry. On a
+// revision, blank Spec fields carry over from the previous entry.
+type Input struct {
+	Brand       string   `json:"brand"`
+	SO          string   `json:"so"`
+	RootApptID  string   `json:"rootApptId"`
+	RequestedBy string   `json:"requestedBy"`
+	Spec        Spec     `json:"spec"`
+	Notes       string   `json:"notes"`
+	Attachments []string `json:"attachments"`
+}
+
+// Filter narrows List results. Empty fields are ignored.
+type Filter struct {
+	Brand      string
+	SO         string
+	RootApptID string
+	Limit      int
+	Offset     int
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service owns the 3D Tracker and keeps Custom Order Status in step with it.
+type Service struct {
+	db     *sql.DB
+	appts  *appointments.Service
+	orders *orders.Service
+	loc    *time.Location
+	logger *logging.Logger
+	now    func() time.Time
+	nextID func(ctx context.Context, q queryer, day time.Time) (string, error)
+}
+
+// NewService constructs a 3D design service. Paste dates are shown in loc.
+func NewService(db *sql.DB, appts *appointments.Service, ordersSvc *orders.Service, loc *time.Location, logger *logging.Logger) *Service {
+	return &Service{db: db, appts: appts, orders: ordersSvc, loc: loc, logger: logger, now: time.Now, nextID: nextID}
+}
+
+const entryColumns = `design_id, brand, so_number, root_appt_id, revision_no, kind, customer_name,
+        assigned_rep, assisted_rep, requested_by, spec, design_request, notes, attachments, created_at`
+
+// Start records Start 3D (Revision # 0) for an SO and sets Custom Order
+// Status to "3D Requested" on the SO's appointments. An SO starts 3D once;
+// later changes go through Revise.
+func (s *Service) Start(ctx context.Context, in Input, actor string) (*Entry, error) {
+	return s.record(ctx, KindStart, in, actor)
+}
+
+// Revise records the next numbered revision for an SO that has started 3D
+// and sets Custom Order Status to "3D Revision Requested".
+func (s *Service) Revise(ctx context.Context, in Input, actor string) (*Entry, error) {
+	return s.record(ctx, KindRevision, in, actor)
+}
+
+// Preview renders the Odoo paste for an entry without saving it
+// (previewOdooPaste), numbered as the next entry on the SO would be.
+func (s *Service) Preview(ctx context.Context, kind string, in Input, actor string) (*Entry, error) {
+	e, err := s.prepare(ctx, kind, in, actor)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := latestEntry(ctx, s.db, e.Brand, e.SONumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := number(e, prev); err != nil {
+		return nil, err
+	}
+	e.OdooPaste = s.odooPaste(e)
+	return e, nil
+}
+
+func (s *Service) record(ctx context.Context, kind string, in Input, actor string) (*Entry, error) {
+	e, err := s.prepare(ctx, kind, in, actor)
+	if err != nil {
+		return nil, err
+	}
+	status := orders.StatusThreeDRequested
+	if kind == KindRevision {
+		status = StatusRevisionRequested
+	}
+
+	// A concurrent writer can take the same Design ID or Revision # between
+	// our read and insert; renumber in a fresh transaction and try again,
+	// merging the requested spec onto whichever revision is latest then.
+	requested := e.Spec
+	for attempt := 1; ; attempt++ {
+		e.Spec = requested
+		err = s.insert(ctx, e, status)
+		if !isUniqueViolation(err) || attempt == insertAttempts {
+			break
+		}
+	}
+	if isUniqueViolation(err) {
+		return nil, &ValidationError{Field: "so", Message: fmt.Sprintf("revision %d already recorded for SO %s", e.RevisionNo, e.SONumber)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("design3d_recorded", map[string]any{
+		"design_id":    e.DesignID,
+		"so":           e.SONumber,
+		"revision_no":  e.RevisionNo,
+		"root_appt_id": e.RootApptID,
+	})
+	e.OdooPaste = s.odooPaste(e)
+	return e, nil
+}
+
+// insertAttempts bounds how often record renumbers after losing a race.
+const insertAttempts = 5
+
+// insert numbers e, stores it and sets the SO's Custom Order Status in one
+// transaction.
+func (s *Service) insert(ctx context.Context, e *Entry, status string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin record 3d entry: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if e.DesignID, err = s.nextID(ctx, tx, s.now().In(s.loc)); err != nil {
+		return err
+	}
+	prev, err := latestEntry(ctx, tx, e.Brand, e.SONumber)
+	if err != nil {
+		return err
+	}
+	if err := number(e, prev); err != nil {
+		return err
+	}
+	if err := insertEntry(ctx, tx, e); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE appointments SET custom_order_status = ?, updated_at = ?
+        WHERE root_appt_id = ? AND so_number = ?`, status, e.CreatedAt, e.RootApptID, e.SONumber)
+	if err != nil {
+		return fmt.Errorf("set custom order status for %s: %w", e.SONumber, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit record 3d entry: %w", err)
+	}
+	return nil
+}
+
+// prepare validates input and snapshots the customer and reps from the SO's
+// root appointment. Lookups happen here, before any transaction is opened.
+func (s *Service) prepare(ctx context.Context, kind string, in Input, actor string) (*Entry, error) {
+	if kind != KindStart && kind != KindRevision {
+		return nil, &ValidationError{Field: "kind", Message: "must be start or revision"}
+	}
+	order, err := s.orders.Lookup(ctx, in.Brand, in.SO)
+	if err != nil {
+		return nil, err
+	}
+	if root := strings.TrimSpace(in.RootApptID); root != "" && root != order.RootApptID {
+		return nil, &ValidationError{Field: "rootApptId", Message: fmt.Sprintf("SO %s belongs to %s", order.SOPretty, order.RootApptID)}
+	}
+	appt, err := s.appts.Get(ctx, order.RootApptID)
+	if err != nil {
+		return nil, err
+	}
+	requestedBy := strings.TrimSpace(in.RequestedBy)
+	if requestedBy == "" {
+		requestedBy = actor
+	}
+	attachments := []string{}
+	for _, a := range in.Attachments {
+		if a = strings.TrimSpace(a); a != "" {
+			attachments = append(attachments, a)
+		}
+	}
+	return &Entry{
+		Brand:        order.Brand,
+		SONumber:     order.SOPretty,
+		RootApptID:   order.RootApptID,
+		Kind:         kind,
+		CustomerName: appt.CustomerName,
+		AssignedRep:  appt.AssignedRep,
+		AssistedRep:  appt.AssistedRep,
+		RequestedBy:  requestedBy,
+		Spec:         trimSpec(in.Spec),
+		Notes:        strings.TrimSpace(in.Notes),
+		Attachments:  attachments,
+		CreatedAt:    s.now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// number assigns the Revision # after prev, the SO's latest entry, and
+// fills a revision's blank spec fields from it.
+func number(e, prev *Entry) error {
+	switch e.Kind {
+	case KindStart:
+		if prev != nil {
+			return &ValidationError{Field: "so", Message: fmt.Sprintf("3D already started for SO %s; request a revision instead", e.SONumber)}
+		}
+		e.RevisionNo = 0
+	case KindRevision:
+		if prev == nil {
+			return &ValidationError{Field: "so", Message: fmt.Sprintf("3D has not been started for SO %s", e.SONumber)}
+		}
+		e.RevisionNo = prev.RevisionNo + 1
+		e.Spec = mergeSpec(prev.Spec, e.Spec)
+	}
+	if e.Spec == (Spec{}) && e.Notes == "" {
+		return &ValidationError{Field: "spec", Message: "describe the design or add notes"}
+	}
+	e.DesignRequest = designRequest(e.Spec)
+	return nil
+}
+
+// Get returns one entry.
+func (s *Service) Get(ctx context.Context, id string) (*Entry, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+entryColumns+` FROM design_revisions WHERE design_id = ?`, strings.TrimSpace(id))
+	e, err := scanEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select 3d entry: %w", err)
+	}
+	e.OdooPaste = s.odooPaste(e)
+	return e, nil
+}
+
+// List returns tracker history, oldest revision first within each SO, the
+// way copy3DTrackerToSO_ laid it out on the SO's sheet.
+func (s *Service) List(ctx context.Context, f Filter) ([]Entry, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if v := strings.ToUpper(strings.TrimSpace(f.Brand)); v != "" {
+		where = append(where, "brand = ?")
+		args = append(args, v)
+	}
+	if v := orders.SOKey(f.SO); v != "" {
+		where = append(where, "so_key = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.RootApptID); v != "" {
+		where = append(where, "root_appt_id = ?")
+		args = append(args, v)
+	}
+
+	query := `SELECT ` + entryColumns + ` FROM design_revisions`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY brand, so_key, revision_no LIMIT ? OFFSET ?`
+	args = append(args, clampLimit(f.Limit), max(f.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list 3d entries: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Entry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan 3d entry: %w", err)
+		}
+		e.OdooPaste = s.odooPaste(e)
+		out = append(out, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate 3d entries: %w", err)
+	}
+	return out, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// latestEntry returns the SO's highest revision, or nil before 3D starts.
+func latestEntry(ctx context.Context, q queryer, brand, so string) (*Entry, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+entryColumns+` FROM design_revisions
+        WHERE brand = ? AND so_key = ? ORDER BY revision_no DESC LIMIT 1`, brand, orders.SOKey(so))
+	e, err := scanEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select latest 3d entry: %w", err)
+	}
+	return e, nil
+}
+
+func insertEntry(ctx context.Context, q queryer, e *Entry) error {
+	specJSON, err := json.Marshal(e.Spec)
+	if err != nil {
+		return fmt.Errorf("encode spec: %w", err)
+	}
+	attachmentsJSON, err := json.Marshal(e.Attachments)
+	if err != nil {
+		return fmt.Errorf("encode attachments: %w", err)
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO design_revisions(`+entryColumns+`, so_key)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.DesignID, e.Brand, e.SONumber, e.RootApptID, e.RevisionNo, e.Kind, e.CustomerName,
+		e.AssignedRep, e.AssistedRep, e.RequestedBy, string(specJSON), e.DesignRequest, e.Notes,
+		string(attachmentsJSON), e.CreatedAt, orders.SOKey(e.SONumber))
+	if err != nil {
+		return fmt.Errorf("insert 3d entry: %w", err)
+	}
+	return nil
+}
+
+// nextID issues 3D-YYYYMMDD-### for day, numbering from the highest entry
+// already recorded that day. Two writers can draw the same ID; the primary
+// key rejects the second and record retries it.
+func nextID(ctx context.Context, q queryer, day time.Time) (string, error) {
+	prefix := "3D-" + day.Format("20060102") + "-"
+	rows, err := q.QueryContext(ctx, `SELECT design_id FROM design_revisions WHERE design_id LIKE ?`, prefix+"%")
+	if err != nil {
+		return "", fmt.Errorf("select 3d entry ids: %w", err)
+	}
+	defer rows.Close()
+	seq := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", fmt.Errorf("scan 3d entry id: %w", err)
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(id, prefix)); err == nil {
+			seq = max(seq, n)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("iterate 3d entry ids: %w", err)
+	}
+	return fmt.Sprintf("%s%03d", prefix, seq+1), nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row scanner) (*Entry, error) {
+	var (
+		e                         Entry
+		specJSON, attachmentsJSON string
+	)
+	err := row.Scan(&e.DesignID, &e.Brand, &e.SONumber, &e.RootApptID, &e.RevisionNo, &e.Kind, &e.CustomerName,
+		&e.AssignedRep, &e.AssistedRep, &e.RequestedBy, &specJSON, &e.DesignRequest, &e.Notes,
+		&attachmentsJSON, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(specJSON), &e.Spec); err != nil {
+		return nil, fmt.Errorf("decode spec: %w", err)
+	}
+	if err := json.Unmarshal([]byte(attachmentsJSON), &e.Attachments); err != nil {
+		return nil, fmt.Errorf("decode attachments: %w", err)
+	}
+	if e.Attachments == nil {
+		e.Attachments = []string{}
+	}
+	return &e, nil
+}
+
+func isUniqueViolation(err error) bool {
+	return db.IsUniqueViolation(err)
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
@@ -0,0 +1,123 @@
+package design3d
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// newTestService returns a service with SO 12.0001 linked to client R1 and
+// today 2024-05-10.
+func newTestService(t *testing.T) (*Service, *appointments.Service) {
+	t.Helper()
+	conn := dbtest.Open(t, db.DriverSQLite)
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	ordersSvc := orders.NewService(conn, appts, logger)
+	ctx := context.Background()
+	if _, err := appts.Create(ctx, appointments.Appointment{ApptID: "R1", Brand: appointments.BrandVVS,
+		CustomerName: "Jamie", VisitDate: "2024-04-02", AssignedRep: "Alice"}); err != nil {
+		t.Fatalf("create appointment: %v", err)
+	}
+	if _, err := ordersSvc.Assign(ctx, orders.AssignInput{ApptID: "R1", Brand: "VVS", SO: "120001", OdooURL: "odoo.example.com"}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	svc := NewService(conn, appts, ordersSvc, time.UTC, logger)
+	svc.now = func() time.Time { return time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC) }
+	return svc, appts
+}
+
+func TestRevisionNumbering(t *testing.T) {
+	svc, appts := newTestService(t)
+	ctx := context.Background()
+	in := Input{Brand: "VVS", SO: "12.0001", Spec: Spec{Metal: "Platinum", Shape: "Oval"}}
+
+	var verr *ValidationError
+	if _, err := svc.Revise(ctx, in, "alice"); !errors.As(err, &verr) {
+		t.Errorf("revise before start = %v, want a validation error", err)
+	}
+	start, err := svc.Start(ctx, in, "alice")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if start.RevisionNo != 0 || start.DesignID != "3D-20240510-001" || start.RequestedBy != "alice" {
+		t.Errorf("start = %+v", start)
+	}
+	if appt, err := appts.Get(ctx, "R1"); err != nil || appt.CustomOrderStatus != orders.StatusThreeDRequested {
+		t.Errorf("custom order status after start = %q, %v", appt.CustomOrderStatus, err)
+	}
+	if _, err := svc.Start(ctx, in, "alice"); !errors.As(err, &verr) {
+		t.Errorf("second start = %v, want a validation error", err)
+	}
+
+	rev, err := svc.Revise(ctx, Input{Brand: "VVS", SO: "120001", Spec: Spec{Metal: "18K Gold"}}, "bob")
+	if err != nil {
+		t.Fatalf("revise: %v", err)
+	}
+	if rev.RevisionNo != 1 || rev.DesignID != "3D-20240510-002" || rev.Spec.Metal != "18K Gold" || rev.Spec.Shape != "Oval" {
+		t.Errorf("revision = %+v", rev)
+	}
+	appt, err := appts.Get(ctx, "R1")
+	if err != nil || appt.CustomOrderStatus != StatusRevisionRequested {
+		t.Errorf("custom order status = %q, %v", appt.CustomOrderStatus, err)
+	}
+
+	// A blank revision form inherits the latest spec.
+	rev2, err := svc.Revise(ctx, Input{Brand: "VVS", SO: "120001"}, "bob")
+	if err != nil || rev2.RevisionNo != 2 || rev2.Spec.Metal != "18K Gold" {
+		t.Errorf("blank revision = %+v, %v", rev2, err)
+	}
+}
+
+// TestRecordRetriesCollision loses the Design ID race once while another
+// revision lands, and checks the retry renumbers and merges onto it.
+func TestRecordRetriesCollision(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	if _, err := svc.Start(ctx, Input{Brand: "VVS", SO: "120001", Spec: Spec{Metal: "Platinum", Shape: "Oval"}}, "alice"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	calls := 0
+	svc.nextID = func(ctx context.Context, q queryer, day time.Time) (string, error) {
+		calls++
+		switch calls {
+		case 1:
+			return "3D-20240510-001", nil // taken by Start 3D
+		case 2:
+			// The writer that beat us committed a revision of its own.
+			other := &Entry{DesignID: "3D-20240510-002", Brand: "VVS", SONumber: "12.0001", RootApptID: "R1",
+				RevisionNo: 1, Kind: KindRevision, Spec: Spec{Metal: "Platinum", Shape: "Pear"},
+				Attachments: []string{}, CreatedAt: "2024-05-10T15:00:00Z"}
+			if err := insertEntry(ctx, q, other); err != nil {
+				return "", err
+			}
+		}
+		return nextID(ctx, q, day)
+	}
+	rev, err := svc.Revise(ctx, Input{Brand: "VVS", SO: "120001", Spec: Spec{Metal: "18K Gold"}}, "bob")
+	if err != nil {
+		t.Fatalf("revise: %v", err)
+	}
+	if calls != 2 || rev.DesignID != "3D-20240510-003" || rev.RevisionNo != 2 {
+		t.Errorf("revision = %s #%d after %d draws", rev.DesignID, rev.RevisionNo, calls)
+	}
+	if want := (Spec{Metal: "18K Gold", Shape: "Pear"}); rev.Spec != want {
+		t.Errorf("spec = %+v, want %+v merged onto the latest revision", rev.Spec, want)
+	}
+
+	// A writer that keeps losing gives up with the conflict.
+	svc.nextID = func(context.Context, queryer, time.Time) (string, error) { return "3D-20240510-001", nil }
+	var verr *ValidationError
+	if _, err := svc.Revise(ctx, Input{Brand: "VVS", SO: "120001", Notes: "again"}, "bob"); !errors.As(err, &verr) {
+		t.Errorf("repeated collisions = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,758 @@
+package diamonds
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// ErrNotFound is returned when a stone does not exist.
+var ErrNotFound = errors.New("stone not found")
+
+// ValidationError reports input that cannot be recorded.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// returnWindowDays is how long after the memo date a stone must go back to
+// the vendor unless purchased.
+const returnWindowDays = 20
+
+// Stone mirrors one row of 200_ "0. MASTER LG SHEET".
+type Stone struct {
+	StoneID        string  `json:"stoneId"`
+	RootApptID     string  `json:"rootApptId"`
+	Brand          string  `json:"brand"`
+	CustomerName   string  `json:"customerName"`
+	AssignedRep    string  `json:"assignedRep"`
+	ApptTime       string  `json:"apptTime"`
+	StoneStatus    string  `json:"stoneStatus"`
+	OrderStatus    string  `json:"orderStatus"`
+	Decision       string  `json:"decision"`
+	RequestedBy    string  `json:"requestedBy"`
+	RequestDate    string  `json:"requestDate"`
+	OrderedBy      string  `json:"orderedBy"`
+	OrderedDate    string  `json:"orderedDate"`
+	MemoDate       string  `json:"memoDate"`
+	ReturnDueDate  string  `json:"returnDueDate"`
+	Vendor         string  `json:"vendor"`
+	StoneType      string  `json:"stoneType"`
+	Shape          string  `json:"shape"`
+	Carat          float64 `json:"carat"`
+	Color          string  `json:"color"`
+	Clarity        string  `json:"clarity"`
+	Cut            string  `json:"cut"`
+	Polish         string  `json:"polish"`
+	Symmetry       string  `json:"symmetry"`
+	FluorIntensity string  `json:"fluorIntensity"`
+	FluorColor     string  `json:"fluorColor"`
+	Measurements   string  `json:"measurements"`
+	LWRatio        string  `json:"lwRatio"`
+	Lab            string  `json:"lab"`
+	CertificateNo  string  `json:"certNo"`
+	CreatedAt      string  `json:"createdAt"`
+	UpdatedBy      string  `json:"updatedBy"`
+	UpdatedAt      string  `json:"updatedAt"`
+}
+
+// StoneInput is one row of the Propose Diamonds dialog.
+type StoneInput struct {
+	Vendor    string  `json:"vendor"`
+	StoneType string  `json:"stoneType"`
+	Shape     string  `json:"shape"`
+	Carat     float64 `json:"carat"`
+	Color     string  `json:"color"`
+	// FancyColor and FancyIntensity are required when Color is "Fancy".
+	FancyColor     string  `json:"fancyColor"`
+	FancyIntensity string  `json:"fancyIntensity"`
+	Clarity        string  `json:"clarity"`
+	Lab            string  `json:"lab"`
+	CertificateNo  string  `json:"certNo"`
+	Measurements   string  `json:"measurements"`
+	LWRatio        float64 `json:"lwRatio"`
+	Cut            string  `json:"cut"`
+	Polish         string  `json:"pol"`
+	Symmetry       string  `json:"sym"`
+	FluorIntensity string  `json:"fluorIntensity"`
+	FluorColor     string  `json:"fluorColor"`
+}
+
+// ProposeInput adds stones for the client of an appointment.
+type ProposeInput struct {
+	ApptID string       `json:"apptId"`
+	Stones []StoneInput `json:"stones"`
+}
+
+// ApproveItem approves ("On the Way") or declines ("Not Approved") one
+// proposed stone.
+type ApproveItem struct {
+	StoneID     string `json:"stoneId"`
+	Decision    string `json:"decision"`
+	OrderedBy   string `json:"orderedBy"`
+	OrderedDate string `json:"orderedDate"`
+}
+
+// ApproveInput mirrors the Order Approve dialog. Ordered By falls back to
+// the default and then the caller; the ordered date to the default and then
+// today.
+type ApproveInput struct {
+	Items              []ApproveItem `json:"items"`
+	DefaultOrderedBy   string        `json:"defaultOrderedBy"`
+	DefaultOrderDate   string        `json:"defaultOrderDate"`
+	ApplyDefaultsToAll bool          `json:"applyDefaultsToAll"`
+}
+
+// DeliveryItem confirms one stone arrived on memo.
+type DeliveryItem struct {
+	StoneID  string `json:"stoneId"`
+	MemoDate string `json:"memoDate"`
+}
+
+// DeliveryInput mirrors the Confirm Delivery dialog. The memo date falls
+// back to the default and then today.
+type DeliveryInput struct {
+	Items             []DeliveryItem `json:"items"`
+	DefaultMemoDate   string         `json:"defaultMemoDate"`
+	ApplyDefaultToAll bool           `json:"applyDefaultToAll"`
+}
+
+// DecisionItem records the customer's decision on a delivered stone. An
+// empty Decision leaves it unchanged; a nil Hold leaves the hold flag alone.
+type DecisionItem struct {
+	StoneID  string `json:"stoneId"`
+	Decision string `json:"decision"`
+	Hold     *bool  `json:"hold"`
+}
+
+// DecisionInput mirrors the Stone Decision dialog.
+type DecisionInput struct {
+	Items []DecisionItem `json:"items"`
+}
+
+// Client is the per-client view the appointment row used to carry: the
+// stones, their counts, DV Stones Summary, DV Stones (JSON Lines) and the
+// derived Center Stone Order Status.
+type Client struct {
+	RootApptID             string  `json:"rootApptId"`
+	Counts                 Counts  `json:"counts"`
+	Summary                string  `json:"summary"`
+	JSONLines              string  `json:"jsonLines"`
+	CenterStoneOrderStatus string  `json:"centerStoneOrderStatus"`
+	Stones                 []Stone `json:"stones"`
+}
+
+// Result is returned by every lifecycle step.
+type Result struct {
+	Stones []Stone `json:"stones"`
+	// Duplicates lists proposed certificate numbers already tracked under
+	// another lab; they are recorded anyway.
+	Duplicates []string `json:"duplicates,omitempty"`
+	Clients    []Client `json:"clients"`
+}
+
+// Filter narrows List results. Empty fields are ignored.
+type Filter struct {
+	RootApptID  string
+	OrderStatus string
+	Query       string // matches customer, certificate no or vendor
+	Limit       int
+	Offset      int
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service tracks diamonds and gems through propose, approve, delivery and
+// the customer's decision, keeping each client's Center Stone Order Status
+// in step.
+type Service struct {
+	db     *sql.DB
+	appts  *appointments.Service
+	loc    *time.Location
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs a diamonds service. Default dates are today in loc.
+func NewService(db *sql.DB, appts *appointments.Service, loc *time.Location, logger *logging.Logger) *Service {
+	return &Service{db: db, appts: appts, loc: loc, logger: logger, now: time.Now}
+}
+
+const stoneColumns = `stone_id, root_appt_id, brand, customer_name, assigned_rep, appt_time,
+        stone_status, order_status, decision, requested_by, request_date, ordered_by, ordered_date,
+        memo_date, return_due_date, vendor, stone_type, shape, carat, color, clarity, cut, polish,
+        symmetry, fluor_intensity, fluor_color, measurements, lw_ratio, lab, certificate_no,
+        created_at, updated_by, updated_at`
+
+// StoneID is the key for a certified stone. The Sheets importer uses the
+// same key, so re-importing 200_ updates proposals made here.
+func StoneID(lab, certNo string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(lab + "\x1f" + certNo)))
+	return "STN-" + hex.EncodeToString(sum[:8])
+}
+
+// Propose adds stones for the appointment's client as Diamond Viewing /
+// Proposing and re-derives the client's Center Stone Order Status.
+func (s *Service) Propose(ctx context.Context, in ProposeInput, actor string) (*Result, error) {
+	if len(in.Stones) == 0 {
+		return nil, &ValidationError{Field: "stones", Message: "at least one stone is required"}
+	}
+	appt, err := s.appts.Get(ctx, in.ApptID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	stamp := now.UTC().Format(time.RFC3339)
+	apptTime := ""
+	if appt.VisitDate != "" && appt.VisitTime != "" {
+		apptTime = appt.VisitDate + " " + appt.VisitTime
+	}
+	stones := make([]Stone, 0, len(in.Stones))
+	seen := make(map[string]bool, len(in.Stones))
+	for i, si := range in.Stones {
+		st, err := validateStone(i, si)
+		if err != nil {
+			return nil, err
+		}
+		if seen[st.StoneID] {
+			return nil, &ValidationError{Field: fmt.Sprintf("stones[%d].certNo", i), Message: "is listed twice"}
+		}
+		seen[st.StoneID] = true
+		st.RootApptID = appt.RootApptID
+		st.Brand = appt.Brand
+		st.CustomerName = appt.CustomerName
+		st.AssignedRep = appt.AssignedRep
+		st.ApptTime = apptTime
+		st.StoneStatus = StatusViewing
+		st.OrderStatus = OrderProposing
+		st.RequestedBy = actor
+		st.RequestDate = now.In(s.loc).Format("2006-01-02")
+		st.CreatedAt, st.UpdatedBy, st.UpdatedAt = stamp, actor, stamp
+		stones = append(stones, *st)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin propose stones: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res := &Result{Stones: stones, Duplicates: []string{}}
+	for i := range stones {
+		st := &stones[i]
+		existing, err := getStone(ctx, tx, st.StoneID)
+		switch {
+		case err == nil:
+			return nil, &ValidationError{
+				Field:   fmt.Sprintf("stones[%d].certNo", i),
+				Message: fmt.Sprintf("%s %s is already tracked for %s", st.Lab, st.CertificateNo, existing.RootApptID),
+			}
+		case !errors.Is(err, ErrNotFound):
+			return nil, err
+		}
+		// The same number under another lab is usually a typo in LAB, but
+		// the sheet only warned about it.
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM stones WHERE LOWER(certificate_no) = LOWER(?)`,
+			st.CertificateNo).Scan(&n); err != nil {
+			return nil, fmt.Errorf("check duplicate certificate: %w", err)
+		}
+		if n > 0 {
+			res.Duplicates = append(res.Duplicates, st.CertificateNo)
+		}
+		if err := insertStone(ctx, tx, st); err != nil {
+			return nil, err
+		}
+	}
+	client, err := refreshClient(ctx, tx, appt.RootApptID, stamp)
+	if err != nil {
+		return nil, err
+	}
+	res.Clients = []Client{*client}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit propose stones: %w", err)
+	}
+
+	s.logger.Info("stones_proposed", map[string]any{
+		"root_appt_id": appt.RootApptID,
+		"added":        len(stones),
+		"duplicates":   len(res.Duplicates),
+		"csos":         client.CenterStoneOrderStatus,
+	})
+	return res, nil
+}
+
+// ApproveOrders moves proposed stones to On the Way or Not Approved.
+func (s *Service) ApproveOrders(ctx context.Context, in ApproveInput, actor string) (*Result, error) {
+	defBy := strings.TrimSpace(in.DefaultOrderedBy)
+	if defBy == "" {
+		defBy = actor
+	}
+	defDate, err := s.dateOrToday("defaultOrderDate", in.DefaultOrderDate)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(in.Items))
+	for i, it := range in.Items {
+		if it.Decision != OrderOnTheWay && it.Decision != OrderNotApproved {
+			return nil, &ValidationError{Field: fmt.Sprintf("items[%d].decision", i), Message: "must be On the Way or Not Approved"}
+		}
+		ids[i] = it.StoneID
+	}
+	return s.apply(ctx, "stones_order_approved", ids, actor, func(i int, st *Stone) error {
+		it := in.Items[i]
+		if !canTransition(st.OrderStatus, it.Decision) {
+			return &TransitionError{StoneID: st.StoneID, From: st.OrderStatus, To: it.Decision}
+		}
+		st.OrderStatus = it.Decision
+		if it.Decision == OrderNotApproved {
+			st.OrderedBy, st.OrderedDate = "", ""
+			return nil
+		}
+		st.OrderedBy, st.OrderedDate = defBy, defDate
+		if in.ApplyDefaultsToAll {
+			return nil
+		}
+		if v := strings.TrimSpace(it.OrderedBy); v != "" {
+			st.OrderedBy = v
+		}
+		if strings.TrimSpace(it.OrderedDate) != "" {
+			d, err := parseDate(fmt.Sprintf("items[%d].orderedDate", i), it.OrderedDate)
+			if err != nil {
+				return err
+			}
+			st.OrderedDate = d
+		}
+		return nil
+	})
+}
+
+// ConfirmDelivery marks stones that were on the way as Delivered and In
+// Stock, with the memo date and a return due date twenty days later.
+func (s *Service) ConfirmDelivery(ctx context.Context, in DeliveryInput, actor string) (*Result, error) {
+	defMemo, err := s.dateOrToday("defaultMemoDate", in.DefaultMemoDate)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(in.Items))
+	for i, it := range in.Items {
+		ids[i] = it.StoneID
+	}
+	return s.apply(ctx, "stones_delivered", ids, actor, func(i int, st *Stone) error {
+		if !canTransition(st.OrderStatus, OrderDelivered) {
+			return &TransitionError{StoneID: st.StoneID, From: st.OrderStatus, To: OrderDelivered}
+		}
+		memo := defMemo
+		if v := in.Items[i].MemoDate; !in.ApplyDefaultToAll && strings.TrimSpace(v) != "" {
+			d, err := parseDate(fmt.Sprintf("items[%d].memoDate", i), v)
+			if err != nil {
+				return err
+			}
+			memo = d
+		}
+		day, _ := time.Parse("2006-01-02", memo)
+		st.OrderStatus = OrderDelivered
+		st.StoneStatus = addToken(st.StoneStatus, StatusInStock)
+		st.MemoDate = memo
+		st.ReturnDueDate = day.AddDate(0, 0, returnWindowDays).Format("2006-01-02")
+		return nil
+	})
+}
+
+// RecordDecisions applies Purchase/Return and the customer hold to
+// delivered stones. Purchase adds the Customer Purchased token and Return
+// removes it.
+func (s *Service) RecordDecisions(ctx context.Context, in DecisionInput, actor string) (*Result, error) {
+	ids := make([]string, len(in.Items))
+	for i, it := range in.Items {
+		if it.Decision != "" && it.Decision != DecisionPurchase && it.Decision != DecisionReturn {
+			return nil, &ValidationError{Field: fmt.Sprintf("items[%d].decision", i), Message: "must be Purchase or Return"}
+		}
+		if it.Decision == "" && it.Hold == nil {
+			return nil, &ValidationError{Field: fmt.Sprintf("items[%d]", i), Message: "decision or hold is required"}
+		}
+		ids[i] = it.StoneID
+	}
+	return s.apply(ctx, "stones_decided", ids, actor, func(i int, st *Stone) error {
+		it := in.Items[i]
+		if canonicalOrderStatus(st.OrderStatus) != OrderDelivered {
+			to := it.Decision
+			if to == "" {
+				to = StatusHold
+			}
+			return &TransitionError{StoneID: st.StoneID, From: st.OrderStatus, To: to}
+		}
+		if it.Hold != nil {
+			if *it.Hold {
+				st.StoneStatus = addToken(st.StoneStatus, StatusHold)
+			} else {
+				st.StoneStatus = removeToken(st.StoneStatus, StatusHold)
+			}
+		}
+		switch it.Decision {
+		case DecisionPurchase:
+			st.Decision = DecisionPurchase
+			st.StoneStatus = addToken(st.StoneStatus, StatusPurchased)
+		case DecisionReturn:
+			st.Decision = DecisionReturn
+			st.StoneStatus = removeToken(st.StoneStatus, StatusPurchased)
+		}
+		return nil
+	})
+}
+
+// apply runs change on each stone in one transaction, then refreshes the
+// Center Stone Order Status of every client touched.
+func (s *Service) apply(ctx context.Context, event string, ids []string, actor string, change func(i int, st *Stone) error) (*Result, error) {
+	if len(ids) == 0 {
+		return nil, &ValidationError{Field: "items", Message: "at least one stone is required"}
+	}
+	for i, id := range ids {
+		if strings.TrimSpace(id) == "" {
+			return nil, &ValidationError{Field: fmt.Sprintf("items[%d].stoneId", i), Message: "is required"}
+		}
+	}
+	stamp := s.now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin %s: %w", event, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res := &Result{Stones: make([]Stone, 0, len(ids)), Clients: []Client{}}
+	var roots []string
+	seen := map[string]bool{}
+	for i, id := range ids {
+		st, err := getStone(ctx, tx, strings.TrimSpace(id))
+		if err != nil {
+			return nil, err
+		}
+		if err := change(i, st); err != nil {
+			return nil, err
+		}
+		st.UpdatedBy, st.UpdatedAt = actor, stamp
+		if err := updateStone(ctx, tx, st); err != nil {
+			return nil, err
+		}
+		res.Stones = append(res.Stones, *st)
+		if st.RootApptID != "" && !seen[st.RootApptID] {
+			seen[st.RootApptID] = true
+			roots = append(roots, st.RootApptID)
+		}
+	}
+	for _, root := range roots {
+		client, err := refreshClient(ctx, tx, root, stamp)
+		if err != nil {
+			return nil, err
+		}
+		res.Clients = append(res.Clients, *client)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit %s: %w", event, err)
+	}
+
+	s.logger.Info(event, map[string]any{"stones": len(res.Stones), "clients": len(roots), "actor": actor})
+	return res, nil
+}
+
+// Get returns one stone.
+func (s *Service) Get(ctx context.Context, stoneID string) (*Stone, error) {
+	return getStone(ctx, s.db, stoneID)
+}
+
+// ClientView returns a client's stones with their counts, summary, JSON
+// lines and derived Center Stone Order Status.
+func (s *Service) ClientView(ctx context.Context, rootApptID string) (*Client, error) {
+	root := strings.TrimSpace(rootApptID)
+	if root == "" {
+		return nil, &ValidationError{Field: "rootApptId", Message: "is required"}
+	}
+	return loadClient(ctx, s.db, root)
+}
+
+// List returns stones newest first, for the approve and delivery queues.
+func (s *Service) List(ctx context.Context, f Filter) ([]Stone, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if v := strings.TrimSpace(f.RootApptID); v != "" {
+		where = append(where, "root_appt_id = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.OrderStatus); v != "" {
+		where = append(where, "LOWER(order_status) = LOWER(?)")
+		args = append(args, v)
+	}
+	if q := strings.TrimSpace(f.Query); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		where = append(where, `(LOWER(customer_name) LIKE ? OR LOWER(certificate_no) LIKE ? OR LOWER(vendor) LIKE ?)`)
+		args = append(args, like, like, like)
+	}
+
+	query := `SELECT ` + stoneColumns + ` FROM stones`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, stone_id LIMIT ? OFFSET ?`
+	args = append(args, clampLimit(f.Limit), max(f.Offset, 0))
+	return queryStones(ctx, s.db, query, args...)
+}
+
+func (s *Service) dateOrToday(field, v string) (string, error) {
+	if strings.TrimSpace(v) == "" {
+		return s.now().In(s.loc).Format("2006-01-02"), nil
+	}
+	return parseDate(field, v)
+}
+
+func parseDate(field, v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if _, err := time.Parse("2006-01-02", v); err != nil {
+		return "", &ValidationError{Field: field, Message: "must be YYYY-MM-DD"}
+	}
+	return v, nil
+}
+
+// validateStone applies dp_validateStoneInput_: the identifying columns are
+// required, carat and L/W ratio are rounded to two places and a Fancy color
+// folds its hue and intensity into Color.
+func validateStone(i int, in StoneInput) (*Stone, error) {
+	field := func(name string) string { return fmt.Sprintf("stones[%d].%s", i, name) }
+	st := &Stone{
+		Vendor:         strings.TrimSpace(in.Vendor),
+		StoneType:      strings.TrimSpace(in.StoneType),
+		Shape:          strings.TrimSpace(in.Shape),
+		Color:          strings.TrimSpace(in.Color),
+		Clarity:        strings.TrimSpace(in.Clarity),
+		Lab:            strings.TrimSpace(in.Lab),
+		CertificateNo:  strings.TrimSpace(in.CertificateNo),
+		Measurements:   strings.TrimSpace(in.Measurements),
+		Cut:            strings.TrimSpace(in.Cut),
+		Polish:         strings.TrimSpace(in.Polish),
+		Symmetry:       strings.TrimSpace(in.Symmetry),
+		FluorIntensity: strings.TrimSpace(in.FluorIntensity),
+		FluorColor:     strings.TrimSpace(in.FluorColor),
+	}
+	for _, req := range []struct{ name, v string }{
+		{"stoneType", st.StoneType}, {"shape", st.Shape}, {"vendor", st.Vendor}, {"color", st.Color},
+		{"clarity", st.Clarity}, {"lab", st.Lab}, {"certNo", st.CertificateNo},
+	} {
+		if req.v == "" {
+			return nil, &ValidationError{Field: field(req.name), Message: "is required"}
+		}
+	}
+	if !(in.Carat > 0) {
+		return nil, &ValidationError{Field: field("carat"), Message: "must be a positive number"}
+	}
+	st.Carat = round2(in.Carat)
+	switch {
+	case in.LWRatio < 0 || math.IsNaN(in.LWRatio):
+		return nil, &ValidationError{Field: field("lwRatio"), Message: "must be a positive number"}
+	case in.LWRatio > 0:
+		st.LWRatio = strconv.FormatFloat(round2(in.LWRatio), 'f', -1, 64)
+	}
+	if st.Color == "Fancy" {
+		hue, intensity := strings.TrimSpace(in.FancyColor), strings.TrimSpace(in.FancyIntensity)
+		if hue == "" {
+			return nil, &ValidationError{Field: field("fancyColor"), Message: "is required when color is Fancy"}
+		}
+		if intensity == "" {
+			return nil, &ValidationError{Field: field("fancyIntensity"), Message: "is required when color is Fancy"}
+		}
+		st.Color = "Fancy (" + hue + "; " + intensity + ")"
+	}
+	st.StoneID = StoneID(st.Lab, st.CertificateNo)
+	return st, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// jsonLine is one line of DV Stones (JSON Lines).
+type jsonLine struct {
+	Vendor      string  `json:"vendor"`
+	StoneType   string  `json:"stoneType"`
+	Shape       string  `json:"shape"`
+	Carat       float64 `json:"carat"`
+	Color       string  `json:"color"`
+	Clarity     string  `json:"clarity"`
+	Lab         string  `json:"lab"`
+	CertNo      string  `json:"certNo"`
+	OrderStatus string  `json:"orderStatus"`
+	StoneStatus string  `json:"stoneStatus"`
+	RequestDate string  `json:"requestDate"`
+	OrderedBy   string  `json:"orderedBy"`
+	OrderedDate string  `json:"orderedDate"`
+	Decision    string  `json:"decision,omitempty"`
+	Hold        bool    `json:"hold,omitempty"`
+}
+
+func jsonLines(stones []Stone) (string, error) {
+	lines := make([]string, 0, len(stones))
+	for _, st := range stones {
+		b, err := json.Marshal(jsonLine{
+			Vendor: st.Vendor, StoneType: st.StoneType, Shape: st.Shape, Carat: st.Carat, Color: st.Color,
+			Clarity: st.Clarity, Lab: st.Lab, CertNo: st.CertificateNo, OrderStatus: st.OrderStatus,
+			StoneStatus: st.StoneStatus, RequestDate: st.RequestDate, OrderedBy: st.OrderedBy,
+			OrderedDate: st.OrderedDate, Decision: st.Decision, Hold: hasToken(st.StoneStatus, StatusHold),
+		})
+		if err != nil {
+			return "", fmt.Errorf("encode stone %s: %w", st.StoneID, err)
+		}
+		lines = append(lines, string(b))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// loadClient builds the client view from the stones table; nothing is
+// cached on the appointment except the Center Stone Order Status.
+func loadClient(ctx context.Context, q queryer, root string) (*Client, error) {
+	stones, err := queryStones(ctx, q, `SELECT `+stoneColumns+` FROM stones WHERE root_appt_id = ? ORDER BY created_at, stone_id`, root)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{RootApptID: root, Stones: stones}
+	for i := range stones {
+		c.Counts.add(&stones[i])
+	}
+	c.Summary = c.Counts.Summary()
+	c.CenterStoneOrderStatus = c.Counts.CSOS()
+	if c.JSONLines, err = jsonLines(stones); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// refreshClient re-derives a client's Center Stone Order Status and writes
+// it to every appointment under the root.
+func refreshClient(ctx context.Context, q queryer, root, stamp string) (*Client, error) {
+	c, err := loadClient(ctx, q, root)
+	if err != nil {
+		return nil, err
+	}
+	_, err = q.ExecContext(ctx, `UPDATE appointments SET center_stone_order_status = ?, updated_at = ?
+        WHERE root_appt_id = ? AND center_stone_order_status <> ?`, c.CenterStoneOrderStatus, stamp, root, c.CenterStoneOrderStatus)
+	if err != nil {
+		return nil, fmt.Errorf("update center stone order status for %s: %w", root, err)
+	}
+	return c, nil
+}
+
+func getStone(ctx context.Context, q queryer, id string) (*Stone, error) {
+	st, err := scanStone(q.QueryRowContext(ctx, `SELECT `+stoneColumns+` FROM stones WHERE stone_id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select stone: %w", err)
+	}
+	return st, nil
+}
+
+func queryStones(ctx context.Context, q queryer, query string, args ...any) ([]Stone, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list stones: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Stone{}
+	for rows.Next() {
+		st, err := scanStone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stone: %w", err)
+		}
+		out = append(out, *st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate stones: %w", err)
+	}
+	return out, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanStone(row scanner) (*Stone, error) {
+	var st Stone
+	err := row.Scan(&st.StoneID, &st.RootApptID, &st.Brand, &st.CustomerName, &st.AssignedRep, &st.ApptTime,
+		&st.StoneStatus, &st.OrderStatus, &st.Decision, &st.RequestedBy, &st.RequestDate, &st.OrderedBy, &st.OrderedDate,
+		&st.MemoDate, &st.ReturnDueDate, &st.Vendor, &st.StoneType, &st.Shape, &st.Carat, &st.Color, &st.Clarity,
+		&st.Cut, &st.Polish, &st.Symmetry, &st.FluorIntensity, &st.FluorColor, &st.Measurements, &st.LWRatio,
+		&st.Lab, &st.CertificateNo, &st.CreatedAt, &st.UpdatedBy, &st.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func insertStone(ctx context.Context, q queryer, st *Stone) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO stones(`+stoneColumns+`)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		st.StoneID, st.RootApptID, st.Brand, st.CustomerName, st.AssignedRep, st.ApptTime,
+		st.StoneStatus, st.OrderStatus, st.Decision, st.RequestedBy, st.RequestDate, st.OrderedBy, st.OrderedDate,
+		st.MemoDate, st.ReturnDueDate, st.Vendor, st.StoneType, st.Shape, st.Carat, st.Color, st.Clarity,
+		st.Cut, st.Polish, st.Symmetry, st.FluorIntensity, st.FluorColor, st.Measurements, st.LWRatio,
+		st.Lab, st.CertificateNo, st.CreatedAt, st.UpdatedBy, st.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert stone %s: %w", st.StoneID, err)
+	}
+	return nil
+}
+
+// updateStone writes the lifecycle columns; specs are fixed once proposed.
+func updateStone(ctx context.Context, q queryer, st *Stone) error {
+	_, err := q.ExecContext(ctx, `UPDATE stones SET
+            stone_status = ?, order_status = ?, decision = ?, ordered_by = ?, ordered_date = ?,
+            memo_date = ?, return_due_date = ?, updated_by = ?, updated_at = ?
+        WHERE stone_id = ?`,
+		st.StoneStatus, st.OrderStatus, st.Decision, st.OrderedBy, st.OrderedDate,
+		st.MemoDate, st.ReturnDueDate, st.UpdatedBy, st.UpdatedAt, st.StoneID)
+	if err != nil {
+		return fmt.Errorf("update stone %s: %w", st.StoneID, err)
+	}
+	return nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
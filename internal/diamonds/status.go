@@ -0,0 +1,180 @@
+package diamonds
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Order Status values on 0. MASTER LG SHEET.
+const (
+	OrderProposing   = "Proposing"
+	OrderOnTheWay    = "On the Way"
+	OrderNotApproved = "Not Approved"
+	OrderDelivered   = "Delivered"
+)
+
+// Stone Status tokens. Stone Status is a multi-select cell, so a stone can
+// be "Diamond Viewing, In Stock, HOLD for Cust." at once.
+const (
+	StatusViewing   = "Diamond Viewing"
+	StatusInStock   = "In Stock"
+	StatusHold      = "HOLD for Cust."
+	StatusPurchased = "Customer Purchased"
+)
+
+// Stone Decision (PO, Return) values.
+const (
+	DecisionPurchase = "Purchase"
+	DecisionReturn   = "Return"
+)
+
+// Center Stone Order Status values written to the client's appointments.
+const (
+	CSOSProposed      = "Diamond Memo – Proposed"
+	CSOSOnTheWay      = "Diamond Memo – On the Way"
+	CSOSSomeOnTheWay  = "Diamond Memo – SOME On the Way"
+	CSOSNoneApproved  = "Diamond Memo – NONE APPROVED"
+	CSOSDelivered     = "Diamond Memo – Delivered"
+	CSOSSomeDelivered = "Diamond Memo – SOME Delivered"
+)
+
+// transitions lists the legal Order Status moves. Proposals are approved
+// or declined once; only stones on the way can be delivered.
+var transitions = map[string][]string{
+	OrderProposing: {OrderOnTheWay, OrderNotApproved},
+	OrderOnTheWay:  {OrderDelivered},
+}
+
+// TransitionError is returned when a stone cannot move to the requested
+// Order Status from the one it is in.
+type TransitionError struct {
+	StoneID string
+	From    string
+	To      string
+}
+
+func (e *TransitionError) Error() string {
+	from := e.From
+	if from == "" {
+		from = "(blank)"
+	}
+	return fmt.Sprintf("stone %s: cannot move from %s to %s", e.StoneID, from, e.To)
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range transitions[canonicalOrderStatus(from)] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalOrderStatus matches Order Status case-insensitively, as the
+// sheet's counting regexes did, so imported variants still transition.
+func canonicalOrderStatus(v string) string {
+	v = strings.TrimSpace(v)
+	for _, s := range []string{OrderProposing, OrderOnTheWay, OrderNotApproved, OrderDelivered} {
+		if strings.EqualFold(v, s) {
+			return s
+		}
+	}
+	return v
+}
+
+// Counts tallies a client's stones by Order Status, plus those carrying the
+// In Stock token (dp_computeCountsForAppointment_).
+type Counts struct {
+	Proposing   int `json:"proposing"`
+	OnTheWay    int `json:"onTheWay"`
+	NotApproved int `json:"notApproved"`
+	Delivered   int `json:"delivered"`
+	InStock     int `json:"inStock"`
+	Total       int `json:"total"`
+}
+
+func (c *Counts) add(st *Stone) {
+	c.Total++
+	switch canonicalOrderStatus(st.OrderStatus) {
+	case OrderProposing:
+		c.Proposing++
+	case OrderOnTheWay:
+		c.OnTheWay++
+	case OrderNotApproved:
+		c.NotApproved++
+	case OrderDelivered:
+		c.Delivered++
+	}
+	if hasToken(st.StoneStatus, StatusInStock) {
+		c.InStock++
+	}
+}
+
+// Summary renders the DV Stones Summary badge.
+func (c Counts) Summary() string {
+	return fmt.Sprintf("Proposed: %d • On the Way: %d • Not Approved: %d • In Stock: %d • Total: %d",
+		c.Proposing, c.OnTheWay, c.NotApproved, c.InStock, c.Total)
+}
+
+// CSOS derives the Center Stone Order Status from the counts. Once any
+// stone is delivered the delivery rule (dp_decideCsosAfterDelivery_)
+// applies; before that the approval rule (dp_decideCsosFromCounts_) does.
+func (c Counts) CSOS() string {
+	if c.Delivered > 0 {
+		if c.OnTheWay == 0 && c.Proposing == 0 {
+			return CSOSDelivered
+		}
+		return CSOSSomeDelivered
+	}
+	switch {
+	case c.OnTheWay > 0 && c.Proposing == 0 && c.NotApproved == 0:
+		return CSOSOnTheWay
+	case c.OnTheWay > 0:
+		return CSOSSomeOnTheWay
+	case c.NotApproved > 0 && c.Proposing == 0:
+		return CSOSNoneApproved
+	}
+	return CSOSProposed
+}
+
+var tokenSep = regexp.MustCompile(`\s*[;|,•]\s*`)
+
+func statusTokens(v string) []string {
+	var out []string
+	for _, t := range tokenSep.Split(strings.TrimSpace(v), -1) {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func hasToken(status, token string) bool {
+	for _, t := range statusTokens(status) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// addToken appends token to a Stone Status unless present, re-joining with
+// ", " like the sheet's multi-select chips.
+func addToken(status, token string) string {
+	parts := statusTokens(status)
+	if !hasToken(status, token) {
+		parts = append(parts, token)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func removeToken(status, token string) string {
+	var parts []string
+	for _, t := range statusTokens(status) {
+		if !strings.EqualFold(t, token) {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,69 @@
+package diamonds
+
+import "testing"
+
+func TestCountsAdd(t *testing.T) {
+	var c Counts
+	for _, st := range []Stone{
+		{OrderStatus: "proposing"},
+		{OrderStatus: OrderOnTheWay},
+		{OrderStatus: " Delivered ", StoneStatus: "Diamond Viewing, In Stock"},
+		{OrderStatus: OrderNotApproved, StoneStatus: "in stock; HOLD for Cust."},
+		{OrderStatus: ""},
+	} {
+		c.add(&st)
+	}
+	want := Counts{Proposing: 1, OnTheWay: 1, NotApproved: 1, Delivered: 1, InStock: 2, Total: 5}
+	if c != want {
+		t.Errorf("counts = %+v, want %+v", c, want)
+	}
+}
+
+func TestCountsCSOS(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Counts
+		want string
+	}{
+		{"no stones", Counts{}, CSOSProposed},
+		{"proposing", Counts{Proposing: 2}, CSOSProposed},
+		{"all on the way", Counts{OnTheWay: 2}, CSOSOnTheWay},
+		{"on the way and proposing", Counts{OnTheWay: 1, Proposing: 1}, CSOSSomeOnTheWay},
+		{"on the way and declined", Counts{OnTheWay: 1, NotApproved: 1}, CSOSSomeOnTheWay},
+		{"all declined", Counts{NotApproved: 2}, CSOSNoneApproved},
+		{"declined and proposing", Counts{NotApproved: 1, Proposing: 1}, CSOSProposed},
+		{"all delivered", Counts{Delivered: 2}, CSOSDelivered},
+		{"delivered and declined", Counts{Delivered: 1, NotApproved: 1}, CSOSDelivered},
+		{"delivered and on the way", Counts{Delivered: 1, OnTheWay: 1}, CSOSSomeDelivered},
+		{"delivered and proposing", Counts{Delivered: 1, Proposing: 1}, CSOSSomeDelivered},
+	}
+	for _, tt := range tests {
+		if got := tt.c.CSOS(); got != tt.want {
+			t.Errorf("%s: CSOS(%+v) = %q, want %q", tt.name, tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderProposing, OrderOnTheWay, true},
+		{OrderProposing, OrderNotApproved, true},
+		{"  PROPOSING ", OrderOnTheWay, true},
+		{OrderOnTheWay, OrderDelivered, true},
+		{"on the way", OrderDelivered, true},
+		{OrderProposing, OrderDelivered, false},
+		{OrderOnTheWay, OrderNotApproved, false},
+		{OrderNotApproved, OrderOnTheWay, false},
+		{OrderDelivered, OrderOnTheWay, false},
+		{"", OrderOnTheWay, false},
+		{OrderProposing, "on the way", false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// field is one target column and the sheet labels that may carry it. The
+// first label is canonical; the rest follow the SYN lists in 00_Canon.js and
+// the dp_aliases tables in Diamonds_v1.js.
+type field struct {
+	name    string
+	labels  []string
+	require bool
+}
+
+// headerKey folds a label the way dp_norm_ does: lowercase letters and digits
+// only, so NBSPs, dash variants ("00 – Master"), stray spaces, line breaks
+// and punctuation ("Cert #", "L/W Ratio") never decide a match.
+func headerKey(label string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// cleanLabel trims a header for display; unicode.IsSpace covers NBSPs and
+// the line breaks in wrapped headers.
+func cleanLabel(label string) string {
+	return strings.Join(strings.FieldsFunc(label, unicode.IsSpace), " ")
+}
+
+// headerMap resolves fields to column indexes. As in headerIndexByCanon_,
+// a field tries its canonical label before its synonyms, and every field's
+// canonical label is matched before any synonym, so a generic synonym such as
+// "Status" cannot take the column of a field that is present by name.
+type headerMap struct {
+	cols    map[string]int
+	unknown []string // labels no field claimed
+}
+
+func resolveHeaders(header []string, fields []field) headerMap {
+	byKey := map[string]int{}
+	for i, h := range header {
+		k := headerKey(h)
+		if _, dup := byKey[k]; k != "" && !dup {
+			byKey[k] = i
+		}
+	}
+	hm := headerMap{cols: map[string]int{}}
+	claimed := map[int]bool{}
+	claim := func(f field, label string) bool {
+		i, ok := byKey[headerKey(label)]
+		if !ok || claimed[i] {
+			return false
+		}
+		hm.cols[f.name] = i
+		claimed[i] = true
+		return true
+	}
+	for _, f := range fields {
+		claim(f, f.labels[0])
+	}
+	for _, f := range fields {
+		if _, ok := hm.cols[f.name]; ok {
+			continue
+		}
+		for _, label := range f.labels[1:] {
+			if claim(f, label) {
+				break
+			}
+		}
+	}
+	for i, h := range header {
+		if !claimed[i] && strings.TrimSpace(h) != "" {
+			hm.unknown = append(hm.unknown, cleanLabel(h))
+		}
+	}
+	return hm
+}
+
+// missing lists the canonical labels of required fields with no column.
+func (hm headerMap) missing(fields []field) []string {
+	var out []string
+	for _, f := range fields {
+		if _, ok := hm.cols[f.name]; f.require && !ok {
+			out = append(out, f.labels[0])
+		}
+	}
+	return out
+}
+
+// get returns the trimmed cell for a field, or "" when the column is absent.
+func (hm headerMap) get(row []string, name string) string {
+	i, ok := hm.cols[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(row[i], "\u00a0", " "))
+}
+
+// combineHeaderRows joins the two header rows of the 200_ sheet the way
+// dp_headerMapFor200_ does: "row1 row2" when both are set, else whichever is.
+func combineHeaderRows(top, bottom []string) []string {
+	n := max(len(top), len(bottom))
+	out := make([]string, n)
+	for i := range out {
+		var a, b string
+		if i < len(top) {
+			a = strings.TrimSpace(top[i])
+		}
+		if i < len(bottom) {
+			b = strings.TrimSpace(bottom[i])
+		}
+		switch {
+		case a != "" && b != "":
+			out[i] = a + " " + b
+		case a != "":
+			out[i] = a
+		default:
+			out[i] = b
+		}
+	}
+	return out
+}
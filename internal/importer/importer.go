@@ -0,0 +1,437 @@
+// Package importer loads the Google Sheets workbooks (100_ master, 200_
+// diamonds, 400_ payments) from XLSX or CSV exports into the database.
+// Columns are found by label with the tolerant matching of 00_Canon.js, rows
+// are upserted by their sheet id so a re-run only applies what changed, and
+// rows that cannot be stored are reported rather than stopping the run.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// Operations recorded for a row.
+const (
+	OpInsert    = "insert"
+	OpUpdate    = "update"
+	OpUnchanged = "unchanged"
+)
+
+// ValidationError explains why a row (or the whole run) was refused.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Options controls a run.
+type Options struct {
+	Files []string
+	// Sheet limits an XLSX import to the sheet with this name.
+	Sheet string
+	// Kind forces every sheet to one kind, for CSV files whose names don't
+	// say what they hold.
+	Kind string
+	// DryRun applies everything in a transaction that is rolled back, and
+	// records per-field diffs against the current rows.
+	DryRun bool
+	// Location is the timezone of sheet stamps that carry no offset.
+	Location *time.Location
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	DryRun  bool          `json:"dryRun"`
+	Sheets  []SheetResult `json:"sheets"`
+	Skipped []string      `json:"skipped"`
+	Changes []Change      `json:"changes,omitempty"`
+	Rejects []Reject      `json:"rejects"`
+}
+
+// SheetResult counts rows by outcome for one sheet.
+type SheetResult struct {
+	File      string   `json:"file"`
+	Sheet     string   `json:"sheet"`
+	Kind      string   `json:"kind"`
+	Rows      int      `json:"rows"`
+	Inserted  int      `json:"inserted"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Rejected  int      `json:"rejected"`
+	Ignored   []string `json:"ignoredColumns,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Change is one inserted or updated table row (dry runs only).
+type Change struct {
+	Sheet  string        `json:"sheet"`
+	Row    int           `json:"row"`
+	Table  string        `json:"table"`
+	Key    string        `json:"key"`
+	Op     string        `json:"op"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange is one column's value before and after.
+type FieldChange struct {
+	Column string `json:"column"`
+	Old    any    `json:"old"`
+	New    any    `json:"new"`
+}
+
+// Reject is a row that was not imported.
+type Reject struct {
+	File   string            `json:"file"`
+	Sheet  string            `json:"sheet"`
+	Row    int               `json:"row"`
+	Reason string            `json:"reason"`
+	Values map[string]string `json:"values"`
+}
+
+// record is one table row produced from a sheet row.
+type record struct {
+	table   string
+	keys    int // the leading cols identify the row
+	cols    []string
+	vals    []any
+	fixed   []string // columns an existing row may not change
+	created []string // set to the import time on insert
+	touched []string // set to the import time on insert and on change
+	// after runs once per distinct afterKey when the sheet is done, for
+	// derived rows such as payment balances.
+	after    func(ctx context.Context, tx *sql.Tx) error
+	afterKey string
+}
+
+// stamp adds a sheet timestamp column, or falls back to the import time
+// when the sheet left it blank.
+func (rec *record) stamp(col, v string, touch bool) {
+	switch {
+	case v != "":
+		rec.cols = append(rec.cols, col)
+		rec.vals = append(rec.vals, v)
+	case touch:
+		rec.touched = append(rec.touched, col)
+	default:
+		rec.created = append(rec.created, col)
+	}
+}
+
+func (rec *record) key() string {
+	parts := make([]string, rec.keys)
+	for i := range parts {
+		parts[i] = fmt.Sprint(rec.vals[i])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Service runs imports.
+type Service struct {
+	db     *sql.DB
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs an importer.
+func NewService(db *sql.DB, logger *logging.Logger) *Service {
+	return &Service{db: db, logger: logger, now: time.Now}
+}
+
+// Run imports every recognised sheet of opts.Files in one transaction, in
+// the order Kinds lists. Row problems become Rejects; any other error aborts
+// the run and nothing is written.
+func (s *Service) Run(ctx context.Context, opts Options) (*Report, error) {
+	if len(opts.Files) == 0 {
+		return nil, &ValidationError{Field: "files", Message: "at least one .xlsx or .csv export is required"}
+	}
+	if opts.Kind != "" && specFor("", opts.Kind) == nil {
+		return nil, &ValidationError{Field: "kind", Message: "must be one of " + strings.Join(Kinds(), ", ")}
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	type job struct {
+		file  string
+		table Table
+		spec  *sheetSpec
+	}
+	var jobs []job
+	report := &Report{DryRun: opts.DryRun, Rejects: []Reject{}}
+	for _, f := range opts.Files {
+		tables, err := ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			if opts.Sheet != "" && !strings.EqualFold(strings.TrimSpace(t.Name), strings.TrimSpace(opts.Sheet)) {
+				continue
+			}
+			spec := specFor(t.Name, opts.Kind)
+			if spec == nil {
+				report.Skipped = append(report.Skipped, f+": "+t.Name)
+				continue
+			}
+			jobs = append(jobs, job{file: f, table: t, spec: spec})
+		}
+	}
+	rank := map[string]int{}
+	for i, k := range Kinds() {
+		rank[k] = i
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return rank[jobs[i].spec.kind] < rank[jobs[j].spec.kind]
+	})
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin import: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	now := s.now().UTC().Format(time.RFC3339)
+	for _, j := range jobs {
+		res, err := s.importSheet(ctx, tx, j.file, j.table, j.spec, now, opts, report)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", j.file, j.table.Name, err)
+		}
+		report.Sheets = append(report.Sheets, res)
+	}
+
+	if !opts.DryRun {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit import: %w", err)
+		}
+	}
+
+	totals := map[string]any{"dry_run": opts.DryRun, "sheets": len(report.Sheets), "rejected": len(report.Rejects)}
+	for _, r := range report.Sheets {
+		totals[r.Kind+"_inserted"] = r.Inserted
+		totals[r.Kind+"_updated"] = r.Updated
+	}
+	s.logger.Info("import_completed", totals)
+	return report, nil
+}
+
+func (s *Service) importSheet(ctx context.Context, tx *sql.Tx, file string, t Table, spec *sheetSpec, now string, opts Options, report *Report) (SheetResult, error) {
+	res := SheetResult{File: file, Sheet: t.Name, Kind: spec.kind}
+	if len(t.Rows) < spec.headerRows {
+		res.Error = "no header row"
+		return res, nil
+	}
+	header := t.Rows[0]
+	if spec.headerRows == 2 {
+		header = combineHeaderRows(t.Rows[0], t.Rows[1])
+	}
+	hm := resolveHeaders(header, spec.fields)
+	res.Ignored = hm.unknown
+	if missing := hm.missing(spec.fields); len(missing) > 0 {
+		res.Error = "missing columns: " + strings.Join(missing, ", ")
+		return res, nil
+	}
+
+	afters := map[string]func(context.Context, *sql.Tx) error{}
+	var afterOrder []string
+	for i, cells := range t.Rows[spec.headerRows:] {
+		rowNum := spec.headerRows + i + 1
+		if isBlank(cells) {
+			continue
+		}
+		res.Rows++
+		reject := func(reason string) {
+			res.Rejected++
+			report.Rejects = append(report.Rejects, Reject{
+				File: file, Sheet: t.Name, Row: rowNum, Reason: reason, Values: rowValues(header, cells),
+			})
+		}
+
+		recs, err := spec.build(&row{ctx: ctx, tx: tx, hm: hm, cells: cells, loc: opts.Location, spec: spec})
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			reject(verr.Error())
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("row %d: %w", rowNum, err)
+		}
+
+		plans := make([]plan, len(recs))
+		conflict := ""
+		for k := range recs {
+			if plans[k], err = planRecord(ctx, tx, &recs[k]); err != nil {
+				return res, fmt.Errorf("row %d: %w", rowNum, err)
+			}
+			if conflict == "" {
+				conflict = plans[k].conflict
+			}
+		}
+		if conflict != "" {
+			reject(conflict)
+			continue
+		}
+		for k, p := range plans {
+			if err := execPlan(ctx, tx, p, now); err != nil {
+				return res, fmt.Errorf("row %d: %w", rowNum, err)
+			}
+			if k == 0 {
+				switch p.op {
+				case OpInsert:
+					res.Inserted++
+				case OpUpdate:
+					res.Updated++
+				default:
+					res.Unchanged++
+				}
+			}
+			if p.op == OpUnchanged {
+				continue
+			}
+			if p.rec.after != nil {
+				if _, ok := afters[p.rec.afterKey]; !ok {
+					afterOrder = append(afterOrder, p.rec.afterKey)
+				}
+				afters[p.rec.afterKey] = p.rec.after
+			}
+			if opts.DryRun {
+				report.Changes = append(report.Changes, Change{
+					Sheet: t.Name, Row: rowNum, Table: p.rec.table, Key: p.rec.key(), Op: p.op, Fields: p.changes,
+				})
+			}
+		}
+	}
+	for _, k := range afterOrder {
+		if err := afters[k](ctx, tx); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// plan is what applying a record would do.
+type plan struct {
+	rec      *record
+	op       string
+	changes  []FieldChange
+	conflict string
+}
+
+func planRecord(ctx context.Context, tx *sql.Tx, rec *record) (plan, error) {
+	p := plan{rec: rec}
+	current := make([]any, len(rec.cols))
+	ptrs := make([]any, len(rec.cols))
+	for i := range current {
+		ptrs[i] = &current[i]
+	}
+	err := tx.QueryRowContext(ctx, `SELECT `+strings.Join(rec.cols, ", ")+` FROM `+rec.table+
+		` WHERE `+keyWhere(rec), rec.vals[:rec.keys]...).Scan(ptrs...)
+	if errors.Is(err, sql.ErrNoRows) {
+		p.op = OpInsert
+		for i := rec.keys; i < len(rec.cols); i++ {
+			if fmt.Sprint(rec.vals[i]) != "" && fmt.Sprint(rec.vals[i]) != "0" {
+				p.changes = append(p.changes, FieldChange{Column: rec.cols[i], New: rec.vals[i]})
+			}
+		}
+		return p, nil
+	}
+	if err != nil {
+		return p, fmt.Errorf("select %s %s: %w", rec.table, rec.key(), err)
+	}
+	for i := rec.keys; i < len(rec.cols); i++ {
+		old := current[i]
+		if b, ok := old.([]byte); ok {
+			old = string(b)
+		}
+		if fmt.Sprint(old) == fmt.Sprint(rec.vals[i]) {
+			continue
+		}
+		for _, f := range rec.fixed {
+			if f == rec.cols[i] {
+				p.conflict = fmt.Sprintf("%s %s already belongs to %s %v", rec.table, rec.key(), f, old)
+			}
+		}
+		p.changes = append(p.changes, FieldChange{Column: rec.cols[i], Old: old, New: rec.vals[i]})
+	}
+	p.op = OpUnchanged
+	if len(p.changes) > 0 {
+		p.op = OpUpdate
+	}
+	return p, nil
+}
+
+func execPlan(ctx context.Context, tx *sql.Tx, p plan, now string) error {
+	rec := p.rec
+	switch p.op {
+	case OpInsert:
+		cols := append(append(append([]string{}, rec.cols...), rec.created...), rec.touched...)
+		args := append([]any{}, rec.vals...)
+		for len(args) < len(cols) {
+			args = append(args, now)
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+rec.table+`(`+strings.Join(cols, ", ")+`)
+            VALUES(`+strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")+`)`, args...)
+		if err != nil {
+			return fmt.Errorf("insert %s %s: %w", rec.table, rec.key(), err)
+		}
+	case OpUpdate:
+		var sets []string
+		var args []any
+		for _, c := range p.changes {
+			sets = append(sets, c.Column+" = ?")
+			args = append(args, c.New)
+		}
+		for _, c := range rec.touched {
+			sets = append(sets, c+" = ?")
+			args = append(args, now)
+		}
+		args = append(args, rec.vals[:rec.keys]...)
+		_, err := tx.ExecContext(ctx, `UPDATE `+rec.table+` SET `+strings.Join(sets, ", ")+` WHERE `+keyWhere(rec), args...)
+		if err != nil {
+			return fmt.Errorf("update %s %s: %w", rec.table, rec.key(), err)
+		}
+	}
+	return nil
+}
+
+func keyWhere(rec *record) string {
+	conds := make([]string, rec.keys)
+	for i := range conds {
+		conds[i] = rec.cols[i] + " = ?"
+	}
+	return strings.Join(conds, " AND ")
+}
+
+func isBlank(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func rowValues(header, cells []string) map[string]string {
+	out := map[string]string{}
+	for i, c := range cells {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		label := fmt.Sprintf("Column %d", i+1)
+		if i < len(header) && strings.TrimSpace(header[i]) != "" {
+			label = cleanLabel(header[i])
+		}
+		out[label] = c
+	}
+	return out
+}
@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+const masterCSV = `APPT_ID,RootApptID,Brand,Customer Name,Visit Date,Assigned Rep,Status,SO#
+R1,,vvs,Jamie Lee,2024-04-02,Alice,In Production,120001
+A2,R1,VVS,Jamie Lee,5/1/2024,Alice,In Production,12.0001
+R3,,HPUSA,Morgan,2024-04-09,Bea,,
+`
+
+// writeCSV stores content as a CSV export called name and returns its path.
+func writeCSV(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func newTestService(t *testing.T) (*Service, *sql.DB) {
+	t.Helper()
+	conn := dbtest.Open(t, db.DriverSQLite)
+	svc := NewService(conn, logging.NewWriter("error", io.Discard))
+	svc.now = func() time.Time { return time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC) }
+	return svc, conn
+}
+
+func count(t *testing.T, conn *sql.DB, table string) int {
+	t.Helper()
+	var n int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestResolveHeaders(t *testing.T) {
+	fields := specFor("", KindMaster).fields
+	tests := []struct {
+		name    string
+		header  []string
+		want    map[string]int
+		unknown []string
+		missing []string
+	}{
+		{
+			name:   "canonical labels",
+			header: []string{"APPT_ID", "Brand", "Customer Name", "Visit Date"},
+			want:   map[string]int{"appt_id": 0, "brand": 1, "customer_name": 2, "visit_date": 3},
+		},
+		{
+			name:   "folded case, spacing and punctuation",
+			header: []string{"appt id", " BRAND ", "Customer Name", "Visit\nDate", "SO #"},
+			want:   map[string]int{"appt_id": 0, "brand": 1, "customer_name": 2, "visit_date": 3, "so_number": 4},
+		},
+		{
+			name:   "synonyms",
+			header: []string{"APPT_ID", "Company", "Client Name", "Visit Date", "Sales Rep", "Order Status"},
+			want:   map[string]int{"appt_id": 0, "brand": 1, "customer_name": 2, "visit_date": 3, "assigned_rep": 4, "custom_order_status": 5},
+		},
+		{
+			// "Status" is a synonym of Custom Order Status; the canonical
+			// column wins and the generic one is left over.
+			name:    "canonical beats synonym",
+			header:  []string{"Status", "APPT_ID", "Brand", "Customer Name", "Visit Date", "Custom Order Status"},
+			want:    map[string]int{"appt_id": 1, "brand": 2, "customer_name": 3, "visit_date": 4, "custom_order_status": 5},
+			unknown: []string{"Status"},
+		},
+		{
+			name:    "missing and unknown columns",
+			header:  []string{"APPT_ID", "Customer Name", "Lead Source"},
+			want:    map[string]int{"appt_id": 0, "customer_name": 1},
+			unknown: []string{"Lead Source"},
+			missing: []string{"Brand", "Visit Date"},
+		},
+	}
+	for _, tt := range tests {
+		hm := resolveHeaders(tt.header, fields)
+		if len(hm.cols) != len(tt.want) {
+			t.Errorf("%s: cols = %v, want %v", tt.name, hm.cols, tt.want)
+		}
+		for name, i := range tt.want {
+			if got, ok := hm.cols[name]; !ok || got != i {
+				t.Errorf("%s: %s at %d (%v), want %d", tt.name, name, got, ok, i)
+			}
+		}
+		if got, want := strings.Join(hm.unknown, "|"), strings.Join(tt.unknown, "|"); got != want {
+			t.Errorf("%s: unknown = %q, want %q", tt.name, got, want)
+		}
+		if got, want := strings.Join(hm.missing(fields), "|"), strings.Join(tt.missing, "|"); got != want {
+			t.Errorf("%s: missing = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestCombineHeaderRows(t *testing.T) {
+	got := combineHeaderRows([]string{"Center Stone", "", " Cert "}, []string{"Shape", "Carat", "", "L/W Ratio"})
+	want := []string{"Center Stone Shape", "Carat", "Cert", "L/W Ratio"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("combined = %q, want %q", got, want)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	svc, conn := newTestService(t)
+	ctx := context.Background()
+	file := writeCSV(t, "00_Master.csv", masterCSV)
+
+	report, err := svc.Run(ctx, Options{Files: []string{file}, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !report.DryRun || len(report.Sheets) != 1 || len(report.Rejects) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if res := report.Sheets[0]; res.Kind != KindMaster || res.Rows != 3 || res.Inserted != 3 {
+		t.Errorf("sheet = %+v", res)
+	}
+	// Three appointments, plus the SO link R1 creates; A2 repeats the link
+	// and leaves it unchanged.
+	var ops []string
+	for _, c := range report.Changes {
+		ops = append(ops, c.Table+" "+c.Key+" "+c.Op)
+	}
+	want := "appointments R1 insert,sales_orders VVS 120001 insert,appointments A2 insert,appointments R3 insert"
+	if got := strings.Join(ops, ","); got != want {
+		t.Errorf("changes = %s, want %s", got, want)
+	}
+	if n := count(t, conn, "appointments") + count(t, conn, "sales_orders"); n != 0 {
+		t.Errorf("%d rows left after a dry run, want 0", n)
+	}
+
+	// Against stored rows a dry run reports the fields it would change.
+	if _, err := svc.Run(ctx, Options{Files: []string{file}}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	edited := writeCSV(t, "00_Master.csv", strings.Replace(masterCSV, "R3,,HPUSA,Morgan,2024-04-09,Bea", "R3,,HPUSA,Morgan,2024-04-09,Cara", 1))
+	report, err = svc.Run(ctx, Options{Files: []string{edited}, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run after import: %v", err)
+	}
+	if len(report.Changes) != 1 {
+		t.Fatalf("changes = %+v, want one update", report.Changes)
+	}
+	c := report.Changes[0]
+	if c.Key != "R3" || c.Op != OpUpdate || len(c.Fields) != 1 || c.Fields[0].Column != "assigned_rep" ||
+		c.Fields[0].Old != "Bea" || c.Fields[0].New != "Cara" {
+		t.Errorf("change = %+v", c)
+	}
+	var rep string
+	if err := conn.QueryRow(`SELECT assigned_rep FROM appointments WHERE appt_id = 'R3'`).Scan(&rep); err != nil || rep != "Bea" {
+		t.Errorf("R3 rep after dry run = %q, %v", rep, err)
+	}
+}
+
+func TestReimport(t *testing.T) {
+	svc, conn := newTestService(t)
+	ctx := context.Background()
+	file := writeCSV(t, "00_Master.csv", masterCSV)
+
+	if _, err := svc.Run(ctx, Options{Files: []string{file}}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	var updatedAt string
+	if err := conn.QueryRow(`SELECT updated_at FROM appointments WHERE appt_id = 'R1'`).Scan(&updatedAt); err != nil {
+		t.Fatalf("select R1: %v", err)
+	}
+
+	svc.now = func() time.Time { return time.Date(2024, 5, 11, 9, 0, 0, 0, time.UTC) }
+	report, err := svc.Run(ctx, Options{Files: []string{file}})
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if res := report.Sheets[0]; res.Rows != 3 || res.Unchanged != 3 || res.Inserted+res.Updated+res.Rejected != 0 {
+		t.Errorf("re-import = %+v, want every row unchanged", res)
+	}
+	if got := report.Summary(); got != "1 sheets: 0 inserted, 0 updated, 3 unchanged, 0 rejected" {
+		t.Errorf("summary = %q", got)
+	}
+	if n := count(t, conn, "appointments"); n != 3 {
+		t.Errorf("appointments = %d, want 3", n)
+	}
+	if n := count(t, conn, "sales_orders"); n != 1 {
+		t.Errorf("sales orders = %d, want 1", n)
+	}
+	var again string
+	if err := conn.QueryRow(`SELECT updated_at FROM appointments WHERE appt_id = 'R1'`).Scan(&again); err != nil || again != updatedAt {
+		t.Errorf("R1 updated_at = %q, %v, want %q untouched", again, err, updatedAt)
+	}
+}
+
+func TestRejects(t *testing.T) {
+	svc, conn := newTestService(t)
+	ctx := context.Background()
+	if _, err := svc.Run(ctx, Options{Files: []string{writeCSV(t, "00_Master.csv", masterCSV)}}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		row    string
+		reason string
+	}{
+		{"SO owned by another root", "R4,,VVS,Pat,2024-04-20,Alice,,12.0001", "sales_orders VVS 120001 already belongs to root_appt_id R1"},
+		{"root moved off its SO", "R1,R3,VVS,Jamie Lee,2024-04-02,Alice,,120001", "sales_orders VVS 120001 already belongs to root_appt_id R1"},
+		{"bad brand", "R5,,ACME,Pat,2024-04-20,,,", "Brand: must be VVS or HPUSA"},
+		{"bad date", "R6,,VVS,Pat,someday,,,", `Visit Date: "someday" is not a date`},
+		{"no customer", "R7,,VVS,,2024-04-20,,,", "Customer Name: is required"},
+	}
+	for _, tt := range tests {
+		header, _, _ := strings.Cut(masterCSV, "\n")
+		report, err := svc.Run(ctx, Options{Files: []string{writeCSV(t, "00_Master.csv", header+"\n"+tt.row+"\n")}})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(report.Rejects) != 1 || report.Rejects[0].Reason != tt.reason || report.Rejects[0].Row != 2 {
+			t.Errorf("%s: rejects = %+v, want %q", tt.name, report.Rejects, tt.reason)
+		}
+		if res := report.Sheets[0]; res.Rejected != 1 || res.Inserted+res.Updated != 0 {
+			t.Errorf("%s: sheet = %+v", tt.name, res)
+		}
+	}
+
+	// A rejected row writes nothing, not even its appointment.
+	var root string
+	if err := conn.QueryRow(`SELECT root_appt_id FROM appointments WHERE appt_id = 'R1'`).Scan(&root); err != nil || root != "R1" {
+		t.Errorf("R1 root = %q, %v", root, err)
+	}
+	if n := count(t, conn, "appointments"); n != 3 {
+		t.Errorf("appointments = %d, want 3", n)
+	}
+}
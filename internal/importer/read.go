@@ -0,0 +1,312 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table is one sheet of an export: raw cell text, rows in sheet order.
+type Table struct {
+	Name string
+	Rows [][]string
+}
+
+// ReadFile loads every sheet of an .xlsx workbook, or the single sheet of a
+// .csv export (named after the file).
+func ReadFile(name string) ([]Table, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		rows, err := readCSV(f)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		return []Table{{Name: strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)), Rows: rows}}, nil
+	case ".xlsx":
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		defer zr.Close()
+		tables, err := readXLSX(&zr.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		return tables, nil
+	default:
+		return nil, fmt.Errorf("%s: only .xlsx and .csv exports are supported", name)
+	}
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// Excel re-saves CSV exports with a UTF-8 BOM.
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}
+
+// The XLSX reader handles what Google Sheets and Excel write: shared and
+// inline strings, numbers, booleans and date-formatted serials. Formulas are
+// read from their cached values.
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+	Date1904 struct {
+		Value string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt xlsxRichText) text() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var b strings.Builder
+	for _, r := range rt.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSST struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Style  int          `xml:"s,attr"`
+			V      string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(zr *zip.Reader) ([]Table, error) {
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	var wb xlsxWorkbook
+	if err := decodeZipXML(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels xlsxRels
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, r := range rels.Rels {
+		t := strings.TrimPrefix(r.Target, "/")
+		if !strings.HasPrefix(t, "xl/") {
+			t = path.Join("xl", t)
+		}
+		targets[r.ID] = t
+	}
+
+	var sst xlsxSST
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+	}
+	dateStyles := map[int]bool{}
+	if _, ok := files["xl/styles.xml"]; ok {
+		var st xlsxStyles
+		if err := decodeZipXML(files, "xl/styles.xml", &st); err != nil {
+			return nil, err
+		}
+		custom := map[int]string{}
+		for _, f := range st.NumFmts {
+			custom[f.ID] = f.Code
+		}
+		for i, xf := range st.CellXfs {
+			dateStyles[i] = isDateFormat(xf.NumFmtID, custom[xf.NumFmtID])
+		}
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if v := wb.Date1904.Value; v == "1" || v == "true" {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var tables []Table
+	for _, sh := range wb.Sheets {
+		target, ok := targets[sh.RID]
+		if !ok {
+			return nil, fmt.Errorf("sheet %q: missing relationship %s", sh.Name, sh.RID)
+		}
+		var data xlsxSheet
+		if err := decodeZipXML(files, target, &data); err != nil {
+			return nil, err
+		}
+		t := Table{Name: sh.Name}
+		for _, row := range data.Rows {
+			idx := row.R - 1
+			if idx < len(t.Rows) {
+				idx = len(t.Rows)
+			}
+			for len(t.Rows) <= idx {
+				t.Rows = append(t.Rows, nil)
+			}
+			var cells []string
+			for i, c := range row.Cells {
+				col := i
+				if c.Ref != "" {
+					col = columnIndex(c.Ref)
+				}
+				for len(cells) <= col {
+					cells = append(cells, "")
+				}
+				cells[col] = cellText(c.Type, c.V, c.Inline, sst, dateStyles[c.Style], epoch)
+			}
+			t.Rows[idx] = cells
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("open %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+func cellText(typ, v string, inline xlsxRichText, sst xlsxSST, dateStyle bool, epoch time.Time) string {
+	switch typ {
+	case "s":
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= len(sst.Items) {
+			return ""
+		}
+		return sst.Items[i].text()
+	case "inlineStr":
+		return inline.text()
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return v
+	}
+	if v == "" || !dateStyle {
+		return v
+	}
+	serial, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	// Round to the second; serials carry float noise.
+	secs := math.Round(serial * 86400)
+	t := epoch.Add(time.Duration(secs) * time.Second)
+	switch {
+	case serial < 1:
+		return t.Format("15:04")
+	case math.Mod(secs, 86400) == 0:
+		return t.Format("2006-01-02")
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
+
+// isDateFormat reports whether a number format renders dates or times: the
+// built-in ids Excel reserves for them, or a custom code using d/m/y/h/s
+// outside quoted or bracketed sections.
+func isDateFormat(id int, code string) bool {
+	if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+		return true
+	}
+	if code == "" {
+		return false
+	}
+	inQuote, inBracket := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case strings.ContainsRune("dmyhs", r):
+			return true
+		}
+	}
+	return false
+}
+
+// columnIndex turns a cell reference ("AB12") into a 0-based column.
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
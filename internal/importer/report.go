@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteSummary prints one line per sheet with its row counts.
+func (r *Report) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SHEET\tKIND\tROWS\tINSERTED\tUPDATED\tUNCHANGED\tREJECTED")
+	for _, s := range r.Sheets {
+		if s.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t%s\n", s.Sheet, s.Kind, s.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n", s.Sheet, s.Kind, s.Rows, s.Inserted, s.Updated, s.Unchanged, s.Rejected)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, s := range r.Sheets {
+		if len(s.Ignored) > 0 {
+			fmt.Fprintf(w, "%s: ignored columns: %s\n", s.Sheet, strings.Join(s.Ignored, ", "))
+		}
+	}
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "skipped (unrecognised sheet): %s\n", s)
+	}
+	if r.DryRun {
+		fmt.Fprintln(w, "dry run: nothing was written")
+	}
+	return nil
+}
+
+// Summary totals the run in one line, for the activity log.
+func (r *Report) Summary() string {
+	var t SheetResult
+	for _, s := range r.Sheets {
+		t.Inserted += s.Inserted
+		t.Updated += s.Updated
+		t.Unchanged += s.Unchanged
+		t.Rejected += s.Rejected
+	}
+	return fmt.Sprintf("%d sheets: %d inserted, %d updated, %d unchanged, %d rejected",
+		len(r.Sheets), t.Inserted, t.Updated, t.Unchanged, t.Rejected)
+}
+
+// WriteDiff prints each change of a dry run as "+" (new row) or "~" (changed
+// row) followed by the columns that differ.
+func (r *Report) WriteDiff(w io.Writer) error {
+	for _, c := range r.Changes {
+		mark := "~"
+		if c.Op == OpInsert {
+			mark = "+"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %s  (%s row %d)\n", mark, c.Table, c.Key, c.Sheet, c.Row); err != nil {
+			return err
+		}
+		for _, f := range c.Fields {
+			var err error
+			if c.Op == OpInsert {
+				_, err = fmt.Fprintf(w, "    %s: %q\n", f.Column, fmt.Sprint(f.New))
+			} else {
+				_, err = fmt.Fprintf(w, "    %s: %q -> %q\n", f.Column, fmt.Sprint(f.Old), fmt.Sprint(f.New))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteRejects writes the rejected-rows report as CSV. The original cells
+// are kept as a JSON object of label to value so sheets with different
+// headers share one file.
+func (r *Report) WriteRejects(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"File", "Sheet", "Row", "Reason", "Values"}); err != nil {
+		return fmt.Errorf("write rejects header: %w", err)
+	}
+	for _, rej := range r.Rejects {
+		values, err := json.Marshal(rej.Values)
+		if err != nil {
+			return fmt.Errorf("encode reject values: %w", err)
+		}
+		if err := cw.Write([]string{rej.File, rej.Sheet, fmt.Sprint(rej.Row), rej.Reason, string(values)}); err != nil {
+			return fmt.Errorf("write reject: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,663 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/diamonds"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/payments"
+)
+
+// Sheet kinds the importer understands.
+const (
+	KindMaster    = "master"
+	KindStatusLog = "status-log"
+	KindReminders = "reminders"
+	KindWax       = "wax"
+	KindDiamonds  = "diamonds"
+	KindPayments  = "payments"
+)
+
+// sheetSpec maps one sheet onto table records. Specs are listed in import
+// order: appointments and orders first, so payments can resolve their root
+// from an SO linked in the same run.
+type sheetSpec struct {
+	kind       string
+	names      []string // sheet or file names, compared by headerKey suffix
+	headerRows int
+	fields     []field
+	build      func(r *row) ([]record, error)
+}
+
+// row is one data row with its resolved header.
+type row struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	hm    headerMap
+	cells []string
+	loc   *time.Location
+	spec  *sheetSpec
+}
+
+func (r *row) get(name string) string {
+	return r.hm.get(r.cells, name)
+}
+
+// label is the canonical sheet label of a field, for reject reasons.
+func (r *row) label(name string) string {
+	for _, f := range r.spec.fields {
+		if f.name == name {
+			return f.labels[0]
+		}
+	}
+	return name
+}
+
+func (r *row) reject(name, msg string) error {
+	return &ValidationError{Field: r.label(name), Message: msg}
+}
+
+func (r *row) required(name string) (string, error) {
+	v := r.get(name)
+	if v == "" {
+		return "", r.reject(name, "is required")
+	}
+	return v, nil
+}
+
+func (r *row) date(name string) (string, error) {
+	v, err := parseDate(r.get(name))
+	if err != nil {
+		return "", r.reject(name, fmt.Sprintf("%q is not a date", r.get(name)))
+	}
+	return v, nil
+}
+
+func (r *row) timestamp(name string) (string, error) {
+	v, err := parseTimestamp(r.get(name), r.loc)
+	if err != nil {
+		return "", r.reject(name, fmt.Sprintf("%q is not a date/time", r.get(name)))
+	}
+	return v, nil
+}
+
+func (r *row) money(name string) (int64, error) {
+	v, err := parseMoney(r.get(name))
+	if err != nil {
+		return 0, r.reject(name, fmt.Sprintf("%q is not an amount", r.get(name)))
+	}
+	return v, nil
+}
+
+func (r *row) number(name string) (float64, error) {
+	v, err := parseNumber(r.get(name))
+	if err != nil {
+		return 0, r.reject(name, fmt.Sprintf("%q is not a number", r.get(name)))
+	}
+	return v, nil
+}
+
+func (r *row) integer(name string) (int64, error) {
+	v, err := parseInt(r.get(name))
+	if err != nil {
+		return 0, r.reject(name, fmt.Sprintf("%q is not a whole number", r.get(name)))
+	}
+	return v, nil
+}
+
+// firstErr returns the first non-nil error, so builders can parse every
+// column and reject on the leftmost problem.
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var specs = []*sheetSpec{
+	{
+		kind:       KindMaster,
+		names:      []string{"00_Master Appointments", "00_Master", "00 – Master", "00 Master"},
+		headerRows: 1,
+		fields: []field{
+			{name: "appt_id", labels: []string{"APPT_ID"}, require: true},
+			{name: "root_appt_id", labels: []string{"RootApptID", "Appt ID", "ApptID", "Appointment ID", "Root Appt ID"}},
+			{name: "brand", labels: []string{"Brand", "Company", "Company Brand"}, require: true},
+			{name: "customer_name", labels: []string{"Customer Name", "Customer", "Name", "Client Name"}, require: true},
+			{name: "email_lower", labels: []string{"EmailLower", "Email", "Customer Email"}},
+			{name: "phone_norm", labels: []string{"PhoneNorm", "Phone", "Customer Phone"}},
+			{name: "visit_date", labels: []string{"Visit Date"}, require: true},
+			{name: "visit_time", labels: []string{"Visit Time"}},
+			{name: "visit_type", labels: []string{"Visit Type"}},
+			{name: "visit_number", labels: []string{"Visit #", "Visit Number"}},
+			{name: "assigned_rep", labels: []string{"Assigned Rep", "Sales Rep", "Rep", "Owner", "Assigned Sales Rep"}},
+			{name: "assisted_rep", labels: []string{"Assisted Rep"}},
+			{name: "sales_stage", labels: []string{"Sales Stage"}},
+			{name: "conversion_status", labels: []string{"Conversion Status"}},
+			{name: "custom_order_status", labels: []string{"Custom Order Status", "Order Status", "Status"}},
+			{name: "center_stone_order_status", labels: []string{"Center Stone Order Status", "CSOS"}},
+			{name: "next_steps", labels: []string{"Next Steps"}},
+			{name: "so_number", labels: []string{"SO#", "SO Number", "SO No", "SO"}},
+			{name: "odoo_so_url", labels: []string{"Odoo SO URL", "SO URL", "Odoo Link"}},
+			{name: "so_linked_at", labels: []string{"SO Linked At"}},
+		},
+		build: buildMaster,
+	},
+	{
+		kind:       KindStatusLog,
+		names:      []string{"03_Client_Status_Log", "Client Status Log"},
+		headerRows: 1,
+		fields: []field{
+			{name: "appt_id", labels: []string{"APPT_ID", "RootApptID"}, require: true},
+			{name: "log_date", labels: []string{"Log Date"}},
+			{name: "sales_stage", labels: []string{"Sales Stage"}},
+			{name: "conversion_status", labels: []string{"Conversion Status"}},
+			{name: "custom_order_status", labels: []string{"Custom Order Status", "Order Status"}},
+			{name: "in_production_status", labels: []string{"In Production Status"}},
+			{name: "center_stone_order_status", labels: []string{"Center Stone Order Status", "CSOS"}},
+			{name: "next_steps", labels: []string{"Next Steps"}},
+			{name: "assisted_rep", labels: []string{"Assisted Rep"}},
+			{name: "updated_by", labels: []string{"Updated By"}},
+			{name: "updated_at", labels: []string{"Updated At"}},
+			{name: "applied_to", labels: []string{"Applied To"}},
+			{name: "deadline_type", labels: []string{"Deadline Type"}},
+			{name: "deadline_date", labels: []string{"Deadline Date"}},
+			{name: "move_count", labels: []string{"Move Count", "# of Times Moved"}},
+		},
+		build: buildStatusLog,
+	},
+	{
+		kind:       KindReminders,
+		names:      []string{"04_Reminders_Queue", "Reminders Queue"},
+		headerRows: 1,
+		fields: []field{
+			{name: "id", labels: []string{"id", "Reminder ID"}, require: true},
+			{name: "type", labels: []string{"type"}, require: true},
+			{name: "status", labels: []string{"status"}, require: true},
+			{name: "so_number", labels: []string{"soNumber", "SO#", "SO"}},
+			{name: "root_appt_id", labels: []string{"rootApptId", "RootApptID"}},
+			{name: "customer_name", labels: []string{"customerName", "Customer"}},
+			{name: "next_steps", labels: []string{"nextSteps"}},
+			{name: "notes", labels: []string{"notes"}},
+			{name: "assigned_rep", labels: []string{"assignedRepName", "Assigned Rep", "Assigned"}},
+			{name: "assisted_rep", labels: []string{"assistedRepName", "Assisted Rep", "Assisted"}},
+			{name: "first_due_date", labels: []string{"firstDueDate"}, require: true},
+			{name: "next_due_at", labels: []string{"nextDueAt"}, require: true},
+			{name: "snooze_until", labels: []string{"snoozeUntil"}},
+			{name: "attempts", labels: []string{"attempts"}},
+			{name: "last_sent_at", labels: []string{"lastSentAt"}},
+			{name: "confirmed_at", labels: []string{"confirmedAt"}},
+			{name: "confirmed_by", labels: []string{"confirmedBy"}},
+			{name: "created_at", labels: []string{"createdAt"}},
+			{name: "created_by", labels: []string{"createdBy"}},
+			{name: "updated_at", labels: []string{"updatedAt"}},
+		},
+		build: buildReminder,
+	},
+	{
+		kind:       KindWax,
+		names:      []string{"05_Wax_Requests", "Wax Requests"},
+		headerRows: 1,
+		fields: []field{
+			{name: "wax_request_id", labels: []string{"WaxRequestID", "Wax Request ID"}, require: true},
+			{name: "root_appt_id", labels: []string{"RootApptID", "APPT_ID"}},
+			{name: "so_number", labels: []string{"SO/MO Number", "SO#", "SO Number"}},
+			{name: "brand", labels: []string{"Brand", "Company"}},
+			{name: "customer_name", labels: []string{"Customer Name", "Customer"}},
+			{name: "assigned_rep", labels: []string{"Assigned Rep", "Sales Rep"}},
+			{name: "assisted_rep", labels: []string{"Assisted Rep"}},
+			{name: "requested_by", labels: []string{"Requested By"}},
+			{name: "priority", labels: []string{"Priority"}},
+			{name: "requested_date", labels: []string{"Requested Date"}},
+			{name: "needed_by", labels: []string{"Needed By (Rep)", "Needed By"}},
+			{name: "status", labels: []string{"Wax Print Status", "Status"}},
+			{name: "admin_deadline", labels: []string{"Wax Deadline (Admin)", "Wax Deadline"}},
+			{name: "estimated_print_date", labels: []string{"Estimated Print Date"}},
+			{name: "completed_print_date", labels: []string{"Completed Print Date"}},
+			{name: "status_notes", labels: []string{"Status Notes"}},
+			{name: "updated_by", labels: []string{"Updated By"}},
+			{name: "updated_at", labels: []string{"Updated At"}},
+		},
+		build: buildWax,
+	},
+	{
+		kind:       KindDiamonds,
+		names:      []string{"0. MASTER LG SHEET", "MASTER LG SHEET"},
+		headerRows: 2,
+		fields: []field{
+			{name: "root_appt_id", labels: []string{"RootApptID", "APPT_ID"}},
+			{name: "stone_status", labels: []string{"Stone Status"}},
+			{name: "order_status", labels: []string{"Order Status"}},
+			{name: "ordered_by", labels: []string{"Ordered By"}},
+			{name: "requested_by", labels: []string{"Requested By"}},
+			{name: "request_date", labels: []string{"Request Date"}},
+			{name: "ordered_date", labels: []string{"Purchased / Ordered Date", "Purchased/Ordered Date"}},
+			{name: "assigned_rep", labels: []string{"Assigned Rep", "Sales Rep", "Assigned Sales Rep"}},
+			{name: "customer_name", labels: []string{"Customer Name", "Client Name", "Customer"}},
+			{name: "appt_time", labels: []string{"Customer Appt Time & Date"}},
+			{name: "brand", labels: []string{"Company", "Brand"}},
+			{name: "vendor", labels: []string{"Vendor"}},
+			{name: "stone_type", labels: []string{"Stone Type"}},
+			{name: "shape", labels: []string{"Shape"}},
+			{name: "carat", labels: []string{"Carat"}},
+			{name: "color", labels: []string{"Color"}},
+			{name: "clarity", labels: []string{"Clarity"}},
+			{name: "measurements", labels: []string{"Measurements", "Measurement", "Meas."}},
+			{name: "lw_ratio", labels: []string{"L/W Ratio", "L-W Ratio", "LW Ratio"}},
+			{name: "lab", labels: []string{"LAB", "Grading Lab"}},
+			{name: "certificate_no", labels: []string{"Certificate No", "Cert #", "Cert No", "Certificate #", "Certificate Number"}},
+			{name: "decision", labels: []string{"Stone Decision (PO, Return)", "Stone Decision"}},
+			{name: "cut", labels: []string{"Cut"}},
+			{name: "polish", labels: []string{"Pol.", "Polish"}},
+			{name: "symmetry", labels: []string{"Sym.", "Symmetry"}},
+			{name: "fluor_intensity", labels: []string{"Fluor.Intesity", "Fluor.Intensity", "Fluor Intensity"}},
+			{name: "fluor_color", labels: []string{"Fluor.Color", "Fluorescence Color"}},
+			{name: "memo_date", labels: []string{"Memo/ Invoice Date", "Memo Invoice Date"}},
+			{name: "return_due_date", labels: []string{"Return DUE DATE", "Return Due"}},
+		},
+		build: buildStone,
+	},
+	{
+		kind:       KindPayments,
+		names:      []string{"400_Payments", "Payments"},
+		headerRows: 1,
+		fields: []field{
+			{name: "payment_id", labels: []string{"PAYMENT_ID", "Payment ID"}, require: true},
+			{name: "brand", labels: []string{"Brand"}},
+			{name: "root_appt_id", labels: []string{"RootApptID", "Root Appt ID", "APPT_ID"}},
+			{name: "so_number", labels: []string{"SO#", "SO"}},
+			{name: "anchor_type", labels: []string{"AnchorType"}},
+			{name: "basket_id", labels: []string{"BasketID"}},
+			{name: "customer_name", labels: []string{"Customer Name", "CustomerName", "Customer"}},
+			{name: "doc_type", labels: []string{"DocType", "Doc Type"}, require: true},
+			{name: "doc_role", labels: []string{"DocRole"}},
+			{name: "doc_status", labels: []string{"DocStatus", "Status"}},
+			{name: "payment_date_time", labels: []string{"PaymentDateTime"}},
+			{name: "method", labels: []string{"Method", "Payment Method"}},
+			{name: "reference", labels: []string{"Reference"}},
+			{name: "notes", labels: []string{"Notes"}},
+			{name: "lines_json", labels: []string{"LinesJSON"}},
+			{name: "subtotal", labels: []string{"Subtotal"}},
+			{name: "amount_gross", labels: []string{"AmountGross"}},
+			{name: "fee_percent", labels: []string{"FeePercent", "Fee %"}},
+			{name: "fee_amount", labels: []string{"FeeAmount"}},
+			{name: "amount_net", labels: []string{"AmountNet"}},
+			{name: "allocated_to_so", labels: []string{"AllocatedToSO"}},
+			{name: "requested_amount", labels: []string{"RequestedAmount"}},
+			{name: "order_total", labels: []string{"Order Total_SO", "Order Total"}},
+			{name: "paid_before", labels: []string{"Paid-To-Date_SO", "Paid-to-Date"}},
+			{name: "balance_before", labels: []string{"Balance_SO"}},
+			{name: "submitted_by", labels: []string{"Submitted By"}},
+			{name: "submitted_at", labels: []string{"Submitted Date/Time", "Submitted At"}, require: true},
+		},
+		build: buildPayment,
+	},
+}
+
+// specFor finds the spec for a sheet: by kind when one is forced, else by
+// the sheet (or CSV file) name ending in one of the spec's names, so
+// "100_ Master - 00_Master Appointments" matches.
+func specFor(sheetName, kind string) *sheetSpec {
+	if kind != "" {
+		for _, s := range specs {
+			if s.kind == kind {
+				return s
+			}
+		}
+		return nil
+	}
+	key := headerKey(sheetName)
+	for _, s := range specs {
+		for _, n := range s.names {
+			if strings.HasSuffix(key, headerKey(n)) {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// Kinds lists the sheet kinds in import order.
+func Kinds() []string {
+	out := make([]string, len(specs))
+	for i, s := range specs {
+		out[i] = s.kind
+	}
+	return out
+}
+
+func buildMaster(r *row) ([]record, error) {
+	apptID, err := r.required("appt_id")
+	if err != nil {
+		return nil, err
+	}
+	root := r.get("root_appt_id")
+	if root == "" {
+		root = apptID
+	}
+	brand := strings.ToUpper(r.get("brand"))
+	if brand != appointments.BrandVVS && brand != appointments.BrandHPUSA {
+		return nil, r.reject("brand", "must be VVS or HPUSA")
+	}
+	name, err := r.required("customer_name")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.required("visit_date"); err != nil {
+		return nil, err
+	}
+	visitDate, dateErr := r.date("visit_date")
+	visitTime, timeErr := parseClock(r.get("visit_time"))
+	if timeErr != nil {
+		timeErr = r.reject("visit_time", fmt.Sprintf("%q is not a time", r.get("visit_time")))
+	}
+	visitNumber, numErr := r.integer("visit_number")
+	linkedAt, linkErr := r.timestamp("so_linked_at")
+	if err := firstErr(dateErr, timeErr, numErr, linkErr); err != nil {
+		return nil, err
+	}
+	so := soNumber(r.get("so_number"))
+
+	appt := record{
+		table: "appointments",
+		keys:  1,
+		cols: []string{"appt_id", "root_appt_id", "brand", "customer_name", "email_lower", "phone_norm",
+			"visit_date", "visit_time", "visit_type", "visit_number", "assigned_rep", "assisted_rep",
+			"sales_stage", "conversion_status", "custom_order_status", "center_stone_order_status",
+			"next_steps", "so_number", "odoo_so_url", "so_linked_at"},
+		vals: []any{apptID, root, brand, name, appointments.NormEmail(r.get("email_lower")),
+			appointments.NormPhone(r.get("phone_norm")), visitDate, visitTime, r.get("visit_type"), visitNumber,
+			r.get("assigned_rep"), r.get("assisted_rep"), r.get("sales_stage"), r.get("conversion_status"),
+			r.get("custom_order_status"), r.get("center_stone_order_status"), r.get("next_steps"),
+			so, r.get("odoo_so_url"), linkedAt},
+		created: []string{"created_at"},
+		touched: []string{"updated_at"},
+	}
+	if so == "" {
+		return []record{appt}, nil
+	}
+	// Every visit row of a root repeats its SO; the registry keeps one link
+	// and refuses to move an SO to another root, as orders.Assign does.
+	order := record{
+		table:   "sales_orders",
+		keys:    2,
+		cols:    []string{"brand", "so_key", "so_pretty", "root_appt_id", "odoo_url"},
+		vals:    []any{brand, orders.SOKey(so), so, root, r.get("odoo_so_url")},
+		fixed:   []string{"root_appt_id"},
+		created: []string{"created_at"},
+		touched: []string{"updated_at"},
+	}
+	if linkedAt != "" {
+		order.cols = append(order.cols, "linked_at")
+		order.vals = append(order.vals, linkedAt)
+	} else {
+		order.created = append(order.created, "linked_at")
+	}
+	return []record{appt, order}, nil
+}
+
+func buildStatusLog(r *row) ([]record, error) {
+	apptID, err := r.required("appt_id")
+	if err != nil {
+		return nil, err
+	}
+	logDate, dateErr := r.date("log_date")
+	updatedAt, stampErr := r.timestamp("updated_at")
+	deadline, deadlineErr := r.date("deadline_date")
+	moves, movesErr := r.integer("move_count")
+	if err := firstErr(dateErr, stampErr, deadlineErr, movesErr); err != nil {
+		return nil, err
+	}
+	// The log has no id column: a row is its appointment plus the moment it
+	// was written, or its whole content when the stamp is missing.
+	key := rowKey(apptID, updatedAt)
+	if updatedAt == "" {
+		key = rowKey(append([]string{apptID}, r.cells...)...)
+	}
+	return []record{{
+		table: "client_status_log",
+		keys:  1,
+		cols: []string{"source_key", "appt_id", "log_date", "sales_stage", "conversion_status",
+			"custom_order_status", "in_production_status", "center_stone_order_status", "next_steps",
+			"assisted_rep", "updated_by", "updated_at", "applied_to", "deadline_type", "deadline_date", "move_count"},
+		vals: []any{key, apptID, logDate, r.get("sales_stage"), r.get("conversion_status"),
+			r.get("custom_order_status"), r.get("in_production_status"), r.get("center_stone_order_status"),
+			r.get("next_steps"), r.get("assisted_rep"), r.get("updated_by"), updatedAt, r.get("applied_to"),
+			r.get("deadline_type"), deadline, moves},
+		created: []string{"created_at"},
+	}}, nil
+}
+
+func buildReminder(r *row) ([]record, error) {
+	id, err := r.required("id")
+	if err != nil {
+		return nil, err
+	}
+	remType, err := r.required("type")
+	if err != nil {
+		return nil, err
+	}
+	status, err := r.required("status")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.required("first_due_date"); err != nil {
+		return nil, err
+	}
+	if _, err := r.required("next_due_at"); err != nil {
+		return nil, err
+	}
+	firstDue, e1 := r.date("first_due_date")
+	nextDue, e2 := r.timestamp("next_due_at")
+	snooze, e3 := r.date("snooze_until")
+	attempts, e4 := r.integer("attempts")
+	lastSent, e5 := r.timestamp("last_sent_at")
+	confirmedAt, e6 := r.timestamp("confirmed_at")
+	createdAt, e7 := r.timestamp("created_at")
+	updatedAt, e8 := r.timestamp("updated_at")
+	if err := firstErr(e1, e2, e3, e4, e5, e6, e7, e8); err != nil {
+		return nil, err
+	}
+	rec := record{
+		table: "reminders_queue",
+		keys:  1,
+		cols: []string{"id", "type", "so_number", "root_appt_id", "customer_name", "next_steps", "notes",
+			"assigned_rep", "assisted_rep", "status", "first_due_date", "next_due_at", "snooze_until",
+			"attempts", "last_sent_at", "confirmed_at", "confirmed_by", "created_by"},
+		vals: []any{id, strings.ToUpper(remType), orders.SOPretty(r.get("so_number")), r.get("root_appt_id"),
+			r.get("customer_name"), r.get("next_steps"), r.get("notes"), r.get("assigned_rep"), r.get("assisted_rep"),
+			strings.ToUpper(status), firstDue, nextDue, snooze, attempts, lastSent, confirmedAt,
+			r.get("confirmed_by"), r.get("created_by")},
+	}
+	rec.stamp("created_at", createdAt, false)
+	rec.stamp("updated_at", updatedAt, true)
+	return []record{rec}, nil
+}
+
+func buildWax(r *row) ([]record, error) {
+	id, err := r.required("wax_request_id")
+	if err != nil {
+		return nil, err
+	}
+	requested, e1 := r.date("requested_date")
+	neededBy, e2 := r.date("needed_by")
+	deadline, e3 := r.date("admin_deadline")
+	estimated, e4 := r.date("estimated_print_date")
+	completed, e5 := r.date("completed_print_date")
+	updatedAt, e6 := r.timestamp("updated_at")
+	if err := firstErr(e1, e2, e3, e4, e5, e6); err != nil {
+		return nil, err
+	}
+	rec := record{
+		table: "wax_requests",
+		keys:  1,
+		cols: []string{"wax_request_id", "root_appt_id", "so_number", "brand", "customer_name", "assigned_rep",
+			"assisted_rep", "requested_by", "priority", "requested_date", "needed_by", "status", "admin_deadline",
+			"estimated_print_date", "completed_print_date", "status_notes", "updated_by"},
+		vals: []any{id, r.get("root_appt_id"), soNumber(r.get("so_number")), strings.ToUpper(r.get("brand")),
+			r.get("customer_name"), r.get("assigned_rep"), r.get("assisted_rep"), r.get("requested_by"),
+			r.get("priority"), requested, neededBy, r.get("status"), deadline, estimated, completed,
+			r.get("status_notes"), r.get("updated_by")},
+		created: []string{"created_at"},
+	}
+	rec.stamp("updated_at", updatedAt, true)
+	return []record{rec}, nil
+}
+
+func buildStone(r *row) ([]record, error) {
+	cert := r.get("certificate_no")
+	if cert == "" && r.get("shape") == "" {
+		return nil, &ValidationError{Field: "Certificate No", Message: "is required (or at least Shape to identify the stone)"}
+	}
+	carat, e1 := r.number("carat")
+	requested, e2 := r.date("request_date")
+	ordered, e3 := r.date("ordered_date")
+	memo, e4 := r.date("memo_date")
+	due, e5 := r.date("return_due_date")
+	if err := firstErr(e1, e2, e3, e4, e5); err != nil {
+		return nil, err
+	}
+	root := r.get("root_appt_id")
+	// Certificates identify a stone across rows; uncertified proposals fall
+	// back to their descriptive columns.
+	id := diamonds.StoneID(r.get("lab"), cert)
+	if cert == "" {
+		id = "STN-" + rowKey(root, r.get("vendor"), r.get("shape"), r.get("carat"), r.get("color"), r.get("clarity"), r.get("measurements"))
+	}
+	return []record{{
+		table: "stones",
+		keys:  1,
+		cols: []string{"stone_id", "root_appt_id", "brand", "customer_name", "assigned_rep", "appt_time",
+			"stone_status", "order_status", "decision", "requested_by", "request_date", "ordered_by", "ordered_date",
+			"memo_date", "return_due_date", "vendor", "stone_type", "shape", "carat", "color", "clarity", "cut", "polish", "symmetry",
+			"fluor_intensity", "fluor_color", "measurements", "lw_ratio", "lab", "certificate_no"},
+		vals: []any{id, root, strings.ToUpper(r.get("brand")), r.get("customer_name"), r.get("assigned_rep"),
+			r.get("appt_time"), r.get("stone_status"), r.get("order_status"), r.get("decision"), r.get("requested_by"),
+			requested, r.get("ordered_by"), ordered, memo, due, r.get("vendor"), r.get("stone_type"), r.get("shape"), carat,
+			r.get("color"), r.get("clarity"), r.get("cut"), r.get("polish"), r.get("symmetry"),
+			r.get("fluor_intensity"), r.get("fluor_color"), r.get("measurements"), r.get("lw_ratio"), r.get("lab"), cert},
+		created: []string{"created_at"},
+		touched: []string{"updated_at"},
+	}}, nil
+}
+
+func buildPayment(r *row) ([]record, error) {
+	id, err := r.required("payment_id")
+	if err != nil {
+		return nil, err
+	}
+	docType, err := r.required("doc_type")
+	if err != nil {
+		return nil, err
+	}
+	kind := payments.DocKind(docType)
+	if kind == "" {
+		return nil, r.reject("doc_type", "must name a receipt, invoice or credit")
+	}
+	if _, err := r.required("submitted_at"); err != nil {
+		return nil, err
+	}
+	submittedAt, e1 := r.timestamp("submitted_at")
+	paidAt, e2 := r.timestamp("payment_date_time")
+	subtotal, e3 := r.money("subtotal")
+	gross, e4 := r.money("amount_gross")
+	feePct, e5 := r.number("fee_percent")
+	fee, e6 := r.money("fee_amount")
+	net, e7 := r.money("amount_net")
+	alloc, e8 := r.money("allocated_to_so")
+	requested, e9 := r.money("requested_amount")
+	total, e10 := r.money("order_total")
+	paidBefore, e11 := r.money("paid_before")
+	balBefore, e12 := r.money("balance_before")
+	if err := firstErr(e1, e2, e3, e4, e5, e6, e7, e8, e9, e10, e11, e12); err != nil {
+		return nil, err
+	}
+
+	lines := []byte("[]")
+	if raw := r.get("lines_json"); raw != "" {
+		var parsed []json.RawMessage
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			return nil, r.reject("lines_json", "is not a JSON array")
+		}
+		if lines, err = json.Marshal(parsed); err != nil {
+			return nil, fmt.Errorf("encode lines: %w", err)
+		}
+	}
+
+	brand := strings.ToUpper(r.get("brand"))
+	so := orders.SOPretty(r.get("so_number"))
+	root := r.get("root_appt_id")
+	if root == "" && so != "" {
+		// SO-anchored rows may predate the RootApptID column.
+		err := r.tx.QueryRowContext(r.ctx, `SELECT root_appt_id FROM sales_orders WHERE brand = ? AND so_key = ?`,
+			brand, orders.SOKey(so)).Scan(&root)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("resolve payment root: %w", err)
+		}
+	}
+	if root == "" {
+		return nil, r.reject("root_appt_id", "is required when the SO is not registered")
+	}
+	anchor := strings.ToUpper(r.get("anchor_type"))
+	if anchor == "" {
+		anchor = payments.AnchorAPPT
+		if so != "" {
+			anchor = payments.AnchorSO
+		}
+	}
+	role := strings.ToUpper(r.get("doc_role"))
+	if role == "" {
+		role = payments.DefaultDocRole(docType, string(lines) != "[]")
+	}
+	status := strings.ToUpper(r.get("doc_status"))
+	if status == "" {
+		// Rows from before the DocStatus column were issued documents, as
+		// Payment_Summary_v1.js assumes.
+		status = payments.StatusIssued
+	}
+	if feePct > 1 {
+		feePct /= 100 // "3%" and 3 both mean 0.03
+	}
+
+	// The ledger snapshots the SO before the document; after-values follow
+	// the same rules as payments.Record.
+	paidAfter, balAfter := paidBefore, balBefore
+	if payments.Counts(status) {
+		switch kind {
+		case payments.KindReceipt:
+			paidAfter += alloc
+			balAfter -= alloc
+		case payments.KindCredit:
+			balAfter -= subtotal
+		}
+	}
+	balAfter = max(balAfter, 0)
+
+	return []record{{
+		table: "payments",
+		keys:  1,
+		cols: []string{"payment_id", "brand", "root_appt_id", "so_number", "anchor_type", "basket_id", "customer_name",
+			"doc_type", "doc_kind", "doc_role", "doc_status", "payment_date_time", "method", "reference", "notes",
+			"lines_json", "subtotal_cents", "amount_gross_cents", "fee_percent", "fee_amount_cents", "amount_net_cents",
+			"allocated_to_so_cents", "requested_amount_cents", "order_total_cents", "paid_before_cents",
+			"balance_before_cents", "paid_after_cents", "balance_after_cents", "submitted_by", "submitted_at"},
+		vals: []any{id, brand, root, so, anchor, r.get("basket_id"), r.get("customer_name"),
+			docType, kind, role, status, paidAt, r.get("method"), r.get("reference"), r.get("notes"),
+			string(lines), subtotal, gross, feePct, fee, net,
+			alloc, requested, total, paidBefore,
+			balBefore, paidAfter, balAfter, r.get("submitted_by"), submittedAt},
+		after: func(ctx context.Context, tx *sql.Tx) error {
+			return payments.RefreshBalance(ctx, tx, brand, so, root)
+		},
+		afterKey: payments.AnchorKey(brand, so, root),
+	}}, nil
+}
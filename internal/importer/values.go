@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/orders"
+)
+
+var errBadValue = errors.New("bad value")
+
+// Sheets display dates and stamps in several shapes depending on the column
+// format and whether the export came from Sheets, Excel or the CSV download.
+var (
+	dateLayouts = []string{
+		"2006-01-02", "1/2/2006", "1/2/06", "Jan 2, 2006", "January 2, 2006", "2-Jan-2006", "Mon, Jan 2, 2006",
+	}
+	clockLayouts = []string{
+		"15:04:05", "15:04", "3:04:05 PM", "3:04 PM", "3:04PM", "3PM", "3 PM",
+	}
+)
+
+// parseDate returns v as YYYY-MM-DD. Stamps keep only their date.
+func parseDate(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	if t, err := parseStamp(v, time.UTC); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	return "", errBadValue
+}
+
+// parseClock returns v as 24h HH:MM.
+func parseClock(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, strings.ToUpper(v)); err == nil {
+			return t.Format("15:04"), nil
+		}
+	}
+	// Time cells exported with a date part ("1899-12-30 14:00:00").
+	if t, err := parseStamp(v, time.UTC); err == nil {
+		return t.Format("15:04"), nil
+	}
+	return "", errBadValue
+}
+
+// parseTimestamp returns v as RFC 3339 UTC. Sheet stamps without an offset
+// are wall-clock times in loc.
+func parseTimestamp(v string, loc *time.Location) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	t, err := parseStamp(v, loc)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+func parseStamp(v string, loc *time.Location) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	for _, d := range dateLayouts {
+		if t, err := time.ParseInLocation(d, v, loc); err == nil {
+			return t, nil
+		}
+		for _, c := range clockLayouts {
+			for _, sep := range []string{" ", "T", ", "} {
+				if t, err := time.ParseInLocation(d+sep+c, strings.ToUpper(v), loc); err == nil {
+					return t, nil
+				}
+			}
+		}
+	}
+	return time.Time{}, errBadValue
+}
+
+// parseMoney returns dollars as cents, accepting "$1,234.50" and accounting
+// negatives "(25.00)".
+func parseMoney(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	neg := strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")")
+	clean := strings.NewReplacer("$", "", ",", "", "(", "", ")", "", " ", "").Replace(v)
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, errBadValue
+	}
+	if neg {
+		f = -f
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+// parseNumber accepts plain decimals with optional thousands separators and
+// a trailing % (returned as written, not divided).
+func parseNumber(v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	clean := strings.NewReplacer(",", "", "%", "", " ", "").Replace(v)
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, errBadValue
+	}
+	return f, nil
+}
+
+func parseInt(v string) (int64, error) {
+	f, err := parseNumber(v)
+	if err != nil || f != math.Trunc(f) {
+		return 0, errBadValue
+	}
+	return int64(f), nil
+}
+
+// soNumber renders SO-like input in the dotted form the orders registry
+// stores; other references (MO numbers) are kept as written.
+func soNumber(v string) string {
+	if strings.HasPrefix(strings.ToUpper(v), "MO") {
+		return v
+	}
+	return orders.SOPretty(v)
+}
+
+// rowKey derives a stable id from the parts that identify a sheet row that
+// has no id column of its own.
+func rowKey(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(strings.Join(parts, "\x1f"))))
+	return hex.EncodeToString(sum[:8])
+}
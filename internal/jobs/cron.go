@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression: minute hour day-of-month
+// month day-of-week. Each field accepts "*", "*/n", "a", "a-b", "a-b/n" and
+// comma-separated lists of those. Day-of-week uses 0-6 with Sunday as 0.
+type Schedule struct {
+	expr   string
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+	// domAny/dowAny record "*" so standard cron OR semantics apply when both
+	// day fields are restricted.
+	domAny bool
+	dowAny bool
+}
+
+// ParseSchedule parses a cron expression.
+func ParseSchedule(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	s := &Schedule{expr: strings.Join(fields, " ")}
+	if err := parseField(fields[0], 0, 59, s.minute[:]); err != nil {
+		return nil, fmt.Errorf("cron %q minute: %w", expr, err)
+	}
+	if err := parseField(fields[1], 0, 23, s.hour[:]); err != nil {
+		return nil, fmt.Errorf("cron %q hour: %w", expr, err)
+	}
+	if err := parseField(fields[2], 1, 31, s.dom[:]); err != nil {
+		return nil, fmt.Errorf("cron %q day-of-month: %w", expr, err)
+	}
+	if err := parseField(fields[3], 1, 12, s.month[:]); err != nil {
+		return nil, fmt.Errorf("cron %q month: %w", expr, err)
+	}
+	if err := parseField(fields[4], 0, 6, s.dow[:]); err != nil {
+		return nil, fmt.Errorf("cron %q day-of-week: %w", expr, err)
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return s, nil
+}
+
+// String returns the normalized expression.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Matches reports whether t (already in the schedule's location) falls on the schedule.
+func (s *Schedule) Matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	domOK, dowOK := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowOK
+	case s.dowAny:
+		return domOK
+	default:
+		return domOK || dowOK
+	}
+}
+
+// Next returns the first matching minute strictly after t, or the zero time
+// if none occurs within a year.
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(1, 0, 0)
+	for next.Before(limit) {
+		if s.Matches(next) {
+			return next
+		}
+		next = next.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+func parseField(field string, lo, hi int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		start, end := lo, hi
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil {
+				return fmt.Errorf("invalid range %q", part)
+			}
+			start, end = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			start, end = n, n
+			if step > 1 {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return fmt.Errorf("value out of range %d-%d in %q", lo, hi, part)
+		}
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) accepted", expr)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+	// 2024-05-01 is a Wednesday, 2024-05-06 a Monday.
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(1, 13, 7), true},
+		{"0 6 * * *", at(1, 6, 0), true},
+		{"0 6 * * *", at(1, 6, 1), false},
+		{"*/15 * * * *", at(1, 9, 45), true},
+		{"*/15 * * * *", at(1, 9, 50), false},
+		{"5/20 * * * *", at(1, 9, 25), true},
+		{"5/20 * * * *", at(1, 9, 5), true},
+		{"5/20 * * * *", at(1, 9, 20), false},
+		{"0 8-18/2 * * *", at(1, 12, 0), true},
+		{"0 8-18/2 * * *", at(1, 13, 0), false},
+		{"30 9 * * 1-5", at(1, 9, 30), true},
+		{"30 9 * * 0,6", at(1, 9, 30), false},
+		{"0 0 1 * *", at(1, 0, 0), true},
+		{"0 0 1 * *", at(2, 0, 0), false},
+		// Both day fields restricted: either may match.
+		{"0 0 15 * 1", at(6, 0, 0), true},
+		{"0 0 1 * 1", at(1, 0, 0), true},
+		{"0 0 15 * 1", at(1, 0, 0), false},
+		{"0 0 * 6 *", at(1, 0, 0), false},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", tt.expr, err)
+		}
+		if got := s.Matches(tt.t); got != tt.want {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tt.expr, tt.t.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	s, err := ParseSchedule(" 55  23 * * * ")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if s.String() != "55 23 * * *" {
+		t.Errorf("String = %q", s.String())
+	}
+	from := time.Date(2024, 5, 1, 23, 55, 30, 0, time.UTC)
+	if got, want := s.Next(from), time.Date(2024, 5, 2, 23, 55, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+
+	never, err := ParseSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := never.Next(from); !got.IsZero() {
+		t.Errorf("Next for 31 February = %s, want zero", got)
+	}
+}
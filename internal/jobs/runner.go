@@ -0,0 +1,432 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// ErrUnknownJob is returned when a job name is not registered.
+var ErrUnknownJob = errors.New("unknown job")
+
+// ErrAlreadyRunning is returned when a run is requested while the same job
+// is in flight in this or any other process sharing the database.
+var ErrAlreadyRunning = errors.New("job already running")
+
+// Run statuses recorded in job_runs.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Triggers recorded in job_runs.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// A running job_runs row is a lease on its job: the unique index
+// idx_job_runs_one_running admits one per job, the holder refreshes
+// heartbeat_at every heartbeatInterval, and a lease whose heartbeat is older
+// than leaseTTL belongs to a dead process and may be failed by anyone.
+const (
+	heartbeatInterval = 30 * time.Second
+	leaseTTL          = 2 * time.Minute
+)
+
+// Job is a named unit of background work.
+type Job struct {
+	Name        string
+	Description string
+	// Schedule is a five-field cron expression evaluated in the runner's
+	// location; empty means the job only runs on demand.
+	Schedule string
+	Run      func(ctx context.Context) error
+}
+
+// Info describes a registered job for the admin UI.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Schedule    string `json:"schedule"`
+	NextRunAt   string `json:"nextRunAt"`
+	Running     bool   `json:"running"`
+	LastRun     *Run   `json:"lastRun"`
+}
+
+// Run is one persisted execution.
+type Run struct {
+	ID          int64  `json:"id"`
+	JobName     string `json:"jobName"`
+	Trigger     string `json:"trigger"`
+	TriggeredBy string `json:"triggeredBy"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	StartedAt   string `json:"startedAt"`
+	FinishedAt  string `json:"finishedAt"`
+	HeartbeatAt string `json:"heartbeatAt"`
+}
+
+const runColumns = `id, job_name, trigger, triggered_by, status, error, started_at, finished_at, heartbeat_at`
+
+type entry struct {
+	job      Job
+	schedule *Schedule
+}
+
+// Runner owns the job registry, the minute scheduler and run history.
+type Runner struct {
+	db     *sql.DB
+	loc    *time.Location
+	logger *logging.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	baseCtx context.Context
+	wg      sync.WaitGroup
+}
+
+// NewRunner constructs a runner that evaluates schedules in loc.
+func NewRunner(db *sql.DB, loc *time.Location, logger *logging.Logger) *Runner {
+	return &Runner{
+		db:      db,
+		loc:     loc,
+		logger:  logger,
+		now:     time.Now,
+		jobs:    make(map[string]*entry),
+		baseCtx: context.Background(),
+	}
+}
+
+// Register adds a job. Names must be unique and schedules must parse.
+func (r *Runner) Register(job Job) error {
+	job.Name = strings.TrimSpace(job.Name)
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job name and run func are required")
+	}
+	e := &entry{job: job}
+	if strings.TrimSpace(job.Schedule) != "" {
+		sched, err := ParseSchedule(job.Schedule)
+		if err != nil {
+			return fmt.Errorf("register %s: %w", job.Name, err)
+		}
+		e.schedule = sched
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s already registered", job.Name)
+	}
+	r.jobs[job.Name] = e
+	return nil
+}
+
+// Start fails runs whose lease expired with the process that held them and
+// then fires scheduled jobs every minute until ctx is cancelled. Runs still
+// heartbeating in another process are left alone. Call Wait after
+// cancelling ctx to let in-flight runs finish.
+func (r *Runner) Start(ctx context.Context) error {
+	if err := r.expireLeases(ctx, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.baseCtx = ctx
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.loop(ctx)
+	}()
+	return nil
+}
+
+// Wait blocks until the scheduler loop and all in-flight runs have returned.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context) {
+	for {
+		now := r.now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		tick := next.In(r.loc)
+		for _, name := range r.names() {
+			r.mu.Lock()
+			e := r.jobs[name]
+			r.mu.Unlock()
+			if e.schedule == nil || !e.schedule.Matches(tick) {
+				continue
+			}
+			if _, err := r.start(name, TriggerSchedule, "system"); err != nil {
+				r.logger.Error("job_schedule_skipped", map[string]any{"job": name, "error": err.Error()})
+			}
+		}
+	}
+}
+
+// RunNow starts a job in the background and returns its run record.
+func (r *Runner) RunNow(name, actor string) (*Run, error) {
+	return r.start(name, TriggerManual, actor)
+}
+
+// RunAllNow starts every registered job that is not already running.
+func (r *Runner) RunAllNow(actor string) []Run {
+	var started []Run
+	for _, name := range r.names() {
+		run, err := r.start(name, TriggerManual, actor)
+		if err != nil {
+			continue
+		}
+		started = append(started, *run)
+	}
+	return started
+}
+
+// RunSync runs a job in the calling goroutine and returns the finished run.
+// The command line uses it so the process exits only once the job is done.
+func (r *Runner) RunSync(ctx context.Context, name, actor string) (*Run, error) {
+	e, run, err := r.begin(ctx, name, TriggerManual, actor)
+	if err != nil {
+		return nil, err
+	}
+	r.execute(ctx, e.job, run)
+	return r.getRun(context.Background(), run.ID)
+}
+
+func (r *Runner) start(name, trigger, actor string) (*Run, error) {
+	r.mu.Lock()
+	ctx := r.baseCtx
+	r.mu.Unlock()
+
+	e, run, err := r.begin(ctx, name, trigger, actor)
+	if err != nil {
+		return nil, err
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.execute(ctx, e.job, run)
+	}()
+	return run, nil
+}
+
+// begin takes the lease on name by recording the start of a run. A live
+// lease held by any process makes it fail with ErrAlreadyRunning.
+func (r *Runner) begin(ctx context.Context, name, trigger, actor string) (*entry, *Run, error) {
+	r.mu.Lock()
+	e, ok := r.jobs[name]
+	r.mu.Unlock()
+	if !ok {
+		return nil, nil, ErrUnknownJob
+	}
+	if err := r.expireLeases(ctx, name); err != nil {
+		return nil, nil, err
+	}
+
+	stamp := r.now().UTC().Format(time.RFC3339)
+	run := &Run{
+		JobName:     name,
+		Trigger:     trigger,
+		TriggeredBy: actor,
+		Status:      StatusRunning,
+		StartedAt:   stamp,
+		HeartbeatAt: stamp,
+	}
+	err := r.db.QueryRowContext(ctx, `INSERT INTO job_runs(job_name, trigger, triggered_by, status, started_at, heartbeat_at)
+        VALUES(?, ?, ?, ?, ?, ?) RETURNING id`, run.JobName, run.Trigger, run.TriggeredBy, run.Status, run.StartedAt, run.HeartbeatAt).Scan(&run.ID)
+	if db.IsUniqueViolation(err) {
+		return nil, nil, ErrAlreadyRunning
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("record job start: %w", err)
+	}
+	return e, run, nil
+}
+
+// expireLeases fails running rows whose heartbeat is older than leaseTTL,
+// for one job or, when name is empty, for all of them.
+func (r *Runner) expireLeases(ctx context.Context, name string) error {
+	now := r.now().UTC()
+	query := `UPDATE job_runs SET status = ?, error = ?, finished_at = ? WHERE status = ? AND heartbeat_at < ?`
+	args := []any{StatusFailed, "lease expired; the process running it stopped",
+		now.Format(time.RFC3339), StatusRunning, now.Add(-leaseTTL).Format(time.RFC3339)}
+	if name != "" {
+		query += ` AND job_name = ?`
+		args = append(args, name)
+	}
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("expire job leases: %w", err)
+	}
+	return nil
+}
+
+// heartbeat keeps run's lease fresh until stop is closed.
+func (r *Runner) heartbeat(run *Run, stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		stamp := r.now().UTC().Format(time.RFC3339)
+		if _, err := r.db.ExecContext(context.Background(), `UPDATE job_runs SET heartbeat_at = ? WHERE id = ? AND status = ?`,
+			stamp, run.ID, StatusRunning); err != nil {
+			r.logger.Error("job_heartbeat_failed", map[string]any{"job": run.JobName, "run_id": run.ID, "error": err.Error()})
+		}
+	}
+}
+
+func (r *Runner) execute(ctx context.Context, job Job, run *Run) {
+	r.logger.Info("job_started", map[string]any{"job": job.Name, "run_id": run.ID, "trigger": run.Trigger})
+
+	stop := make(chan struct{})
+	beats := make(chan struct{})
+	go func() {
+		defer close(beats)
+		r.heartbeat(run, stop)
+	}()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return job.Run(ctx)
+	}()
+
+	close(stop)
+	<-beats
+
+	status, errText := StatusSucceeded, ""
+	if err != nil {
+		status, errText = StatusFailed, err.Error()
+	}
+	finished := r.now().UTC().Format(time.RFC3339)
+	// Record completion even if ctx was cancelled during shutdown. A run
+	// whose lease was expired by another process keeps its failed status.
+	res, dbErr := r.db.ExecContext(context.Background(), `UPDATE job_runs SET status = ?, error = ?, finished_at = ? WHERE id = ? AND status = ?`,
+		status, errText, finished, run.ID, StatusRunning)
+	if dbErr == nil {
+		if n, _ := res.RowsAffected(); n == 0 {
+			dbErr = errors.New("lease lost before the run finished")
+		}
+	}
+	if dbErr != nil {
+		r.logger.Error("job_finish_record_failed", map[string]any{"job": job.Name, "run_id": run.ID, "error": dbErr.Error()})
+	}
+
+	fields := map[string]any{"job": job.Name, "run_id": run.ID, "status": status}
+	if err != nil {
+		fields["error"] = errText
+		r.logger.Error("job_finished", fields)
+		return
+	}
+	r.logger.Info("job_finished", fields)
+}
+
+func (r *Runner) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Jobs lists registered jobs with their next scheduled time and last run.
+func (r *Runner) Jobs(ctx context.Context) ([]Info, error) {
+	now := r.now().In(r.loc)
+	out := []Info{}
+	for _, name := range r.names() {
+		r.mu.Lock()
+		e := r.jobs[name]
+		r.mu.Unlock()
+
+		info := Info{Name: name, Description: e.job.Description}
+		if e.schedule != nil {
+			info.Schedule = e.schedule.String()
+			if next := e.schedule.Next(now); !next.IsZero() {
+				info.NextRunAt = next.Format(time.RFC3339)
+			}
+		}
+		last, err := r.History(ctx, name, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(last) > 0 {
+			info.LastRun = &last[0]
+			info.Running = last[0].Status == StatusRunning
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+func (r *Runner) getRun(ctx context.Context, id int64) (*Run, error) {
+	var run Run
+	err := r.db.QueryRowContext(ctx, `SELECT `+runColumns+` FROM job_runs WHERE id = ?`, id).
+		Scan(&run.ID, &run.JobName, &run.Trigger, &run.TriggeredBy, &run.Status, &run.Error, &run.StartedAt, &run.FinishedAt, &run.HeartbeatAt)
+	if err != nil {
+		return nil, fmt.Errorf("select job run: %w", err)
+	}
+	return &run, nil
+}
+
+// History returns recent runs, newest first. An empty name returns all jobs.
+func (r *Runner) History(ctx context.Context, name string, limit int) ([]Run, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	query := `SELECT ` + runColumns + ` FROM job_runs`
+	var args []any
+	if name = strings.TrimSpace(name); name != "" {
+		query += ` WHERE job_name = ?`
+		args = append(args, name)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select job runs: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Run{}
+	for rows.Next() {
+		var run Run
+		if err := rows.Scan(&run.ID, &run.JobName, &run.Trigger, &run.TriggeredBy, &run.Status, &run.Error, &run.StartedAt, &run.FinishedAt, &run.HeartbeatAt); err != nil {
+			return nil, fmt.Errorf("scan job run: %w", err)
+		}
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate job runs: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+func newTestRunner(t *testing.T, conn *sql.DB, now time.Time, run func(context.Context) error) *Runner {
+	t.Helper()
+	r := NewRunner(conn, time.UTC, logging.NewWriter("error", io.Discard))
+	r.now = func() time.Time { return now }
+	if err := r.Register(Job{Name: "audit_master", Run: run}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return r
+}
+
+// TestSingleFlightAcrossRunners runs two runners against one database, as
+// two app processes (or the app and the CLI) would.
+func TestSingleFlightAcrossRunners(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	a := newTestRunner(t, conn, now, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	b := newTestRunner(t, conn, now, func(context.Context) error { return nil })
+	ctx := context.Background()
+
+	run, err := a.RunNow("audit_master", "alice")
+	if err != nil {
+		t.Fatalf("run now: %v", err)
+	}
+	<-started
+	if _, err := a.RunNow("audit_master", "alice"); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("same runner = %v, want ErrAlreadyRunning", err)
+	}
+	if _, err := b.RunSync(ctx, "audit_master", "cli"); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("other runner = %v, want ErrAlreadyRunning", err)
+	}
+	// Another process starting up leaves the live lease alone.
+	if err := b.expireLeases(ctx, ""); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	infos, err := b.Jobs(ctx)
+	if err != nil || len(infos) != 1 || !infos[0].Running {
+		t.Fatalf("jobs while running = %+v, %v", infos, err)
+	}
+
+	close(release)
+	a.Wait()
+	got, err := a.getRun(ctx, run.ID)
+	if err != nil || got.Status != StatusSucceeded {
+		t.Fatalf("finished run = %+v, %v", got, err)
+	}
+	done, err := b.RunSync(ctx, "audit_master", "cli")
+	if err != nil || done.Status != StatusSucceeded {
+		t.Errorf("run after release = %+v, %v", done, err)
+	}
+}
+
+func TestExpiredLease(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	lease := func(job string, heartbeat time.Time) int64 {
+		t.Helper()
+		var id int64
+		err := conn.QueryRowContext(ctx, `INSERT INTO job_runs(job_name, trigger, status, started_at, heartbeat_at)
+            VALUES(?, ?, ?, ?, ?) RETURNING id`, job, TriggerSchedule, StatusRunning,
+			heartbeat.Format(time.RFC3339), heartbeat.Format(time.RFC3339)).Scan(&id)
+		if err != nil {
+			t.Fatalf("insert lease: %v", err)
+		}
+		return id
+	}
+	r := newTestRunner(t, conn, now, func(context.Context) error { return nil })
+	if err := r.Register(Job{Name: "kpi_snapshot", Run: func(context.Context) error { return nil }}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	stale := lease("audit_master", now.Add(-leaseTTL-time.Second))
+	fresh := lease("kpi_snapshot", now.Add(-heartbeatInterval))
+
+	// begin takes over an expired lease without waiting for a restart.
+	run, err := r.RunSync(ctx, "audit_master", "alice")
+	if err != nil || run.Status != StatusSucceeded {
+		t.Fatalf("run over stale lease = %+v, %v", run, err)
+	}
+	old, err := r.getRun(ctx, stale)
+	if err != nil || old.Status != StatusFailed || old.FinishedAt == "" {
+		t.Errorf("stale run = %+v, %v", old, err)
+	}
+	if _, err := r.RunSync(ctx, "kpi_snapshot", "alice"); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("run over fresh lease = %v, want ErrAlreadyRunning", err)
+	}
+
+	// Start fails only the leases that have expired by now.
+	ctx, cancel := context.WithCancel(ctx)
+	r.now = func() time.Time { return now.Add(time.Minute) }
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	cancel()
+	r.Wait()
+	got, err := r.getRun(context.Background(), fresh)
+	if err != nil || got.Status != StatusRunning {
+		t.Errorf("fresh lease after start = %+v, %v", got, err)
+	}
+	r.now = func() time.Time { return now.Add(leaseTTL) }
+	if err := r.expireLeases(context.Background(), ""); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	got, err = r.getRun(context.Background(), fresh)
+	if err != nil || got.Status != StatusFailed {
+		t.Errorf("fresh lease once expired = %+v, %v", got, err)
+	}
+}
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"encoding/json"
+	"io"
 	"os"
 	"strings"
 	"sync"
@@ -25,10 +26,15 @@ type Logger struct {
 
 // New creates a logger writing JSON to stdout.
 func New(level string) *Logger {
+	return NewWriter(level, os.Stdout)
+}
+
+// NewWriter creates a logger writing JSON to w.
+func NewWriter(level string, w io.Writer) *Logger {
 	return &Logger{
 		level:      parseLevel(level),
 		baseFields: map[string]any{},
-		encoder:    json.NewEncoder(os.Stdout),
+		encoder:    json.NewEncoder(w),
 	}
 }
 
@@ -0,0 +1,109 @@
+package orders
+
+import "strings"
+
+// The helpers below port the conservative sibling matchers from
+// start3d_server.js (normalizeEmail_, phoneMatch_, nameTokens_, nameMatch_).
+
+func normalizeEmail(e string) string {
+	e = strings.ToLower(strings.TrimSpace(e))
+	parts := strings.Split(e, "@")
+	if len(parts) != 2 {
+		if len(parts) < 2 {
+			return ""
+		}
+		return e
+	}
+	local, domain := parts[0], parts[1]
+	if i := strings.Index(local, "+"); i >= 0 {
+		local = local[:i]
+	}
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+	return local + "@" + domain
+}
+
+func phoneDigits(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func lastN(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
+
+func phoneMatch(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if a == b || lastN(a, 10) == lastN(b, 10) {
+		return true
+	}
+	return lastN(a, 7) == lastN(b, 7)
+}
+
+var nameStopWords = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "miss": true, "dr": true,
+	"jr": true, "sr": true, "ii": true, "iii": true, "iv": true,
+}
+
+func nameTokens(s string) []string {
+	clean := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return ' '
+	}, strings.ToLower(s))
+
+	var out []string
+	for _, t := range strings.Fields(clean) {
+		if len(t) >= 2 && !nameStopWords[t] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func nameMatch(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	set := make(map[string]bool, len(b))
+	for _, t := range b {
+		set[t] = true
+	}
+	hits := 0
+	for _, t := range a {
+		if set[t] {
+			hits++
+		}
+	}
+	if hits >= 2 {
+		return true
+	}
+	// Containment fallback for single-token names; require length >= 5 to avoid "kevin".
+	ja, jb := strings.Join(a, " "), strings.Join(b, " ")
+	return (len(ja) >= 5 && strings.Contains(jb, ja)) || (len(jb) >= 5 && strings.Contains(ja, jb))
+}
+
+// sameCustomer reports whether two appointment rows plausibly belong to the same
+// client (email OR phone OR name), used to gate sibling propagation.
+func sameCustomer(emailA, phoneA, nameA, emailB, phoneB, nameB string) bool {
+	if ea := normalizeEmail(emailA); ea != "" && ea == normalizeEmail(emailB) {
+		return true
+	}
+	if phoneMatch(phoneDigits(phoneA), phoneDigits(phoneB)) {
+		return true
+	}
+	return nameMatch(nameTokens(nameA), nameTokens(nameB))
+}
@@ -0,0 +1,46 @@
+package orders
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := map[string]string{
+		"":                               "",
+		"not-an-email":                   "",
+		" Jamie.Rivera+rings@Gmail.com ": "jamierivera@gmail.com",
+		"j.rivera@googlemail.com":        "jrivera@gmail.com",
+		"j.rivera+x@example.com":         "j.rivera@example.com",
+		"a@b@c":                          "a@b@c",
+	}
+	for in, want := range tests {
+		if got := normalizeEmail(in); got != want {
+			t.Errorf("normalizeEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSameCustomer(t *testing.T) {
+	tests := []struct {
+		name                  string
+		emailA, phoneA, nameA string
+		emailB, phoneB, nameB string
+		want                  bool
+	}{
+		{"gmail dots and tags", "jamie.rivera+x@gmail.com", "", "", "JamieRivera@gmail.com", "", "", true},
+		{"phone country code", "", "+1 (555) 010-2030", "", "", "555-010-2030", "", true},
+		{"phone last seven", "", "212 555 0102", "", "", "646 555 0102", "", true},
+		{"two name tokens", "", "", "Mr. Jamie Alex Rivera", "", "", "Rivera, Jamie", true},
+		{"single long token contained", "", "", "Whitfield", "", "", "Jordan Whitfield", true},
+		{"single short token", "", "", "Ann", "", "", "Ann Park", false},
+		{"stop words only overlap", "", "", "Dr Jr Kim", "", "", "Dr Jr Lee", false},
+		{"nothing in common", "a@example.com", "5550001111", "Sam Park", "b@example.com", "5550002222", "Taylor Brooks", false},
+		{"blank rows", "", "", "", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sameCustomer(tt.emailA, tt.phoneA, tt.nameA, tt.emailB, tt.phoneB, tt.nameB)
+			if got != tt.want {
+				t.Errorf("sameCustomer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// CheckThreeD is the "3 days since 3D start" review tracked in order_checks.
+const CheckThreeD = "3d_check"
+
+const (
+	defaultQueueLimit = 10
+	maxQueueLimit     = 100
+)
+
+// QueueItem is one row of a dashboard action queue. Money is in dollars.
+type QueueItem struct {
+	Brand             string  `json:"brand"`
+	SO                string  `json:"so"`
+	RootApptID        string  `json:"rootApptId"`
+	CustomerName      string  `json:"customerName"`
+	AssignedRep       string  `json:"assignedRep"`
+	CustomOrderStatus string  `json:"customOrderStatus"`
+	StartedAt         string  `json:"startedAt"`
+	DaysSinceStart    int     `json:"daysSinceStart"`
+	OrderTotal        float64 `json:"orderTotal"`
+	PaidToDate        float64 `json:"paidToDate"`
+	BalanceDue        float64 `json:"balanceDue"`
+	LastActivityAt    string  `json:"lastActivityAt"`
+	Link              string  `json:"link"`
+}
+
+// QueueFilter narrows a queue. Empty fields are ignored.
+type QueueFilter struct {
+	Brand string
+	Rep   string // matches a name in Assigned Rep or Assisted Rep
+	Limit int
+}
+
+// CheckInput marks a queue item reviewed or snoozes it.
+type CheckInput struct {
+	Type  string `json:"type"`
+	Brand string `json:"brand"`
+	SO    string `json:"so"`
+	// Until is the local date (YYYY-MM-DD) a snoozed item returns on.
+	Until string `json:"until"`
+	Note  string `json:"note"`
+}
+
+// Check is the stored review state of one order for one check type.
+type Check struct {
+	Type        string `json:"type"`
+	Brand       string `json:"brand"`
+	SO          string `json:"so"`
+	ReviewedAt  string `json:"reviewedAt"`
+	ReviewedBy  string `json:"reviewedBy"`
+	SnoozeUntil string `json:"snoozeUntil"`
+	SnoozedBy   string `json:"snoozedBy"`
+	Note        string `json:"note"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// Queues answers the home dashboard's action queues.
+type Queues struct {
+	db         *sql.DB
+	checkDays  int
+	threeD     []string
+	production []string
+	loc        *time.Location
+	logger     *logging.Logger
+	now        func() time.Time
+}
+
+// NewQueues constructs the queue service. production lists the Custom Order
+// Status values behind the in-production queue (reports.production_statuses).
+func NewQueues(db *sql.DB, cfg config.QueuesConfig, production []string, loc *time.Location, logger *logging.Logger) *Queues {
+	return &Queues{
+		db:         db,
+		checkDays:  cfg.ThreeDCheckDays,
+		threeD:     foldStatuses(cfg.ThreeDStatuses),
+		production: foldStatuses(production),
+		loc:        loc,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// queueSelect joins each SO to its root's latest appointment, its Start 3D
+// entry, its own balance and its check state. The latest visit carries the
+// current Custom Order Status. An SO with no Start 3D in the tracker (such
+// as one imported from the sheet) reports its SO Linked At as started.
+const queueSelect = `SELECT o.brand, o.so_pretty, o.root_appt_id, a.customer_name, a.assigned_rep,
+        a.custom_order_status, COALESCE(d.created_at, o.linked_at),
+        COALESCE(b.order_total_cents, 0), COALESCE(b.paid_to_date_cents, 0), COALESCE(b.remaining_balance_cents, 0),
+        COALESCE(NULLIF(b.last_payment_at, ''), a.updated_at)
+    FROM sales_orders o
+    JOIN appointments a ON a.appt_id = (
+        SELECT x.appt_id FROM appointments x WHERE x.root_appt_id = o.root_appt_id
+        ORDER BY x.visit_date DESC, x.visit_time DESC, x.appt_id DESC LIMIT 1)
+    LEFT JOIN design_revisions d ON d.brand = o.brand AND d.so_key = o.so_key AND d.revision_no = 0
+    LEFT JOIN payment_balances b ON b.brand = o.brand AND b.so_key = o.so_key
+    LEFT JOIN order_checks c ON c.check_type = ? AND c.brand = o.brand AND c.so_key = o.so_key`
+
+// statusSQL folds an appointment's Custom Order Status the way foldStatuses
+// folds config values. It must match the expression behind
+// idx_appointments_custom_order_status_folded for the index to apply.
+func statusSQL(alias string) string {
+	return `REPLACE(LOWER(TRIM(` + alias + `.custom_order_status)), '–', '-')`
+}
+
+// statusWhere matches orders whose latest visit is in one of the folded
+// statuses. The semi-join lets the folded index pick candidate roots before
+// the latest visit of each is checked.
+func statusWhere(statuses []string) ([]string, []any) {
+	in := ` IN (` + placeholders(len(statuses)) + `)`
+	where := []string{
+		`o.root_appt_id IN (SELECT s.root_appt_id FROM appointments s WHERE ` + statusSQL("s") + in + `)`,
+		statusSQL("a") + in,
+	}
+	args := append(stringArgs(statuses), stringArgs(statuses)...)
+	return where, args
+}
+
+// DueThreeDChecks lists orders whose Start 3D (Revision # 0 in the 3D
+// Tracker) is at least ThreeDCheckDays old, are still in a 3D status and
+// haven't been reviewed since their latest revision, oldest first. Snoozed
+// orders stay hidden until their snooze date.
+func (q *Queues) DueThreeDChecks(ctx context.Context, f QueueFilter) ([]QueueItem, error) {
+	now := q.now()
+	cutoff := now.AddDate(0, 0, -q.checkDays).UTC().Format(time.RFC3339)
+	where, statusArgs := statusWhere(q.threeD)
+	where = append(where,
+		`d.created_at <= ?`,
+		`(c.reviewed_at IS NULL OR c.reviewed_at < (SELECT MAX(r.created_at) FROM design_revisions r
+            WHERE r.brand = o.brand AND r.so_key = o.so_key))`,
+		`(c.snooze_until IS NULL OR c.snooze_until <= ?)`,
+	)
+	args := append([]any{CheckThreeD}, statusArgs...)
+	args = append(args, cutoff, now.In(q.loc).Format("2006-01-02"))
+	return q.list(ctx, where, args, f, `d.created_at, o.so_key`)
+}
+
+// AwaitingPayment lists orders with an Order Total and a remaining balance,
+// least recently paid first.
+func (q *Queues) AwaitingPayment(ctx context.Context, f QueueFilter) ([]QueueItem, error) {
+	where := []string{`b.order_total_cents > 0`, `b.remaining_balance_cents > 0`}
+	return q.list(ctx, where, []any{CheckThreeD}, f, `COALESCE(NULLIF(b.last_payment_at, ''), a.updated_at), o.so_key`)
+}
+
+// InProduction lists orders whose current Custom Order Status is a production
+// status, longest running first.
+func (q *Queues) InProduction(ctx context.Context, f QueueFilter) ([]QueueItem, error) {
+	where, statusArgs := statusWhere(q.production)
+	args := append([]any{CheckThreeD}, statusArgs...)
+	return q.list(ctx, where, args, f, `COALESCE(d.created_at, o.linked_at), o.so_key`)
+}
+
+func (q *Queues) list(ctx context.Context, where []string, args []any, f QueueFilter, order string) ([]QueueItem, error) {
+	if v := strings.TrimSpace(f.Brand); v != "" {
+		where = append(where, "o.brand = ?")
+		args = append(args, strings.ToUpper(v))
+	}
+	if v := strings.TrimSpace(f.Rep); v != "" {
+		where = append(where, "("+reps.MatchSQL("a.assigned_rep")+" OR "+reps.MatchSQL("a.assisted_rep")+")")
+		args = append(args, reps.MatchArg(v), reps.MatchArg(v))
+	}
+	query := queueSelect + ` WHERE ` + strings.Join(where, " AND ") + ` ORDER BY ` + order + ` LIMIT ?`
+	args = append(args, clampQueueLimit(f.Limit))
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select queue: %w", err)
+	}
+	defer rows.Close()
+
+	now := q.now()
+	out := []QueueItem{}
+	for rows.Next() {
+		var (
+			it                   QueueItem
+			total, paid, balance int64
+		)
+		if err := rows.Scan(&it.Brand, &it.SO, &it.RootApptID, &it.CustomerName, &it.AssignedRep,
+			&it.CustomOrderStatus, &it.StartedAt, &total, &paid, &balance, &it.LastActivityAt); err != nil {
+			return nil, fmt.Errorf("scan queue item: %w", err)
+		}
+		if started, err := time.Parse(time.RFC3339, it.StartedAt); err == nil {
+			it.DaysSinceStart = int(now.Sub(started).Hours() / 24)
+		}
+		it.OrderTotal, it.PaidToDate, it.BalanceDue = float64(total)/100, float64(paid)/100, float64(balance)/100
+		it.Link = "/orders/" + it.SO
+		out = append(out, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate queue: %w", err)
+	}
+	return out, nil
+}
+
+// MarkReviewed records that an order's check was done. The order drops out of
+// the queue until a newer 3D revision is recorded.
+func (q *Queues) MarkReviewed(ctx context.Context, in CheckInput, actor string) (*Check, error) {
+	brand, key, err := q.checkTarget(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	stamp := q.now().UTC().Format(time.RFC3339)
+	_, err = q.db.ExecContext(ctx, `INSERT INTO order_checks(check_type, brand, so_key, reviewed_at, reviewed_by, note, updated_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(check_type, brand, so_key) DO UPDATE SET
+            reviewed_at = excluded.reviewed_at, reviewed_by = excluded.reviewed_by,
+            snooze_until = '', snoozed_by = '', note = excluded.note, updated_at = excluded.updated_at`,
+		CheckThreeD, brand, key, stamp, actor, strings.TrimSpace(in.Note), stamp)
+	if err != nil {
+		return nil, fmt.Errorf("mark reviewed: %w", err)
+	}
+	q.logger.Info("order_check_reviewed", map[string]any{"type": CheckThreeD, "brand": brand, "so_key": key, "actor": actor})
+	return q.getCheck(ctx, brand, key)
+}
+
+// Snooze hides an order from the queue until the given local date.
+func (q *Queues) Snooze(ctx context.Context, in CheckInput, actor string) (*Check, error) {
+	until := strings.TrimSpace(in.Until)
+	day, err := time.ParseInLocation("2006-01-02", until, q.loc)
+	if err != nil {
+		return nil, &ValidationError{Field: "until", Message: "must be a date (YYYY-MM-DD)"}
+	}
+	if !day.After(q.now().In(q.loc)) {
+		return nil, &ValidationError{Field: "until", Message: "must be in the future"}
+	}
+	brand, key, err := q.checkTarget(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	stamp := q.now().UTC().Format(time.RFC3339)
+	_, err = q.db.ExecContext(ctx, `INSERT INTO order_checks(check_type, brand, so_key, snooze_until, snoozed_by, note, updated_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(check_type, brand, so_key) DO UPDATE SET
+            snooze_until = excluded.snooze_until, snoozed_by = excluded.snoozed_by,
+            note = excluded.note, updated_at = excluded.updated_at`,
+		CheckThreeD, brand, key, until, actor, strings.TrimSpace(in.Note), stamp)
+	if err != nil {
+		return nil, fmt.Errorf("snooze check: %w", err)
+	}
+	q.logger.Info("order_check_snoozed", map[string]any{"type": CheckThreeD, "brand": brand, "so_key": key, "until": until, "actor": actor})
+	return q.getCheck(ctx, brand, key)
+}
+
+// checkTarget validates the check type and resolves the order it applies to.
+func (q *Queues) checkTarget(ctx context.Context, in CheckInput) (string, string, error) {
+	if t := strings.TrimSpace(in.Type); t != "" && t != CheckThreeD {
+		return "", "", &ValidationError{Field: "type", Message: "must be " + CheckThreeD}
+	}
+	brand, key, err := parseBrandSO(in.Brand, in.SO)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := getOrder(ctx, q.db, brand, key); err != nil {
+		return "", "", err
+	}
+	return brand, key, nil
+}
+
+func (q *Queues) getCheck(ctx context.Context, brand, key string) (*Check, error) {
+	c := Check{Type: CheckThreeD, Brand: brand, SO: key[:2] + "." + key[2:]}
+	err := q.db.QueryRowContext(ctx, `SELECT reviewed_at, reviewed_by, snooze_until, snoozed_by, note, updated_at
+        FROM order_checks WHERE check_type = ? AND brand = ? AND so_key = ?`, CheckThreeD, brand, key).
+		Scan(&c.ReviewedAt, &c.ReviewedBy, &c.SnoozeUntil, &c.SnoozedBy, &c.Note, &c.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("select order check: %w", err)
+	}
+	return &c, nil
+}
+
+func foldStatuses(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, strings.ReplaceAll(strings.ToLower(strings.TrimSpace(v)), "–", "-"))
+	}
+	return out
+}
+
+// placeholders returns n comma-separated "?"; an empty list matches nothing.
+func placeholders(n int) string {
+	if n == 0 {
+		return "NULL"
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func stringArgs(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func clampQueueLimit(limit int) int {
+	if limit <= 0 {
+		return defaultQueueLimit
+	}
+	if limit > maxQueueLimit {
+		return maxQueueLimit
+	}
+	return limit
+}
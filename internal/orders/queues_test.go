@@ -0,0 +1,141 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+type queueFixture struct {
+	t    *testing.T
+	conn *sql.DB
+	q    *Queues
+	now  time.Time
+}
+
+func newQueueFixture(t *testing.T) *queueFixture {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	now := time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)
+	q := NewQueues(conn, config.QueuesConfig{ThreeDCheckDays: 3, ThreeDStatuses: []string{"3D Requested", "3D Revision Requested"}},
+		[]string{"In Production", "Final Photos - Waiting Approval"}, time.UTC, logging.NewWriter("error", io.Discard))
+	q.now = func() time.Time { return now }
+	return &queueFixture{t: t, conn: conn, q: q, now: now}
+}
+
+func (f *queueFixture) exec(query string, args ...any) {
+	f.t.Helper()
+	if _, err := f.conn.Exec(query, args...); err != nil {
+		f.t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// order adds an SO linked daysAgo whose root's latest visit is in status.
+// A non-negative startedDaysAgo records Start 3D in the tracker.
+func (f *queueFixture) order(so, status string, linkedDaysAgo, startedDaysAgo int) {
+	f.t.Helper()
+	key := SOKey(so)
+	root := "R" + key
+	linked := f.now.AddDate(0, 0, -linkedDaysAgo).Format(time.RFC3339)
+	f.exec(`INSERT INTO appointments(appt_id, root_appt_id, brand, customer_name, visit_date, visit_time,
+        custom_order_status, so_number, created_at, updated_at) VALUES(?, ?, 'VVS', ?, '2024-04-01', '10:00', ?, ?, ?, ?)`,
+		root, root, "Client "+so, status, so, linked, linked)
+	f.exec(`INSERT INTO sales_orders(brand, so_key, so_pretty, root_appt_id, linked_at, created_at, updated_at)
+        VALUES('VVS', ?, ?, ?, ?, ?, ?)`, key, so, root, linked, linked, linked)
+	if startedDaysAgo >= 0 {
+		started := f.now.AddDate(0, 0, -startedDaysAgo).Format(time.RFC3339)
+		f.exec(`INSERT INTO design_revisions(design_id, brand, so_number, so_key, root_appt_id, revision_no, kind, created_at)
+            VALUES(?, 'VVS', ?, ?, ?, 0, 'start', ?)`, "D-"+key, so, key, root, started)
+	}
+}
+
+func sos(items []QueueItem) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, it.SO)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestDueThreeDChecks(t *testing.T) {
+	f := newQueueFixture(t)
+	ctx := context.Background()
+	f.order("11.0001", "3D Requested", 30, 5)
+	f.order("11.0002", " 3d revision requested ", 30, 4)
+	f.order("11.0003", "3D Requested", 30, 1)  // started too recently
+	f.order("11.0004", "3D Requested", 30, -1) // linked long ago, never started 3D
+	f.order("11.0005", "In Production", 30, 9)
+	// An older visit in a 3D status doesn't count once a later one moved on.
+	f.exec(`INSERT INTO appointments(appt_id, root_appt_id, brand, customer_name, visit_date, visit_time,
+        custom_order_status, created_at, updated_at) VALUES('OLD', 'R110005', 'VVS', 'Client', '2024-03-01', '10:00',
+        '3D Requested', 'x', 'x')`)
+
+	due, err := f.q.DueThreeDChecks(ctx, QueueFilter{})
+	if err != nil {
+		t.Fatalf("due: %v", err)
+	}
+	if got := sos(due); got != "11.0001,11.0002" {
+		t.Fatalf("due = %s, want 11.0001,11.0002", got)
+	}
+	if due[0].DaysSinceStart != 5 || due[0].StartedAt != f.now.AddDate(0, 0, -5).Format(time.RFC3339) {
+		t.Errorf("first item = %+v, want 5 days since Start 3D", due[0])
+	}
+
+	if _, err := f.q.MarkReviewed(ctx, CheckInput{Brand: "VVS", SO: "11.0001"}, "alice"); err != nil {
+		t.Fatalf("mark reviewed: %v", err)
+	}
+	if _, err := f.q.Snooze(ctx, CheckInput{Brand: "VVS", SO: "11.0002", Until: "2024-05-12"}, "alice"); err != nil {
+		t.Fatalf("snooze: %v", err)
+	}
+	if due, err = f.q.DueThreeDChecks(ctx, QueueFilter{}); err != nil || len(due) != 0 {
+		t.Fatalf("due after review and snooze = %s, %v", sos(due), err)
+	}
+	f.q.now = func() time.Time { return f.now.AddDate(0, 0, 2) }
+	if due, err = f.q.DueThreeDChecks(ctx, QueueFilter{}); err != nil || sos(due) != "11.0002,11.0003" {
+		t.Errorf("due after snooze ends = %s, %v", sos(due), err)
+	}
+
+	// A revision recorded after the review reopens the check.
+	f.exec(`INSERT INTO design_revisions(design_id, brand, so_number, so_key, root_appt_id, revision_no, kind, created_at)
+        VALUES('D-110001-1', 'VVS', '11.0001', '110001', 'R110001', 1, 'revision', ?)`,
+		f.now.AddDate(0, 0, 1).Format(time.RFC3339))
+	if due, err = f.q.DueThreeDChecks(ctx, QueueFilter{}); err != nil || sos(due) != "11.0001,11.0002,11.0003" {
+		t.Errorf("due after revision = %s, %v", sos(due), err)
+	}
+
+	prod, err := f.q.InProduction(ctx, QueueFilter{})
+	if err != nil || sos(prod) != "11.0005" {
+		t.Errorf("in production = %s, %v", sos(prod), err)
+	}
+}
+
+// TestStatusIndex keeps the folded Custom Order Status match on its index.
+func TestStatusIndex(t *testing.T) {
+	f := newQueueFixture(t)
+	where, args := statusWhere(f.q.production)
+	rows, err := f.conn.Query(`EXPLAIN QUERY PLAN `+queueSelect+` WHERE `+strings.Join(where, " AND "),
+		append([]any{CheckThreeD}, args...)...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	if !strings.Contains(strings.Join(plan, "\n"), "idx_appointments_custom_order_status_folded") {
+		t.Errorf("plan does not use the folded status index:\n%s", strings.Join(plan, "\n"))
+	}
+}
@@ -0,0 +1,444 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// ErrNotFound is returned when a sales order does not exist.
+var ErrNotFound = errors.New("sales order not found")
+
+// ValidationError reports input that cannot be stored.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ConflictError is returned when an SO is already linked to a different root
+// appointment (the DUPLICATE_SO case in saveAssignedSO).
+type ConflictError struct {
+	Brand      string
+	SOPretty   string
+	RootApptID string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s SO %s is already linked to root appointment %s", e.Brand, e.SOPretty, e.RootApptID)
+}
+
+// StatusThreeDRequested is written to Custom Order Status when an SO is linked
+// and the status is still blank.
+const StatusThreeDRequested = "3D Requested"
+
+// Order is one entry in the SO registry.
+type Order struct {
+	ID         int64  `json:"id"`
+	Brand      string `json:"brand"`
+	SOKey      string `json:"soKey"`
+	SOPretty   string `json:"so"`
+	RootApptID string `json:"rootApptId"`
+	OdooURL    string `json:"odooUrl"`
+	LinkedAt   string `json:"linkedAt"`
+	// WaxRequestID, WaxStatus and WaxDeadline summarise the order's latest
+	// wax print request; the wax service keeps them current.
+	WaxRequestID string `json:"waxRequestId"`
+	WaxStatus    string `json:"waxStatus"`
+	WaxDeadline  string `json:"waxDeadline"`
+	CreatedAt    string `json:"createdAt"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// AssignInput links an SO to the appointment row it was created from.
+type AssignInput struct {
+	ApptID  string `json:"apptId"`
+	Brand   string `json:"brand"`
+	SO      string `json:"so"`
+	OdooURL string `json:"odooUrl"`
+	// Force moves an SO that is linked to another root and skips the
+	// secondary email/phone/name check during propagation.
+	Force bool `json:"forceOverwrite"`
+}
+
+// PropagationResult lists sibling appointments touched by propagation.
+type PropagationResult struct {
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
+// AssignResult is returned by Assign.
+type AssignResult struct {
+	Order       *Order            `json:"order"`
+	Propagation PropagationResult `json:"propagation"`
+}
+
+// ConflictReport mirrors the checkSOConflicts response shape.
+type ConflictReport struct {
+	Brand          string      `json:"brand"`
+	SOPretty       string      `json:"so"`
+	ExistsInMaster bool        `json:"existsInMaster"`
+	SameRoot       bool        `json:"sameRoot"`
+	Order          *Order      `json:"order,omitempty"`
+	MasterHits     []MasterHit `json:"masterHits"`
+}
+
+// MasterHit is an appointment row that already carries the SO.
+type MasterHit struct {
+	ApptID     string `json:"apptId"`
+	RootApptID string `json:"rootApptId"`
+}
+
+// Service maintains the SO registry and mirrors SO links onto appointments.
+type Service struct {
+	db     *sql.DB
+	appts  *appointments.Service
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs an orders service.
+func NewService(db *sql.DB, appts *appointments.Service, logger *logging.Logger) *Service {
+	return &Service{db: db, appts: appts, logger: logger, now: time.Now}
+}
+
+// Same rule as saveAssignedSO: a .com address, protocol optional.
+var odooURLPattern = regexp.MustCompile(`(?i)^(https?://)?[^\s]+\.com(/|\?|#|$)`)
+
+const orderColumns = `id, brand, so_key, so_pretty, root_appt_id, odoo_url, linked_at,
+        wax_request_id, wax_status, wax_deadline, created_at, updated_at`
+
+// Assign links an SO to an appointment's root, writes SO#/URL/linked-at onto
+// the appointment and propagates them to same-root siblings.
+func (s *Service) Assign(ctx context.Context, in AssignInput) (*AssignResult, error) {
+	brand, key, err := parseBrandSO(in.Brand, in.SO)
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSpace(in.OdooURL)
+	if !odooURLPattern.MatchString(url) {
+		return nil, &ValidationError{Field: "odooUrl", Message: "must be a .com address (protocol optional)"}
+	}
+	appt, err := s.appts.Get(ctx, in.ApptID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin assign: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	existing, err := getOrder(ctx, tx, brand, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.RootApptID != appt.RootApptID && !in.Force {
+		return nil, &ConflictError{Brand: brand, SOPretty: existing.SOPretty, RootApptID: existing.RootApptID}
+	}
+
+	stamp := s.now().UTC().Format(time.RFC3339)
+	pretty := key[:2] + "." + key[2:]
+	if existing == nil {
+		_, err = tx.ExecContext(ctx, `INSERT INTO sales_orders(brand, so_key, so_pretty, root_appt_id, odoo_url, linked_at, created_at, updated_at)
+            VALUES(?, ?, ?, ?, ?, ?, ?, ?)`, brand, key, pretty, appt.RootApptID, url, stamp, stamp, stamp)
+	} else {
+		_, err = tx.ExecContext(ctx, `UPDATE sales_orders SET root_appt_id = ?, odoo_url = ?, linked_at = ?, updated_at = ?
+            WHERE id = ?`, appt.RootApptID, url, stamp, stamp, existing.ID)
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			// Another assign linked the SO first. The failed insert aborts
+			// tx on Postgres, so read the winner's row outside it.
+			_ = tx.Rollback()
+			owner, lerr := getOrder(ctx, s.db, brand, key)
+			if lerr != nil {
+				return nil, fmt.Errorf("select conflicting sales order: %w", lerr)
+			}
+			return nil, &ConflictError{Brand: brand, SOPretty: owner.SOPretty, RootApptID: owner.RootApptID}
+		}
+		return nil, fmt.Errorf("save sales order: %w", err)
+	}
+	if existing != nil && existing.RootApptID != appt.RootApptID {
+		// The SO's balance moves with it to the new client.
+		if _, err := tx.ExecContext(ctx, `UPDATE payment_balances SET root_appt_id = ? WHERE brand = ? AND so_key = ?`,
+			appt.RootApptID, brand, key); err != nil {
+			return nil, fmt.Errorf("move payment balance: %w", err)
+		}
+	}
+
+	if err := linkAppointment(ctx, tx, appt.ApptID, pretty, url, stamp); err != nil {
+		return nil, err
+	}
+	prop, err := propagate(ctx, tx, appt, pretty, url, stamp, in.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := getOrder(ctx, tx, brand, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit assign: %w", err)
+	}
+
+	s.logger.Info("sales_order_assigned", map[string]any{
+		"brand":        brand,
+		"so":           pretty,
+		"appt_id":      appt.ApptID,
+		"root_appt_id": appt.RootApptID,
+		"propagated":   len(prop.Updated),
+		"skipped":      len(prop.Skipped),
+	})
+	return &AssignResult{Order: order, Propagation: prop}, nil
+}
+
+// Lookup returns the registry entry for a brand and raw SO number.
+func (s *Service) Lookup(ctx context.Context, brand, rawSO string) (*Order, error) {
+	b, key, err := parseBrandSO(brand, rawSO)
+	if err != nil {
+		return nil, err
+	}
+	return getOrder(ctx, s.db, b, key)
+}
+
+// ListByRoot returns every SO linked to a root appointment.
+func (s *Service) ListByRoot(ctx context.Context, rootApptID string) ([]Order, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+orderColumns+` FROM sales_orders WHERE root_appt_id = ? ORDER BY linked_at DESC`, strings.TrimSpace(rootApptID))
+	if err != nil {
+		return nil, fmt.Errorf("list sales orders: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan sales order: %w", err)
+		}
+		out = append(out, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sales orders: %w", err)
+	}
+	return out, nil
+}
+
+// CheckConflicts reports whether an SO is already in use, mirroring
+// checkSOConflicts. When apptID is given, hits on that row are ignored and
+// SameRoot reports whether the existing link belongs to the same root.
+func (s *Service) CheckConflicts(ctx context.Context, brand, rawSO, apptID string) (*ConflictReport, error) {
+	b, key, err := parseBrandSO(brand, rawSO)
+	if err != nil {
+		return nil, err
+	}
+	pretty := key[:2] + "." + key[2:]
+	report := &ConflictReport{Brand: b, SOPretty: pretty, MasterHits: []MasterHit{}}
+
+	var currentRoot string
+	if apptID = strings.TrimSpace(apptID); apptID != "" {
+		appt, err := s.appts.Get(ctx, apptID)
+		if err != nil {
+			return nil, err
+		}
+		currentRoot = appt.RootApptID
+	}
+
+	order, err := getOrder(ctx, s.db, b, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	report.Order = order
+
+	rows, err := s.db.QueryContext(ctx, `SELECT appt_id, root_appt_id FROM appointments
+        WHERE brand = ? AND so_number = ? AND appt_id <> ? ORDER BY appt_id`, b, pretty, apptID)
+	if err != nil {
+		return nil, fmt.Errorf("select master hits: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit MasterHit
+		if err := rows.Scan(&hit.ApptID, &hit.RootApptID); err != nil {
+			return nil, fmt.Errorf("scan master hit: %w", err)
+		}
+		report.MasterHits = append(report.MasterHits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate master hits: %w", err)
+	}
+
+	report.ExistsInMaster = order != nil || len(report.MasterHits) > 0
+	if currentRoot != "" && report.ExistsInMaster {
+		report.SameRoot = order == nil || order.RootApptID == currentRoot
+		for _, hit := range report.MasterHits {
+			if hit.RootApptID != currentRoot {
+				report.SameRoot = false
+			}
+		}
+	}
+	return report, nil
+}
+
+// Propagate re-applies a registered SO to every appointment under its root.
+func (s *Service) Propagate(ctx context.Context, brand, rawSO string, force bool) (*PropagationResult, error) {
+	order, err := s.Lookup(ctx, brand, rawSO)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the earliest-linked row under the root as the reference customer.
+	var source *appointments.Appointment
+	list, err := s.appts.List(ctx, appointments.Filter{RootApptID: order.RootApptID, Limit: 1000})
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].SONumber == order.SOPretty && (source == nil || list[i].SOLinkedAt < source.SOLinkedAt) {
+			source = &list[i]
+		}
+	}
+	if source == nil {
+		return nil, &ValidationError{Field: "so", Message: "no appointment under root " + order.RootApptID + " carries this SO"}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin propagate: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := linkAppointment(ctx, tx, source.ApptID, order.SOPretty, order.OdooURL, order.LinkedAt); err != nil {
+		return nil, err
+	}
+	prop, err := propagate(ctx, tx, source, order.SOPretty, order.OdooURL, order.LinkedAt, force)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit propagate: %w", err)
+	}
+
+	s.logger.Info("sales_order_propagated", map[string]any{
+		"brand":      order.Brand,
+		"so":         order.SOPretty,
+		"propagated": len(prop.Updated),
+		"skipped":    len(prop.Skipped),
+	})
+	return &prop, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func getOrder(ctx context.Context, q queryer, brand, key string) (*Order, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM sales_orders WHERE brand = ? AND so_key = ?`, brand, key)
+	o, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select sales order: %w", err)
+	}
+	return o, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(row scanner) (*Order, error) {
+	var o Order
+	if err := row.Scan(&o.ID, &o.Brand, &o.SOKey, &o.SOPretty, &o.RootApptID, &o.OdooURL, &o.LinkedAt,
+		&o.WaxRequestID, &o.WaxStatus, &o.WaxDeadline, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// linkAppointment writes SO#, Odoo SO URL and SO Linked At onto a row and sets
+// Custom Order Status to "3D Requested" only when it is still blank.
+func linkAppointment(ctx context.Context, q queryer, apptID, pretty, url, linkedAt string) error {
+	_, err := q.ExecContext(ctx, `UPDATE appointments SET
+            so_number = ?, odoo_so_url = ?, so_linked_at = ?,
+            custom_order_status = CASE WHEN TRIM(custom_order_status) = '' THEN ? ELSE custom_order_status END,
+            updated_at = ?
+        WHERE appt_id = ?`, pretty, url, linkedAt, StatusThreeDRequested, linkedAt, apptID)
+	if err != nil {
+		return fmt.Errorf("link appointment %s: %w", apptID, err)
+	}
+	return nil
+}
+
+// propagate mirrors propagateSOToSiblingRows_: siblings under the same root are
+// updated only when email, phone or name also match, unless force is set.
+func propagate(ctx context.Context, q queryer, source *appointments.Appointment, pretty, url, linkedAt string, force bool) (PropagationResult, error) {
+	res := PropagationResult{Updated: []string{}, Skipped: []string{}}
+
+	rows, err := q.QueryContext(ctx, `SELECT appt_id, email_lower, phone_norm, customer_name FROM appointments
+        WHERE root_appt_id = ? AND appt_id <> ? ORDER BY visit_date, appt_id`, source.RootApptID, source.ApptID)
+	if err != nil {
+		return res, fmt.Errorf("select siblings: %w", err)
+	}
+	type sibling struct{ id, email, phone, name string }
+	var siblings []sibling
+	for rows.Next() {
+		var sb sibling
+		if err := rows.Scan(&sb.id, &sb.email, &sb.phone, &sb.name); err != nil {
+			rows.Close()
+			return res, fmt.Errorf("scan sibling: %w", err)
+		}
+		siblings = append(siblings, sb)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, fmt.Errorf("iterate siblings: %w", err)
+	}
+
+	for _, sb := range siblings {
+		if !force && !sameCustomer(source.EmailLower, source.PhoneNorm, source.CustomerName, sb.email, sb.phone, sb.name) {
+			res.Skipped = append(res.Skipped, sb.id)
+			continue
+		}
+		if err := linkAppointment(ctx, q, sb.id, pretty, url, linkedAt); err != nil {
+			return res, err
+		}
+		res.Updated = append(res.Updated, sb.id)
+	}
+	return res, nil
+}
+
+func parseBrandSO(brand, rawSO string) (string, string, error) {
+	b := strings.ToUpper(strings.TrimSpace(brand))
+	if b != appointments.BrandVVS && b != appointments.BrandHPUSA {
+		return "", "", &ValidationError{Field: "brand", Message: "must be VVS or HPUSA"}
+	}
+	key := SOKey(rawSO)
+	if key == "" {
+		return "", "", &ValidationError{Field: "so", Message: "must contain an SO number like 12.3456"}
+	}
+	return b, key, nil
+}
+
+func isUniqueViolation(err error) bool {
+	return db.IsUniqueViolation(err)
+}
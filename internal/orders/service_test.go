@@ -0,0 +1,88 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+func newTestService(t *testing.T, conn *sql.DB, roots ...string) *Service {
+	t.Helper()
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	for _, root := range roots {
+		if _, err := appts.Create(context.Background(), appointments.Appointment{ApptID: root, Brand: appointments.BrandVVS,
+			CustomerName: "Client " + root, VisitDate: "2024-04-02"}); err != nil {
+			t.Fatalf("create %s: %v", root, err)
+		}
+	}
+	return NewService(conn, appts, logger)
+}
+
+func TestAssignConflict(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		svc := newTestService(t, conn, "R1", "R2")
+		ctx := context.Background()
+
+		if _, err := svc.Assign(ctx, AssignInput{ApptID: "R1", Brand: "VVS", SO: "880001", OdooURL: "odoo.example.com"}); err != nil {
+			t.Fatalf("assign: %v", err)
+		}
+		_, err := svc.Assign(ctx, AssignInput{ApptID: "R2", Brand: "VVS", SO: "88.0001", OdooURL: "odoo.example.com"})
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || conflict.RootApptID != "R1" || conflict.SOPretty != "88.0001" {
+			t.Fatalf("second assign = %v, want a conflict naming R1", err)
+		}
+
+		res, err := svc.Assign(ctx, AssignInput{ApptID: "R2", Brand: "VVS", SO: "880001", OdooURL: "odoo.example.com", Force: true})
+		if err != nil || res.Order.RootApptID != "R2" {
+			t.Fatalf("forced assign = %+v, %v", res, err)
+		}
+	})
+}
+
+// TestConcurrentAssign races two clients for one SO; whichever loses is told
+// the winner's root, whether the pre-check or the unique index caught it.
+func TestConcurrentAssign(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		svc := newTestService(t, conn, "R1", "R2")
+		ctx := context.Background()
+
+		roots := []string{"R1", "R2"}
+		errs := make([]error, len(roots))
+		var wg sync.WaitGroup
+		for i, root := range roots {
+			wg.Add(1)
+			go func(i int, root string) {
+				defer wg.Done()
+				_, errs[i] = svc.Assign(ctx, AssignInput{ApptID: root, Brand: "VVS", SO: "990001", OdooURL: "odoo.example.com"})
+			}(i, root)
+		}
+		wg.Wait()
+
+		owner, err := svc.Lookup(ctx, "VVS", "990001")
+		if err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+		losses := 0
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+			losses++
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) || conflict.RootApptID != owner.RootApptID || roots[i] == owner.RootApptID {
+				t.Errorf("%s: err = %v, want a conflict naming %s", roots[i], err, owner.RootApptID)
+			}
+		}
+		if losses != 1 {
+			t.Errorf("%d assigns failed, want 1: %v", losses, errs)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package orders
+
+import "strings"
+
+// SOKey canonicalizes a raw SO number to its 6-digit key, mirroring _soKey_ in
+// dv_queue_upserts.js: "SO#1293", "'00.1293" and "00 1293" all become "001293".
+// It returns "" when the input has no digits.
+func SOKey(raw string) string {
+	s := strings.TrimSpace(raw)
+	s = strings.TrimLeft(s, "'")
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.EqualFold(s[:2], "SO") {
+		s = strings.TrimPrefix(s[2:], "#")
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	switch {
+	case digits == "":
+		return ""
+	case len(digits) < 6:
+		return strings.Repeat("0", 6-len(digits)) + digits
+	default:
+		return digits[len(digits)-6:]
+	}
+}
+
+// SOPretty renders the dotted display form used in the sheets ("00.1293").
+func SOPretty(raw string) string {
+	k := SOKey(raw)
+	if k == "" {
+		return ""
+	}
+	return k[:2] + "." + k[2:]
+}
@@ -0,0 +1,53 @@
+package orders
+
+import "testing"
+
+func TestSOKey(t *testing.T) {
+	tests := map[string]string{
+		"":             "",
+		"SO#":          "",
+		"n/a":          "",
+		"SO#1293":      "001293",
+		"so1293":       "001293",
+		"'00.1293":     "001293",
+		"00 1293":      "001293",
+		"12.3456":      "123456",
+		" 123456 ":     "123456",
+		"SO 12-3456":   "123456",
+		"9912.3456":    "123456",
+		"'SO#00.0007":  "000007",
+		"Order 45.678": "045678",
+	}
+	for in, want := range tests {
+		if got := SOKey(in); got != want {
+			t.Errorf("SOKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSOPretty(t *testing.T) {
+	tests := map[string]string{
+		"":        "",
+		"SO#1293": "00.1293",
+		"123456":  "12.3456",
+		"12.3456": "12.3456",
+	}
+	for in, want := range tests {
+		if got := SOPretty(in); got != want {
+			t.Errorf("SOPretty(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseBrandSO(t *testing.T) {
+	brand, key, err := parseBrandSO(" hpusa ", "SO#1293")
+	if err != nil || brand != "HPUSA" || key != "001293" {
+		t.Errorf("parseBrandSO = %q, %q, %v", brand, key, err)
+	}
+	if _, _, err := parseBrandSO("ACME", "123456"); err == nil {
+		t.Error("unknown brand accepted")
+	}
+	if _, _, err := parseBrandSO("VVS", "none"); err == nil {
+		t.Error("SO without digits accepted")
+	}
+}
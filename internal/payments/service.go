@@ -0,0 +1,754 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// ErrNotFound is returned when a ledger entry does not exist.
+var ErrNotFound = errors.New("payment not found")
+
+// ValidationError reports input that cannot be recorded.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Anchor types: a document is recorded against an SO or, before an SO
+// exists, against the root appointment.
+const (
+	AnchorSO   = "SO"
+	AnchorAPPT = "APPT"
+)
+
+// Document kinds derived from the free-form Doc Type.
+const (
+	KindReceipt = "receipt"
+	KindInvoice = "invoice"
+	KindCredit  = "credit"
+)
+
+// Document statuses (RP_DOC_STATUS in Payments_v1.js).
+const (
+	StatusDraft    = "DRAFT"
+	StatusIssued   = "ISSUED"
+	StatusReplaced = "REPLACED"
+	StatusVoid     = "VOID"
+)
+
+// Counts reports whether a document with status counts toward its balance.
+// Like the ledger summaries in Payments_v1.js, VOID, REPLACED and DRAFT
+// documents are skipped.
+func Counts(status string) bool {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case StatusVoid, StatusReplaced, StatusDraft:
+		return false
+	}
+	return true
+}
+
+// Line is one row of the document's line table.
+type Line struct {
+	Desc string  `json:"desc"`
+	Qty  float64 `json:"qty"`
+	Amt  float64 `json:"amt"`
+}
+
+// PaymentDetails carries the receipt/invoice amount block from the dialog.
+type PaymentDetails struct {
+	Amount    float64 `json:"amount"`
+	DateTime  string  `json:"dateTime"`
+	Method    string  `json:"method"`
+	Reference string  `json:"reference"`
+	Notes     string  `json:"notes"`
+}
+
+// RecordInput mirrors the rp_submit payload from dlg_record_payment_v1.html.
+type RecordInput struct {
+	AnchorType string         `json:"anchorType"`
+	Brand      string         `json:"brand"`
+	RootApptID string         `json:"rootApptId"`
+	SO         string         `json:"soNumber"`
+	DocType    string         `json:"docType"`
+	DocRole    string         `json:"docRole"`
+	DocStatus  string         `json:"docStatus"`
+	Lines      []Line         `json:"lines"`
+	Payment    PaymentDetails `json:"pmt"`
+	// SetOrderTotal replaces the anchor's Order Total with this document's
+	// Lines Subtotal (the "set Order Total" checkbox).
+	SetOrderTotal bool `json:"setOrderTotal"`
+}
+
+// Payment is one ledger entry with the balance snapshot taken when it was recorded.
+type Payment struct {
+	PaymentID       string  `json:"paymentId"`
+	Brand           string  `json:"brand"`
+	RootApptID      string  `json:"rootApptId"`
+	SONumber        string  `json:"soNumber"`
+	AnchorType      string  `json:"anchorType"`
+	BasketID        string  `json:"basketId"`
+	CustomerName    string  `json:"customerName"`
+	DocType         string  `json:"docType"`
+	DocKind         string  `json:"docKind"`
+	DocRole         string  `json:"docRole"`
+	DocStatus       string  `json:"docStatus"`
+	PaymentDateTime string  `json:"paymentDateTime"`
+	Method          string  `json:"method"`
+	Reference       string  `json:"reference"`
+	Notes           string  `json:"notes"`
+	Lines           []Line  `json:"lines"`
+	Subtotal        float64 `json:"subtotal"`
+	AmountGross     float64 `json:"amountGross"`
+	FeePercent      float64 `json:"feePercent"`
+	FeeAmount       float64 `json:"feeAmount"`
+	AmountNet       float64 `json:"amountNet"`
+	AllocatedToSO   float64 `json:"allocatedToSo"`
+	RequestedAmount float64 `json:"requestedAmount"`
+	OrderTotal      float64 `json:"orderTotal"`
+	PaidBefore      float64 `json:"paidToDateBefore"`
+	BalanceBefore   float64 `json:"balanceBefore"`
+	PaidAfter       float64 `json:"paidToDateAfter"`
+	BalanceAfter    float64 `json:"balanceAfter"`
+	SubmittedBy     string  `json:"submittedBy"`
+	SubmittedAt     string  `json:"submittedAt"`
+}
+
+// Balance is the running Order Total / Paid-to-Date / Remaining Balance of
+// an SO, or of a root appointment across all of its SOs.
+type Balance struct {
+	RootApptID       string  `json:"rootApptId"`
+	Brand            string  `json:"brand"`
+	SONumber         string  `json:"soNumber"`
+	OrderTotal       float64 `json:"orderTotal"`
+	PaidToDate       float64 `json:"paidToDate"`
+	Credits          float64 `json:"credits"`
+	RemainingBalance float64 `json:"remainingBalance"`
+	LastPaymentAt    string  `json:"lastPaymentAt"`
+	UpdatedAt        string  `json:"updatedAt"`
+}
+
+// Filter narrows List results. Empty fields are ignored.
+type Filter struct {
+	RootApptID string
+	SO         string
+	DocKind    string
+	From       string // inclusive, YYYY-MM-DD or RFC3339, on submitted time
+	To         string // inclusive, YYYY-MM-DD or RFC3339, on submitted time
+	Query      string
+	Limit      int
+	Offset     int
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service records ledger entries and maintains a balance per SO. Documents
+// recorded before the client has an SO keep a balance on the root appointment.
+type Service struct {
+	db     *sql.DB
+	appts  *appointments.Service
+	orders *orders.Service
+	fees   map[string]float64
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs a payments service.
+func NewService(db *sql.DB, appts *appointments.Service, ordersSvc *orders.Service, cfg config.PaymentsConfig, logger *logging.Logger) *Service {
+	fees := make(map[string]float64, len(cfg.FeePercent))
+	for method, pct := range cfg.FeePercent {
+		fees[strings.ToLower(method)] = pct
+	}
+	return &Service{db: db, appts: appts, orders: ordersSvc, fees: fees, logger: logger, now: time.Now}
+}
+
+const paymentColumns = `payment_id, brand, root_appt_id, so_number, anchor_type, basket_id, customer_name,
+        doc_type, doc_kind, doc_role, doc_status, payment_date_time, method, reference, notes,
+        lines_json, subtotal_cents, amount_gross_cents, fee_percent, fee_amount_cents, amount_net_cents,
+        allocated_to_so_cents, requested_amount_cents, order_total_cents, paid_before_cents,
+        balance_before_cents, paid_after_cents, balance_after_cents, submitted_by, submitted_at`
+
+// Record validates a document, stores it and recomputes the anchor's balance
+// in a single transaction, holding the anchor's lock (lockAnchor) throughout.
+//
+// Balance rules (Payments_v1.js):
+//   - Order Total falls back to the Lines Subtotal when none has been set.
+//   - Receipts add their allocated amount to Paid-to-Date, so a receipt's
+//     summary reads Lines Subtotal − Payment on a fresh anchor.
+//   - Invoices record a Requested Amount but never change the balance.
+//   - Credits reduce what is owed by their Lines Subtotal.
+func (s *Service) Record(ctx context.Context, in RecordInput, submittedBy string) (*Payment, error) {
+	p, err := s.prepare(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	p.SubmittedBy = submittedBy
+	now := s.now().UTC()
+	p.SubmittedAt = now.Format(time.RFC3339)
+	basket := p.SONumber
+	if basket == "" {
+		basket = p.RootApptID
+	}
+	p.PaymentID = "PAY-" + uuid.NewString()
+	p.BasketID = "BASK-" + basket + "-" + now.Format("20060102-150405")
+	a := anchorFor(p.Brand, p.SONumber, p.RootApptID)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin record payment: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := lockAnchor(ctx, tx, a); err != nil {
+		return nil, err
+	}
+	bal, err := loadBalance(ctx, tx, a.key)
+	if err != nil {
+		return nil, err
+	}
+	orderTotal := bal.orderTotal
+	if in.SetOrderTotal || orderTotal == 0 {
+		orderTotal = toCents(p.Subtotal)
+	}
+	paid, credits, err := ledgerSums(ctx, tx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	p.OrderTotal = fromCents(orderTotal)
+	p.PaidBefore = fromCents(paid)
+	p.BalanceBefore = fromCents(remaining(orderTotal, paid, credits))
+	if Counts(p.DocStatus) {
+		switch p.DocKind {
+		case KindReceipt:
+			paid += toCents(p.AllocatedToSO)
+		case KindCredit:
+			credits += toCents(p.Subtotal)
+		}
+	}
+	p.PaidAfter = fromCents(paid)
+	p.BalanceAfter = fromCents(remaining(orderTotal, paid, credits))
+
+	if err := insertPayment(ctx, tx, p); err != nil {
+		return nil, err
+	}
+
+	// Re-derive from the ledger rather than trusting the running values above.
+	if err := deriveBalance(ctx, tx, a, orderTotal, p.SubmittedAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit record payment: %w", err)
+	}
+
+	s.logger.Info("payment_recorded", map[string]any{
+		"payment_id":   p.PaymentID,
+		"root_appt_id": p.RootApptID,
+		"so":           p.SONumber,
+		"doc_type":     p.DocType,
+		"balance":      p.BalanceAfter,
+	})
+	return p, nil
+}
+
+// prepare validates input, resolves the anchor and computes document amounts.
+func (s *Service) prepare(ctx context.Context, in RecordInput) (*Payment, error) {
+	p := &Payment{
+		AnchorType: strings.ToUpper(strings.TrimSpace(in.AnchorType)),
+		DocType:    strings.TrimSpace(in.DocType),
+		DocRole:    strings.ToUpper(strings.TrimSpace(in.DocRole)),
+		DocStatus:  strings.ToUpper(strings.TrimSpace(in.DocStatus)),
+		Lines:      in.Lines,
+	}
+	if p.DocType == "" {
+		return nil, &ValidationError{Field: "docType", Message: "is required"}
+	}
+	p.DocKind = DocKind(p.DocType)
+	if p.DocKind == "" {
+		return nil, &ValidationError{Field: "docType", Message: "must name a receipt, invoice or credit"}
+	}
+	if p.DocStatus == "" {
+		p.DocStatus = StatusDraft
+	}
+	if p.DocRole == "" {
+		p.DocRole = DefaultDocRole(p.DocType, len(in.Lines) > 0)
+	}
+	if len(in.Lines) == 0 {
+		return nil, &ValidationError{Field: "lines", Message: "at least one line is required"}
+	}
+	var subtotal float64
+	for _, ln := range in.Lines {
+		subtotal += ln.Qty * ln.Amt
+	}
+	p.Subtotal = round2(subtotal)
+	if p.Subtotal <= 0 {
+		return nil, &ValidationError{Field: "lines", Message: "subtotal must be greater than 0"}
+	}
+
+	switch p.AnchorType {
+	case AnchorSO:
+		order, err := s.orders.Lookup(ctx, in.Brand, in.SO)
+		if err != nil {
+			return nil, err
+		}
+		p.Brand, p.SONumber, p.RootApptID = order.Brand, order.SOPretty, order.RootApptID
+	case AnchorAPPT:
+		p.RootApptID = strings.TrimSpace(in.RootApptID)
+		if p.RootApptID == "" {
+			return nil, &ValidationError{Field: "rootApptId", Message: "is required for APPT anchors"}
+		}
+		p.Brand = strings.ToUpper(strings.TrimSpace(in.Brand))
+	default:
+		return nil, &ValidationError{Field: "anchorType", Message: "must be SO or APPT"}
+	}
+
+	if appt, err := s.appts.Get(ctx, p.RootApptID); err == nil {
+		p.CustomerName = appt.CustomerName
+		if p.Brand == "" {
+			p.Brand = appt.Brand
+		}
+		if p.SONumber == "" {
+			p.SONumber = appt.SONumber
+		}
+	} else if !errors.Is(err, appointments.ErrNotFound) {
+		return nil, err
+	}
+	if p.Brand == "" {
+		return nil, &ValidationError{Field: "brand", Message: "is required when the root appointment is unknown"}
+	}
+
+	switch p.DocKind {
+	case KindReceipt:
+		gross := round2(in.Payment.Amount)
+		if gross <= 0 {
+			return nil, &ValidationError{Field: "pmt.amount", Message: "is required for receipts"}
+		}
+		p.PaymentDateTime = strings.TrimSpace(in.Payment.DateTime)
+		p.Method = strings.TrimSpace(in.Payment.Method)
+		p.Reference = strings.TrimSpace(in.Payment.Reference)
+		p.Notes = strings.TrimSpace(in.Payment.Notes)
+		p.AmountGross = gross
+		p.FeePercent = s.fees[strings.ToLower(p.Method)]
+		p.FeeAmount = round2(gross * p.FeePercent)
+		p.AmountNet = round2(gross - p.FeeAmount)
+		// Allocation UI is disabled upstream, so the full gross applies to the SO.
+		p.AllocatedToSO = gross
+	case KindInvoice:
+		p.RequestedAmount = round2(in.Payment.Amount)
+		p.Notes = strings.TrimSpace(in.Payment.Notes)
+	case KindCredit:
+		p.Reference = strings.TrimSpace(in.Payment.Reference)
+		p.Notes = strings.TrimSpace(in.Payment.Notes)
+	}
+	return p, nil
+}
+
+// Get returns a ledger entry by PAYMENT_ID.
+func (s *Service) Get(ctx context.Context, paymentID string) (*Payment, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE payment_id = ?`, strings.TrimSpace(paymentID))
+	p, err := scanPayment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select payment: %w", err)
+	}
+	return p, nil
+}
+
+// List returns ledger entries matching the filter, newest first.
+func (s *Service) List(ctx context.Context, f Filter) ([]Payment, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if v := strings.TrimSpace(f.RootApptID); v != "" {
+		where = append(where, "root_appt_id = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.SO); v != "" {
+		where = append(where, "so_number = ?")
+		args = append(args, orders.SOPretty(v))
+	}
+	if v := strings.ToLower(strings.TrimSpace(f.DocKind)); v != "" {
+		where = append(where, "doc_kind = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.From); v != "" {
+		where = append(where, "submitted_at >= ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.To); v != "" {
+		// A bare date is inclusive of the whole day.
+		if len(v) == len("2006-01-02") {
+			v += "T23:59:59Z"
+		}
+		where = append(where, "submitted_at <= ?")
+		args = append(args, v)
+	}
+	if q := strings.TrimSpace(f.Query); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		where = append(where, `(LOWER(payment_id) LIKE ? OR LOWER(customer_name) LIKE ? OR so_number LIKE ?
+            OR LOWER(root_appt_id) LIKE ? OR LOWER(reference) LIKE ? OR LOWER(doc_type) LIKE ?)`)
+		args = append(args, like, like, like, like, like, like)
+	}
+
+	query := `SELECT ` + paymentColumns + ` FROM payments`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY submitted_at DESC, payment_id LIMIT ? OFFSET ?`
+	args = append(args, clampLimit(f.Limit), max(f.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list payments: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan payment: %w", err)
+		}
+		out = append(out, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate payments: %w", err)
+	}
+	return out, nil
+}
+
+// BalanceForRoot returns a client's balance summed over its SOs and any
+// documents recorded before the first SO. SONumber is the highest SO# when
+// there are several. Clients with no ledger activity report a zero balance.
+func (s *Service) BalanceForRoot(ctx context.Context, rootApptID string) (*Balance, error) {
+	rootApptID = strings.TrimSpace(rootApptID)
+	if rootApptID == "" {
+		return nil, &ValidationError{Field: "rootApptId", Message: "is required"}
+	}
+	var b balanceRow
+	err := s.db.QueryRowContext(ctx, `SELECT brand, so_number, order_total_cents, paid_to_date_cents, credits_cents,
+            remaining_balance_cents, last_payment_at, updated_at
+        FROM root_payment_balances WHERE root_appt_id = ?`, rootApptID).
+		Scan(&b.brand, &b.soNumber, &b.orderTotal, &b.paid, &b.credits, &b.remaining, &b.lastPaymentAt, &b.updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("select root payment balance: %w", err)
+	}
+	return b.export(rootApptID), nil
+}
+
+// BalanceForSO returns the balance of one SO.
+func (s *Service) BalanceForSO(ctx context.Context, brand, rawSO string) (*Balance, error) {
+	order, err := s.orders.Lookup(ctx, brand, rawSO)
+	if err != nil {
+		return nil, err
+	}
+	bal, err := loadBalance(ctx, s.db, anchorFor(order.Brand, order.SOPretty, order.RootApptID).key)
+	if err != nil {
+		return nil, err
+	}
+	bal.brand, bal.soNumber = order.Brand, order.SOPretty
+	return bal.export(order.RootApptID), nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type balanceRow struct {
+	brand         string
+	soNumber      string
+	orderTotal    int64
+	paid          int64
+	credits       int64
+	remaining     int64
+	lastPaymentAt string
+	updatedAt     string
+}
+
+func (b balanceRow) export(root string) *Balance {
+	return &Balance{
+		RootApptID:       root,
+		Brand:            b.brand,
+		SONumber:         b.soNumber,
+		OrderTotal:       fromCents(b.orderTotal),
+		PaidToDate:       fromCents(b.paid),
+		Credits:          fromCents(b.credits),
+		RemainingBalance: fromCents(b.remaining),
+		LastPaymentAt:    b.lastPaymentAt,
+		UpdatedAt:        b.updatedAt,
+	}
+}
+
+// balanceAnchor identifies the payment_balances row a document counts
+// toward: its SO when it has one, otherwise its root appointment.
+type balanceAnchor struct {
+	key      string
+	brand    string
+	soKey    string
+	soNumber string
+	root     string
+}
+
+func anchorFor(brand, soNumber, root string) balanceAnchor {
+	if key := orders.SOKey(soNumber); key != "" {
+		return balanceAnchor{key: "SO|" + brand + "|" + key, brand: brand, soKey: key, soNumber: orders.SOPretty(key), root: root}
+	}
+	return balanceAnchor{key: "APPT|" + root, brand: brand, root: root}
+}
+
+// AnchorKey returns the key of the balance a document with these Brand, SO#
+// and RootApptID values counts toward.
+func AnchorKey(brand, soNumber, rootApptID string) string {
+	return anchorFor(brand, soNumber, rootApptID).key
+}
+
+// ledgerFilter matches the anchor's documents in payments.
+func (a balanceAnchor) ledgerFilter() (string, []any) {
+	if a.soKey != "" {
+		return `brand = ? AND so_number = ?`, []any{a.brand, a.soNumber}
+	}
+	return `root_appt_id = ? AND so_number = ''`, []any{a.root}
+}
+
+func loadBalance(ctx context.Context, q queryer, key string) (balanceRow, error) {
+	var b balanceRow
+	err := q.QueryRowContext(ctx, `SELECT brand, so_number, order_total_cents, paid_to_date_cents, credits_cents,
+            remaining_balance_cents, last_payment_at, updated_at
+        FROM payment_balances WHERE anchor_key = ?`, key).
+		Scan(&b.brand, &b.soNumber, &b.orderTotal, &b.paid, &b.credits, &b.remaining, &b.lastPaymentAt, &b.updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return balanceRow{}, nil
+	}
+	if err != nil {
+		return balanceRow{}, fmt.Errorf("select payment balance: %w", err)
+	}
+	return b, nil
+}
+
+// countedSQL matches the documents Counts accepts.
+const countedSQL = `doc_status NOT IN ('` + StatusVoid + `', '` + StatusReplaced + `', '` + StatusDraft + `')`
+
+func ledgerSums(ctx context.Context, q queryer, a balanceAnchor) (paid, credits int64, err error) {
+	filter, args := a.ledgerFilter()
+	err = q.QueryRowContext(ctx, `SELECT
+            COALESCE(SUM(CASE WHEN doc_kind = ? THEN allocated_to_so_cents ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN doc_kind = ? THEN subtotal_cents ELSE 0 END), 0)
+        FROM payments WHERE `+filter+` AND `+countedSQL,
+		append([]any{KindReceipt, KindCredit}, args...)...).Scan(&paid, &credits)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sum payments: %w", err)
+	}
+	return paid, credits, nil
+}
+
+// saveBalance upserts the anchor's payment_balances row.
+func saveBalance(ctx context.Context, q queryer, a balanceAnchor, b balanceRow) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO payment_balances(anchor_key, brand, so_key, so_number, root_appt_id,
+            order_total_cents, paid_to_date_cents, credits_cents, remaining_balance_cents, last_payment_at, updated_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(anchor_key) DO UPDATE SET
+            brand = excluded.brand, root_appt_id = excluded.root_appt_id, order_total_cents = excluded.order_total_cents,
+            paid_to_date_cents = excluded.paid_to_date_cents, credits_cents = excluded.credits_cents,
+            remaining_balance_cents = excluded.remaining_balance_cents, last_payment_at = excluded.last_payment_at,
+            updated_at = excluded.updated_at`,
+		a.key, a.brand, a.soKey, a.soNumber, a.root, b.orderTotal, b.paid, b.credits,
+		remaining(b.orderTotal, b.paid, b.credits), b.lastPaymentAt, b.updatedAt)
+	if err != nil {
+		return fmt.Errorf("upsert payment balance: %w", err)
+	}
+	return nil
+}
+
+// RefreshBalance re-derives the payment_balances row of the anchor with
+// these Brand, SO# and RootApptID values from its ledger inside tx, for
+// writers that load ledger rows directly (the Sheets importer). It applies
+// the same rules as Record; see deriveBalance.
+func RefreshBalance(ctx context.Context, tx *sql.Tx, brand, soNumber, rootApptID string) error {
+	a := anchorFor(brand, soNumber, rootApptID)
+	if err := lockAnchor(ctx, tx, a); err != nil {
+		return err
+	}
+	return deriveBalance(ctx, tx, a, 0, time.Now().UTC().Format(time.RFC3339))
+}
+
+// lockAnchor makes sure the anchor's payment_balances row exists and takes
+// its row lock for the rest of the transaction, so concurrent writers to one
+// anchor sum the ledger one after another. Without it two read-committed
+// transactions could each miss the other's document and the last upsert
+// would store a stale balance.
+func lockAnchor(ctx context.Context, q queryer, a balanceAnchor) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO payment_balances(anchor_key, brand, so_key, so_number, root_appt_id, updated_at)
+        VALUES(?, ?, ?, ?, ?, '')
+        ON CONFLICT(anchor_key) DO UPDATE SET anchor_key = excluded.anchor_key`,
+		a.key, a.brand, a.soKey, a.soNumber, a.root)
+	if err != nil {
+		return fmt.Errorf("lock payment balance: %w", err)
+	}
+	return nil
+}
+
+// deriveBalance recomputes the anchor's payment_balances row from its ledger
+// and saves it, stamped updatedAt. A non-zero orderTotal wins; otherwise the
+// stored order total is kept, falling back to the first counted document's
+// snapshot for a new anchor.
+func deriveBalance(ctx context.Context, q queryer, a balanceAnchor, orderTotal int64, updatedAt string) error {
+	filter, args := a.ledgerFilter()
+	if orderTotal == 0 {
+		bal, err := loadBalance(ctx, q, a.key)
+		if err != nil {
+			return err
+		}
+		orderTotal = bal.orderTotal
+	}
+	if orderTotal == 0 {
+		err := q.QueryRowContext(ctx, `SELECT order_total_cents FROM payments
+            WHERE `+filter+` AND `+countedSQL+` ORDER BY submitted_at, payment_id LIMIT 1`, args...).
+			Scan(&orderTotal)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("select first payment: %w", err)
+		}
+	}
+	paid, credits, err := ledgerSums(ctx, q, a)
+	if err != nil {
+		return err
+	}
+	var lastPaymentAt string
+	err = q.QueryRowContext(ctx, `SELECT COALESCE(MAX(submitted_at), '') FROM payments
+        WHERE `+filter+` AND doc_kind = ? AND `+countedSQL, append(args, KindReceipt)...).Scan(&lastPaymentAt)
+	if err != nil {
+		return fmt.Errorf("select last payment: %w", err)
+	}
+	return saveBalance(ctx, q, a, balanceRow{orderTotal: orderTotal, paid: paid, credits: credits,
+		lastPaymentAt: lastPaymentAt, updatedAt: updatedAt})
+}
+
+func insertPayment(ctx context.Context, q queryer, p *Payment) error {
+	linesJSON, err := json.Marshal(p.Lines)
+	if err != nil {
+		return fmt.Errorf("encode lines: %w", err)
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO payments(`+paymentColumns+`)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.PaymentID, p.Brand, p.RootApptID, p.SONumber, p.AnchorType, p.BasketID, p.CustomerName,
+		p.DocType, p.DocKind, p.DocRole, p.DocStatus, p.PaymentDateTime, p.Method, p.Reference, p.Notes,
+		string(linesJSON), toCents(p.Subtotal), toCents(p.AmountGross), p.FeePercent, toCents(p.FeeAmount), toCents(p.AmountNet),
+		toCents(p.AllocatedToSO), toCents(p.RequestedAmount), toCents(p.OrderTotal), toCents(p.PaidBefore),
+		toCents(p.BalanceBefore), toCents(p.PaidAfter), toCents(p.BalanceAfter), p.SubmittedBy, p.SubmittedAt)
+	if err != nil {
+		return fmt.Errorf("insert payment: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPayment(row scanner) (*Payment, error) {
+	var (
+		p         Payment
+		linesJSON string
+		cents     [11]int64
+	)
+	err := row.Scan(&p.PaymentID, &p.Brand, &p.RootApptID, &p.SONumber, &p.AnchorType, &p.BasketID, &p.CustomerName,
+		&p.DocType, &p.DocKind, &p.DocRole, &p.DocStatus, &p.PaymentDateTime, &p.Method, &p.Reference, &p.Notes,
+		&linesJSON, &cents[0], &cents[1], &p.FeePercent, &cents[2], &cents[3],
+		&cents[4], &cents[5], &cents[6], &cents[7],
+		&cents[8], &cents[9], &cents[10], &p.SubmittedBy, &p.SubmittedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(linesJSON), &p.Lines); err != nil {
+		return nil, fmt.Errorf("decode lines: %w", err)
+	}
+	p.Subtotal, p.AmountGross, p.FeeAmount, p.AmountNet = fromCents(cents[0]), fromCents(cents[1]), fromCents(cents[2]), fromCents(cents[3])
+	p.AllocatedToSO, p.RequestedAmount, p.OrderTotal, p.PaidBefore = fromCents(cents[4]), fromCents(cents[5]), fromCents(cents[6]), fromCents(cents[7])
+	p.BalanceBefore, p.PaidAfter, p.BalanceAfter = fromCents(cents[8]), fromCents(cents[9]), fromCents(cents[10])
+	return &p, nil
+}
+
+// DocKind classifies a Doc Type ("Deposit Receipt", "Sales Invoice", "Credit Memo", ...).
+func DocKind(docType string) string {
+	t := strings.ToLower(docType)
+	switch {
+	case strings.Contains(t, "credit"):
+		return KindCredit
+	case strings.Contains(t, "receipt"):
+		return KindReceipt
+	case strings.Contains(t, "invoice"):
+		return KindInvoice
+	default:
+		return ""
+	}
+}
+
+// DefaultDocRole mirrors the role inference in rp_submit.
+func DefaultDocRole(docType string, hasLines bool) string {
+	t := strings.ToUpper(docType)
+	switch {
+	case strings.Contains(t, "CREDIT"):
+		return "CREDIT"
+	case strings.Contains(t, "PROGRESS"):
+		return "PROGRESS"
+	case strings.Contains(t, "DEPOSIT") && strings.Contains(t, "INVOICE"):
+		return "DEPOSIT"
+	case strings.Contains(t, "INVOICE"):
+		return "FINAL"
+	case hasLines:
+		return "SALES_RECEIPT"
+	default:
+		return "PAYMENT_RECEIPT"
+	}
+}
+
+func remaining(orderTotal, paid, credits int64) int64 {
+	return max(orderTotal-credits-paid, 0)
+}
+
+func toCents(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+func fromCents(c int64) float64 {
+	return float64(c) / 100
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
@@ -0,0 +1,393 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+func TestRemaining(t *testing.T) {
+	tests := []struct {
+		total, paid, credits, want int64
+	}{
+		{500000, 0, 0, 500000},
+		{500000, 100000, 0, 400000},
+		{500000, 100000, 25000, 375000},
+		{500000, 600000, 0, 0},
+		{0, 100000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := remaining(tt.total, tt.paid, tt.credits); got != tt.want {
+			t.Errorf("remaining(%d, %d, %d) = %d, want %d", tt.total, tt.paid, tt.credits, got, tt.want)
+		}
+	}
+}
+
+func TestCents(t *testing.T) {
+	for v, want := range map[float64]int64{0: 0, 0.1 + 0.2: 30, 19.999: 2000, 1234.56: 123456, -5.005: -501} {
+		if got := toCents(v); got != want {
+			t.Errorf("toCents(%v) = %d, want %d", v, got, want)
+		}
+	}
+	if got := round2(2.675 * 3); got != 8.03 {
+		t.Errorf("round2 = %v, want 8.03", got)
+	}
+}
+
+func TestCounts(t *testing.T) {
+	for status, want := range map[string]bool{
+		StatusIssued: true, "": true, "issued": true,
+		StatusDraft: false, StatusReplaced: false, StatusVoid: false, " void ": false,
+	} {
+		if got := Counts(status); got != want {
+			t.Errorf("Counts(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestAnchorFor(t *testing.T) {
+	so := anchorFor("VVS", "SO#12.3456", "R1")
+	if so.key != "SO|VVS|123456" || so.soNumber != "12.3456" || so.root != "R1" {
+		t.Errorf("SO anchor = %+v", so)
+	}
+	if other := anchorFor("HPUSA", "12.3456", "R1"); other.key == so.key {
+		t.Error("brands share an SO anchor")
+	}
+	appt := anchorFor("VVS", "", "R1")
+	if appt.key != "APPT|R1" || appt.soKey != "" {
+		t.Errorf("APPT anchor = %+v", appt)
+	}
+}
+
+func TestDocKindAndRole(t *testing.T) {
+	tests := []struct {
+		docType, kind, role string
+		hasLines            bool
+	}{
+		{"Deposit Receipt", KindReceipt, "SALES_RECEIPT", true},
+		{"Payment Receipt", KindReceipt, "PAYMENT_RECEIPT", false},
+		{"Deposit Invoice", KindInvoice, "DEPOSIT", true},
+		{"Progress Invoice", KindInvoice, "PROGRESS", true},
+		{"Sales Invoice", KindInvoice, "FINAL", true},
+		{"Credit Memo", KindCredit, "CREDIT", true},
+		{"Estimate", "", "SALES_RECEIPT", true},
+	}
+	for _, tt := range tests {
+		if got := DocKind(tt.docType); got != tt.kind {
+			t.Errorf("DocKind(%q) = %q, want %q", tt.docType, got, tt.kind)
+		}
+		if got := DefaultDocRole(tt.docType, tt.hasLines); got != tt.role {
+			t.Errorf("DefaultDocRole(%q) = %q, want %q", tt.docType, got, tt.role)
+		}
+	}
+}
+
+type fixture struct {
+	svc    *Service
+	orders *orders.Service
+	appts  *appointments.Service
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	return newFixtureOn(t, dbtest.Open(t, db.DriverSQLite))
+}
+
+func newFixtureOn(t *testing.T, conn *sql.DB) *fixture {
+	t.Helper()
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	ordersSvc := orders.NewService(conn, appts, logger)
+	svc := NewService(conn, appts, ordersSvc, config.PaymentsConfig{}, logger)
+	clock := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	return &fixture{svc: svc, orders: ordersSvc, appts: appts}
+}
+
+func (f *fixture) client(t *testing.T, id string, sos ...string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := f.appts.Create(ctx, appointments.Appointment{ApptID: id, Brand: appointments.BrandVVS,
+		CustomerName: "Client " + id, VisitDate: "2024-04-02"}); err != nil {
+		t.Fatalf("create appointment: %v", err)
+	}
+	for _, so := range sos {
+		if _, err := f.orders.Assign(ctx, orders.AssignInput{ApptID: id, Brand: "VVS", SO: so, OdooURL: "odoo.example.com"}); err != nil {
+			t.Fatalf("assign %s: %v", so, err)
+		}
+	}
+}
+
+func (f *fixture) record(t *testing.T, in RecordInput) *Payment {
+	t.Helper()
+	if in.Brand == "" {
+		in.Brand = "VVS"
+	}
+	if in.AnchorType == "" {
+		in.AnchorType = AnchorSO
+	}
+	p, err := f.svc.Record(context.Background(), in, "tester")
+	if err != nil {
+		t.Fatalf("record %s: %v", in.DocType, err)
+	}
+	return p
+}
+
+func lines(amt float64) []Line {
+	return []Line{{Desc: "Ring", Qty: 1, Amt: amt}}
+}
+
+func TestRecordBalanceMath(t *testing.T) {
+	f := newFixture(t)
+	f.client(t, "R1", "110001")
+	ctx := context.Background()
+
+	p := f.record(t, RecordInput{SO: "110001", DocType: "Deposit Receipt", DocStatus: StatusIssued,
+		Lines: lines(5000), Payment: PaymentDetails{Amount: 1000}})
+	if p.OrderTotal != 5000 || p.PaidBefore != 0 || p.BalanceBefore != 5000 || p.PaidAfter != 1000 || p.BalanceAfter != 4000 {
+		t.Errorf("first receipt snapshot = %+v", p)
+	}
+
+	// Invoices never move the balance; drafts, voids and replaced documents
+	// are recorded but don't count.
+	f.record(t, RecordInput{SO: "110001", DocType: "Progress Invoice", DocStatus: StatusIssued,
+		Lines: lines(2000), Payment: PaymentDetails{Amount: 2000}})
+	for _, status := range []string{StatusDraft, StatusVoid, StatusReplaced, ""} {
+		p := f.record(t, RecordInput{SO: "110001", DocType: "Payment Receipt", DocStatus: status,
+			Lines: lines(1), Payment: PaymentDetails{Amount: 700}})
+		if p.PaidAfter != 1000 || p.BalanceAfter != 4000 {
+			t.Errorf("%q receipt moved the balance: %+v", status, p)
+		}
+	}
+
+	f.record(t, RecordInput{SO: "110001", DocType: "Credit Memo", DocStatus: StatusIssued, Lines: lines(250)})
+	p = f.record(t, RecordInput{SO: "110001", DocType: "Payment Receipt", DocStatus: StatusIssued,
+		Lines: lines(1), Payment: PaymentDetails{Amount: 500}})
+	if p.PaidBefore != 1000 || p.BalanceBefore != 3750 || p.BalanceAfter != 3250 {
+		t.Errorf("receipt after credit = before %v/%v, after %v", p.PaidBefore, p.BalanceBefore, p.BalanceAfter)
+	}
+
+	bal, err := f.svc.BalanceForSO(ctx, "VVS", "11.0001")
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if bal.OrderTotal != 5000 || bal.PaidToDate != 1500 || bal.Credits != 250 || bal.RemainingBalance != 3250 {
+		t.Errorf("balance = %+v", bal)
+	}
+	if bal.LastPaymentAt != p.SubmittedAt {
+		t.Errorf("last payment = %q, want %q", bal.LastPaymentAt, p.SubmittedAt)
+	}
+
+	// The "set Order Total" checkbox replaces the total.
+	p = f.record(t, RecordInput{SO: "110001", DocType: "Sales Invoice", DocStatus: StatusIssued,
+		Lines: lines(6000), SetOrderTotal: true})
+	if p.OrderTotal != 6000 || p.BalanceAfter != 4250 {
+		t.Errorf("set order total = %v, balance %v", p.OrderTotal, p.BalanceAfter)
+	}
+}
+
+func TestBalancesPerSO(t *testing.T) {
+	f := newFixture(t)
+	f.client(t, "R1", "220001", "220002")
+	ctx := context.Background()
+
+	f.record(t, RecordInput{SO: "220001", DocType: "Deposit Receipt", DocStatus: StatusIssued,
+		Lines: lines(5000), Payment: PaymentDetails{Amount: 1000}})
+	p := f.record(t, RecordInput{SO: "220002", DocType: "Deposit Receipt", DocStatus: StatusIssued,
+		Lines: lines(800), Payment: PaymentDetails{Amount: 200}})
+	if p.OrderTotal != 800 || p.PaidBefore != 0 || p.BalanceAfter != 600 {
+		t.Errorf("second SO's receipt saw the first SO's balance: %+v", p)
+	}
+
+	first, err := f.svc.BalanceForSO(ctx, "VVS", "220001")
+	if err != nil {
+		t.Fatalf("balance 220001: %v", err)
+	}
+	second, err := f.svc.BalanceForSO(ctx, "VVS", "220002")
+	if err != nil {
+		t.Fatalf("balance 220002: %v", err)
+	}
+	if first.RemainingBalance != 4000 || second.RemainingBalance != 600 || second.SONumber != "22.0002" {
+		t.Errorf("per-SO balances = %+v, %+v", first, second)
+	}
+
+	root, err := f.svc.BalanceForRoot(ctx, "R1")
+	if err != nil {
+		t.Fatalf("root balance: %v", err)
+	}
+	if root.OrderTotal != 5800 || root.PaidToDate != 1200 || root.RemainingBalance != 4600 {
+		t.Errorf("root balance = %+v", root)
+	}
+
+	empty, err := f.svc.BalanceForRoot(ctx, "nobody")
+	if err != nil || empty.OrderTotal != 0 || empty.RootApptID != "nobody" {
+		t.Errorf("unknown root = %+v, %v", empty, err)
+	}
+}
+
+func TestBalanceBeforeSO(t *testing.T) {
+	f := newFixture(t)
+	f.client(t, "R1")
+	ctx := context.Background()
+
+	p := f.record(t, RecordInput{AnchorType: AnchorAPPT, RootApptID: "R1", DocType: "Deposit Receipt",
+		DocStatus: StatusIssued, Lines: lines(3000), Payment: PaymentDetails{Amount: 500}})
+	if p.SONumber != "" || p.BalanceAfter != 2500 {
+		t.Errorf("pre-SO receipt = %+v", p)
+	}
+	bal, err := f.svc.BalanceForRoot(ctx, "R1")
+	if err != nil || bal.RemainingBalance != 2500 {
+		t.Fatalf("root balance = %+v, %v", bal, err)
+	}
+
+	// Once the client has an SO, APPT-anchored documents count toward it.
+	if _, err := f.orders.Assign(ctx, orders.AssignInput{ApptID: "R1", Brand: "VVS", SO: "330001", OdooURL: "odoo.example.com"}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	p = f.record(t, RecordInput{AnchorType: AnchorAPPT, RootApptID: "R1", DocType: "Deposit Receipt",
+		DocStatus: StatusIssued, Lines: lines(4000), Payment: PaymentDetails{Amount: 1000}})
+	if p.SONumber != "33.0001" || p.OrderTotal != 4000 || p.BalanceAfter != 3000 {
+		t.Errorf("post-SO receipt = %+v", p)
+	}
+}
+
+func TestMovedSOKeepsBalance(t *testing.T) {
+	f := newFixture(t)
+	f.client(t, "R1", "440001")
+	f.client(t, "R2")
+	ctx := context.Background()
+
+	f.record(t, RecordInput{SO: "440001", DocType: "Deposit Receipt", DocStatus: StatusIssued,
+		Lines: lines(1000), Payment: PaymentDetails{Amount: 100}})
+	if _, err := f.orders.Assign(ctx, orders.AssignInput{ApptID: "R2", Brand: "VVS", SO: "440001",
+		OdooURL: "odoo.example.com", Force: true}); err != nil {
+		t.Fatalf("move SO: %v", err)
+	}
+	moved, err := f.svc.BalanceForRoot(ctx, "R2")
+	if err != nil || moved.RemainingBalance != 900 {
+		t.Errorf("new root balance = %+v, %v", moved, err)
+	}
+	old, err := f.svc.BalanceForRoot(ctx, "R1")
+	if err != nil || old.RemainingBalance != 0 {
+		t.Errorf("old root balance = %+v, %v", old, err)
+	}
+}
+
+func TestRefreshBalance(t *testing.T) {
+	f := newFixture(t)
+	f.client(t, "R1", "550001")
+	ctx := context.Background()
+
+	f.record(t, RecordInput{SO: "550001", DocType: "Deposit Receipt", DocStatus: StatusIssued,
+		Lines: lines(5000), Payment: PaymentDetails{Amount: 1000}})
+	f.record(t, RecordInput{SO: "550001", DocType: "Credit Memo", DocStatus: StatusIssued, Lines: lines(250)})
+	f.record(t, RecordInput{SO: "550001", DocType: "Payment Receipt", DocStatus: StatusVoid,
+		Lines: lines(1), Payment: PaymentDetails{Amount: 700}})
+	want, err := f.svc.BalanceForSO(ctx, "VVS", "550001")
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+
+	// An imported ledger row carries its own snapshot; the first counted
+	// document's order total is used when the anchor has none stored.
+	refresh := func(p *Payment) {
+		t.Helper()
+		tx, err := f.svc.db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		if _, err := tx.ExecContext(ctx, `DELETE FROM payment_balances`); err != nil {
+			t.Fatalf("clear balances: %v", err)
+		}
+		if p != nil {
+			if err := insertPayment(ctx, tx, p); err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+		}
+		if err := RefreshBalance(ctx, tx, "VVS", "55.0001", "R1"); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+	}
+
+	refresh(nil)
+	got, err := f.svc.BalanceForSO(ctx, "VVS", "550001")
+	if err != nil {
+		t.Fatalf("refreshed balance: %v", err)
+	}
+	if got.OrderTotal != want.OrderTotal || got.PaidToDate != want.PaidToDate || got.Credits != want.Credits ||
+		got.RemainingBalance != want.RemainingBalance || got.LastPaymentAt != want.LastPaymentAt {
+		t.Errorf("refreshed balance = %+v, recorded %+v", got, want)
+	}
+	if got.UpdatedAt == got.LastPaymentAt {
+		t.Errorf("updated_at = %q, want the refresh time", got.UpdatedAt)
+	}
+
+	refresh(&Payment{PaymentID: "PAY-import", Brand: "VVS", RootApptID: "R1", SONumber: "55.0001",
+		AnchorType: AnchorSO, DocType: "Payment Receipt", DocKind: KindReceipt, DocStatus: StatusIssued,
+		Subtotal: 9000, AllocatedToSO: 500, OrderTotal: 9000, SubmittedAt: "2024-06-01T00:00:00Z"})
+	got, err = f.svc.BalanceForSO(ctx, "VVS", "550001")
+	if err != nil {
+		t.Fatalf("imported balance: %v", err)
+	}
+	if got.OrderTotal != 5000 || got.PaidToDate != 1500 || got.RemainingBalance != 3250 ||
+		got.LastPaymentAt != "2024-06-01T00:00:00Z" {
+		t.Errorf("imported balance = %+v", got)
+	}
+}
+
+// TestConcurrentRecord sums every receipt when several land on one SO at
+// once; the anchor lock keeps a writer from saving a stale balance.
+func TestConcurrentRecord(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, conn *sql.DB) {
+		f := newFixtureOn(t, conn)
+		f.client(t, "R1", "770001")
+		f.svc.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+		ctx := context.Background()
+		f.record(t, RecordInput{SO: "770001", DocType: "Sales Invoice", DocStatus: StatusIssued, Lines: lines(10000)})
+
+		const writers = 6
+		var wg sync.WaitGroup
+		errs := make([]error, writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = f.svc.Record(ctx, RecordInput{Brand: "VVS", AnchorType: AnchorSO, SO: "770001",
+					DocType: "Payment Receipt", DocStatus: StatusIssued, Lines: lines(1),
+					Payment: PaymentDetails{Amount: 100}}, "tester")
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("record: %v", err)
+			}
+		}
+		bal, err := f.svc.BalanceForSO(ctx, "VVS", "770001")
+		if err != nil {
+			t.Fatalf("balance: %v", err)
+		}
+		if bal.PaidToDate != 100*writers || bal.RemainingBalance != 10000-100*writers {
+			t.Errorf("balance = %+v, want %d paid", bal, 100*writers)
+		}
+	})
+}
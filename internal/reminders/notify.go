@@ -0,0 +1,92 @@
+package reminders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// Notifier delivers the daily digest of due reminders.
+type Notifier interface {
+	Notify(ctx context.Context, due []Reminder) error
+}
+
+// LogNotifier writes each due reminder to the structured log. It is the
+// default when no webhook is configured.
+type LogNotifier struct {
+	Logger *logging.Logger
+}
+
+// Notify logs one entry per due reminder.
+func (n LogNotifier) Notify(_ context.Context, due []Reminder) error {
+	for _, rem := range due {
+		n.Logger.Info("reminder_due", map[string]any{
+			"id":            rem.ID,
+			"type":          rem.Type,
+			"so":            rem.SONumber,
+			"customer_name": rem.CustomerName,
+			"assigned_rep":  rem.AssignedRep,
+		})
+	}
+	return nil
+}
+
+// WebhookNotifier posts a plain-text digest to a chat webhook (Google Chat
+// and Slack incoming webhooks both accept {"text": ...}).
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier constructs a notifier with a bounded HTTP timeout.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify posts the digest and fails on any non-2xx response.
+func (n *WebhookNotifier) Notify(ctx context.Context, due []Reminder) error {
+	body, err := json.Marshal(map[string]string{"text": digest(due)})
+	if err != nil {
+		return fmt.Errorf("encode digest: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post webhook: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func digest(due []Reminder) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Reminders due today (%d)\n", len(due))
+	for _, rem := range due {
+		label := rem.SONumber
+		if label == "" {
+			label = rem.RootApptID
+		}
+		fmt.Fprintf(&b, "• [%s] %s — %s", rem.Type, label, rem.CustomerName)
+		if rem.AssignedRep != "" {
+			fmt.Fprintf(&b, " (%s)", rem.AssignedRep)
+		}
+		if rem.NextSteps != "" {
+			fmt.Fprintf(&b, ": %s", rem.NextSteps)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,570 @@
+package reminders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// ErrNotFound is returned when a reminder does not exist.
+var ErrNotFound = errors.New("reminder not found")
+
+// ValidationError reports input that cannot be queued.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Reminder types from Reminders_v1.js and dv_reminders_constants.js.
+const (
+	TypeStart3D          = "START3D"
+	TypeAssignSO         = "ASSIGNSO"
+	TypeRev3D            = "REV3D"
+	TypeFollowUp         = "FOLLOWUP"
+	TypeCOS              = "COS"
+	TypeDVProposeNudge   = "DV_PROPOSE_NUDGE"
+	TypeDVUrgentOTWDaily = "DV_URGENT_OTW_DAILY"
+	// Slipping 3D and Production Deadlines, queued by the deadlines job.
+	TypeDeadline3D         = "DEADLINE_3D"
+	TypeDeadlineProduction = "DEADLINE_PRODUCTION"
+)
+
+// Queue statuses. They are internal to the queue and never alter business statuses.
+const (
+	StatusPending   = "PENDING"
+	StatusSnoozed   = "SNOOZED"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Reminder is one row of the reminders queue.
+type Reminder struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	SONumber     string `json:"soNumber"`
+	RootApptID   string `json:"rootApptId"`
+	CustomerName string `json:"customerName"`
+	NextSteps    string `json:"nextSteps"`
+	Notes        string `json:"notes"`
+	AssignedRep  string `json:"assignedRep"`
+	AssistedRep  string `json:"assistedRep"`
+	Status       string `json:"status"`
+	FirstDueDate string `json:"firstDueDate"`
+	NextDueAt    string `json:"nextDueAt"`
+	SnoozeUntil  string `json:"snoozeUntil"`
+	Attempts     int    `json:"attempts"`
+	LastSentAt   string `json:"lastSentAt"`
+	ConfirmedAt  string `json:"confirmedAt"`
+	ConfirmedBy  string `json:"confirmedBy"`
+	CreatedAt    string `json:"createdAt"`
+	CreatedBy    string `json:"createdBy"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// UpsertInput queues or refreshes a reminder. ID is optional; when empty it is
+// derived from the SO (or root appointment) and type.
+type UpsertInput struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	SO           string    `json:"soNumber"`
+	RootApptID   string    `json:"rootApptId"`
+	DueAt        time.Time `json:"dueAt"`
+	CustomerName string    `json:"customerName"`
+	NextSteps    string    `json:"nextSteps"`
+	Notes        string    `json:"notes"`
+	Status       string    `json:"status"`
+	AssignedRep  string    `json:"assignedRep"`
+	AssistedRep  string    `json:"assistedRep"`
+}
+
+// Filter narrows List results. Empty fields are ignored.
+type Filter struct {
+	SO     string
+	Status string
+	Type   string
+	Rep    string // matches a name in Assigned Rep or Assisted Rep
+	Limit  int
+}
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	// dueGrace matches the one-minute grace in the Apps Script due check.
+	dueGrace = time.Minute
+)
+
+// Service owns the reminders queue.
+type Service struct {
+	db       *sql.DB
+	logger   *logging.Logger
+	loc      *time.Location
+	hour     int
+	minute   int
+	notifier Notifier
+	now      func() time.Time
+}
+
+// NewService constructs a reminders service. dailyAt ("HH:MM") in loc is the
+// time of day snoozes resolve to and rescheduled reminders fall due.
+func NewService(db *sql.DB, loc *time.Location, dailyAt string, notifier Notifier, logger *logging.Logger) (*Service, error) {
+	hour, minute, err := parseClock(dailyAt)
+	if err != nil {
+		return nil, err
+	}
+	if notifier == nil {
+		notifier = LogNotifier{Logger: logger}
+	}
+	return &Service{
+		db:       db,
+		logger:   logger,
+		loc:      loc,
+		hour:     hour,
+		minute:   minute,
+		notifier: notifier,
+		now:      time.Now,
+	}, nil
+}
+
+// ID builds the stable dedupe key for a reminder: SO|<soKey>|<type> for SO
+// reminders, or DV|<root>|<type>[|<dayKey>] for DV nudges.
+func ID(soOrRoot, remType, dayKey string) string {
+	if key := orders.SOKey(soOrRoot); key != "" && !strings.HasPrefix(remType, "DV_") {
+		return "SO|" + key + "|" + remType
+	}
+	parts := []string{"DV", strings.TrimSpace(soOrRoot), remType}
+	if dayKey != "" {
+		parts = append(parts, dayKey)
+	}
+	return strings.Join(parts, "|")
+}
+
+// RootID builds the dedupe key for a reminder anchored to a root
+// appointment rather than an SO: APPT|<root>|<type>, or the DV key for DV
+// nudges. The root is never read as an SO#, so clients whose APPT_IDs share
+// trailing digits keep separate reminders.
+func RootID(root, remType, dayKey string) string {
+	if strings.HasPrefix(remType, "DV_") {
+		return ID(root, remType, dayKey)
+	}
+	return "APPT|" + strings.TrimSpace(root) + "|" + remType
+}
+
+const reminderColumns = `id, type, so_number, root_appt_id, customer_name, next_steps, notes,
+        assigned_rep, assisted_rep, status, first_due_date, next_due_at, snooze_until, attempts,
+        last_sent_at, confirmed_at, confirmed_by, created_at, created_by, updated_at`
+
+// Upsert inserts a reminder or refreshes an existing one. The earlier
+// nextDueAt wins, firstDueDate keeps the earliest date seen and an existing
+// status is never overwritten.
+func (s *Service) Upsert(ctx context.Context, in UpsertInput, actor string) (*Reminder, error) {
+	in.Type = strings.ToUpper(strings.TrimSpace(in.Type))
+	if in.Type == "" {
+		return nil, &ValidationError{Field: "type", Message: "is required"}
+	}
+	if in.DueAt.IsZero() {
+		return nil, &ValidationError{Field: "dueAt", Message: "is required"}
+	}
+	so := orders.SOPretty(in.SO)
+	root := strings.TrimSpace(in.RootApptID)
+	id := strings.TrimSpace(in.ID)
+	if id == "" {
+		switch {
+		case so != "":
+			id = ID(so, in.Type, "")
+		case root != "":
+			id = RootID(root, in.Type, "")
+		default:
+			return nil, &ValidationError{Field: "id", Message: "id, soNumber or rootApptId is required"}
+		}
+	}
+	status := strings.ToUpper(strings.TrimSpace(in.Status))
+	switch status {
+	case "":
+		status = StatusPending
+	case StatusPending, StatusSnoozed, StatusConfirmed, StatusCancelled:
+	default:
+		return nil, &ValidationError{Field: "status", Message: "must be PENDING, SNOOZED, CONFIRMED or CANCELLED"}
+	}
+
+	due := in.DueAt.UTC()
+	dueStr := due.Format(time.RFC3339)
+	dueDate := due.In(s.loc).Format("2006-01-02")
+	stamp := s.now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin reminder upsert: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	existing, err := getReminder(ctx, tx, id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		_, err = tx.ExecContext(ctx, `INSERT INTO reminders_queue(id, type, so_number, root_appt_id, customer_name,
+                next_steps, notes, assigned_rep, assisted_rep, status, first_due_date, next_due_at,
+                created_at, created_by, updated_at)
+            VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, in.Type, so, root, strings.TrimSpace(in.CustomerName), in.NextSteps, in.Notes,
+			strings.TrimSpace(in.AssignedRep), strings.TrimSpace(in.AssistedRep), status, dueDate, dueStr,
+			stamp, actor, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("insert reminder: %w", err)
+		}
+		if err := logAction(ctx, tx, id, so, in.Type, "CREATED", actor, "", stamp); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		nextDue := existing.NextDueAt
+		if dueStr < nextDue {
+			nextDue = dueStr
+		}
+		firstDue := existing.FirstDueDate
+		if dueDate < firstDue {
+			firstDue = dueDate
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE reminders_queue SET
+                type = ?, next_due_at = ?, first_due_date = ?,
+                so_number = CASE WHEN ? <> '' THEN ? ELSE so_number END,
+                root_appt_id = CASE WHEN ? <> '' THEN ? ELSE root_appt_id END,
+                customer_name = CASE WHEN ? <> '' THEN ? ELSE customer_name END,
+                next_steps = CASE WHEN ? <> '' THEN ? ELSE next_steps END,
+                notes = CASE WHEN ? <> '' THEN ? ELSE notes END,
+                assigned_rep = CASE WHEN ? <> '' THEN ? ELSE assigned_rep END,
+                assisted_rep = CASE WHEN ? <> '' THEN ? ELSE assisted_rep END,
+                status = CASE WHEN TRIM(status) = '' THEN ? ELSE status END,
+                updated_at = ?
+            WHERE id = ?`,
+			in.Type, nextDue, firstDue,
+			so, so, root, root,
+			strings.TrimSpace(in.CustomerName), strings.TrimSpace(in.CustomerName),
+			in.NextSteps, in.NextSteps, in.Notes, in.Notes,
+			strings.TrimSpace(in.AssignedRep), strings.TrimSpace(in.AssignedRep),
+			strings.TrimSpace(in.AssistedRep), strings.TrimSpace(in.AssistedRep),
+			status, stamp, id)
+		if err != nil {
+			return nil, fmt.Errorf("update reminder: %w", err)
+		}
+	}
+
+	rem, err := getReminder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit reminder upsert: %w", err)
+	}
+	return rem, nil
+}
+
+// Get returns one reminder by id.
+func (s *Service) Get(ctx context.Context, id string) (*Reminder, error) {
+	return getReminder(ctx, s.db, strings.TrimSpace(id))
+}
+
+// List returns reminders matching the filter, soonest due first.
+func (s *Service) List(ctx context.Context, f Filter) ([]Reminder, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if v := orders.SOPretty(f.SO); v != "" {
+		where = append(where, "so_number = ?")
+		args = append(args, v)
+	}
+	if v := strings.ToUpper(strings.TrimSpace(f.Status)); v != "" {
+		where = append(where, "status = ?")
+		args = append(args, v)
+	}
+	if v := strings.ToUpper(strings.TrimSpace(f.Type)); v != "" {
+		where = append(where, "type = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.Rep); v != "" {
+		where = append(where, "("+reps.MatchSQL("assigned_rep")+" OR "+reps.MatchSQL("assisted_rep")+")")
+		args = append(args, reps.MatchArg(v), reps.MatchArg(v))
+	}
+	query := `SELECT ` + reminderColumns + ` FROM reminders_queue`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY next_due_at, id LIMIT ?`
+	args = append(args, clampLimit(f.Limit))
+	return queryReminders(ctx, s.db, query, args...)
+}
+
+// ListDue returns active reminders whose nextDueAt has passed and that are not
+// snoozed into the future.
+func (s *Service) ListDue(ctx context.Context) ([]Reminder, error) {
+	cutoff := s.now().Add(dueGrace).UTC().Format(time.RFC3339)
+	return queryReminders(ctx, s.db, `SELECT `+reminderColumns+` FROM reminders_queue
+        WHERE status IN (?, ?) AND next_due_at <= ?
+          AND NOT (status = ? AND snooze_until <> '' AND snooze_until > ?)
+        ORDER BY next_due_at, id`,
+		StatusPending, StatusSnoozed, cutoff, StatusSnoozed, cutoff)
+}
+
+// SnoozeSO snoozes every active reminder for an SO until the daily send time
+// on the given local date (YYYY-MM-DD). It returns the number of rows touched.
+func (s *Service) SnoozeSO(ctx context.Context, rawSO, untilDate, actor string) (int, error) {
+	so := orders.SOPretty(rawSO)
+	if so == "" {
+		return 0, &ValidationError{Field: "soNumber", Message: "is required"}
+	}
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(untilDate), s.loc)
+	if err != nil {
+		return 0, &ValidationError{Field: "until", Message: "must be YYYY-MM-DD"}
+	}
+	until := s.atDaily(day).UTC().Format(time.RFC3339)
+	return s.setStatusForSO(ctx, so, StatusSnoozed, until, actor, "Until "+until)
+}
+
+// CancelSO cancels every active reminder for an SO.
+func (s *Service) CancelSO(ctx context.Context, rawSO, actor string) (int, error) {
+	so := orders.SOPretty(rawSO)
+	if so == "" {
+		return 0, &ValidationError{Field: "soNumber", Message: "is required"}
+	}
+	return s.setStatusForSO(ctx, so, StatusCancelled, "", actor, "Manually cancelled")
+}
+
+// Cancel cancels one reminder unless it is already confirmed or cancelled.
+// It reports whether the reminder was active.
+func (s *Service) Cancel(ctx context.Context, id, actor, note string) (bool, error) {
+	stamp := s.now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin reminder cancel: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rem, err := getReminder(ctx, tx, strings.TrimSpace(id))
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if rem.Status == StatusConfirmed || rem.Status == StatusCancelled {
+		return false, nil
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE reminders_queue SET status = ?, updated_at = ? WHERE id = ?`,
+		StatusCancelled, stamp, rem.ID)
+	if err != nil {
+		return false, fmt.Errorf("cancel reminder: %w", err)
+	}
+	if err := logAction(ctx, tx, rem.ID, rem.SONumber, rem.Type, StatusCancelled, actor, note, stamp); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit reminder cancel: %w", err)
+	}
+	return true, nil
+}
+
+func (s *Service) setStatusForSO(ctx context.Context, so, status, snoozeUntil, actor, note string) (int, error) {
+	stamp := s.now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin reminder status: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	active, err := queryReminders(ctx, tx, `SELECT `+reminderColumns+` FROM reminders_queue
+        WHERE so_number = ? AND status NOT IN (?, ?)`, so, StatusConfirmed, StatusCancelled)
+	if err != nil {
+		return 0, err
+	}
+	for _, rem := range active {
+		_, err := tx.ExecContext(ctx, `UPDATE reminders_queue SET status = ?,
+                snooze_until = CASE WHEN ? <> '' THEN ? ELSE snooze_until END, updated_at = ?
+            WHERE id = ?`, status, snoozeUntil, snoozeUntil, stamp, rem.ID)
+		if err != nil {
+			return 0, fmt.Errorf("update reminder status: %w", err)
+		}
+		if err := logAction(ctx, tx, rem.ID, so, rem.Type, status, actor, note, stamp); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit reminder status: %w", err)
+	}
+
+	s.logger.Info("reminders_status_changed", map[string]any{"so": so, "status": status, "count": len(active)})
+	return len(active), nil
+}
+
+// RunResult summarizes one daily send.
+type RunResult struct {
+	Sent        int `json:"sent"`
+	Confirmed   int `json:"confirmed"`
+	Rescheduled int `json:"rescheduled"`
+}
+
+// RunDaily sends every due reminder, then applies the after-send policy:
+// DV propose nudges auto-confirm, everything else moves to the next day's
+// send time and snoozed rows return to PENDING.
+func (s *Service) RunDaily(ctx context.Context) (*RunResult, error) {
+	due, err := s.ListDue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &RunResult{}
+	if len(due) == 0 {
+		return res, nil
+	}
+	if err := s.notifier.Notify(ctx, due); err != nil {
+		return nil, fmt.Errorf("notify due reminders: %w", err)
+	}
+
+	now := s.now()
+	stamp := now.UTC().Format(time.RFC3339)
+	tomorrow := s.atDaily(now.In(s.loc).AddDate(0, 0, 1)).UTC().Format(time.RFC3339)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin reminders run: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, rem := range due {
+		if rem.Type == TypeDVProposeNudge {
+			_, err = tx.ExecContext(ctx, `UPDATE reminders_queue SET status = ?, confirmed_at = ?, confirmed_by = ?,
+                    attempts = attempts + 1, last_sent_at = ?, updated_at = ? WHERE id = ?`,
+				StatusConfirmed, stamp, "system:auto", stamp, stamp, rem.ID)
+			res.Confirmed++
+		} else {
+			_, err = tx.ExecContext(ctx, `UPDATE reminders_queue SET status = ?, next_due_at = ?,
+                    attempts = attempts + 1, last_sent_at = ?, updated_at = ? WHERE id = ?`,
+				StatusPending, tomorrow, stamp, stamp, rem.ID)
+			res.Rescheduled++
+		}
+		if err != nil {
+			return nil, fmt.Errorf("update sent reminder: %w", err)
+		}
+		if err := logAction(ctx, tx, rem.ID, rem.SONumber, rem.Type, "SENT", "", "", stamp); err != nil {
+			return nil, err
+		}
+		res.Sent++
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit reminders run: %w", err)
+	}
+
+	s.logger.Info("reminders_daily_sent", map[string]any{"sent": res.Sent, "confirmed": res.Confirmed, "rescheduled": res.Rescheduled})
+	return res, nil
+}
+
+// DailySchedule returns the configured send time as a cron expression for
+// the jobs runner (e.g. "30 9 * * *").
+func (s *Service) DailySchedule() string {
+	return fmt.Sprintf("%d %d * * *", s.minute, s.hour)
+}
+
+// atDaily returns the configured send time on day's local calendar date.
+func (s *Service) atDaily(day time.Time) time.Time {
+	d := day.In(s.loc)
+	return time.Date(d.Year(), d.Month(), d.Day(), s.hour, s.minute, 0, 0, s.loc)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func getReminder(ctx context.Context, q queryer, id string) (*Reminder, error) {
+	rem, err := scanReminder(q.QueryRowContext(ctx, `SELECT `+reminderColumns+` FROM reminders_queue WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select reminder: %w", err)
+	}
+	return rem, nil
+}
+
+func queryReminders(ctx context.Context, q queryer, query string, args ...any) ([]Reminder, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Reminder{}
+	for rows.Next() {
+		rem, err := scanReminder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan reminder: %w", err)
+		}
+		out = append(out, *rem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate reminders: %w", err)
+	}
+	return out, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReminder(row scanner) (*Reminder, error) {
+	var r Reminder
+	err := row.Scan(&r.ID, &r.Type, &r.SONumber, &r.RootApptID, &r.CustomerName, &r.NextSteps, &r.Notes,
+		&r.AssignedRep, &r.AssistedRep, &r.Status, &r.FirstDueDate, &r.NextDueAt, &r.SnoozeUntil, &r.Attempts,
+		&r.LastSentAt, &r.ConfirmedAt, &r.ConfirmedBy, &r.CreatedAt, &r.CreatedBy, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func logAction(ctx context.Context, q queryer, id, so, remType, action, actor, note, stamp string) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO reminders_log(reminder_id, so_number, type, action, actor, note, created_at)
+        VALUES(?, ?, ?, ?, ?, ?, ?)`, id, so, remType, action, actor, note, stamp)
+	if err != nil {
+		return fmt.Errorf("log reminder action: %w", err)
+	}
+	return nil
+}
+
+func parseClock(hhmm string) (int, int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(hhmm))
+	if err != nil {
+		return 0, 0, fmt.Errorf("reminders daily_at must be HH:MM: %w", err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
@@ -0,0 +1,230 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+func TestID(t *testing.T) {
+	tests := []struct {
+		soOrRoot, remType, dayKey, want string
+	}{
+		{"SO#12.3456", TypeFollowUp, "", "SO|123456|FOLLOWUP"},
+		{"123456", TypeStart3D, "", "SO|123456|START3D"},
+		{"12.3456", TypeDVProposeNudge, "", "DV|12.3456|DV_PROPOSE_NUDGE"},
+		{" APPT-1 ", TypeDVUrgentOTWDaily, "2024-05-01", "DV|APPT-1|DV_URGENT_OTW_DAILY|2024-05-01"},
+		{"APPT-X", TypeFollowUp, "", "DV|APPT-X|FOLLOWUP"},
+	}
+	for _, tt := range tests {
+		if got := ID(tt.soOrRoot, tt.remType, tt.dayKey); got != tt.want {
+			t.Errorf("ID(%q, %q, %q) = %q, want %q", tt.soOrRoot, tt.remType, tt.dayKey, got, tt.want)
+		}
+	}
+}
+
+func TestRootID(t *testing.T) {
+	tests := []struct {
+		root, remType, dayKey, want string
+	}{
+		{"AP-20250101-001", TypeFollowUp, "", "APPT|AP-20250101-001|FOLLOWUP"},
+		{" 123456 ", TypeStart3D, "", "APPT|123456|START3D"},
+		{"APPT-1", TypeDVUrgentOTWDaily, "2024-05-01", "DV|APPT-1|DV_URGENT_OTW_DAILY|2024-05-01"},
+	}
+	for _, tt := range tests {
+		if got := RootID(tt.root, tt.remType, tt.dayKey); got != tt.want {
+			t.Errorf("RootID(%q, %q, %q) = %q, want %q", tt.root, tt.remType, tt.dayKey, got, tt.want)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	h, m, err := parseClock(" 09:30 ")
+	if err != nil || h != 9 || m != 30 {
+		t.Errorf("parseClock = %d, %d, %v", h, m, err)
+	}
+	for _, bad := range []string{"", "9.30", "25:00", "09:60"} {
+		if _, _, err := parseClock(bad); err == nil {
+			t.Errorf("parseClock(%q) accepted", bad)
+		}
+	}
+}
+
+type recorder struct {
+	sent [][]Reminder
+}
+
+func (r *recorder) Notify(_ context.Context, due []Reminder) error {
+	r.sent = append(r.sent, due)
+	return nil
+}
+
+func newTestService(t *testing.T, now time.Time) (*Service, *recorder) {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data: %v", err)
+	}
+	rec := &recorder{}
+	svc, err := NewService(dbtest.Open(t, db.DriverSQLite), loc, "09:30", rec, logging.NewWriter("error", io.Discard))
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	svc.now = func() time.Time { return now }
+	return svc, rec
+}
+
+func TestUpsertStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	svc, _ := newTestService(t, now)
+	ctx := context.Background()
+
+	for _, status := range []string{"DONE", "pending please", "SENT"} {
+		_, err := svc.Upsert(ctx, UpsertInput{Type: TypeFollowUp, SO: "123456", DueAt: now, Status: status}, "tester")
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Field != "status" {
+			t.Errorf("Upsert status %q = %v, want a status validation error", status, err)
+		}
+	}
+	if _, err := svc.Get(ctx, "SO|123456|FOLLOWUP"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rejected upsert stored a row: %v", err)
+	}
+
+	rem, err := svc.Upsert(ctx, UpsertInput{Type: TypeFollowUp, SO: "123456", DueAt: now, Status: " snoozed "}, "tester")
+	if err != nil || rem.Status != StatusSnoozed {
+		t.Fatalf("Upsert snoozed = %+v, %v", rem, err)
+	}
+	// An existing status is never overwritten.
+	rem, err = svc.Upsert(ctx, UpsertInput{Type: TypeFollowUp, SO: "123456", DueAt: now, Status: StatusCancelled}, "tester")
+	if err != nil || rem.Status != StatusSnoozed {
+		t.Fatalf("re-upsert = %+v, %v", rem, err)
+	}
+}
+
+func TestUpsertKeepsEarliestDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	svc, _ := newTestService(t, now)
+	ctx := context.Background()
+
+	later := now.Add(72 * time.Hour)
+	if _, err := svc.Upsert(ctx, UpsertInput{Type: TypeStart3D, SO: "123456", DueAt: later}, "tester"); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	rem, err := svc.Upsert(ctx, UpsertInput{Type: TypeStart3D, SO: "12.3456", DueAt: now, Notes: "sooner"}, "tester")
+	if err != nil {
+		t.Fatalf("second upsert: %v", err)
+	}
+	if rem.NextDueAt != now.Format(time.RFC3339) || rem.FirstDueDate != "2024-05-01" || rem.Notes != "sooner" {
+		t.Errorf("merged reminder = %+v", rem)
+	}
+	rem, err = svc.Upsert(ctx, UpsertInput{Type: TypeStart3D, SO: "123456", DueAt: later}, "tester")
+	if err != nil || rem.NextDueAt != now.Format(time.RFC3339) || rem.Notes != "sooner" {
+		t.Errorf("later upsert = %+v, %v", rem, err)
+	}
+}
+
+// TestUpsertRootsShareDigits keeps clients whose APPT_IDs end in the same
+// digits, and the SO those digits spell, on separate reminders.
+func TestUpsertRootsShareDigits(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	svc, _ := newTestService(t, now)
+	ctx := context.Background()
+
+	for _, in := range []UpsertInput{
+		{Type: TypeFollowUp, RootApptID: "AP-20250101-001", DueAt: now.Add(48 * time.Hour), Notes: "first"},
+		{Type: TypeFollowUp, RootApptID: "AP-20240101-001", DueAt: now.Add(24 * time.Hour), Notes: "second"},
+		{Type: TypeFollowUp, SO: "10.1001", DueAt: now, Notes: "so"},
+	} {
+		if _, err := svc.Upsert(ctx, in, "tester"); err != nil {
+			t.Fatalf("upsert %+v: %v", in, err)
+		}
+	}
+	for id, notes := range map[string]string{
+		"APPT|AP-20250101-001|FOLLOWUP": "first",
+		"APPT|AP-20240101-001|FOLLOWUP": "second",
+		"SO|101001|FOLLOWUP":            "so",
+	} {
+		rem, err := svc.Get(ctx, id)
+		if err != nil || rem.Notes != notes {
+			t.Errorf("Get(%q) = %+v, %v; want notes %q", id, rem, err, notes)
+		}
+	}
+}
+
+func TestRunDaily(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC) // 10:00 in New York
+	svc, rec := newTestService(t, now)
+	ctx := context.Background()
+
+	for _, in := range []UpsertInput{
+		{Type: TypeFollowUp, SO: "111111", DueAt: now.Add(-time.Hour)},
+		{Type: TypeDVProposeNudge, RootApptID: "APPT-1", DueAt: now.Add(-time.Hour)},
+		{Type: TypeFollowUp, SO: "222222", DueAt: now.Add(time.Hour)},
+	} {
+		if _, err := svc.Upsert(ctx, in, "tester"); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+
+	res, err := svc.RunDaily(ctx)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if res.Sent != 2 || res.Confirmed != 1 || res.Rescheduled != 1 || len(rec.sent) != 1 {
+		t.Fatalf("run = %+v, notified %d times", res, len(rec.sent))
+	}
+	followUp, err := svc.Get(ctx, "SO|111111|FOLLOWUP")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if followUp.Status != StatusPending || followUp.NextDueAt != "2024-05-02T13:30:00Z" || followUp.Attempts != 1 {
+		t.Errorf("rescheduled = %+v", followUp)
+	}
+	nudge, err := svc.Get(ctx, "DV|APPT-1|DV_PROPOSE_NUDGE")
+	if err != nil || nudge.Status != StatusConfirmed {
+		t.Errorf("nudge = %+v, %v", nudge, err)
+	}
+
+	res, err = svc.RunDaily(ctx)
+	if err != nil || res.Sent != 0 {
+		t.Errorf("second run = %+v, %v", res, err)
+	}
+}
+
+func TestSnoozeAndCancelSO(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	svc, _ := newTestService(t, now)
+	ctx := context.Background()
+
+	for _, typ := range []string{TypeFollowUp, TypeStart3D} {
+		if _, err := svc.Upsert(ctx, UpsertInput{Type: typ, SO: "123456", DueAt: now.Add(-time.Hour)}, "tester"); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	n, err := svc.SnoozeSO(ctx, "12.3456", "2024-05-03", "tester")
+	if err != nil || n != 2 {
+		t.Fatalf("snooze = %d, %v", n, err)
+	}
+	due, err := svc.ListDue(ctx)
+	if err != nil || len(due) != 0 {
+		t.Errorf("due while snoozed = %d, %v", len(due), err)
+	}
+	if _, err := svc.SnoozeSO(ctx, "123456", "May 3", "tester"); err == nil {
+		t.Error("bad snooze date accepted")
+	}
+
+	n, err = svc.CancelSO(ctx, "SO#123456", "tester")
+	if err != nil || n != 2 {
+		t.Fatalf("cancel = %d, %v", n, err)
+	}
+	ok, err := svc.Cancel(ctx, "SO|123456|FOLLOWUP", "tester", "")
+	if err != nil || ok {
+		t.Errorf("cancel already cancelled = %v, %v", ok, err)
+	}
+}
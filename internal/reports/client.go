@@ -0,0 +1,128 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KindClient is the per-client Client Status Report.
+const KindClient = "client"
+
+// ErrClientNotFound is returned when the client's root appointment does
+// not exist.
+var ErrClientNotFound = errors.New("client not found")
+
+// ClientStatus builds the Client Status Report for a root appointment from
+// its status log, oldest entry first, with status cells filled from
+// reports.status_colors (ClientStatus_v1.js's template copy).
+func (s *Service) ClientStatus(ctx context.Context, rootApptID, generatedBy string) (*Listing, error) {
+	rootApptID = strings.TrimSpace(rootApptID)
+	if rootApptID == "" {
+		return nil, &ValidationError{Field: "rootApptId", Message: "is required"}
+	}
+	var brand, customer, assigned string
+	err := s.db.QueryRowContext(ctx, `SELECT brand, customer_name, assigned_rep FROM appointments WHERE appt_id = ?`,
+		rootApptID).Scan(&brand, &customer, &assigned)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select client: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT log_date, sales_stage, conversion_status, custom_order_status,
+            in_production_status, center_stone_order_status, next_steps, deadline_type, deadline_date, move_count,
+            assisted_rep, updated_by, updated_at
+        FROM client_status_log
+        WHERE appt_id = ? OR appt_id IN (SELECT appt_id FROM appointments WHERE root_appt_id = ?)
+        ORDER BY log_date, updated_at, id`, rootApptID, rootApptID)
+	if err != nil {
+		return nil, fmt.Errorf("select client status log: %w", err)
+	}
+	defer rows.Close()
+
+	l := &Listing{
+		Kind:  KindClient,
+		Title: fmt.Sprintf("%s – %s – Client Status Report", brand, rootApptID),
+		Columns: []Column{
+			{"Log Date", ColumnDate}, {"Sales Stage", ColumnText}, {"Conversion Status", ColumnText},
+			{"Custom Order Status", ColumnText}, {"In Production Status", ColumnText},
+			{"Center Stone Order Status", ColumnText}, {"Next Steps", ColumnText},
+			{"Deadline Type", ColumnText}, {"Deadline Date", ColumnDate}, {"Move Count", ColumnInt},
+			{"Assisted Rep", ColumnText}, {"Updated By", ColumnText}, {"Updated At", ColumnText},
+		},
+		Rows:        [][]Cell{},
+		Summary:     Summary{Groups: map[string]map[string]int{}},
+		GeneratedAt: s.now(),
+	}
+	meta := []string{"Customer: " + customer}
+	if assigned != "" {
+		meta = append(meta, "Assigned Rep: "+assigned)
+	}
+	if by := strings.TrimSpace(generatedBy); by != "" {
+		meta = append(meta, "Generated by "+by)
+	}
+	l.Filters = strings.Join(meta, " • ")
+
+	for rows.Next() {
+		var (
+			logDate, stage, conversion, custom, production, csos, next string
+			deadlineType, deadlineDate, assisted, by, at               string
+			moves                                                      int
+		)
+		if err := rows.Scan(&logDate, &stage, &conversion, &custom, &production, &csos, &next,
+			&deadlineType, &deadlineDate, &moves, &assisted, &by, &at); err != nil {
+			return nil, fmt.Errorf("scan client status log: %w", err)
+		}
+		moveCell := Cell{}
+		if deadlineType != "" {
+			n := float64(moves)
+			moveCell = Cell{Text: strconv.Itoa(moves), Num: &n}
+		}
+		updated := at
+		if t, err := time.Parse(time.RFC3339, at); err == nil {
+			updated = t.In(s.loc).Format("2006-01-02 3:04PM")
+		}
+		l.Rows = append(l.Rows, []Cell{
+			{Text: logDate},
+			colorCell(stage, s.colors.SalesStage),
+			colorCell(conversion, s.colors.ConversionStatus),
+			colorCell(custom, s.colors.CustomOrderStatus),
+			colorCell(production, s.colors.InProductionStatus),
+			colorCell(csos, s.colors.CenterStoneOrderStatus),
+			{Text: next},
+			{Text: deadlineType},
+			{Text: deadlineDate},
+			moveCell,
+			{Text: assisted},
+			{Text: by},
+			{Text: updated},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate client status log: %w", err)
+	}
+	l.Total = len(l.Rows)
+	l.Summary.TotalRows = l.Total
+	return l, nil
+}
+
+// colorCell fills v with its configured hex code, matching case-insensitively.
+func colorCell(v string, colors map[string]string) Cell {
+	c := Cell{Text: v}
+	if strings.TrimSpace(v) == "" {
+		return c
+	}
+	for value, hex := range colors {
+		if strings.EqualFold(strings.TrimSpace(value), strings.TrimSpace(v)) && hexColor.MatchString(hex) {
+			c.Fill = hex
+			break
+		}
+	}
+	return c
+}
@@ -0,0 +1,94 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Export formats.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+	FormatHTML = "html"
+)
+
+// ExportFile describes how a listing is served as a download.
+type ExportFile struct {
+	Name        string
+	ContentType string
+}
+
+// File returns the download name and content type of l in format.
+func (l *Listing) File(format string) (ExportFile, error) {
+	types := map[string]string{
+		FormatCSV:  "text/csv; charset=utf-8",
+		FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		FormatPDF:  "application/pdf",
+		FormatHTML: "text/html; charset=utf-8",
+	}
+	ct, ok := types[format]
+	if !ok {
+		return ExportFile{}, &ValidationError{Field: "format", Message: "must be json, csv, xlsx, pdf or html"}
+	}
+	return ExportFile{Name: safeFileName(l.Title) + "." + format, ContentType: ct}, nil
+}
+
+// Write renders l to w in format.
+func (l *Listing) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		return l.writeCSV(w)
+	case FormatXLSX:
+		return l.writeXLSX(w)
+	case FormatPDF:
+		return l.writePDF(w)
+	case FormatHTML:
+		return l.writeHTML(w)
+	default:
+		return &ValidationError{Field: "format", Message: "must be json, csv, xlsx, pdf or html"}
+	}
+}
+
+func (l *Listing) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(l.Columns))
+	for i, c := range l.Columns {
+		header[i] = c.Label
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	record := make([]string, len(l.Columns))
+	for _, row := range l.Rows {
+		for i, cell := range row {
+			// Plain numbers import cleanly; the $ text stays in the other formats.
+			if l.Columns[i].Type == ColumnMoney && cell.Num != nil {
+				record[i] = fmt.Sprintf("%.2f", *cell.Num)
+				continue
+			}
+			record[i] = cell.Text
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write csv row: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// safeFileName strips the characters report_showDownloadDialog_ replaced.
+func safeFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) || r < ' ' {
+			return '-'
+		}
+		return r
+	}, title)
+	if strings.TrimSpace(name) == "" {
+		return "report"
+	}
+	return name
+}
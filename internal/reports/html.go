@@ -0,0 +1,77 @@
+package reports
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+)
+
+// htmlTemplate mirrors the PDF layout: a title, the filter line and one
+// table with each cell's fill.
+var htmlTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+@page { size: letter landscape; margin: 12mm; }
+body { font-family: Helvetica, Arial, sans-serif; font-size: 12px; color: #111; margin: 24px; }
+h1 { font-size: 18px; margin: 0 0 4px; }
+.meta { color: #555; margin-bottom: 12px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #d9d9d9; padding: 4px 6px; text-align: left; vertical-align: top; white-space: pre-wrap; }
+th { background: #f4f4f4; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">{{if .Filters}}{{.Filters}} • {{end}}Rows: {{.Total}}</div>
+<table>
+<thead><tr>{{range .Columns}}<th>{{.Label}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td{{if .Class}} class="{{.Class}}"{{end}}{{if .Fill}} style="background: {{.Fill}}"{{end}}>{{.Text}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type htmlCell struct {
+	Text  string
+	Class string
+	Fill  template.CSS
+}
+
+// writeHTML renders the listing as a standalone page that prints like the
+// PDF export.
+func (l *Listing) writeHTML(w io.Writer) error {
+	rows := make([][]htmlCell, len(l.Rows))
+	for i, row := range l.Rows {
+		rows[i] = make([]htmlCell, len(row))
+		for j, cell := range row {
+			c := htmlCell{Text: cell.Text}
+			if t := l.Columns[j].Type; t == ColumnMoney || t == ColumnInt {
+				c.Class = "num"
+			}
+			if hexColor.MatchString(cell.Fill) {
+				c.Fill = template.CSS(cell.Fill)
+			}
+			rows[i][j] = c
+		}
+	}
+	err := htmlTemplate.Execute(w, map[string]any{
+		"Title":   l.Title,
+		"Filters": l.Filters,
+		"Total":   l.Total,
+		"Columns": l.Columns,
+		"Rows":    rows,
+	})
+	if err != nil {
+		return fmt.Errorf("write html: %w", err)
+	}
+	return nil
+}
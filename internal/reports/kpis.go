@@ -0,0 +1,225 @@
+// Package reports computes the dashboard KPIs ported from dashboard.js
+// (00_Dashboard cards and the 100_Metrics_View history block).
+package reports
+
+import (
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/example/vvsapp/internal/config"
+)
+
+// KPI keys, in card order.
+const (
+	KPINewLeads         = "newLeads"
+	KPIHotLeads         = "hotLeads"
+	KPIDepositsTaken    = "depositsTaken"
+	KPIInProduction     = "inProduction"
+	KPIShipped          = "shipped"
+	KPIPaymentsReceived = "paymentsReceived"
+	KPIWeightedPipeline = "weightedPipeline"
+)
+
+// Value formats for the UI.
+const (
+	FormatCount = "count"
+	FormatMoney = "money"
+)
+
+type definition struct {
+	key    string
+	label  string
+	format string
+	// pointInTime KPIs describe the current state rather than activity in
+	// the window, so their previous value comes from kpi_history.
+	pointInTime bool
+	// link builds the filtered module view the card opens.
+	link func(w Window, brand, rep string) string
+}
+
+var definitions = []definition{
+	{key: KPINewLeads, label: "New Leads", format: FormatCount, link: windowLink("/customers", nil)},
+	{key: KPIHotLeads, label: "Hot Leads", format: FormatCount, link: windowLink("/customers", url.Values{"stage": {"Hot Lead"}})},
+	{key: KPIDepositsTaken, label: "Deposits Taken", format: FormatCount, link: windowLink("/payments", url.Values{"docKind": {"receipt"}})},
+	{key: KPIInProduction, label: "In Production", format: FormatCount, pointInTime: true, link: stateLink("/orders", url.Values{"status": {"in_production"}})},
+	{key: KPIShipped, label: "Shipped", format: FormatCount, link: windowLink("/orders", url.Values{"status": {"shipped"}})},
+	{key: KPIPaymentsReceived, label: "Payments Received", format: FormatMoney, link: windowLink("/payments", url.Values{"docKind": {"receipt"}})},
+	{key: KPIWeightedPipeline, label: "Weighted Pipeline", format: FormatMoney, pointInTime: true, link: stateLink("/reports", nil)},
+}
+
+func windowLink(path string, base url.Values) func(Window, string, string) string {
+	return func(w Window, brand, rep string) string {
+		q := scopeValues(base, brand, rep)
+		q.Set("from", w.Start)
+		q.Set("to", w.End)
+		return path + "?" + q.Encode()
+	}
+}
+
+func stateLink(path string, base url.Values) func(Window, string, string) string {
+	return func(_ Window, brand, rep string) string {
+		q := scopeValues(base, brand, rep)
+		if len(q) == 0 {
+			return path
+		}
+		return path + "?" + q.Encode()
+	}
+}
+
+func scopeValues(base url.Values, brand, rep string) url.Values {
+	q := url.Values{}
+	for k, v := range base {
+		q[k] = append([]string(nil), v...)
+	}
+	if brand != "" {
+		q.Set("brand", brand)
+	}
+	if rep != "" {
+		q.Set("rep", rep)
+	}
+	return q
+}
+
+// root is one root appointment as the KPIs see it: visit range from all its
+// rows, stage and order status from its latest visit.
+type root struct {
+	id                string
+	firstVisit        string
+	lastVisit         string
+	salesStage        string
+	customOrderStatus string
+	// statusDate is the date the latest row was last edited, the closest the
+	// Master gets to "when did the order status change".
+	statusDate   string
+	orderTotal   int64
+	firstDeposit string
+}
+
+// receipt is one non-void receipt in the ledger; money is in cents.
+type receipt struct {
+	rootApptID string
+	date       string
+	net        int64
+}
+
+// dataset is everything one KPI computation needs for a brand/rep scope.
+type dataset struct {
+	roots    []root
+	receipts []receipt
+}
+
+// calculator evaluates KPIs under the configured weights and statuses.
+type calculator struct {
+	weights         map[string]float64
+	production      map[string]bool
+	shipped         map[string]bool
+	minFirstDeposit int64
+	activeDays      int
+}
+
+func newCalculator(cfg config.ReportsConfig) *calculator {
+	c := &calculator{
+		weights:         map[string]float64{},
+		production:      foldSet(cfg.ProductionStatuses),
+		shipped:         foldSet(cfg.ShippedStatuses),
+		minFirstDeposit: int64(math.Round(cfg.MinFirstDeposit * 100)),
+		activeDays:      cfg.ActiveDays,
+	}
+	for stage, w := range cfg.StageWeights {
+		c.weights[strings.ToLower(strings.TrimSpace(stage))] = w
+	}
+	return c
+}
+
+// compute returns every windowed KPI for w plus the point-in-time KPIs as of w.End.
+func (c *calculator) compute(d *dataset, w Window) map[string]float64 {
+	inWindow := func(date string) bool { return date != "" && date >= w.Start && date <= w.End }
+	activeSince := addDays(w.End, -c.activeDays)
+
+	out := make(map[string]float64, len(definitions))
+	for _, def := range definitions {
+		out[def.key] = 0
+	}
+	var pipelineCents float64
+	for _, r := range d.roots {
+		if inWindow(r.firstVisit) {
+			out[KPINewLeads]++
+		}
+		if stageKey(r.salesStage) == "hot lead" && inWindow(r.lastVisit) {
+			out[KPIHotLeads]++
+		}
+		if inWindow(r.firstDeposit) {
+			out[KPIDepositsTaken]++
+		}
+		status := statusKey(r.customOrderStatus)
+		if c.production[status] {
+			out[KPIInProduction]++
+		}
+		if c.shipped[status] && inWindow(r.statusDate) {
+			out[KPIShipped]++
+		}
+		if r.lastVisit >= activeSince && r.lastVisit <= w.End {
+			pipelineCents += c.weight(r.salesStage) * float64(r.orderTotal)
+		}
+	}
+	var received int64
+	for _, p := range d.receipts {
+		if inWindow(p.date) {
+			received += p.net
+		}
+	}
+	out[KPIPaymentsReceived] = float64(received) / 100
+	out[KPIWeightedPipeline] = math.Round(pipelineCents) / 100
+	return out
+}
+
+// weight looks a stage up verbatim first and then through the simplified
+// taxonomy of normalizeStage_, so "Diamond Viewing (2nd)" still scores.
+func (c *calculator) weight(stage string) float64 {
+	if w, ok := c.weights[strings.ToLower(strings.TrimSpace(stage))]; ok {
+		return w
+	}
+	return c.weights[stageKey(stage)]
+}
+
+// stageKey collapses Sales Stage spellings onto the stage weight names.
+func stageKey(stage string) string {
+	s := strings.ToLower(strings.TrimSpace(stage))
+	switch {
+	case s == "":
+		return ""
+	case strings.Contains(s, "lost") || s == "won":
+		return s
+	case strings.Contains(s, "hot"):
+		return "hot lead"
+	case strings.Contains(s, "deposit"):
+		return "deposit"
+	case strings.Contains(s, "order") && strings.Contains(s, "complet"):
+		return "order completed"
+	case strings.Contains(s, "diamond"):
+		return "diamond viewing"
+	case strings.Contains(s, "consult"):
+		return "consult"
+	case strings.Contains(s, "appoint"):
+		return "appointment"
+	case strings.Contains(s, "lead"):
+		return "lead"
+	default:
+		return s
+	}
+}
+
+// statusKey folds case and the en dash the Master uses in
+// "Final Photos – Waiting Approval".
+func statusKey(status string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(status)), "–", "-")
+}
+
+func foldSet(values []string) map[string]bool {
+	out := make(map[string]bool, len(values))
+	for _, v := range values {
+		out[statusKey(v)] = true
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package reports
+
+import (
+	"testing"
+
+	"github.com/example/vvsapp/internal/config"
+)
+
+func testCalculator() *calculator {
+	return newCalculator(config.ReportsConfig{
+		StageWeights:       map[string]float64{"Lead": 0.1, " Hot Lead ": 0.2, "Diamond Viewing": 0.5, "Deposit": 0.9},
+		ProductionStatuses: []string{"In Production", "Final Photos - Waiting Approval"},
+		ShippedStatuses:    []string{"Ship to Customer"},
+		MinFirstDeposit:    25,
+		ActiveDays:         30,
+	})
+}
+
+func TestCompute(t *testing.T) {
+	w := Window{Start: "2024-05-01", End: "2024-05-07"}
+	d := &dataset{
+		roots: []root{
+			// New this week and hot.
+			{id: "R1", firstVisit: "2024-05-02", lastVisit: "2024-05-03", salesStage: "Hot Lead", orderTotal: 100000},
+			// Older client, deposit this week, visited within the active window.
+			{id: "R2", firstVisit: "2024-03-01", lastVisit: "2024-04-20", salesStage: "Deposit Paid",
+				firstDeposit: "2024-05-05", orderTotal: 200000},
+			// Hot but not visited this week; too old for the pipeline.
+			{id: "R3", firstVisit: "2024-01-01", lastVisit: "2024-03-01", salesStage: "hot lead", orderTotal: 500000},
+			// In production (en dash spelling), shipped earlier.
+			{id: "R4", firstVisit: "2024-02-01", lastVisit: "2024-04-30", salesStage: "Diamond Viewing (2nd)",
+				customOrderStatus: "Final Photos – Waiting Approval", statusDate: "2024-05-03", orderTotal: 300000},
+			// Shipped this week; stage has no weight.
+			{id: "R5", firstVisit: "2024-02-01", lastVisit: "2024-05-01", salesStage: "Order Completed",
+				customOrderStatus: " ship to customer ", statusDate: "2024-05-06", orderTotal: 400000},
+			// Shipped before the window; visited after it.
+			{id: "R6", firstVisit: "2024-05-08", lastVisit: "2024-05-08", salesStage: "Lead",
+				customOrderStatus: "Ship to Customer", statusDate: "2024-04-01", orderTotal: 900000},
+		},
+		receipts: []receipt{
+			{rootApptID: "R2", date: "2024-05-05", net: 50000},
+			{rootApptID: "R2", date: "2024-05-07", net: -1000},
+			{rootApptID: "R4", date: "2024-04-30", net: 70000},
+			{rootApptID: "R5", date: "2024-05-08", net: 80000},
+		},
+	}
+
+	got := testCalculator().compute(d, w)
+	want := map[string]float64{
+		KPINewLeads:         1,
+		KPIHotLeads:         1,
+		KPIDepositsTaken:    1,
+		KPIInProduction:     1,
+		KPIShipped:          1,
+		KPIPaymentsReceived: 490,
+		// 0.2 × 1000 + 0.9 × 2000 + 0.5 × 3000 + 0 × 4000
+		KPIWeightedPipeline: 3500,
+	}
+	if len(got) != len(definitions) {
+		t.Errorf("compute returned %d KPIs, want %d", len(got), len(definitions))
+	}
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("%s = %v, want %v", key, got[key], v)
+		}
+	}
+
+	empty := testCalculator().compute(&dataset{}, w)
+	for _, def := range definitions {
+		if v, ok := empty[def.key]; !ok || v != 0 {
+			t.Errorf("empty %s = %v, %v; want 0", def.key, v, ok)
+		}
+	}
+}
+
+func TestStageKey(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		" Hot Lead ":            "hot lead",
+		"Deposit Paid":          "deposit",
+		"Order Completed":       "order completed",
+		"Diamond Viewing (2nd)": "diamond viewing",
+		"Consultation":          "consult",
+		"Appointment Booked":    "appointment",
+		"New Lead":              "lead",
+		"Lost - Price":          "lost - price",
+		"Won":                   "won",
+		"Something Else":        "something else",
+	}
+	for in, want := range tests {
+		if got := stageKey(in); got != want {
+			t.Errorf("stageKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWeight(t *testing.T) {
+	c := testCalculator()
+	tests := map[string]float64{
+		"Hot Lead":              0.2,
+		"diamond viewing":       0.5,
+		"Diamond Viewing (3)":   0.5,
+		"Deposit Paid":          0.9,
+		"Lost - Went Elsewhere": 0,
+		"":                      0,
+	}
+	for stage, want := range tests {
+		if got := c.weight(stage); got != want {
+			t.Errorf("weight(%q) = %v, want %v", stage, got, want)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// Listing kinds, after the two report_server.js dialogs.
+const (
+	KindStatus = "status"
+	KindRep    = "rep"
+)
+
+// Column types tell the exporters how to render a cell.
+const (
+	ColumnText  = "text"
+	ColumnDate  = "date"
+	ColumnMoney = "money"
+	ColumnInt   = "int"
+)
+
+// Cell fills, as in report_applyExportFormatting_.
+const (
+	FillBlankRep = "#fff9c4"
+	FillStale    = "#ffa726"
+)
+
+const (
+	// previewLimit caps the rows returned as JSON; exports carry them all.
+	previewLimit = 1000
+	// largeListing is where the dialogs warned the user to narrow filters.
+	largeListing = 10000
+	// staleDays flags Days Since Last Update in orange.
+	staleDays = 3
+)
+
+// ListQuery holds the By Status / By Rep dialog selections. Each list is an
+// OR within itself; lists combine with AND. Assigned and Assisted only apply
+// to KindRep and match when either does.
+type ListQuery struct {
+	Kind              string
+	Brand             string
+	SalesStage        []string
+	ConversionStatus  []string
+	CustomOrderStatus []string
+	CenterStoneStatus []string
+	Assigned          []string
+	Assisted          []string
+	// RepLabel overrides the rep prefix of the export title (cosmetic only).
+	RepLabel string
+	// GeneratedBy is shown in the title as "(gen. by ...)".
+	GeneratedBy string
+}
+
+// Column is one output column.
+type Column struct {
+	Label string `json:"label"`
+	Type  string `json:"type"`
+}
+
+// Cell is one rendered value. Num is set for money (dollars) and int columns
+// so spreadsheets keep them numeric.
+type Cell struct {
+	Text string   `json:"text"`
+	Num  *float64 `json:"num,omitempty"`
+	Fill string   `json:"fill,omitempty"`
+}
+
+// Summary counts the result rows the way report_buildSummary_ does.
+type Summary struct {
+	TotalRows int                       `json:"totalRows"`
+	Groups    map[string]map[string]int `json:"groups"`
+}
+
+// Listing is a shaped By Status or By Rep report.
+type Listing struct {
+	Kind        string    `json:"kind"`
+	Title       string    `json:"title"`
+	Filters     string    `json:"filters"`
+	Columns     []Column  `json:"columns"`
+	Rows        [][]Cell  `json:"rows"`
+	Total       int       `json:"total"`
+	Truncated   bool      `json:"truncated"`
+	Summary     Summary   `json:"summary"`
+	Warn        string    `json:"warn,omitempty"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// listingRow is the latest appointment row of one root plus its balance.
+type listingRow struct {
+	apptID, rootApptID, customerName   string
+	assignedRep, assistedRep           string
+	brand, soNumber, visitDate         string
+	salesStage, conversionStatus       string
+	customOrderStatus, centerStone     string
+	nextSteps, updatedAt, lastEditedBy string
+	orderTotal, paidToDate             int64
+}
+
+// List runs a By Status or By Rep report: the latest row per root
+// appointment, filtered, sorted by Visit Date (blanks last) and shaped into
+// the dialog's columns. Order Total and Total Pay To Date are left out of a
+// status report for Sales Stage "Appointment" alone (Booked Appointment).
+func (s *Service) List(ctx context.Context, q ListQuery) (*Listing, error) {
+	q.Kind = strings.ToLower(strings.TrimSpace(q.Kind))
+	if q.Kind != KindStatus && q.Kind != KindRep {
+		return nil, &ValidationError{Field: "kind", Message: "must be status or rep"}
+	}
+	if q.Kind == KindStatus {
+		q.Assigned, q.Assisted = nil, nil
+	}
+
+	latest, err := s.latestRows(ctx, strings.TrimSpace(q.Brand))
+	if err != nil {
+		return nil, err
+	}
+	stages, convs := foldSet(q.SalesStage), foldSet(q.ConversionStatus)
+	customs, centers := foldSet(q.CustomOrderStatus), foldSet(q.CenterStoneStatus)
+	assigned, assisted := repSet(q.Assigned), repSet(q.Assisted)
+
+	var out []listingRow
+	for _, r := range latest {
+		if !inSet(stages, r.salesStage) || !inSet(convs, r.conversionStatus) ||
+			!inSet(customs, r.customOrderStatus) || !inSet(centers, r.centerStone) {
+			continue
+		}
+		if len(assigned)+len(assisted) > 0 && !anyRep(assigned, r.assignedRep) && !anyRep(assisted, r.assistedRep) {
+			continue
+		}
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].visitDate, out[j].visitDate
+		if a == "" || b == "" {
+			return a != "" && b == ""
+		}
+		return a < b
+	})
+
+	names, err := s.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	omitPay := q.Kind == KindStatus && len(q.SalesStage) == 1 && stageKey(q.SalesStage[0]) == "appointment"
+	now := s.now()
+	today := now.In(s.loc).Format(dateLayout)
+
+	l := &Listing{
+		Kind:        q.Kind,
+		Columns:     listingColumns(omitPay),
+		Rows:        make([][]Cell, 0, len(out)),
+		Total:       len(out),
+		Summary:     summarize(out),
+		Filters:     filtersSummary(q),
+		GeneratedAt: now.UTC(),
+	}
+	for _, r := range out {
+		l.Rows = append(l.Rows, s.shapeRow(r, omitPay, today, names))
+	}
+	l.Title = exportTitle(q, l, now.In(s.loc))
+	if len(out) > largeListing {
+		l.Warn = fmt.Sprintf("Large result (>%d rows). Consider narrowing filters.", largeListing)
+	}
+	return l, nil
+}
+
+// Preview trims l to the rows the dialogs display.
+func (l *Listing) Preview() {
+	if len(l.Rows) > previewLimit {
+		l.Rows = l.Rows[:previewLimit]
+		l.Truncated = true
+	}
+}
+
+func listingColumns(omitPay bool) []Column {
+	cols := []Column{
+		{"APPT_ID", ColumnText}, {"Customer Name", ColumnText}, {"Assigned Rep", ColumnText},
+		{"Assisted Rep", ColumnText}, {"Brand", ColumnText}, {"SO#", ColumnText}, {"Visit Date", ColumnDate},
+	}
+	if !omitPay {
+		cols = append(cols, Column{"Order Total", ColumnMoney}, Column{"Total Pay To Date", ColumnMoney})
+	}
+	return append(cols,
+		Column{"Sales Stage", ColumnText}, Column{"Conversion Status", ColumnText},
+		Column{"Custom Order Status", ColumnText}, Column{"Center Stone Order Status", ColumnText},
+		Column{"Next Steps", ColumnText}, Column{"Days Since Last Update", ColumnInt},
+	)
+}
+
+// shapeRow renders r in listingColumns order. The Assisted Rep cell carries
+// the last editor in parentheses, as the sheet report did.
+func (s *Service) shapeRow(r listingRow, omitPay bool, today string, names map[string]string) []Cell {
+	assisted := r.assistedRep
+	if strings.TrimSpace(assisted) != "" && r.lastEditedBy != "" {
+		editor := strings.ToLower(strings.TrimSpace(r.lastEditedBy))
+		if name := names[editor]; name != "" {
+			editor = name
+		}
+		assisted += " (" + editor + ")"
+	}
+	row := []Cell{
+		{Text: r.apptID}, {Text: r.customerName}, repCell(r.assignedRep), repCell(assisted),
+		{Text: r.brand}, {Text: r.soNumber}, {Text: r.visitDate},
+	}
+	if !omitPay {
+		row = append(row, moneyCell(r.orderTotal), moneyCell(r.paidToDate))
+	}
+	row = append(row,
+		Cell{Text: r.salesStage}, Cell{Text: r.conversionStatus},
+		Cell{Text: r.customOrderStatus}, Cell{Text: r.centerStone},
+		Cell{Text: r.nextSteps}, s.daysCell(r.updatedAt, today),
+	)
+	return row
+}
+
+func repCell(v string) Cell {
+	if strings.TrimSpace(v) == "" {
+		return Cell{Fill: FillBlankRep}
+	}
+	return Cell{Text: v}
+}
+
+func moneyCell(cents int64) Cell {
+	dollars := float64(cents) / 100
+	return Cell{Text: formatDollars(cents), Num: &dollars}
+}
+
+// daysCell counts whole days from the row's last update to today.
+func (s *Service) daysCell(updatedAt, today string) Cell {
+	date := s.localDate(updatedAt)
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return Cell{}
+	}
+	days := daysBetween(date, today)
+	if days < 0 {
+		days = 0
+	}
+	n := float64(days)
+	c := Cell{Text: fmt.Sprint(days), Num: &n}
+	if days >= staleDays {
+		c.Fill = FillStale
+	}
+	return c
+}
+
+// formatDollars renders cents as $#,##0.
+func formatDollars(cents int64) string {
+	n := int64(math.Round(float64(cents) / 100))
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	digits := fmt.Sprint(n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "$" + b.String()
+}
+
+// latestRows loads the most recent visit of every root appointment.
+func (s *Service) latestRows(ctx context.Context, brand string) ([]listingRow, error) {
+	query := `SELECT a.appt_id, a.root_appt_id, a.customer_name, a.assigned_rep, a.assisted_rep, a.brand,
+        a.so_number, a.visit_date, a.sales_stage, a.conversion_status, a.custom_order_status,
+        a.center_stone_order_status, a.next_steps, a.updated_at,
+        COALESCE(b.order_total_cents, 0), COALESCE(b.paid_to_date_cents, 0),
+        COALESCE((SELECT l.actor FROM activity_log l WHERE l.root_appt_id = a.root_appt_id ORDER BY l.id DESC LIMIT 1), '')
+        FROM appointments a
+        LEFT JOIN root_payment_balances b ON b.root_appt_id = a.root_appt_id
+        WHERE a.appt_id = (SELECT x.appt_id FROM appointments x WHERE x.root_appt_id = a.root_appt_id
+            ORDER BY x.visit_date DESC, x.visit_time DESC, x.appt_id DESC LIMIT 1)`
+	var args []any
+	if brand != "" {
+		query += ` AND LOWER(a.brand) = LOWER(?)`
+		args = append(args, brand)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select report rows: %w", err)
+	}
+	defer rows.Close()
+
+	var out []listingRow
+	for rows.Next() {
+		var r listingRow
+		if err := rows.Scan(&r.apptID, &r.rootApptID, &r.customerName, &r.assignedRep, &r.assistedRep, &r.brand,
+			&r.soNumber, &r.visitDate, &r.salesStage, &r.conversionStatus, &r.customOrderStatus,
+			&r.centerStone, &r.nextSteps, &r.updatedAt, &r.orderTotal, &r.paidToDate, &r.lastEditedBy); err != nil {
+			return nil, fmt.Errorf("scan report row: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate report rows: %w", err)
+	}
+	return out, nil
+}
+
+// userNames maps login emails to display names for the last-editor suffix.
+func (s *Service) userNames(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT LOWER(email), COALESCE(NULLIF(display_name, ''), NULLIF(rep_name, ''), '') FROM users`)
+	if err != nil {
+		return nil, fmt.Errorf("select user names: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var email, name string
+		if err := rows.Scan(&email, &name); err != nil {
+			return nil, fmt.Errorf("scan user name: %w", err)
+		}
+		out[email] = name
+	}
+	return out, rows.Err()
+}
+
+func inSet(set map[string]bool, v string) bool {
+	return len(set) == 0 || set[statusKey(v)]
+}
+
+func repSet(names []string) map[string]bool {
+	out := map[string]bool{}
+	for _, n := range names {
+		if n = strings.ToLower(strings.TrimSpace(n)); n != "" {
+			out[n] = true
+		}
+	}
+	return out
+}
+
+func anyRep(set map[string]bool, cell string) bool {
+	for _, name := range reps.ParseNames(cell) {
+		if set[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+func summarize(rows []listingRow) Summary {
+	groups := map[string]map[string]int{
+		"rep": {}, "salesStage": {}, "conversionStatus": {}, "customOrderStatus": {}, "centerStoneStatus": {},
+	}
+	count := func(group, v string) {
+		if v = strings.TrimSpace(v); v == "" {
+			v = "(blank)"
+		}
+		groups[group][v]++
+	}
+	for _, r := range rows {
+		count("salesStage", r.salesStage)
+		count("conversionStatus", r.conversionStatus)
+		count("customOrderStatus", r.customOrderStatus)
+		count("centerStoneStatus", r.centerStone)
+		seen := map[string]bool{}
+		for _, name := range append(reps.ParseNames(r.assignedRep), reps.ParseNames(r.assistedRep)...) {
+			if !seen[name] {
+				seen[name] = true
+				count("rep", name)
+			}
+		}
+		if len(seen) == 0 {
+			count("rep", "")
+		}
+	}
+	return Summary{TotalRows: len(rows), Groups: groups}
+}
+
+// filtersSummary is the meta line printed above the exported table.
+func filtersSummary(q ListQuery) string {
+	list := func(v []string) string {
+		if len(v) == 0 {
+			return "All"
+		}
+		return strings.Join(v, ", ")
+	}
+	parts := []string{}
+	if q.Kind == KindRep {
+		parts = append(parts, "Assigned: "+list(q.Assigned), "Assisted: "+list(q.Assisted))
+	}
+	parts = append(parts,
+		"Sales Stage: "+list(q.SalesStage),
+		"Conversion: "+list(q.ConversionStatus),
+		"Custom Order: "+list(q.CustomOrderStatus),
+		"Center Stone: "+list(q.CenterStoneStatus),
+	)
+	if q.Brand != "" {
+		parts = append(parts, "Brand: "+q.Brand)
+	}
+	return strings.Join(parts, " • ")
+}
+
+// exportTitle follows report_buildFriendlyExportName_: the chosen (or most
+// common non-blank) status, a rep prefix for the statuses reps hand to customers, the
+// local time and who generated it.
+func exportTitle(q ListQuery, l *Listing, now time.Time) string {
+	status := ""
+	if len(q.ConversionStatus) == 1 {
+		status = strings.TrimSpace(q.ConversionStatus[0])
+	}
+	if status == "" && len(l.Rows) > 0 {
+		status = mostCommon(l, "Conversion Status")
+	}
+	if status == "" && len(q.SalesStage) == 1 {
+		status = strings.TrimSpace(q.SalesStage[0])
+	}
+	if status == "" && len(q.CustomOrderStatus) == 1 {
+		status = strings.TrimSpace(q.CustomOrderStatus[0])
+	}
+	if status == "" {
+		status = "All"
+	}
+	if status == "Appointment" {
+		status = "Booked Appointment"
+	}
+
+	prefix := strings.TrimSpace(q.RepLabel)
+	switch strings.ToLower(status) {
+	case "deposit paid", "in production", "viewing scheduled":
+		if prefix == "" && len(q.Assigned) == 1 {
+			prefix = strings.TrimSpace(q.Assigned[0])
+		}
+		if prefix == "" {
+			prefix = soleValue(l, "Assigned Rep")
+		}
+	}
+	title := status + " - " + now.Format("2006-01-02 3:04PM")
+	if prefix != "" {
+		title = prefix + ": " + title
+	}
+	if by := strings.TrimSpace(q.GeneratedBy); by != "" {
+		title += " (gen. by " + by + ")"
+	}
+	if r := []rune(title); len(r) > 100 {
+		title = string(r[:100])
+	}
+	return title
+}
+
+func (l *Listing) column(label string) int {
+	for i, c := range l.Columns {
+		if c.Label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+func mostCommon(l *Listing, label string) string {
+	col := l.column(label)
+	if col < 0 {
+		return ""
+	}
+	counts := map[string]int{}
+	for _, row := range l.Rows {
+		if v := strings.TrimSpace(row[col].Text); v != "" {
+			counts[v]++
+		}
+	}
+	best := ""
+	for v, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && v < best) {
+			best = v
+		}
+	}
+	return best
+}
+
+func soleValue(l *Listing, label string) string {
+	col := l.column(label)
+	if col < 0 {
+		return ""
+	}
+	only := ""
+	for _, row := range l.Rows {
+		v := strings.TrimSpace(row[col].Text)
+		switch {
+		case v == "":
+		case only == "":
+			only = v
+		case v != only:
+			return ""
+		}
+	}
+	return only
+}
@@ -0,0 +1,370 @@
+package reports
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF layout in points: Letter landscape with 12mm margins, like the
+// @page rule of report_buildReportHtml_.
+const (
+	pdfPageW     = 792.0
+	pdfPageH     = 612.0
+	pdfMargin    = 34.0
+	pdfFontSize  = 7.0
+	pdfLineH     = 8.6
+	pdfPad       = 3.0
+	pdfMaxLines  = 10
+	pdfMinColW   = 28.0
+	pdfMaxColW   = 150.0
+	pdfMaxWordW  = 80.0
+	pdfTitleSize = 12.0
+	pdfFooterY   = 18.0
+)
+
+// Standard Helvetica and Helvetica-Bold advance widths for ' '..'~' (1/1000 em).
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// winAnsiExtra maps the cp1252 characters outside Latin-1 that show up in
+// Master text (smart quotes, dashes, bullets).
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfText measures and encodes text for the built-in fonts.
+type pdfText struct{ bold bool }
+
+func (t pdfText) width(s string, size float64) float64 {
+	table := &helveticaWidths
+	if t.bold {
+		table = &helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			total += table[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfEscape encodes s as the body of a WinAnsi literal string.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		var c byte
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			c = byte(r)
+		case r >= ' ' && r <= '~':
+			c = byte(r)
+		case r >= 0xA0 && r <= 0xFF:
+			c = byte(r)
+		default:
+			var ok bool
+			if c, ok = winAnsiExtra[r]; !ok {
+				c = '?'
+			}
+		}
+		if c >= 0x80 {
+			fmt.Fprintf(&b, "\\%03o", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// wrap breaks s into lines no wider than width, splitting words that do not
+// fit on their own, and ellipsizes past max lines.
+func (t pdfText) wrap(s string, width, size float64, max int) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r", ""), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if t.width(candidate, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			for t.width(word, size) > width {
+				cut := 1
+				for cut < len([]rune(word)) && t.width(string([]rune(word)[:cut+1]), size) <= width {
+					cut++
+				}
+				lines = append(lines, string([]rune(word)[:cut]))
+				word = string([]rune(word)[cut:])
+			}
+			line = word
+		}
+		if line != "" || len(lines) == 0 {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > max {
+		lines = lines[:max]
+		lines[max-1] = strings.TrimRight(lines[max-1], " ") + "…"
+	}
+	return lines
+}
+
+// pdfColor turns "#rrggbb" into PDF rgb operands.
+func pdfColor(hex string) string {
+	var r, g, b int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(hex, "#"), "%02x%02x%02x", &r, &g, &b); err != nil {
+		return "1 1 1"
+	}
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// writePDF renders the listing as a paginated table using the standard
+// Helvetica fonts, so nothing needs embedding or an external converter.
+func (l *Listing) writePDF(w io.Writer) error {
+	regular, bold := pdfText{}, pdfText{bold: true}
+	tableW := pdfPageW - 2*pdfMargin
+	widths := l.pdfColumnWidths(tableW)
+
+	header := make([][]string, len(l.Columns))
+	headerLines := 1
+	for i, c := range l.Columns {
+		header[i] = bold.wrap(c.Label, widths[i]-2*pdfPad, pdfFontSize, 4)
+		if len(header[i]) > headerLines {
+			headerLines = len(header[i])
+		}
+	}
+	headerH := float64(headerLines)*pdfLineH + 2*pdfPad
+
+	var pages []*bytes.Buffer
+	var page *bytes.Buffer
+	y := 0.0
+	startPage := func() {
+		page = &bytes.Buffer{}
+		pages = append(pages, page)
+		y = pdfPageH - pdfMargin
+		if len(pages) == 1 {
+			y -= pdfTitleSize
+			pdfString(page, true, pdfTitleSize, pdfMargin, y, l.Title)
+			y -= 6
+			meta := fmt.Sprintf("Rows: %d", l.Total)
+			if l.Filters != "" {
+				meta = l.Filters + " • " + meta
+			}
+			for _, line := range regular.wrap(meta, tableW, pdfFontSize+1, 3) {
+				y -= pdfLineH + 1
+				fmt.Fprintf(page, "0.333 0.333 0.333 rg\n")
+				pdfString(page, false, pdfFontSize+1, pdfMargin, y, line)
+			}
+			y -= 8
+		}
+		l.pdfRow(page, widths, header, nil, y, headerH, true)
+		y -= headerH
+	}
+	startPage()
+
+	for _, row := range l.Rows {
+		cells := make([][]string, len(row))
+		lines := 1
+		for i, cell := range row {
+			cells[i] = regular.wrap(cell.Text, widths[i]-2*pdfPad, pdfFontSize, pdfMaxLines)
+			if len(cells[i]) > lines {
+				lines = len(cells[i])
+			}
+		}
+		h := float64(lines)*pdfLineH + 2*pdfPad
+		if y-h < pdfMargin {
+			startPage()
+		}
+		l.pdfRow(page, widths, cells, row, y, h, false)
+		y -= h
+	}
+
+	for i, p := range pages {
+		fmt.Fprintf(p, "0.4 0.4 0.4 rg\n")
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		pdfString(p, false, pdfFontSize, pdfPageW-pdfMargin-regular.width(footer, pdfFontSize), pdfFooterY, footer)
+	}
+	return writePDFDocument(w, l.Title, pages)
+}
+
+// pdfColumnWidths sizes columns to their content (capped at pdfMaxColW) and
+// fits them to the table width, keeping each wide enough for its longest
+// word so dates and amounts do not break mid-value.
+func (l *Listing) pdfColumnWidths(tableW float64) []float64 {
+	regular, bold := pdfText{}, pdfText{bold: true}
+	natural := make([]float64, len(l.Columns))
+	minimum := make([]float64, len(l.Columns))
+	var sumNatural, sumMin float64
+	for i, c := range l.Columns {
+		minimum[i] = pdfMinColW
+		for _, word := range strings.Fields(c.Label) {
+			if w := bold.width(word, pdfFontSize) + 2*pdfPad; w > minimum[i] {
+				minimum[i] = w
+			}
+		}
+		w := minimum[i]
+		for _, row := range l.Rows {
+			if cw := regular.width(row[i].Text, pdfFontSize) + 2*pdfPad; cw > w {
+				w = cw
+			}
+			for _, word := range strings.Fields(row[i].Text) {
+				ww := regular.width(word, pdfFontSize) + 2*pdfPad
+				if ww > pdfMaxWordW {
+					ww = pdfMaxWordW
+				}
+				if ww > minimum[i] {
+					minimum[i] = ww
+				}
+			}
+		}
+		maxW := pdfMaxColW
+		if c.Label == "Next Steps" {
+			maxW *= 1.5
+		}
+		if w > maxW && maxW > minimum[i] {
+			w = maxW
+		}
+		natural[i] = w
+		sumNatural += w
+		sumMin += minimum[i]
+	}
+
+	widths := make([]float64, len(l.Columns))
+	switch {
+	case sumNatural <= tableW:
+		for i := range widths {
+			widths[i] = natural[i] * tableW / sumNatural
+		}
+	case sumMin >= tableW:
+		for i := range widths {
+			widths[i] = minimum[i] * tableW / sumMin
+		}
+	default:
+		share := (tableW - sumMin) / (sumNatural - sumMin)
+		for i := range widths {
+			widths[i] = minimum[i] + (natural[i]-minimum[i])*share
+		}
+	}
+	return widths
+}
+
+// pdfRow draws one table row whose top edge is at y.
+func (l *Listing) pdfRow(page *bytes.Buffer, widths []float64, lines [][]string, cells []Cell, y, h float64, header bool) {
+	x := pdfMargin
+	for i, w := range widths {
+		fill := ""
+		switch {
+		case header:
+			fill = "#f4f4f4"
+		case cells != nil:
+			fill = cells[i].Fill
+		}
+		if fill != "" {
+			fmt.Fprintf(page, "%s rg %.2f %.2f %.2f %.2f re f\n", pdfColor(fill), x, y-h, w, h)
+		}
+		fmt.Fprintf(page, "0.067 0.067 0.067 rg\n")
+		for j, line := range lines[i] {
+			if line == "" {
+				continue
+			}
+			pdfString(page, header, pdfFontSize, x+pdfPad, y-pdfPad-float64(j+1)*pdfLineH+2, line)
+		}
+		fmt.Fprintf(page, "0.5 w 0.85 0.85 0.85 RG %.2f %.2f %.2f %.2f re S\n", x, y-h, w, h)
+		x += w
+	}
+}
+
+func pdfString(page *bytes.Buffer, bold bool, size, x, y float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+// writePDFDocument assembles the page content streams into a PDF 1.4 file.
+func writePDFDocument(w io.Writer, title string, pages []*bytes.Buffer) error {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	const firstPage = 5 // after catalog, pages, two fonts
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, p := range pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageW, pdfPageH, firstPage+2*i+1))
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(p.Bytes()); err != nil {
+			return fmt.Errorf("compress pdf page: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("compress pdf page: %w", err)
+		}
+		obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.String()))
+	}
+	obj(fmt.Sprintf("<< /Title %s /Producer (vvsapp) >>", pdfUTF16(title)))
+	info := len(offsets)
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfUTF16 encodes s as a UTF-16BE hex string for the document info.
+func pdfUTF16(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,480 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// maxWindowDays bounds a requested window; longer spans belong in an export.
+	maxWindowDays = 366
+	// historyPoints is how many snapshots feed each sparkline (TAKE(..., 12)).
+	historyPoints = 12
+	// snapshotDays is the trailing window recorded by Snapshot.
+	snapshotDays = 7
+)
+
+// ValidationError reports a query that cannot be answered.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Window is an inclusive range of local dates (YYYY-MM-DD).
+type Window struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Query selects the window and scope of a KPI report. Start and End accept a
+// date or an RFC 3339 timestamp; End defaults to today and Start to six days
+// before End.
+type Query struct {
+	Start string
+	End   string
+	Brand string
+	Rep   string // matches a name in Assigned Rep
+}
+
+// Point is one kpi_history sample.
+type Point struct {
+	AsOf  string  `json:"asOf"`
+	Value float64 `json:"value"`
+}
+
+// KPI is one dashboard card.
+type KPI struct {
+	Key    string  `json:"key"`
+	Label  string  `json:"label"`
+	Format string  `json:"format"`
+	Value  float64 `json:"value"`
+	// Previous is the value for the preceding window of equal length; nil when
+	// it is a point-in-time KPI with no snapshot for that date.
+	Previous *float64 `json:"previous"`
+	Delta    *float64 `json:"delta"`
+	DeltaPct *float64 `json:"deltaPct"`
+	// PointInTime KPIs count the current state and ignore the window start.
+	PointInTime bool    `json:"pointInTime"`
+	Link        string  `json:"link"`
+	History     []Point `json:"history"`
+}
+
+// Report is the GET /api/reports/kpis payload.
+type Report struct {
+	Window         Window `json:"window"`
+	PreviousWindow Window `json:"previousWindow"`
+	Brand          string `json:"brand"`
+	Rep            string `json:"rep"`
+	KPIs           []KPI  `json:"kpis"`
+	GeneratedAt    string `json:"generatedAt"`
+}
+
+// SnapshotResult summarizes one kpi_history pass.
+type SnapshotResult struct {
+	AsOf   string `json:"asOf"`
+	Scopes int    `json:"scopes"`
+	Rows   int    `json:"rows"`
+}
+
+// Service computes KPIs and maintains their history.
+type Service struct {
+	db     *sql.DB
+	calc   *calculator
+	colors config.StatusColors
+	reps   *reps.Service
+	loc    *time.Location
+	logger *logging.Logger
+	now    func() time.Time
+}
+
+// NewService constructs a reports service; dates are local to loc.
+func NewService(db *sql.DB, cfg config.ReportsConfig, loc *time.Location, repsSvc *reps.Service, logger *logging.Logger) *Service {
+	return &Service{db: db, calc: newCalculator(cfg), colors: cfg.StatusColors, reps: repsSvc, loc: loc, logger: logger, now: time.Now}
+}
+
+// KPIs computes the cards for q's window alongside the preceding window of
+// the same length and the recent history for sparklines.
+func (s *Service) KPIs(ctx context.Context, q Query) (*Report, error) {
+	win, err := s.window(q.Start, q.End)
+	if err != nil {
+		return nil, err
+	}
+	days := daysBetween(win.Start, win.End) + 1
+	prev := Window{Start: addDays(win.Start, -days), End: addDays(win.Start, -1)}
+	brand, rep := strings.TrimSpace(q.Brand), strings.TrimSpace(q.Rep)
+
+	data, err := s.load(ctx, brand, rep)
+	if err != nil {
+		return nil, err
+	}
+	cur := s.calc.compute(data, win)
+	before := s.calc.compute(data, prev)
+
+	history, err := s.history(ctx, brand, rep, win.End)
+	if err != nil {
+		return nil, err
+	}
+	snapshotPrev, err := s.snapshotAt(ctx, brand, rep, prev.End)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Window:         win,
+		PreviousWindow: prev,
+		Brand:          brand,
+		Rep:            rep,
+		KPIs:           make([]KPI, 0, len(definitions)),
+		GeneratedAt:    s.now().UTC().Format(time.RFC3339),
+	}
+	for _, def := range definitions {
+		k := KPI{
+			Key:         def.key,
+			Label:       def.label,
+			Format:      def.format,
+			Value:       cur[def.key],
+			PointInTime: def.pointInTime,
+			Link:        def.link(win, brand, rep),
+			History:     history[def.key],
+		}
+		if k.History == nil {
+			k.History = []Point{}
+		}
+		if def.pointInTime {
+			// The Master only holds today's stage and order status, so an
+			// earlier value can only come from a snapshot.
+			if v, ok := snapshotPrev[def.key]; ok {
+				k.Previous = &v
+			}
+		} else {
+			v := before[def.key]
+			k.Previous = &v
+		}
+		if k.Previous != nil {
+			delta := round2(k.Value - *k.Previous)
+			k.Delta = &delta
+			if *k.Previous != 0 {
+				pct := round4(delta / *k.Previous)
+				k.DeltaPct = &pct
+			}
+		}
+		report.KPIs = append(report.KPIs, k)
+	}
+	return report, nil
+}
+
+// Snapshot records the trailing seven-day KPIs ending today for the whole
+// business, each brand and each assigned rep, replacing any earlier snapshot
+// for the same day (snapshotKpisForHistory_).
+func (s *Service) Snapshot(ctx context.Context) (*SnapshotResult, error) {
+	end := s.now().In(s.loc).Format(dateLayout)
+	win := Window{Start: addDays(end, -(snapshotDays - 1)), End: end}
+
+	type scope struct{ brand, rep string }
+	scopes := []scope{{}}
+	brands, err := s.brands(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range brands {
+		scopes = append(scopes, scope{brand: b})
+	}
+	roster, err := s.reps.Roster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roster {
+		if r.AssignedRoots > 0 {
+			scopes = append(scopes, scope{rep: r.Name})
+		}
+	}
+
+	values := make([]map[string]float64, len(scopes))
+	for i, sc := range scopes {
+		data, err := s.load(ctx, sc.brand, sc.rep)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = s.calc.compute(data, win)
+	}
+
+	stamp := s.now().UTC().Format(time.RFC3339)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin kpi snapshot: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res := &SnapshotResult{AsOf: end, Scopes: len(scopes)}
+	for i, sc := range scopes {
+		for _, def := range definitions {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO kpi_history(as_of, brand, rep, kpi, value, window_start, window_end, created_at)
+                VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+                ON CONFLICT(brand, rep, kpi, as_of) DO UPDATE SET
+                    value = excluded.value, window_start = excluded.window_start,
+                    window_end = excluded.window_end, created_at = excluded.created_at`,
+				end, sc.brand, sc.rep, def.key, values[i][def.key], win.Start, win.End, stamp); err != nil {
+				return nil, fmt.Errorf("upsert kpi history: %w", err)
+			}
+			res.Rows++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit kpi snapshot: %w", err)
+	}
+	s.logger.Info("kpi_snapshot_complete", map[string]any{"as_of": res.AsOf, "scopes": res.Scopes, "rows": res.Rows})
+	return res, nil
+}
+
+// window resolves the requested range into local dates.
+func (s *Service) window(rawStart, rawEnd string) (Window, error) {
+	end := s.now().In(s.loc).Format(dateLayout)
+	if v := strings.TrimSpace(rawEnd); v != "" {
+		d, err := s.parseDate(v)
+		if err != nil {
+			return Window{}, &ValidationError{Field: "end", Message: "must be YYYY-MM-DD or an RFC 3339 timestamp"}
+		}
+		end = d
+	}
+	start := addDays(end, -(snapshotDays - 1))
+	if v := strings.TrimSpace(rawStart); v != "" {
+		d, err := s.parseDate(v)
+		if err != nil {
+			return Window{}, &ValidationError{Field: "start", Message: "must be YYYY-MM-DD or an RFC 3339 timestamp"}
+		}
+		start = d
+	}
+	if start > end {
+		return Window{}, &ValidationError{Field: "start", Message: "must not be after end"}
+	}
+	if daysBetween(start, end) >= maxWindowDays {
+		return Window{}, &ValidationError{Field: "start", Message: fmt.Sprintf("window must be at most %d days", maxWindowDays)}
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func (s *Service) parseDate(v string) (string, error) {
+	if t, err := time.ParseInLocation(dateLayout, v, s.loc); err == nil {
+		return t.Format(dateLayout), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return "", err
+	}
+	return t.In(s.loc).Format(dateLayout), nil
+}
+
+// load reads the roots and receipts in scope. Appointments are folded into
+// roots in visit order so the latest visit supplies the current stage.
+func (s *Service) load(ctx context.Context, brand, rep string) (*dataset, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if brand != "" {
+		where = append(where, "LOWER(a.brand) = LOWER(?)")
+		args = append(args, brand)
+	}
+	if rep != "" {
+		where = append(where, reps.MatchSQL("a.assigned_rep"))
+		args = append(args, reps.MatchArg(rep))
+	}
+	query := `SELECT a.root_appt_id, a.visit_date, a.sales_stage, a.custom_order_status, a.updated_at,
+        COALESCE(b.order_total_cents, 0)
+        FROM appointments a
+        LEFT JOIN root_payment_balances b ON b.root_appt_id = a.root_appt_id`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY a.root_appt_id, a.visit_date, a.visit_time, a.appt_id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select kpi appointments: %w", err)
+	}
+	defer rows.Close()
+
+	data := &dataset{}
+	index := map[string]int{}
+	for rows.Next() {
+		var (
+			id, visitDate, stage, status, updatedAt string
+			orderTotal                              int64
+		)
+		if err := rows.Scan(&id, &visitDate, &stage, &status, &updatedAt, &orderTotal); err != nil {
+			return nil, fmt.Errorf("scan kpi appointment: %w", err)
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(data.roots)
+			index[id] = i
+			data.roots = append(data.roots, root{id: id, firstVisit: visitDate, orderTotal: orderTotal})
+		}
+		r := &data.roots[i]
+		r.lastVisit = visitDate
+		r.salesStage = stage
+		r.customOrderStatus = status
+		r.statusDate = s.localDate(updatedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate kpi appointments: %w", err)
+	}
+	rows.Close()
+
+	receipts, err := s.db.QueryContext(ctx, `SELECT root_appt_id, COALESCE(NULLIF(payment_date_time, ''), submitted_at), amount_net_cents
+        FROM payments WHERE doc_kind = 'receipt' AND doc_status NOT IN ('VOID', 'REPLACED', 'DRAFT')
+        ORDER BY COALESCE(NULLIF(payment_date_time, ''), submitted_at), payment_id`)
+	if err != nil {
+		return nil, fmt.Errorf("select kpi receipts: %w", err)
+	}
+	defer receipts.Close()
+	for receipts.Next() {
+		var p receipt
+		var when string
+		if err := receipts.Scan(&p.rootApptID, &when, &p.net); err != nil {
+			return nil, fmt.Errorf("scan kpi receipt: %w", err)
+		}
+		i, ok := index[p.rootApptID]
+		if !ok {
+			continue
+		}
+		p.date = s.localDate(when)
+		data.receipts = append(data.receipts, p)
+		if r := &data.roots[i]; r.firstDeposit == "" && p.net >= s.calc.minFirstDeposit {
+			r.firstDeposit = p.date
+		}
+	}
+	if err := receipts.Err(); err != nil {
+		return nil, fmt.Errorf("iterate kpi receipts: %w", err)
+	}
+	return data, nil
+}
+
+// localDate turns a stored timestamp into a local date; values that are
+// already dates (or free-form) keep their first ten characters.
+func (s *Service) localDate(v string) string {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.In(s.loc).Format(dateLayout)
+	}
+	if len(v) > len(dateLayout) {
+		return v[:len(dateLayout)]
+	}
+	return v
+}
+
+func (s *Service) brands(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT brand FROM appointments WHERE brand <> '' ORDER BY brand`)
+	if err != nil {
+		return nil, fmt.Errorf("select brands: %w", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var b string
+		if err := rows.Scan(&b); err != nil {
+			return nil, fmt.Errorf("scan brand: %w", err)
+		}
+		out = append(out, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate brands: %w", err)
+	}
+	return out, nil
+}
+
+// history returns the last historyPoints snapshots up to end, oldest first, by KPI.
+func (s *Service) history(ctx context.Context, brand, rep, end string) (map[string][]Point, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kpi, as_of, value FROM kpi_history
+        WHERE LOWER(brand) = LOWER(?) AND LOWER(rep) = LOWER(?) AND as_of IN (
+            SELECT DISTINCT as_of FROM kpi_history
+            WHERE LOWER(brand) = LOWER(?) AND LOWER(rep) = LOWER(?) AND as_of <= ?
+            ORDER BY as_of DESC LIMIT ?)
+        ORDER BY as_of`, brand, rep, brand, rep, end, historyPoints)
+	if err != nil {
+		return nil, fmt.Errorf("select kpi history: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string][]Point{}
+	for rows.Next() {
+		var (
+			kpi string
+			p   Point
+		)
+		if err := rows.Scan(&kpi, &p.AsOf, &p.Value); err != nil {
+			return nil, fmt.Errorf("scan kpi history: %w", err)
+		}
+		out[kpi] = append(out[kpi], p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate kpi history: %w", err)
+	}
+	return out, nil
+}
+
+// snapshotAt returns the snapshot values recorded on asOf, if any.
+func (s *Service) snapshotAt(ctx context.Context, brand, rep, asOf string) (map[string]float64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kpi, value FROM kpi_history
+        WHERE LOWER(brand) = LOWER(?) AND LOWER(rep) = LOWER(?) AND as_of = ?`, brand, rep, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("select kpi snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string]float64{}
+	for rows.Next() {
+		var (
+			kpi   string
+			value float64
+		)
+		if err := rows.Scan(&kpi, &value); err != nil {
+			return nil, fmt.Errorf("scan kpi snapshot: %w", err)
+		}
+		out[kpi] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate kpi snapshot: %w", err)
+	}
+	return out, nil
+}
+
+func addDays(date string, n int) string {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, n).Format(dateLayout)
+}
+
+func daysBetween(start, end string) int {
+	a, errA := time.Parse(dateLayout, start)
+	b, errB := time.Parse(dateLayout, end)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,270 @@
+package reports
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cell styles in xlsxStyles' cellXfs order.
+const (
+	xfDefault = iota
+	xfHeader
+	xfMoney
+	xfDate
+	xfBlankRep
+	xfStaleInt
+	xfInt
+	xfWrap
+)
+
+// xlsxStyles: bold grey header, $#,##0 money, yyyy-mm-dd dates and the
+// FillBlankRep / FillStale highlights of the sheet export.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="2"><numFmt numFmtId="164" formatCode="&quot;$&quot;#,##0"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/></numFmts>
+<fonts count="2"><font><sz val="10"/><name val="Arial"/></font><font><b/><sz val="10"/><name val="Arial"/></font></fonts>
+<fills count="5"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFF5F5F5"/></patternFill></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFFFF9C4"/></patternFill></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFFFA726"/></patternFill></fill></fills>
+<borders count="1"><border/></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="8">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0" applyAlignment="1"><alignment vertical="top"/></xf>
+<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyAlignment="1"><alignment vertical="top"/></xf>
+<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyAlignment="1"><alignment vertical="top"/></xf>
+<xf numFmtId="0" fontId="0" fillId="3" borderId="0" xfId="0" applyFill="1" applyAlignment="1"><alignment vertical="top"/></xf>
+<xf numFmtId="1" fontId="0" fillId="4" borderId="0" xfId="0" applyNumberFormat="1" applyFill="1" applyAlignment="1"><alignment vertical="top"/></xf>
+<xf numFmtId="1" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyAlignment="1"><alignment vertical="top"/></xf>
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>
+</cellXfs>
+</styleSheet>`
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// writeXLSX writes a two-sheet workbook (Report and Summary) with inline
+// strings, so no shared-string table is needed.
+func (l *Listing) writeXLSX(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook()},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", l.xlsxReportSheet()},
+		{"xl/worksheets/sheet2.xml", l.xlsxSummarySheet()},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", p.name, err)
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return fmt.Errorf("write %s: %w", p.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func xlsxWorkbook() string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Report" sheetId="1" r:id="rId1"/><sheet name="Summary" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`
+}
+
+func (l *Listing) xlsxReportSheet() string {
+	var b strings.Builder
+	lastCol := xlsxCol(len(l.Columns) - 1)
+	lastRow := len(l.Rows) + 1
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<cols>`)
+	for i, c := range l.Columns {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, xlsxWidth(c, l.Rows, i))
+	}
+	b.WriteString(`</cols><sheetData><row r="1">`)
+	for i, c := range l.Columns {
+		xlsxString(&b, xlsxCol(i)+"1", c.Label, xfHeader)
+	}
+	b.WriteString(`</row>`)
+	for r, row := range l.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+2)
+		for i, cell := range row {
+			ref := fmt.Sprintf("%s%d", xlsxCol(i), r+2)
+			switch {
+			case cell.Fill == FillBlankRep:
+				xlsxString(&b, ref, cell.Text, xfBlankRep)
+			case l.Columns[i].Type == ColumnMoney && cell.Num != nil:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%.2f</v></c>`, ref, xfMoney, *cell.Num)
+			case l.Columns[i].Type == ColumnInt && cell.Num != nil:
+				style := xfInt
+				if cell.Fill == FillStale {
+					style = xfStaleInt
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, int64(*cell.Num))
+			case l.Columns[i].Type == ColumnDate:
+				if serial, ok := xlsxDate(cell.Text); ok {
+					fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, xfDate, serial)
+				} else {
+					xlsxString(&b, ref, cell.Text, xfDefault)
+				}
+			case l.Columns[i].Label == "Next Steps":
+				xlsxString(&b, ref, cell.Text, xfWrap)
+			default:
+				xlsxString(&b, ref, cell.Text, xfDefault)
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+	fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, lastCol, lastRow)
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// xlsxSummarySheet lists each summary group as Value/Count, most common first.
+func (l *Listing) xlsxSummarySheet() string {
+	sections := []struct{ label, key string }{
+		{"Rep (Assigned or Assisted)", "rep"},
+		{"Sales Stage", "salesStage"},
+		{"Conversion Status", "conversionStatus"},
+		{"Custom Order Status", "customOrderStatus"},
+		{"Center Stone Order Status", "centerStoneStatus"},
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<cols><col min="1" max="1" width="40" customWidth="1"/><col min="2" max="2" width="10" customWidth="1"/></cols><sheetData>`)
+	r := 1
+	for _, sec := range sections {
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		xlsxString(&b, fmt.Sprintf("A%d", r), sec.label, xfHeader)
+		b.WriteString(`</row>`)
+		r++
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		xlsxString(&b, fmt.Sprintf("A%d", r), "Value", xfHeader)
+		xlsxString(&b, fmt.Sprintf("B%d", r), "Count", xfHeader)
+		b.WriteString(`</row>`)
+		r++
+		for _, e := range sortedCounts(l.Summary.Groups[sec.key]) {
+			fmt.Fprintf(&b, `<row r="%d">`, r)
+			xlsxString(&b, fmt.Sprintf("A%d", r), e.value, xfDefault)
+			fmt.Fprintf(&b, `<c r="B%d" s="%d"><v>%d</v></c>`, r, xfInt, e.count)
+			b.WriteString(`</row>`)
+			r++
+		}
+		r++
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+type countEntry struct {
+	value string
+	count int
+}
+
+func sortedCounts(m map[string]int) []countEntry {
+	out := make([]countEntry, 0, len(m))
+	for v, n := range m {
+		out = append(out, countEntry{v, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].value < out[j].value
+	})
+	return out
+}
+
+func xlsxString(b *strings.Builder, ref, text string, style int) {
+	if text == "" {
+		if style != xfDefault {
+			fmt.Fprintf(b, `<c r="%s" s="%d"/>`, ref, style)
+		}
+		return
+	}
+	fmt.Fprintf(b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+	xml.EscapeText(b, []byte(xlsxClean(text)))
+	b.WriteString(`</t></is></c>`)
+}
+
+// xlsxClean drops control characters XML 1.0 cannot carry.
+func xlsxClean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// xlsxCol turns a zero-based index into a column name (0 → A, 26 → AA).
+func xlsxCol(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxDate converts YYYY-MM-DD into an Excel serial day.
+func xlsxDate(v string) (int, bool) {
+	t, err := time.Parse(dateLayout, strings.TrimSpace(v))
+	if err != nil {
+		return 0, false
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(epoch).Hours() / 24), true
+}
+
+// xlsxWidth approximates autoResizeColumns, with Next Steps kept wide for wrapping.
+func xlsxWidth(c Column, rows [][]Cell, col int) int {
+	if c.Label == "Next Steps" {
+		return 50
+	}
+	width := len([]rune(c.Label)) + 2
+	for _, row := range rows {
+		if n := len([]rune(row[col].Text)) + 2; n > width {
+			width = n
+		}
+	}
+	if width > 40 {
+		width = 40
+	}
+	return width
+}
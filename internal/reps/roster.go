@@ -0,0 +1,209 @@
+// Package reps derives the sales rep roster from the Assigned Rep and
+// Assisted Rep columns, mirroring the 08_Reps_Map sheet.
+package reps
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/vvsapp/internal/logging"
+)
+
+// Roles a rep holds on a root appointment; Assigned wins over Assisted.
+const (
+	RoleAssigned = "Assigned"
+	RoleAssisted = "Assisted"
+)
+
+// excludedStages drop a root from "Include?" like EXCLUDE_SALES_STAGES.
+var excludedStages = map[string]bool{"won": true, "lost lead": true}
+
+// Rep is one roster name with how many roots it covers.
+type Rep struct {
+	Name          string `json:"name"`
+	AssignedRoots int    `json:"assignedRoots"`
+	AssistedRoots int    `json:"assistedRoots"`
+}
+
+// Assignment is one 08_Reps_Map row: a rep's role on a root appointment.
+type Assignment struct {
+	RootApptID string `json:"rootApptId"`
+	Rep        string `json:"rep"`
+	Role       string `json:"role"`
+	SalesStage string `json:"salesStage"`
+	Include    bool   `json:"include"`
+}
+
+// ParseNames splits a multi-select rep cell on commas, pipes and newlines.
+func ParseNames(cell string) []string {
+	var out []string
+	for _, part := range strings.FieldsFunc(cell, func(r rune) bool { return r == ',' || r == '|' || r == '\n' }) {
+		if name := strings.TrimSpace(part); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// MatchSQL returns a condition that is true when col (a multi-select rep
+// cell) contains the name bound to its single placeholder. Bind MatchArg(name).
+func MatchSQL(col string) string {
+	// The newline is a literal inside the quotes so the SQL runs on both
+	// SQLite (char) and Postgres (chr).
+	norm := fmt.Sprintf("REPLACE(REPLACE(REPLACE(REPLACE(LOWER(%s), '|', ','), '\n', ','), ', ', ','), ' ,', ',')", col)
+	return `(',' || ` + norm + ` || ',') LIKE ?`
+}
+
+// MatchArg is the bind value for MatchSQL.
+func MatchArg(name string) string {
+	return "%," + strings.ToLower(strings.TrimSpace(name)) + ",%"
+}
+
+// Service reads the roster from appointments.
+type Service struct {
+	db     *sql.DB
+	logger *logging.Logger
+}
+
+// NewService constructs a roster service.
+func NewService(db *sql.DB, logger *logging.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// Roster returns every rep named on an appointment, by name.
+func (s *Service) Roster(ctx context.Context) ([]Rep, error) {
+	rows, err := s.assignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*Rep{}
+	for _, a := range rows {
+		rep := byName[a.Rep]
+		if rep == nil {
+			rep = &Rep{Name: a.Rep}
+			byName[a.Rep] = rep
+		}
+		if a.Role == RoleAssigned {
+			rep.AssignedRoots++
+		} else {
+			rep.AssistedRoots++
+		}
+	}
+	out := make([]Rep, 0, len(byName))
+	for _, rep := range byName {
+		out = append(out, *rep)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out, nil
+}
+
+// Canonical maps name to its roster spelling, case-insensitively. The
+// trimmed input is returned with false when the name is not on the roster.
+func (s *Service) Canonical(ctx context.Context, name string) (string, bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", false, nil
+	}
+	roster, err := s.Roster(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	for _, rep := range roster {
+		if strings.EqualFold(rep.Name, name) {
+			return rep.Name, true, nil
+		}
+	}
+	return name, false, nil
+}
+
+// Assignments returns the 08_Reps_Map rows for one rep (all reps when empty),
+// sorted by root, then Assigned before Assisted.
+func (s *Service) Assignments(ctx context.Context, rep string) ([]Assignment, error) {
+	rows, err := s.assignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rep = strings.TrimSpace(rep); rep == "" {
+		return rows, nil
+	}
+	out := []Assignment{}
+	for _, a := range rows {
+		if strings.EqualFold(a.Rep, rep) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// assignments builds the deduplicated (root, rep) map across all appointments.
+func (s *Service) assignments(ctx context.Context) ([]Assignment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT root_appt_id, assigned_rep, assisted_rep, sales_stage
+        FROM appointments ORDER BY updated_at`)
+	if err != nil {
+		return nil, fmt.Errorf("select rep assignments: %w", err)
+	}
+	defer rows.Close()
+
+	canon := map[string]string{} // lower -> first spelling seen
+	normalize := func(name string) string {
+		key := strings.ToLower(name)
+		if c, ok := canon[key]; ok {
+			return c
+		}
+		canon[key] = name
+		return name
+	}
+
+	byKey := map[string]*Assignment{}
+	stageByRoot := map[string]string{}
+	for rows.Next() {
+		var root, assigned, assisted, stage string
+		if err := rows.Scan(&root, &assigned, &assisted, &stage); err != nil {
+			return nil, fmt.Errorf("scan rep assignment: %w", err)
+		}
+		if root = strings.TrimSpace(root); root == "" {
+			continue
+		}
+		if stage = strings.TrimSpace(stage); stage != "" {
+			stageByRoot[root] = stage
+		}
+		add := func(cell, role string) {
+			for _, name := range ParseNames(cell) {
+				name = normalize(name)
+				key := root + "||" + name
+				if a, ok := byKey[key]; ok {
+					if role == RoleAssigned {
+						a.Role = RoleAssigned
+					}
+					continue
+				}
+				byKey[key] = &Assignment{RootApptID: root, Rep: name, Role: role}
+			}
+		}
+		add(assigned, RoleAssigned)
+		add(assisted, RoleAssisted)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rep assignments: %w", err)
+	}
+
+	out := make([]Assignment, 0, len(byKey))
+	for _, a := range byKey {
+		a.SalesStage = stageByRoot[a.RootApptID]
+		a.Include = !excludedStages[strings.ToLower(a.SalesStage)]
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].RootApptID != out[j].RootApptID {
+			return out[i].RootApptID < out[j].RootApptID
+		}
+		if out[i].Role != out[j].Role {
+			return out[i].Role == RoleAssigned
+		}
+		return out[i].Rep < out[j].Rep
+	})
+	return out, nil
+}
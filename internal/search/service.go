@@ -0,0 +1,442 @@
+// Package search answers the top-bar search over customers, sales orders and
+// payments from the search tables kept current by triggers (migrations 14
+// and 23): FTS5 on SQLite, tsvector columns on Postgres. Search rows carry
+// the source row's primary key.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/reps"
+)
+
+// Result groups.
+const (
+	TypeCustomers = "customers"
+	TypeOrders    = "orders"
+	TypePayments  = "payments"
+)
+
+// Types lists every group in display order.
+var Types = []string{TypeCustomers, TypeOrders, TypePayments}
+
+const (
+	defaultLimit = 5
+	maxLimit     = 50
+	minQueryLen  = 2
+	// snippet markers are control characters so the stored text can be
+	// HTML-escaped before they become <mark> tags.
+	markOpen  = "\x02"
+	markClose = "\x03"
+	// headlineOptions make Postgres' ts_headline mark matches like snippet().
+	headlineOptions = `StartSel="` + markOpen + `", StopSel="` + markClose + `", MaxWords=10, MinWords=3, MaxFragments=1, FragmentDelimiter="…"`
+)
+
+// ValidationError reports a query that cannot be run.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Query is one search request.
+type Query struct {
+	Q     string
+	Types []string // empty searches every group
+	Rep   string   // customers and orders only: matches Assigned or Assisted Rep
+	Limit int      // per group
+}
+
+// Hit is one ranked result.
+type Hit struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	RootApptID string `json:"rootApptId"`
+	Title      string `json:"title"`
+	Subtitle   string `json:"subtitle"`
+	// Snippet is HTML-escaped text with matches wrapped in <mark>.
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+	Link    string  `json:"link"`
+}
+
+// Results holds hits by group, best match first within each.
+type Results struct {
+	Query  string           `json:"query"`
+	Groups map[string][]Hit `json:"groups"`
+}
+
+// Service runs searches.
+type Service struct {
+	db       *sql.DB
+	postgres bool
+	logger   *logging.Logger
+}
+
+// NewService constructs a search service.
+func NewService(conn *sql.DB, logger *logging.Logger) *Service {
+	return &Service{db: conn, postgres: db.DriverOf(conn) == db.DriverPostgres, logger: logger}
+}
+
+// Search runs q against each requested group.
+func (s *Service) Search(ctx context.Context, q Query) (*Results, error) {
+	text := strings.TrimSpace(q.Q)
+	match := MatchExpr(text)
+	if s.postgres {
+		match = TSQuery(text)
+	}
+	if len([]rune(text)) < minQueryLen || match == "" {
+		return nil, &ValidationError{Field: "q", Message: fmt.Sprintf("must be at least %d characters", minQueryLen)}
+	}
+	types := q.Types
+	if len(types) == 0 {
+		types = Types
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	res := &Results{Query: text, Groups: map[string][]Hit{}}
+	for _, t := range types {
+		var (
+			hits []Hit
+			err  error
+		)
+		switch strings.ToLower(strings.TrimSpace(t)) {
+		case TypeCustomers:
+			hits, err = s.customers(ctx, match, q.Rep, limit)
+		case TypeOrders:
+			hits, err = s.orders(ctx, match, q.Rep, limit)
+		case TypePayments:
+			hits, err = s.payments(ctx, match, limit)
+		default:
+			return nil, &ValidationError{Field: "types", Message: "must be customers, orders or payments"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Groups[strings.ToLower(strings.TrimSpace(t))] = hits
+	}
+	return res, nil
+}
+
+// customers returns one hit per root appointment, ranked by its best row.
+func (s *Service) customers(ctx context.Context, match, rep string, limit int) ([]Hit, error) {
+	query := `SELECT a.appt_id, a.root_appt_id, a.customer_name, a.brand, a.visit_date, a.email_lower, a.so_number,
+        snippet(search_customers, -1, '` + markOpen + `', '` + markClose + `', '…', 10), bm25(search_customers) AS rank
+        FROM search_customers JOIN appointments a ON a.appt_id = search_customers.appt_id
+        WHERE search_customers MATCH ?`
+	args := []any{match}
+	if s.postgres {
+		query = `SELECT a.appt_id, a.root_appt_id, a.customer_name, a.brand, a.visit_date, a.email_lower, a.so_number,
+        ts_headline('simple', sc.name || ' ' || sc.contact || ' ' || sc.so, m.q, ?), -ts_rank(sc.doc, m.q) AS rank
+        FROM search_customers sc JOIN appointments a ON a.appt_id = sc.appt_id
+        CROSS JOIN to_tsquery('simple', ?) AS m(q)
+        WHERE sc.doc @@ m.q`
+		args = []any{headlineOptions, match}
+	}
+	if rep = strings.TrimSpace(rep); rep != "" {
+		query += ` AND (` + reps.MatchSQL("a.assigned_rep") + ` OR ` + reps.MatchSQL("a.assisted_rep") + `)`
+		args = append(args, reps.MatchArg(rep), reps.MatchArg(rep))
+	}
+	// Over-fetch so collapsing rows into roots still fills the limit.
+	query += ` ORDER BY rank LIMIT ?`
+	args = append(args, limit*5)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search customers: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Hit{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var (
+			apptID, root, name, brand, visitDate, email, so, snippet string
+			rank                                                     float64
+		)
+		if err := rows.Scan(&apptID, &root, &name, &brand, &visitDate, &email, &so, &snippet, &rank); err != nil {
+			return nil, fmt.Errorf("scan customer hit: %w", err)
+		}
+		if seen[root] || len(out) >= limit {
+			continue
+		}
+		seen[root] = true
+		out = append(out, Hit{
+			Type:       TypeCustomers,
+			ID:         root,
+			RootApptID: root,
+			Title:      name,
+			Subtitle:   joinNonEmpty(" · ", brand, so, email, visitDate),
+			Snippet:    renderSnippet(snippet),
+			Score:      score(rank),
+			Link:       "/customers/" + root,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate customer hits: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Service) orders(ctx context.Context, match, rep string, limit int) ([]Hit, error) {
+	query := `SELECT o.brand, o.so_pretty, o.root_appt_id, search_orders.name,
+        snippet(search_orders, -1, '` + markOpen + `', '` + markClose + `', '…', 10), bm25(search_orders) AS rank
+        FROM search_orders JOIN sales_orders o ON o.id = search_orders.rowid
+        WHERE search_orders MATCH ?`
+	args := []any{match}
+	if s.postgres {
+		query = `SELECT o.brand, o.so_pretty, o.root_appt_id, so.name,
+        ts_headline('simple', so.so || ' ' || so.name, m.q, ?), -ts_rank(so.doc, m.q) AS rank
+        FROM search_orders so JOIN sales_orders o ON o.id = so.order_id
+        CROSS JOIN to_tsquery('simple', ?) AS m(q)
+        WHERE so.doc @@ m.q`
+		args = []any{headlineOptions, match}
+	}
+	if rep = strings.TrimSpace(rep); rep != "" {
+		query += ` AND EXISTS (SELECT 1 FROM appointments a WHERE a.root_appt_id = o.root_appt_id AND (` +
+			reps.MatchSQL("a.assigned_rep") + ` OR ` + reps.MatchSQL("a.assisted_rep") + `))`
+		args = append(args, reps.MatchArg(rep), reps.MatchArg(rep))
+	}
+	query += ` ORDER BY rank LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search orders: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Hit{}
+	for rows.Next() {
+		var (
+			brand, so, root, name, snippet string
+			rank                           float64
+		)
+		if err := rows.Scan(&brand, &so, &root, &name, &snippet, &rank); err != nil {
+			return nil, fmt.Errorf("scan order hit: %w", err)
+		}
+		out = append(out, Hit{
+			Type:       TypeOrders,
+			ID:         brand + " " + so,
+			RootApptID: root,
+			Title:      brand + " " + so,
+			Subtitle:   name,
+			Snippet:    renderSnippet(snippet),
+			Score:      score(rank),
+			Link:       "/orders/" + so,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate order hits: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Service) payments(ctx context.Context, match string, limit int) ([]Hit, error) {
+	query := `SELECT p.payment_id, p.root_appt_id, p.doc_type, p.customer_name, p.so_number,
+        p.amount_net_cents, p.submitted_at,
+        snippet(search_payments, -1, '` + markOpen + `', '` + markClose + `', '…', 10), bm25(search_payments) AS rank
+        FROM search_payments JOIN payments p ON p.payment_id = search_payments.payment_id
+        WHERE search_payments MATCH ?
+        ORDER BY rank LIMIT ?`
+	args := []any{match, limit}
+	if s.postgres {
+		query = `SELECT p.payment_id, p.root_appt_id, p.doc_type, p.customer_name, p.so_number,
+        p.amount_net_cents, p.submitted_at,
+        ts_headline('simple', sp.ref || ' ' || sp.name || ' ' || sp.doc_text, m.q, ?), -ts_rank(sp.doc, m.q) AS rank
+        FROM search_payments sp JOIN payments p ON p.payment_id = sp.payment_id
+        CROSS JOIN to_tsquery('simple', ?) AS m(q)
+        WHERE sp.doc @@ m.q
+        ORDER BY rank LIMIT ?`
+		args = []any{headlineOptions, match, limit}
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search payments: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Hit{}
+	for rows.Next() {
+		var (
+			id, root, docType, name, so, submittedAt, snippet string
+			net                                               int64
+			rank                                              float64
+		)
+		if err := rows.Scan(&id, &root, &docType, &name, &so, &net, &submittedAt, &snippet, &rank); err != nil {
+			return nil, fmt.Errorf("scan payment hit: %w", err)
+		}
+		out = append(out, Hit{
+			Type:       TypePayments,
+			ID:         id,
+			RootApptID: root,
+			Title:      joinNonEmpty(" · ", docType, name),
+			Subtitle:   joinNonEmpty(" · ", so, fmt.Sprintf("$%.2f", float64(net)/100), submittedAt),
+			Snippet:    renderSnippet(snippet),
+			Score:      score(rank),
+			Link:       "/payments/" + id,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate payment hits: %w", err)
+	}
+	return out, nil
+}
+
+var (
+	phoneish = regexp.MustCompile(`^[\d\s().+-]+$`)
+	soish    = regexp.MustCompile(`(?i)^(so\s*#?\s*)?'?[\d.\s]+$`)
+)
+
+// querySyntax spells the operators of a full-text query language.
+type querySyntax struct {
+	prefix  func(word string) string
+	or, and string
+}
+
+var (
+	fts5Syntax    = querySyntax{prefix: prefixTerm, or: " OR ", and: " AND "}
+	tsquerySyntax = querySyntax{prefix: tsPrefixTerm, or: " | ", and: " & "}
+)
+
+// MatchExpr turns free text into an FTS5 query: every word must match as a
+// prefix. Phone-like input is searched as one digit run and SO-like words
+// also match their 6-digit key, so "555-123", "SO#1293" and "00.1293" find
+// what was stored from any other spelling.
+func MatchExpr(text string) string {
+	return buildQuery(text, fts5Syntax)
+}
+
+// TSQuery is MatchExpr for Postgres: the same terms as a to_tsquery input.
+func TSQuery(text string) string {
+	return buildQuery(text, tsquerySyntax)
+}
+
+func buildQuery(text string, syn querySyntax) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	// Punctuated digit runs are phones (or a dotted SO): search the digits
+	// as one prefix, the way the contact column stores them.
+	if phoneish.MatchString(text) && strings.ContainsAny(text, " ()+-.") {
+		if digits := onlyDigits(text); len(digits) >= 4 {
+			if len(digits) > 11 {
+				digits = digits[len(digits)-10:]
+			}
+			term := syn.prefix(digits)
+			if key := orders.SOKey(text); key != "" && soish.MatchString(text) && key != digits {
+				term = "(" + term + syn.or + syn.prefix(key) + ")"
+			}
+			return term
+		}
+	}
+
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		alts := []string{}
+		if t := syn.prefix(word); t != "" {
+			alts = append(alts, t)
+		}
+		if soish.MatchString(word) && strings.ContainsAny(word, "0123456789") {
+			if key := orders.SOKey(word); key != "" {
+				alts = append(alts, syn.prefix(key))
+			}
+		}
+		switch len(alts) {
+		case 0:
+		case 1:
+			terms = append(terms, alts[0])
+		default:
+			terms = append(terms, "("+strings.Join(alts, syn.or)+")")
+		}
+	}
+	return strings.Join(terms, syn.and)
+}
+
+// prefixTerm quotes a word as an FTS5 string so punctuation can't inject
+// operators; the tokenizer splits it into a phrase, the last token a prefix.
+func prefixTerm(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if r == '"' || r < ' ' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	w := strings.TrimSpace(b.String())
+	if !strings.ContainsFunc(w, isWordRune) {
+		return ""
+	}
+	return `"` + w + `"*`
+}
+
+// tsPrefixTerm splits a word the way the search documents are folded
+// (letters and digits only) into a tsquery phrase, the last token a prefix.
+func tsPrefixTerm(word string) string {
+	tokens := strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) == 0 {
+		return ""
+	}
+	for i, t := range tokens {
+		tokens[i] = "'" + t + "'"
+	}
+	return strings.Join(tokens, " <-> ") + ":*"
+}
+
+func isWordRune(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
+
+func onlyDigits(v string) string {
+	var b strings.Builder
+	for _, r := range v {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// renderSnippet escapes stored text and turns the match markers into <mark>.
+func renderSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, markOpen, "<mark>")
+	return strings.ReplaceAll(escaped, markClose, "</mark>")
+}
+
+// score flips bm25, or the negated ts_rank on Postgres (lower is better),
+// into a positive relevance.
+func score(rank float64) float64 {
+	return -rank
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
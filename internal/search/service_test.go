@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+func TestMatchExpr(t *testing.T) {
+	tests := []struct {
+		in, fts5, tsquery string
+	}{
+		{"", "", ""},
+		{"--", "", ""},
+		{"Jamie", `"Jamie"*`, `'jamie':*`},
+		{"jamie whit", `"jamie"* AND "whit"*`, `'jamie':* & 'whit':*`},
+		{"Renée", `"Renée"*`, `'renée':*`},
+		// Quotes can't break out of the FTS5 string.
+		{`ab"c`, `"abc"*`, `'ab' <-> 'c':*`},
+		{"o'brien", `"o'brien"*`, `'o' <-> 'brien':*`},
+		// Phones search as one digit run.
+		{"555-010-2030", `"5550102030"*`, `'5550102030':*`},
+		{"+1 (555) 010 2030", `"15550102030"*`, `'15550102030':*`},
+		{"+44 20 7946 0958 12", `"7946095812"*`, `'7946095812':*`},
+		// SO spellings also match the 6-digit key.
+		{"00.1293", `"001293"*`, `'001293':*`},
+		{"SO#1293", `("SO#1293"* OR "001293"*)`, `('so' <-> '1293':* | '001293':*)`},
+		{"1293 ring", `("1293"* OR "001293"*) AND "ring"*`, `('1293':* | '001293':*) & 'ring':*`},
+	}
+	for _, tt := range tests {
+		if got := MatchExpr(tt.in); got != tt.fts5 {
+			t.Errorf("MatchExpr(%q) = %q, want %q", tt.in, got, tt.fts5)
+		}
+		if got := TSQuery(tt.in); got != tt.tsquery {
+			t.Errorf("TSQuery(%q) = %q, want %q", tt.in, got, tt.tsquery)
+		}
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	got := renderSnippet("<b>" + markOpen + "Jamie" + markClose + " & co")
+	if want := "&lt;b&gt;<mark>Jamie</mark> &amp; co"; got != want {
+		t.Errorf("renderSnippet = %q, want %q", got, want)
+	}
+}
+
+// TestSearchJoinsByKey moves source rows off the rowids their search rows
+// were created with, as a copy or restore of the TEXT-keyed appointments and
+// payments tables may; hits must still point at the right rows.
+func TestSearchJoinsByKey(t *testing.T) {
+	conn := dbtest.Open(t, db.DriverSQLite)
+	svc := NewService(conn, logging.NewWriter("error", io.Discard))
+	ctx := context.Background()
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	for _, c := range []struct{ id, name string }{{"A1", "Avery Stone"}, {"A2", "Blake Rivera"}, {"A3", "Casey Whitfield"}} {
+		exec(`INSERT INTO appointments(appt_id, root_appt_id, brand, customer_name, visit_date, created_at, updated_at)
+            VALUES(?, ?, 'VVS', ?, '2024-04-01', 'x', 'x')`, c.id, c.id, c.name)
+		exec(`INSERT INTO payments(payment_id, brand, root_appt_id, so_number, anchor_type, basket_id, doc_type,
+            doc_kind, doc_status, lines_json, subtotal_cents, allocated_to_so_cents, reference, customer_name, submitted_at)
+            VALUES(?, 'VVS', ?, '', 'APPT', '', 'Deposit Receipt', 'receipt', 'ISSUED', '[]', 0, 0, ?, ?, 'x')`,
+			"P"+c.id, c.id, "REF-"+c.id, c.name)
+	}
+	exec(`DELETE FROM appointments WHERE appt_id = 'A1'`)
+	exec(`DELETE FROM payments WHERE payment_id = 'PA1'`)
+	exec(`UPDATE appointments SET rowid = rowid + 100`)
+	exec(`UPDATE payments SET rowid = rowid + 100`)
+	exec(`UPDATE appointments SET customer_name = 'Blake Rivers' WHERE appt_id = 'A2'`)
+
+	find := func(q, typ string) []Hit {
+		t.Helper()
+		res, err := svc.Search(ctx, Query{Q: q, Types: []string{typ}})
+		if err != nil {
+			t.Fatalf("search %q: %v", q, err)
+		}
+		return res.Groups[typ]
+	}
+	if hits := find("whitf", TypeCustomers); len(hits) != 1 || hits[0].ID != "A3" || hits[0].Title != "Casey Whitfield" {
+		t.Errorf("customers whitf = %+v", hits)
+	}
+	if hits := find("rivers", TypeCustomers); len(hits) != 1 || hits[0].ID != "A2" {
+		t.Errorf("customers rivers = %+v", hits)
+	}
+	if hits := find("stone", TypeCustomers); len(hits) != 0 {
+		t.Errorf("deleted customer still found: %+v", hits)
+	}
+	if hits := find("REF-A3", TypePayments); len(hits) != 1 || hits[0].ID != "PA3" || hits[0].RootApptID != "A3" {
+		t.Errorf("payments REF-A3 = %+v", hits)
+	}
+
+	if _, err := svc.Search(ctx, Query{Q: "a"}); err == nil {
+		t.Error("one-character query accepted")
+	}
+	if _, err := svc.Search(ctx, Query{Q: "casey", Types: []string{"invoices"}}); err == nil {
+		t.Error("unknown type accepted")
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/auth"
+)
+
+type activityKey struct{}
+
+// statusRecorder holds back the status and body a handler writes until the
+// activity entry is stored. Headers go straight to the underlying writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// flush sends the held response.
+func (w *statusRecorder) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// withActivity records every successful write on the route. Handlers refine
+// the entry (entity id, diff, summary) through noteActivity; on public
+// routes claims is nil and the handler names the actor. The response is sent
+// only once the entry is stored, and a write that cannot be recorded answers
+// 500 instead of passing silently.
+func (s *Server) withActivity(rt route, claims *auth.Claims, w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || s.activitySvc == nil {
+		rt.handler(w, r)
+		return
+	}
+
+	entry := &activity.Entry{
+		Action:     defaultAction(r.Method),
+		EntityType: rt.entity,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+	if claims != nil {
+		entry.Actor, entry.ActorRole = claims.Email, auth.NormalizeRole(claims.Role)
+	}
+	rec := &statusRecorder{ResponseWriter: w}
+	rt.handler(rec, r.WithContext(context.WithValue(r.Context(), activityKey{}, entry)))
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if rec.status >= http.StatusBadRequest {
+		rec.flush()
+		return
+	}
+	entry.Status = rec.status
+	// The change is committed; record it even if the client has gone.
+	if err := s.activitySvc.Record(context.WithoutCancel(r.Context()), *entry); err != nil {
+		s.logger.Error("activity_record_failed", map[string]any{"path": r.URL.Path, "actor": entry.Actor, "error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("the change was saved but could not be recorded in the activity log"))
+		return
+	}
+	rec.flush()
+}
+
+// noteActivity lets a write handler describe what it changed.
+func noteActivity(r *http.Request, fn func(e *activity.Entry)) {
+	if e, ok := r.Context().Value(activityKey{}).(*activity.Entry); ok {
+		fn(e)
+	}
+}
+
+func defaultAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return activity.ActionCreate
+	case http.MethodDelete:
+		return activity.ActionDelete
+	default:
+		return activity.ActionUpdate
+	}
+}
+
+// handleActivity serves the Recent Activity feed: GET /api/audit?limit=&cursor=.
+// Admins and managers see everyone's activity; other roles see their own.
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	f := activity.Filter{
+		Actor:      q.Get("actor"),
+		EntityType: q.Get("entityType"),
+		EntityID:   q.Get("entityId"),
+		RootApptID: q.Get("rootApptId"),
+		Cursor:     q.Get("cursor"),
+		Limit:      queryInt(r, "limit", 0),
+	}
+	if claims, _ := ClaimsFromContext(r.Context()); !hasRole(managerRoles, auth.NormalizeRole(claims.Role)) {
+		f.Actor = claims.Email
+	}
+	page, err := s.activitySvc.List(r.Context(), f)
+	if err != nil {
+		var verr *activity.ValidationError
+		if errors.As(err, &verr) {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.logger.Error("activity_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, page)
+}
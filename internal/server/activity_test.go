@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+)
+
+type testServer struct {
+	*Server
+	conn     *sql.DB
+	auth     *auth.Service
+	activity *activity.Service
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	conn := dbtest.Open(t, db.DriverSQLite)
+	logger := logging.NewWriter("error", io.Discard)
+	authSvc, err := auth.NewService(conn, config.AuthConfig{JWTSecret: "test-secret-0123456789"}, logger)
+	if err != nil {
+		t.Fatalf("auth: %v", err)
+	}
+	activitySvc := activity.NewService(conn, logger)
+	srv := New(&config.Config{}, logger, conn, Services{Auth: authSvc, Activity: activitySvc})
+	return &testServer{Server: srv, conn: conn, auth: authSvc, activity: activitySvc}
+}
+
+func (ts *testServer) post(path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, req)
+	return w
+}
+
+func TestPasswordResetActivity(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	u, reset, err := ts.auth.CreateUser(ctx, auth.CreateUserInput{Email: "rep@example.com", Role: auth.RoleRep}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("invite: %v", err)
+	}
+
+	if w := ts.post("/api/auth/reset", "", `{"token":"unknown","newPassword":"first password"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad token = %d %s", w.Code, w.Body)
+	}
+	body, _ := json.Marshal(map[string]string{"token": reset.Token, "newPassword": "first password"})
+	if w := ts.post("/api/auth/reset", "", string(body)); w.Code != http.StatusOK {
+		t.Fatalf("reset = %d %s", w.Code, w.Body)
+	}
+
+	page, err := ts.activity.List(ctx, activity.Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(page.Entries) != 1 {
+		t.Fatalf("entries = %+v, want only the redeemed reset", page.Entries)
+	}
+	e := page.Entries[0]
+	if e.Actor != "rep@example.com" || e.ActorRole != auth.RoleRep || e.Action != "reset_password" ||
+		e.EntityType != "user" || e.EntityID != strconv.FormatInt(u.ID, 10) || e.Path != "/api/auth/reset" || e.Status != http.StatusOK {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+// TestActivityRecordFailure checks a write whose activity entry cannot be
+// stored is reported instead of answering success.
+func TestActivityRecordFailure(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	if _, _, err := ts.auth.CreateUser(ctx, auth.CreateUserInput{Email: "rep@example.com", Password: "correct horse", Role: auth.RoleRep}, "admin@example.com"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	tokens, _, err := ts.auth.Authenticate(ctx, "rep@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := ts.conn.Exec(`DROP TABLE activity_log`); err != nil {
+		t.Fatalf("drop activity_log: %v", err)
+	}
+
+	w := ts.post("/api/me/password", tokens.AccessToken, `{"currentPassword":"correct horse","newPassword":"battery staple"}`)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "could not be recorded in the activity log") {
+		t.Errorf("change password = %d %s, want 500 naming the activity log", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), `"ok"`) {
+		t.Errorf("body = %s, want only the error", w.Body)
+	}
+	// Failed writes are not recorded, so they answer as before.
+	if w := ts.post("/api/me/password", tokens.AccessToken, `{"currentPassword":"wrong","newPassword":"battery staple 2"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("wrong password = %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+)
+
+// handleAppointments serves GET (list/filter) and POST (create) on /api/appointments.
+func (s *Server) handleAppointments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		rep, err := s.repFilter(r)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		list, err := s.appointmentsSvc.List(r.Context(), appointments.Filter{
+			RootApptID:       q.Get("rootApptId"),
+			Brand:            q.Get("brand"),
+			AssignedRep:      q.Get("assignedRep"),
+			Rep:              rep,
+			VisitType:        q.Get("visitType"),
+			SalesStage:       q.Get("salesStage"),
+			ConversionStatus: q.Get("conversionStatus"),
+			From:             q.Get("from"),
+			To:               q.Get("to"),
+			Query:            q.Get("query"),
+			Limit:            queryInt(r, "limit", 0),
+			Offset:           queryInt(r, "offset", 0),
+		})
+		if err != nil {
+			s.writeAppointmentError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"appointments": list})
+	case http.MethodPost:
+		var payload appointments.Appointment
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		appt, err := s.appointmentsSvc.Create(r.Context(), payload)
+		if err != nil {
+			s.writeAppointmentError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.EntityID, e.RootApptID, e.Summary = appt.ApptID, appt.RootApptID, appt.CustomerName
+			e.Changes = activity.Diff(nil, appt)
+		})
+		s.writeJSON(w, http.StatusCreated, appt)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handleAppointment serves GET, PUT and DELETE on /api/appointments/{apptId}.
+func (s *Server) handleAppointment(w http.ResponseWriter, r *http.Request) {
+	id := pathID(r, "/api/appointments/")
+	if id == "" {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		appt, err := s.appointmentsSvc.Get(r.Context(), id)
+		if err != nil {
+			s.writeAppointmentError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, appt)
+	case http.MethodPut:
+		var payload appointments.Appointment
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		before, _ := s.appointmentsSvc.Get(r.Context(), id)
+		appt, err := s.appointmentsSvc.Update(r.Context(), id, payload)
+		if err != nil {
+			s.writeAppointmentError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.EntityID, e.RootApptID, e.Summary = appt.ApptID, appt.RootApptID, appt.CustomerName
+			e.Changes = activity.Diff(before, appt)
+		})
+		s.writeJSON(w, http.StatusOK, appt)
+	case http.MethodDelete:
+		before, _ := s.appointmentsSvc.Get(r.Context(), id)
+		if err := s.appointmentsSvc.Delete(r.Context(), id); err != nil {
+			s.writeAppointmentError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.EntityID = id
+			if before != nil {
+				e.RootApptID, e.Summary = before.RootApptID, before.CustomerName
+			}
+			e.Changes = activity.Diff(before, nil)
+		})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) writeAppointmentError(w http.ResponseWriter, err error) {
+	var verr *appointments.ValidationError
+	switch {
+	case errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("appointments_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/audit"
+)
+
+// handleAuditFindings lists data-quality findings:
+// GET /api/audit/findings?status=open|resolved|all&severity=&rep=&mine=1&rule=&rootApptId=.
+func (s *Server) handleAuditFindings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	rep, err := s.repFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q := r.URL.Query()
+	list, err := s.auditSvc.List(r.Context(), audit.Filter{
+		Severity:   q.Get("severity"),
+		Rep:        rep,
+		Rule:       q.Get("rule"),
+		RootApptID: q.Get("rootApptId"),
+		Status:     q.Get("status"),
+		Limit:      queryInt(r, "limit", 0),
+		Offset:     queryInt(r, "offset", 0),
+	})
+	if err != nil {
+		s.writeAuditError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"findings": list})
+}
+
+// handleAuditRun runs the audit immediately and returns its summary: POST /api/audit/run.
+func (s *Server) handleAuditRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	res, err := s.auditSvc.Run(r.Context())
+	if err != nil {
+		s.writeAuditError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "run"
+		e.Summary = fmt.Sprintf("%d open, %d new, %d resolved", res.Open, res.New, res.Resolved)
+	})
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) writeAuditError(w http.ResponseWriter, err error) {
+	var verr *audit.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("audit_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
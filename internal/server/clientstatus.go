@@ -0,0 +1,61 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/clientstatus"
+)
+
+// handleStatusLog serves /api/status-log: GET ?rootApptId= returns the
+// client's history oldest first, POST records a status update.
+func (s *Server) handleStatusLog(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := s.clientStatusSvc.History(r.Context(), r.URL.Query().Get("rootApptId"),
+			queryInt(r, "limit", 0), queryInt(r, "offset", 0))
+		if err != nil {
+			s.writeClientStatusError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"entries": entries})
+	case http.MethodPost:
+		var payload clientstatus.UpdateInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		before, _ := s.appointmentsSvc.Get(r.Context(), payload.ApptID)
+		res, err := s.clientStatusSvc.Post(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writeClientStatusError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			appt := res.Appointment
+			e.Action = "post_status"
+			e.EntityID, e.RootApptID = appt.ApptID, appt.RootApptID
+			e.Summary = fmt.Sprintf("%s: %s", appt.CustomerName, appt.SalesStage)
+			e.Changes = activity.Diff(before, appt)
+		})
+		s.writeJSON(w, http.StatusCreated, res)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) writeClientStatusError(w http.ResponseWriter, err error) {
+	var verr *clientstatus.ValidationError
+	switch {
+	case errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("client_status_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/deadlines"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// handleOrdersDeadlines lists slipping deadlines for the dashboard:
+// GET /api/orders/deadlines?state=overdue|at_risk&type=&brand=&rep=&mine=1.
+func (s *Server) handleOrdersDeadlines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	rep, err := s.repFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q := r.URL.Query()
+	items, err := s.deadlinesSvc.Slipping(r.Context(), deadlines.SlippingFilter{
+		Brand: q.Get("brand"),
+		Rep:   rep,
+		Type:  q.Get("type"),
+		State: q.Get("state"),
+		Limit: queryInt(r, "limit", 0),
+	})
+	if err != nil {
+		s.writeDeadlineError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// handleOrderDeadlines serves /api/orders/{so}/deadlines?brand=: GET returns
+// current deadlines and history, POST sets or moves one. POST to
+// /api/orders/{so}/deadlines/met marks one met.
+func (s *Server) handleOrderDeadlines(w http.ResponseWriter, r *http.Request) {
+	rest := pathID(r, "/api/orders/")
+	so, action, ok := strings.Cut(rest, "/")
+	if !ok || so == "" || (action != "deadlines" && action != "deadlines/met") {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	brand := r.URL.Query().Get("brand")
+
+	switch {
+	case action == "deadlines" && r.Method == http.MethodGet:
+		res, err := s.deadlinesSvc.ForOrder(r.Context(), brand, so)
+		if err != nil {
+			s.writeDeadlineError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, res)
+	case action == "deadlines" && r.Method == http.MethodPost:
+		var payload deadlines.SetInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		payload.SO = so
+		if payload.Brand == "" {
+			payload.Brand = brand
+		}
+		d, err := s.deadlinesSvc.Set(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writeDeadlineError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action = "set_deadline"
+			e.EntityID = orders.SOPretty(so)
+			e.Summary = fmt.Sprintf("%s %s: %s (moved %d)", e.EntityID, d.Label, d.Date, d.MoveCount)
+		})
+		s.writeJSON(w, http.StatusOK, d)
+	case action == "deadlines/met" && r.Method == http.MethodPost:
+		var payload deadlines.MetInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		payload.SO = so
+		if payload.Brand == "" {
+			payload.Brand = brand
+		}
+		d, err := s.deadlinesSvc.Met(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writeDeadlineError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action = "meet_deadline"
+			e.EntityID = orders.SOPretty(so)
+			e.Summary = fmt.Sprintf("%s %s met (%s)", e.EntityID, d.Label, d.Date)
+		})
+		s.writeJSON(w, http.StatusOK, d)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) writeDeadlineError(w http.ResponseWriter, err error) {
+	var (
+		verr      *deadlines.ValidationError
+		orderVerr *orders.ValidationError
+	)
+	switch {
+	case errors.Is(err, orders.ErrNotFound), errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr), errors.As(err, &orderVerr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("deadlines_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/design3d"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// handleDesign3D lists 3D Tracker history: GET /api/design3d?brand=&so=&rootApptId=.
+func (s *Server) handleDesign3D(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	list, err := s.design3dSvc.List(r.Context(), design3d.Filter{
+		Brand:      q.Get("brand"),
+		SO:         q.Get("so"),
+		RootApptID: q.Get("rootApptId"),
+		Limit:      queryInt(r, "limit", 0),
+		Offset:     queryInt(r, "offset", 0),
+	})
+	if err != nil {
+		s.writeDesign3DError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"entries": list})
+}
+
+// handleDesign3DStart starts 3D on an SO: POST /api/design3d/start.
+func (s *Server) handleDesign3DStart(w http.ResponseWriter, r *http.Request) {
+	s.recordDesign3D(w, r, "start_3d", s.design3dSvc.Start)
+}
+
+// handleDesign3DRevision requests the next revision: POST /api/design3d/revision.
+func (s *Server) handleDesign3DRevision(w http.ResponseWriter, r *http.Request) {
+	s.recordDesign3D(w, r, "revise_3d", s.design3dSvc.Revise)
+}
+
+func (s *Server) recordDesign3D(w http.ResponseWriter, r *http.Request, action string,
+	record func(ctx context.Context, in design3d.Input, actor string) (*design3d.Entry, error)) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload design3d.Input
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	e, err := record(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeDesign3DError(w, err)
+		return
+	}
+	noteActivity(r, func(ae *activity.Entry) {
+		ae.Action = action
+		ae.EntityID, ae.RootApptID = e.DesignID, e.RootApptID
+		ae.Summary = fmt.Sprintf("Start 3D for %s %s", e.Brand, e.SONumber)
+		if e.Kind == design3d.KindRevision {
+			ae.Summary = fmt.Sprintf("3D revision #%d for %s %s", e.RevisionNo, e.Brand, e.SONumber)
+		}
+	})
+	s.writeJSON(w, http.StatusCreated, e)
+}
+
+// handleDesign3DPreview renders the Odoo paste without saving:
+// POST /api/design3d/preview with {"kind": "start"|"revision", ...}.
+func (s *Server) handleDesign3DPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		Kind string `json:"kind"`
+		design3d.Input
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	e, err := s.design3dSvc.Preview(r.Context(), strings.ToLower(strings.TrimSpace(payload.Kind)), payload.Input, actorFromRequest(r))
+	if err != nil {
+		s.writeDesign3DError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, e)
+}
+
+// handleDesign3DEntry returns one entry: GET /api/design3d/{designId}, or its
+// Odoo paste as plain text from GET /api/design3d/{designId}/odoo.
+func (s *Server) handleDesign3DEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	id := pathID(r, "/api/design3d/")
+	id, plain := strings.CutSuffix(id, "/odoo")
+	if id == "" {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	e, err := s.design3dSvc.Get(r.Context(), id)
+	if err != nil {
+		s.writeDesign3DError(w, err)
+		return
+	}
+	if plain {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(e.OdooPaste))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) writeDesign3DError(w http.ResponseWriter, err error) {
+	var (
+		verr      *design3d.ValidationError
+		orderVerr *orders.ValidationError
+	)
+	switch {
+	case errors.Is(err, design3d.ErrNotFound), errors.Is(err, orders.ErrNotFound), errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr), errors.As(err, &orderVerr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("design3d_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/jobs"
+)
+
+// handleJobs lists registered jobs with schedule, next run and last run: GET /api/jobs.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	list, err := s.jobs.Jobs(r.Context())
+	if err != nil {
+		s.writeJobError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"jobs": list})
+}
+
+// handleJobsRun starts one job ({"name": "..."}) or, with no name, every job
+// that is not already running: POST /api/jobs/run.
+func (s *Server) handleJobsRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	actor := actorFromRequest(r)
+	if payload.Name == "" {
+		runs := s.jobs.RunAllNow(actor)
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action, e.EntityID = "run", "all"
+		})
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"runs": runs})
+		return
+	}
+	run, err := s.jobs.RunNow(payload.Name, actor)
+	if err != nil {
+		s.writeJobError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action, e.EntityID = "run", payload.Name
+	})
+	s.writeJSON(w, http.StatusAccepted, map[string]any{"runs": []jobs.Run{*run}})
+}
+
+// handleJobsHistory returns recent runs, newest first: GET /api/jobs/history?name=&limit=.
+func (s *Server) handleJobsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	runs, err := s.jobs.History(r.Context(), r.URL.Query().Get("name"), queryInt(r, "limit", 0))
+	if err != nil {
+		s.writeJobError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+func (s *Server) writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrUnknownJob):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, jobs.ErrAlreadyRunning):
+		s.writeError(w, http.StatusConflict, err)
+	default:
+		s.logger.Error("jobs_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
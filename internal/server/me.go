@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/example/vvsapp/internal/auth"
+)
+
+// repIdentity is the roster entry a user account acts as.
+type repIdentity struct {
+	Name string `json:"name"`
+	// Linked is false when the account's rep name is not (yet) on any appointment.
+	Linked        bool `json:"linked"`
+	AssignedRoots int  `json:"assignedRoots"`
+	AssistedRoots int  `json:"assistedRoots"`
+}
+
+// handleMe returns the caller's profile and rep identity: GET /api/me.
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	u, err := s.currentUser(r)
+	if err != nil {
+		s.writeUserError(w, err)
+		return
+	}
+
+	var rep *repIdentity
+	if u.RepName != "" {
+		rep = &repIdentity{Name: u.RepName}
+		roster, err := s.repsSvc.Roster(r.Context())
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		for _, entry := range roster {
+			if strings.EqualFold(entry.Name, u.RepName) {
+				rep.Name = entry.Name
+				rep.Linked = true
+				rep.AssignedRoots = entry.AssignedRoots
+				rep.AssistedRoots = entry.AssistedRoots
+				break
+			}
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"id":          u.ID,
+		"email":       u.Email,
+		"role":        u.Role,
+		"displayName": u.DisplayName,
+		"lastLoginAt": u.LastLoginAt,
+		"rep":         rep,
+	})
+}
+
+// handleReps lists the rep roster, or one rep's roots with ?rep=: GET /api/reps.
+func (s *Server) handleReps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if rep := r.URL.Query().Get("rep"); rep != "" {
+		list, err := s.repsSvc.Assignments(r.Context(), rep)
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"assignments": list})
+		return
+	}
+	roster, err := s.repsSvc.Roster(r.Context())
+	if err != nil {
+		s.writeUserError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"reps": roster})
+}
+
+// currentUser loads the account behind the request's token.
+func (s *Server) currentUser(r *http.Request) (*auth.User, error) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		return nil, auth.ErrUserNotFound
+	}
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return s.authSvc.GetUser(r.Context(), id)
+}
+
+// repFilter resolves ?rep= or ?mine=1 into a rep name for list filters.
+// mine=1 scopes to the caller's linked rep and fails when none is linked.
+func (s *Server) repFilter(r *http.Request) (string, error) {
+	q := r.URL.Query()
+	if q.Get("mine") != "1" && q.Get("mine") != "true" {
+		return q.Get("rep"), nil
+	}
+	u, err := s.currentUser(r)
+	if err != nil {
+		return "", err
+	}
+	if u.RepName == "" {
+		return "", errors.New("no rep is linked to this account")
+	}
+	return u.RepName, nil
+}
+
+// canonicalRep rewrites name to its roster spelling when the rep is known.
+func (s *Server) canonicalRep(r *http.Request, name *string) error {
+	canon, _, err := s.repsSvc.Canonical(r.Context(), *name)
+	if err != nil {
+		return err
+	}
+	*name = canon
+	return nil
+}
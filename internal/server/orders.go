@@ -0,0 +1,135 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// handleOrders lists the SOs linked to a root appointment: GET /api/orders?rootApptId=.
+func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	root := r.URL.Query().Get("rootApptId")
+	if root == "" {
+		s.writeError(w, http.StatusBadRequest, errors.New("rootApptId is required"))
+		return
+	}
+	list, err := s.ordersSvc.ListByRoot(r.Context(), root)
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"orders": list})
+}
+
+// handleOrderAssign links an SO to an appointment: POST /api/orders/assign.
+func (s *Server) handleOrderAssign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload orders.AssignInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.ordersSvc.Assign(r.Context(), payload)
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "assign_so"
+		e.EntityID, e.RootApptID = res.Order.SOPretty, res.Order.RootApptID
+		e.Summary = fmt.Sprintf("Linked %s %s to %s (%d siblings updated)", res.Order.Brand, res.Order.SOPretty, payload.ApptID, len(res.Propagation.Updated))
+	})
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+// handleOrderLookup resolves a raw SO number: GET /api/orders/lookup?brand=&so=.
+func (s *Server) handleOrderLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	order, err := s.ordersSvc.Lookup(r.Context(), q.Get("brand"), q.Get("so"))
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, order)
+}
+
+// handleOrderConflicts mirrors checkSOConflicts: GET /api/orders/conflicts?brand=&so=&apptId=.
+func (s *Server) handleOrderConflicts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	report, err := s.ordersSvc.CheckConflicts(r.Context(), q.Get("brand"), q.Get("so"), q.Get("apptId"))
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, report)
+}
+
+// handleOrderPropagate re-applies an SO to same-root siblings: POST /api/orders/propagate.
+func (s *Server) handleOrderPropagate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		Brand string `json:"brand"`
+		SO    string `json:"so"`
+		Force bool   `json:"forceOverwrite"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.ordersSvc.Propagate(r.Context(), payload.Brand, payload.SO, payload.Force)
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "propagate_so"
+		e.EntityID = orders.SOPretty(payload.SO)
+		e.Summary = fmt.Sprintf("Propagated to %d siblings, skipped %d", len(res.Updated), len(res.Skipped))
+	})
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) writeOrderError(w http.ResponseWriter, err error) {
+	var (
+		verr     *orders.ValidationError
+		conflict *orders.ConflictError
+	)
+	switch {
+	case errors.Is(err, orders.ErrNotFound), errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &conflict):
+		s.writeJSON(w, http.StatusConflict, map[string]any{
+			"error":          err.Error(),
+			"existsInMaster": true,
+			"reason":         "different_root",
+			"rootApptId":     conflict.RootApptID,
+		})
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("orders_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
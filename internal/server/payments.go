@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/payments"
+)
+
+// handlePayments serves GET (list/filter) and POST (record) on /api/payments.
+func (s *Server) handlePayments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		list, err := s.paymentsSvc.List(r.Context(), payments.Filter{
+			RootApptID: q.Get("rootApptId"),
+			SO:         q.Get("so"),
+			DocKind:    q.Get("docKind"),
+			From:       q.Get("from"),
+			To:         q.Get("to"),
+			Query:      q.Get("query"),
+			Limit:      queryInt(r, "limit", 0),
+			Offset:     queryInt(r, "offset", 0),
+		})
+		if err != nil {
+			s.writePaymentError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"payments": list})
+	case http.MethodPost:
+		var payload payments.RecordInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		p, err := s.paymentsSvc.Record(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writePaymentError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action = "record_" + strings.ToLower(p.DocKind)
+			e.EntityID, e.RootApptID = p.PaymentID, p.RootApptID
+			e.Summary = fmt.Sprintf("%s for %s", p.DocType, p.CustomerName)
+		})
+		s.writeJSON(w, http.StatusCreated, p)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handlePayment returns one ledger entry: GET /api/payments/{paymentId}.
+func (s *Server) handlePayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	id := pathID(r, "/api/payments/")
+	if id == "" {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	p, err := s.paymentsSvc.Get(r.Context(), id)
+	if err != nil {
+		s.writePaymentError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, p)
+}
+
+// handlePaymentBalance returns Order Total, Paid-to-Date and Remaining Balance:
+// GET /api/payments/balance?rootApptId= or ?brand=&so=.
+func (s *Server) handlePaymentBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	var (
+		bal *payments.Balance
+		err error
+	)
+	if q.Get("so") != "" {
+		bal, err = s.paymentsSvc.BalanceForSO(r.Context(), q.Get("brand"), q.Get("so"))
+	} else {
+		bal, err = s.paymentsSvc.BalanceForRoot(r.Context(), q.Get("rootApptId"))
+	}
+	if err != nil {
+		s.writePaymentError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, bal)
+}
+
+func (s *Server) writePaymentError(w http.ResponseWriter, err error) {
+	var (
+		verr      *payments.ValidationError
+		orderVerr *orders.ValidationError
+	)
+	switch {
+	case errors.Is(err, payments.ErrNotFound), errors.Is(err, orders.ErrNotFound), errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr), errors.As(err, &orderVerr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("payments_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
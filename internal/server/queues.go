@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// handleOrdersDue lists orders due for a check: GET /api/orders/due?type=3d_check&limit=&brand=&rep=&mine=1.
+func (s *Server) handleOrdersDue(w http.ResponseWriter, r *http.Request) {
+	if t := r.URL.Query().Get("type"); t != "" && t != orders.CheckThreeD {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("type: must be %s", orders.CheckThreeD))
+		return
+	}
+	s.serveQueue(w, r, s.queues.DueThreeDChecks)
+}
+
+// handleOrdersAwaitingPayment lists orders with a balance due: GET /api/orders/awaiting_payment.
+func (s *Server) handleOrdersAwaitingPayment(w http.ResponseWriter, r *http.Request) {
+	s.serveQueue(w, r, s.queues.AwaitingPayment)
+}
+
+// handleOrdersInProduction lists orders in production: GET /api/orders/in_production.
+func (s *Server) handleOrdersInProduction(w http.ResponseWriter, r *http.Request) {
+	s.serveQueue(w, r, s.queues.InProduction)
+}
+
+type queueFunc func(ctx context.Context, f orders.QueueFilter) ([]orders.QueueItem, error)
+
+func (s *Server) serveQueue(w http.ResponseWriter, r *http.Request, list queueFunc) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	rep, err := s.repFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, err := list(r.Context(), orders.QueueFilter{
+		Brand: r.URL.Query().Get("brand"),
+		Rep:   rep,
+		Limit: queryInt(r, "limit", 0),
+	})
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// handleOrdersDueReview marks a due check done: POST /api/orders/due/review.
+func (s *Server) handleOrdersDueReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload orders.CheckInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	check, err := s.queues.MarkReviewed(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "review_" + check.Type
+		e.EntityID = check.SO
+		e.Summary = fmt.Sprintf("Reviewed %s %s", check.Brand, check.SO)
+		if check.Note != "" {
+			e.Summary += ": " + check.Note
+		}
+	})
+	s.writeJSON(w, http.StatusOK, check)
+}
+
+// handleOrdersDueSnooze hides a due check until a date: POST /api/orders/due/snooze.
+func (s *Server) handleOrdersDueSnooze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload orders.CheckInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	check, err := s.queues.Snooze(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeOrderError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "snooze_" + check.Type
+		e.EntityID = check.SO
+		e.Summary = fmt.Sprintf("Snoozed %s %s until %s", check.Brand, check.SO, check.SnoozeUntil)
+	})
+	s.writeJSON(w, http.StatusOK, check)
+}
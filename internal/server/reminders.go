@@ -0,0 +1,134 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/reminders"
+)
+
+// handleReminders serves GET (list) and POST (idempotent upsert) on /api/reminders.
+func (s *Server) handleReminders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		rep, err := s.repFilter(r)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		list, err := s.remindersSvc.List(r.Context(), reminders.Filter{
+			SO:     q.Get("so"),
+			Status: q.Get("status"),
+			Type:   q.Get("type"),
+			Rep:    rep,
+			Limit:  queryInt(r, "limit", 0),
+		})
+		if err != nil {
+			s.writeReminderError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"reminders": list})
+	case http.MethodPost:
+		var payload reminders.UpsertInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rem, err := s.remindersSvc.Upsert(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writeReminderError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action = "upsert"
+			e.EntityID, e.RootApptID, e.Summary = rem.ID, rem.RootApptID, rem.Type+" due "+rem.NextDueAt
+		})
+		s.writeJSON(w, http.StatusOK, rem)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handleRemindersDue lists reminders that would be sent now: GET /api/reminders/due.
+func (s *Server) handleRemindersDue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	list, err := s.remindersSvc.ListDue(r.Context())
+	if err != nil {
+		s.writeReminderError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"reminders": list})
+}
+
+// handleRemindersSnooze snoozes an SO's active reminders: POST /api/reminders/snooze.
+func (s *Server) handleRemindersSnooze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		SO    string `json:"soNumber"`
+		Until string `json:"until"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	n, err := s.remindersSvc.SnoozeSO(r.Context(), payload.SO, payload.Until, actorFromRequest(r))
+	if err != nil {
+		s.writeReminderError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "snooze"
+		e.EntityType, e.EntityID = "order", orders.SOPretty(payload.SO)
+		e.Summary = fmt.Sprintf("Snoozed %d reminders until %s", n, payload.Until)
+	})
+	s.writeJSON(w, http.StatusOK, map[string]any{"updated": n})
+}
+
+// handleRemindersCancel cancels an SO's active reminders: POST /api/reminders/cancel.
+func (s *Server) handleRemindersCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		SO string `json:"soNumber"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	n, err := s.remindersSvc.CancelSO(r.Context(), payload.SO, actorFromRequest(r))
+	if err != nil {
+		s.writeReminderError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = "cancel"
+		e.EntityType, e.EntityID = "order", orders.SOPretty(payload.SO)
+		e.Summary = fmt.Sprintf("Cancelled %d reminders", n)
+	})
+	s.writeJSON(w, http.StatusOK, map[string]any{"updated": n})
+}
+
+func (s *Server) writeReminderError(w http.ResponseWriter, err error) {
+	var verr *reminders.ValidationError
+	switch {
+	case errors.Is(err, reminders.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("reminders_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
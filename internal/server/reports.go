@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/example/vvsapp/internal/reports"
+)
+
+// handleReportKPIs serves the dashboard cards:
+// GET /api/reports/kpis?start=&end=&brand=&rep=&mine=1.
+func (s *Server) handleReportKPIs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	rep, err := s.repFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q := r.URL.Query()
+	report, err := s.reportsSvc.KPIs(r.Context(), reports.Query{
+		Start: q.Get("start"),
+		End:   q.Get("end"),
+		Brand: q.Get("brand"),
+		Rep:   rep,
+	})
+	if err != nil {
+		s.writeReportError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) writeReportError(w http.ResponseWriter, err error) {
+	var verr *reports.ValidationError
+	switch {
+	case errors.Is(err, reports.ErrClientNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("reports_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
+
+// handleReportByStatus serves the By Status report:
+// GET /api/reports/by-status?salesStage=&conversionStatus=&customOrderStatus=&centerStoneStatus=&brand=&format=.
+func (s *Server) handleReportByStatus(w http.ResponseWriter, r *http.Request) {
+	s.serveListing(w, r, reports.KindStatus)
+}
+
+// handleReportByRep serves the By Rep report; it also takes repeated
+// assigned= and assisted= names, and mine=1 for the caller's own rows.
+func (s *Server) handleReportByRep(w http.ResponseWriter, r *http.Request) {
+	s.serveListing(w, r, reports.KindRep)
+}
+
+// serveListing answers JSON (a preview of up to 1000 rows) or, with
+// format=csv|xlsx|pdf|html, a download of every row.
+func (s *Server) serveListing(w http.ResponseWriter, r *http.Request, kind string) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	lq := reports.ListQuery{
+		Kind:              kind,
+		Brand:             q.Get("brand"),
+		SalesStage:        queryList(q["salesStage"]),
+		ConversionStatus:  queryList(q["conversionStatus"]),
+		CustomOrderStatus: queryList(q["customOrderStatus"]),
+		CenterStoneStatus: queryList(q["centerStoneStatus"]),
+		RepLabel:          q.Get("repLabel"),
+		GeneratedBy:       actorFromRequest(r),
+	}
+	if kind == reports.KindRep {
+		rep, err := s.repFilter(r)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		lq.Assigned = queryList(q["assigned"])
+		lq.Assisted = queryList(q["assisted"])
+		if rep != "" {
+			lq.Assigned = append(lq.Assigned, rep)
+			lq.Assisted = append(lq.Assisted, rep)
+		}
+	}
+
+	format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+	listing, err := s.reportsSvc.List(r.Context(), lq)
+	if err != nil {
+		s.writeReportError(w, err)
+		return
+	}
+	s.writeListing(w, listing, format)
+}
+
+// handleReportClientStatus serves one client's Client Status Report:
+// GET /api/reports/client-status?rootApptId=&format=json|html|pdf|csv|xlsx.
+// HTML is shown inline so it can be opened and printed from the browser.
+func (s *Server) handleReportClientStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	listing, err := s.reportsSvc.ClientStatus(r.Context(), q.Get("rootApptId"), actorFromRequest(r))
+	if err != nil {
+		s.writeReportError(w, err)
+		return
+	}
+	s.writeListing(w, listing, strings.ToLower(strings.TrimSpace(q.Get("format"))))
+}
+
+// writeListing answers JSON for an empty format and a file otherwise.
+func (s *Server) writeListing(w http.ResponseWriter, listing *reports.Listing, format string) {
+	if format == "" || format == "json" {
+		listing.Preview()
+		s.writeJSON(w, http.StatusOK, listing)
+		return
+	}
+	file, err := listing.File(format)
+	if err != nil {
+		s.writeReportError(w, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := listing.Write(&buf, format); err != nil {
+		s.writeReportError(w, err)
+		return
+	}
+	disposition := "attachment"
+	if format == reports.FormatHTML {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.Name}))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// queryList trims repeated query values and drops blanks.
+func queryList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/web"
+)
+
+// Role sets used by the route table.
+var (
+	// anyRole admits every authenticated user, including read-only accounts.
+	anyRole = auth.Roles
+	// staffRoles may create and change records.
+	staffRoles = []string{auth.RoleAdmin, auth.RoleManager, auth.RoleStaff, auth.RoleRep}
+	// managerRoles may review team-wide hygiene and reporting.
+	managerRoles = []string{auth.RoleAdmin, auth.RoleManager}
+	// adminRoles may operate the system (jobs, users).
+	adminRoles = []string{auth.RoleAdmin}
+)
+
+// route declares one API endpoint and who may call it. Read roles apply to
+// GET and HEAD; write roles apply to every other method. Public routes skip
+// authentication entirely. Every write is recorded in the activity log,
+// except on public routes that name no entity.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+	public  bool
+	read    []string
+	write   []string
+	// entity names what writes on this route change in the activity log.
+	entity string
+}
+
+// apiRoutes is the declarative route table for /api.
+func (s *Server) apiRoutes() []route {
+	return []route{
+		{pattern: "/api/health", handler: s.handleHealth, public: true},
+		{pattern: "/api/schema", handler: s.handleSchema, read: adminRoles, write: adminRoles},
+		{pattern: "/api/auth/login", handler: s.handleLogin, public: true},
+		{pattern: "/api/auth/refresh", handler: s.handleRefresh, public: true},
+		{pattern: "/api/auth/logout", handler: s.handleLogout, read: anyRole, write: anyRole, entity: "session"},
+		{pattern: "/api/auth/sessions/revoke", handler: s.handleRevokeSessions, read: adminRoles, write: adminRoles, entity: "user"},
+		{pattern: "/api/auth/reset", handler: s.handlePasswordReset, public: true, entity: "user"},
+
+		{pattern: "/api/users", handler: s.handleUsers, read: adminRoles, write: adminRoles, entity: "user"},
+		{pattern: "/api/users/", handler: s.handleUser, read: adminRoles, write: adminRoles, entity: "user"},
+		{pattern: "/api/me", handler: s.handleMe, read: anyRole, write: anyRole, entity: "user"},
+		{pattern: "/api/me/password", handler: s.handleMePassword, read: anyRole, write: anyRole, entity: "user"},
+		{pattern: "/api/reps", handler: s.handleReps, read: anyRole, write: adminRoles, entity: "rep"},
+
+		{pattern: "/api/appointments", handler: s.handleAppointments, read: anyRole, write: staffRoles, entity: "appointment"},
+		{pattern: "/api/appointments/", handler: s.handleAppointment, read: anyRole, write: staffRoles, entity: "appointment"},
+
+		{pattern: "/api/orders", handler: s.handleOrders, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/assign", handler: s.handleOrderAssign, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/lookup", handler: s.handleOrderLookup, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/conflicts", handler: s.handleOrderConflicts, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/propagate", handler: s.handleOrderPropagate, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/due", handler: s.handleOrdersDue, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/due/review", handler: s.handleOrdersDueReview, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/due/snooze", handler: s.handleOrdersDueSnooze, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/awaiting_payment", handler: s.handleOrdersAwaitingPayment, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/in_production", handler: s.handleOrdersInProduction, read: anyRole, write: staffRoles, entity: "order"},
+		{pattern: "/api/orders/deadlines", handler: s.handleOrdersDeadlines, read: anyRole, write: staffRoles, entity: "deadline"},
+		{pattern: "/api/orders/", handler: s.handleOrderDeadlines, read: anyRole, write: staffRoles, entity: "deadline"},
+
+		{pattern: "/api/payments", handler: s.handlePayments, read: anyRole, write: staffRoles, entity: "payment"},
+		{pattern: "/api/payments/balance", handler: s.handlePaymentBalance, read: anyRole, write: staffRoles, entity: "payment"},
+		{pattern: "/api/payments/", handler: s.handlePayment, read: anyRole, write: staffRoles, entity: "payment"},
+
+		{pattern: "/api/stones", handler: s.handleStones, read: anyRole, write: staffRoles, entity: "stone"},
+		{pattern: "/api/stones/propose", handler: s.handleStonesPropose, read: anyRole, write: staffRoles, entity: "stone"},
+		{pattern: "/api/stones/approve", handler: s.handleStonesApprove, read: anyRole, write: staffRoles, entity: "stone"},
+		{pattern: "/api/stones/deliver", handler: s.handleStonesDeliver, read: anyRole, write: staffRoles, entity: "stone"},
+		{pattern: "/api/stones/decision", handler: s.handleStonesDecision, read: anyRole, write: staffRoles, entity: "stone"},
+		{pattern: "/api/stones/", handler: s.handleStone, read: anyRole, write: staffRoles, entity: "stone"},
+
+		{pattern: "/api/wax", handler: s.handleWaxRequests, read: anyRole, write: staffRoles, entity: "wax_request"},
+		{pattern: "/api/wax/", handler: s.handleWaxRequest, read: anyRole, write: staffRoles, entity: "wax_request"},
+
+		{pattern: "/api/design3d", handler: s.handleDesign3D, read: anyRole, write: staffRoles, entity: "design3d"},
+		{pattern: "/api/design3d/start", handler: s.handleDesign3DStart, read: anyRole, write: staffRoles, entity: "design3d"},
+		{pattern: "/api/design3d/revision", handler: s.handleDesign3DRevision, read: anyRole, write: staffRoles, entity: "design3d"},
+		{pattern: "/api/design3d/preview", handler: s.handleDesign3DPreview, read: anyRole, write: staffRoles, entity: "design3d"},
+		{pattern: "/api/design3d/", handler: s.handleDesign3DEntry, read: anyRole, write: staffRoles, entity: "design3d"},
+
+		{pattern: "/api/status-log", handler: s.handleStatusLog, read: anyRole, write: staffRoles, entity: "status_log"},
+
+		{pattern: "/api/reminders", handler: s.handleReminders, read: anyRole, write: staffRoles, entity: "reminder"},
+		{pattern: "/api/reminders/due", handler: s.handleRemindersDue, read: anyRole, write: staffRoles, entity: "reminder"},
+		{pattern: "/api/reminders/snooze", handler: s.handleRemindersSnooze, read: anyRole, write: staffRoles, entity: "reminder"},
+		{pattern: "/api/reminders/cancel", handler: s.handleRemindersCancel, read: anyRole, write: staffRoles, entity: "reminder"},
+
+		{pattern: "/api/reports/kpis", handler: s.handleReportKPIs, read: anyRole, write: adminRoles, entity: "report"},
+		{pattern: "/api/reports/by-status", handler: s.handleReportByStatus, read: anyRole, write: adminRoles, entity: "report"},
+		{pattern: "/api/reports/by-rep", handler: s.handleReportByRep, read: anyRole, write: adminRoles, entity: "report"},
+		{pattern: "/api/reports/client-status", handler: s.handleReportClientStatus, read: anyRole, write: adminRoles, entity: "report"},
+
+		{pattern: "/api/search", handler: s.handleSearch, read: anyRole, write: adminRoles, entity: "search"},
+
+		{pattern: "/api/audit", handler: s.handleActivity, read: anyRole, write: adminRoles},
+		{pattern: "/api/audit/findings", handler: s.handleAuditFindings, read: managerRoles, write: managerRoles, entity: "audit"},
+		{pattern: "/api/audit/run", handler: s.handleAuditRun, read: managerRoles, write: managerRoles, entity: "audit"},
+
+		{pattern: "/api/jobs", handler: s.handleJobs, read: adminRoles, write: adminRoles, entity: "job"},
+		{pattern: "/api/jobs/run", handler: s.handleJobsRun, read: adminRoles, write: adminRoles, entity: "job"},
+		{pattern: "/api/jobs/history", handler: s.handleJobsHistory, read: adminRoles, write: adminRoles, entity: "job"},
+	}
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.apiRoutes() {
+		switch {
+		case rt.public && rt.entity != "":
+			rt := rt
+			mux.HandleFunc(rt.pattern, func(w http.ResponseWriter, r *http.Request) {
+				s.withActivity(rt, nil, w, r)
+			})
+		case rt.public:
+			mux.HandleFunc(rt.pattern, rt.handler)
+		default:
+			mux.Handle(rt.pattern, s.authorize(rt))
+		}
+	}
+	mux.Handle("/", newStaticHandler(web.Assets, s.cfg.Server.WebDir))
+	return mux
+}
+
+// authorize authenticates the bearer token and enforces the route's roles.
+func (s *Server) authorize(rt route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := s.authorizeRequest(r)
+		if err != nil {
+			s.writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		allowed := rt.write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			allowed = rt.read
+		}
+		role := auth.NormalizeRole(claims.Role)
+		if !hasRole(allowed, role) {
+			s.logger.Info("request_forbidden", map[string]any{"path": r.URL.Path, "method": r.Method, "role": role})
+			s.writeJSON(w, http.StatusForbidden, map[string]any{
+				"error":        "forbidden",
+				"code":         "insufficient_role",
+				"role":         role,
+				"allowedRoles": allowed,
+			})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKeyClaims, claims)
+		s.withActivity(rt, claims, w, r.WithContext(ctx))
+	})
+}
+
+func hasRole(allowed []string, role string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(a, role) {
+			return true
+		}
+	}
+	return false
+}
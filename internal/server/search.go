@@ -0,0 +1,50 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/example/vvsapp/internal/search"
+)
+
+// handleSearch serves the top-bar search:
+// GET /api/search?q=&types=customers,orders,payments&limit=&mine=1.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	rep, err := s.repFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q := search.Query{
+		Q:     r.URL.Query().Get("q"),
+		Rep:   rep,
+		Limit: queryInt(r, "limit", 0),
+	}
+	for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			q.Types = append(q.Types, t)
+		}
+	}
+	res, err := s.searchSvc.Search(r.Context(), q)
+	if err != nil {
+		s.writeSearchError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) writeSearchError(w http.ResponseWriter, err error) {
+	var verr *search.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("search_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
@@ -2,16 +2,33 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/audit"
 	"github.com/example/vvsapp/internal/auth"
+	"github.com/example/vvsapp/internal/clientstatus"
 	"github.com/example/vvsapp/internal/config"
 	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/deadlines"
+	"github.com/example/vvsapp/internal/design3d"
+	"github.com/example/vvsapp/internal/diamonds"
+	"github.com/example/vvsapp/internal/jobs"
 	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/payments"
+	"github.com/example/vvsapp/internal/reminders"
+	"github.com/example/vvsapp/internal/reports"
+	"github.com/example/vvsapp/internal/reps"
+	"github.com/example/vvsapp/internal/search"
+	"github.com/example/vvsapp/internal/wax"
 )
 
 // contextKey helps avoid collisions when storing values in request contexts.
@@ -21,64 +38,89 @@ const contextKeyClaims contextKey = "authClaims"
 
 // Server wires HTTP handlers, authentication, and diagnostics.
 type Server struct {
-	cfg     *config.Config
-	logger  *logging.Logger
-	authSvc *auth.Service
-	db      DB
-	router  http.Handler
+	cfg             *config.Config
+	logger          *logging.Logger
+	authSvc         *auth.Service
+	appointmentsSvc *appointments.Service
+	ordersSvc       *orders.Service
+	queues          *orders.Queues
+	paymentsSvc     *payments.Service
+	diamondsSvc     *diamonds.Service
+	waxSvc          *wax.Service
+	design3dSvc     *design3d.Service
+	deadlinesSvc    *deadlines.Service
+	clientStatusSvc *clientstatus.Service
+	remindersSvc    *reminders.Service
+	jobs            *jobs.Runner
+	repsSvc         *reps.Service
+	auditSvc        *audit.Service
+	activitySvc     *activity.Service
+	reportsSvc      *reports.Service
+	searchSvc       *search.Service
+	db              DB
+	router          http.Handler
+}
+
+// Services groups the domain services exposed over HTTP.
+type Services struct {
+	Auth         *auth.Service
+	Appointments *appointments.Service
+	Orders       *orders.Service
+	Queues       *orders.Queues
+	Payments     *payments.Service
+	Diamonds     *diamonds.Service
+	Wax          *wax.Service
+	Design3D     *design3d.Service
+	Deadlines    *deadlines.Service
+	ClientStatus *clientstatus.Service
+	Reminders    *reminders.Service
+	Jobs         *jobs.Runner
+	Reps         *reps.Service
+	Audit        *audit.Service
+	Activity     *activity.Service
+	Reports      *reports.Service
+	Search       *search.Service
 }
 
 // DB defines the subset of database/sql used by the HTTP server (for easier testing).
 type DB interface {
 	PingContext(ctx context.Context) error
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
 // New constructs a server with routes and middleware applied.
-func New(cfg *config.Config, logger *logging.Logger, database DB, authSvc *auth.Service) *Server {
+func New(cfg *config.Config, logger *logging.Logger, database DB, svcs Services) *Server {
 	srv := &Server{
-		cfg:     cfg,
-		logger:  logger,
-		authSvc: authSvc,
-		db:      database,
+		cfg:             cfg,
+		logger:          logger,
+		authSvc:         svcs.Auth,
+		appointmentsSvc: svcs.Appointments,
+		ordersSvc:       svcs.Orders,
+		queues:          svcs.Queues,
+		paymentsSvc:     svcs.Payments,
+		diamondsSvc:     svcs.Diamonds,
+		waxSvc:          svcs.Wax,
+		design3dSvc:     svcs.Design3D,
+		deadlinesSvc:    svcs.Deadlines,
+		clientStatusSvc: svcs.ClientStatus,
+		remindersSvc:    svcs.Reminders,
+		jobs:            svcs.Jobs,
+		repsSvc:         svcs.Reps,
+		auditSvc:        svcs.Audit,
+		activitySvc:     svcs.Activity,
+		reportsSvc:      svcs.Reports,
+		searchSvc:       svcs.Search,
+		db:              database,
 	}
 	srv.router = srv.routes()
 	return srv
 }
 
-func (s *Server) routes() http.Handler {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/health", s.handleHealth)
-	mux.HandleFunc("/api/auth/login", s.handleLogin)
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasPrefix(r.URL.Path, "/api/") && !isPublicAPI(r.URL.Path) {
-			claims, err := s.authorizeRequest(r)
-			if err != nil {
-				s.writeError(w, http.StatusUnauthorized, err)
-				return
-			}
-			ctx := context.WithValue(r.Context(), contextKeyClaims, claims)
-			r = r.WithContext(ctx)
-		}
-
-		mux.ServeHTTP(w, r)
-	})
-}
-
 // ServeHTTP makes Server implement http.Handler.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
-func isPublicAPI(path string) bool {
-	switch path {
-	case "/api/health", "/api/auth/login":
-		return true
-	default:
-		return false
-	}
-}
-
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
@@ -91,13 +133,37 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		dbStatus = "error"
 	}
 
+	// Only the summary is public; /api/schema lists the migrations.
+	schema := map[string]any{"state": "error"}
+	if dbStatus == "ok" {
+		if st, err := db.Status(ctx, s.db); err != nil {
+			s.logger.Error("schema_status_failed", map[string]any{"error": err.Error()})
+		} else {
+			schema = map[string]any{"state": st.State, "version": st.Current, "latest": st.Latest, "pending": st.Pending}
+		}
+	}
+
 	s.writeJSON(w, http.StatusOK, map[string]any{
 		"status": "ok",
 		"db":     dbStatus,
+		"schema": schema,
 		"nowIso": time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	st, err := db.Status(r.Context(), s.db)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, st)
+}
+
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
@@ -117,17 +183,17 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, claims, err := s.authSvc.Authenticate(r.Context(), strings.ToLower(strings.TrimSpace(payload.Email)), payload.Password)
+	tokens, claims, err := s.authSvc.Authenticate(r.Context(), strings.ToLower(strings.TrimSpace(payload.Email)), payload.Password)
+	if errors.Is(err, auth.ErrUserDisabled) {
+		s.writeError(w, http.StatusForbidden, err)
+		return
+	}
 	if err != nil {
 		s.writeError(w, http.StatusUnauthorized, errors.New("invalid credentials"))
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, map[string]any{
-		"token": token,
-		"role":  claims.Role,
-		"email": claims.Email,
-	})
+	s.writeTokens(w, tokens, claims)
 }
 
 func (s *Server) authorizeRequest(r *http.Request) (*auth.Claims, error) {
@@ -143,7 +209,10 @@ func (s *Server) authorizeRequest(r *http.Request) (*auth.Claims, error) {
 	if token == "" {
 		return nil, errors.New("empty token")
 	}
-	claims, err := s.authSvc.ParseToken(token)
+	claims, err := s.authSvc.ParseToken(r.Context(), token)
+	if errors.Is(err, auth.ErrSessionRevoked) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, errors.New("invalid token")
 	}
@@ -156,12 +225,48 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// decodeJSON reads a JSON request body into dst, rejecting unknown fields.
+func decodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return errors.New("invalid JSON payload")
+	}
+	return nil
+}
+
+// queryInt parses an integer query parameter, returning def when absent or malformed.
+func queryInt(r *http.Request, key string, def int) int {
+	raw := strings.TrimSpace(r.URL.Query().Get(key))
+	if raw == "" {
+		return def
+	}
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// pathID returns the trailing path segment after prefix (e.g. /api/appointments/{id}).
+func pathID(r *http.Request, prefix string) string {
+	return strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+}
+
 func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
 	s.writeJSON(w, status, map[string]any{
 		"error": err.Error(),
 	})
 }
 
+// actorFromRequest names the authenticated user for audit columns.
+func actorFromRequest(r *http.Request) string {
+	if claims, ok := ClaimsFromContext(r.Context()); ok {
+		return claims.Email
+	}
+	return ""
+}
+
 // ClaimsFromContext extracts auth claims from request context when available.
 func ClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
 	val, ok := ctx.Value(contextKeyClaims).(*auth.Claims)
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/auth"
+)
+
+// handleRefresh rotates a refresh token into a new token pair: POST /api/auth/refresh.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tokens, claims, err := s.authSvc.Refresh(r.Context(), payload.RefreshToken)
+	if err != nil {
+		s.writeSessionError(w, err)
+		return
+	}
+	s.writeTokens(w, tokens, claims)
+}
+
+// handleLogout revokes the caller's session: POST /api/auth/logout.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	claims, _ := ClaimsFromContext(r.Context())
+	if err := s.authSvc.Logout(r.Context(), claims.SessionID); err != nil {
+		s.writeSessionError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action, e.EntityID = "logout", claims.SessionID
+	})
+	s.writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleRevokeSessions revokes every session for a user: POST /api/auth/sessions/revoke {"email"}.
+func (s *Server) handleRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if payload.Email == "" {
+		s.writeError(w, http.StatusBadRequest, errors.New("email is required"))
+		return
+	}
+	n, err := s.authSvc.RevokeUserSessions(r.Context(), payload.Email)
+	if err != nil {
+		s.writeSessionError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action, e.Summary = "revoke_sessions", fmt.Sprintf("Revoked %d sessions for %s", n, payload.Email)
+	})
+	s.writeJSON(w, http.StatusOK, map[string]any{"revoked": n})
+}
+
+func (s *Server) writeTokens(w http.ResponseWriter, tokens *auth.Tokens, claims *auth.Claims) {
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresAt":    tokens.ExpiresAt.UTC().Format(time.RFC3339),
+		"role":         claims.Role,
+		"email":        claims.Email,
+	})
+}
+
+func (s *Server) writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidRefreshToken), errors.Is(err, auth.ErrRefreshTokenReused), errors.Is(err, auth.ErrSessionRevoked):
+		s.writeError(w, http.StatusUnauthorized, err)
+	case errors.Is(err, auth.ErrUserNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	default:
+		s.logger.Error("session_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
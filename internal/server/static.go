@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spaIndex is served for client-side routes that do not map to a file.
+const spaIndex = "index.html"
+
+var staticContentTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".js":   "text/javascript; charset=utf-8",
+	".json": "application/json",
+	".svg":  "image/svg+xml",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".ico":  "image/x-icon",
+	".txt":  "text/plain; charset=utf-8",
+}
+
+// staticHandler serves UI assets from an optional on-disk override directory,
+// falling back to the files embedded in the binary.
+type staticHandler struct {
+	embedded fs.FS
+	override fs.FS
+	started  time.Time
+
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+type staticAsset struct {
+	name    string
+	content []byte
+	modTime time.Time
+	etag    string
+}
+
+func newStaticHandler(embedded fs.FS, overrideDir string) *staticHandler {
+	h := &staticHandler{
+		embedded: embedded,
+		started:  time.Now().UTC().Truncate(time.Second),
+		etags:    make(map[string]string),
+	}
+	if overrideDir != "" {
+		if info, err := os.Stat(overrideDir); err == nil && info.IsDir() {
+			h.override = os.DirFS(overrideDir)
+		}
+	}
+	return h
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = spaIndex
+	}
+
+	asset, err := h.open(name)
+	if errors.Is(err, fs.ErrNotExist) && isClientRoute(name) {
+		asset, err = h.open(spaIndex)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "unable to read asset", http.StatusInternalServerError)
+		return
+	}
+
+	if ct, ok := staticContentTypes[path.Ext(asset.name)]; ok {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", asset.etag)
+	http.ServeContent(w, r, asset.name, asset.modTime, bytes.NewReader(asset.content))
+}
+
+// open resolves name against the override directory first, then the embedded bundle.
+func (h *staticHandler) open(name string) (*staticAsset, error) {
+	if !fs.ValidPath(name) {
+		return nil, fs.ErrNotExist
+	}
+	if h.override != nil {
+		asset, err := h.read(h.override, name, false)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return asset, err
+		}
+	}
+	return h.read(h.embedded, name, true)
+}
+
+func (h *staticHandler) read(fsys fs.FS, name string, immutable bool) (*staticAsset, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", name, err)
+	}
+	if info.IsDir() {
+		return nil, fs.ErrNotExist
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+
+	modTime := info.ModTime()
+	if modTime.IsZero() {
+		// Embedded files carry no timestamp; the process start stands in for the build.
+		modTime = h.started
+	}
+
+	asset := &staticAsset{name: name, content: content, modTime: modTime}
+	if immutable {
+		asset.etag = h.cachedETag(name, content)
+	} else {
+		asset.etag = computeETag(content)
+	}
+	return asset, nil
+}
+
+func (h *staticHandler) cachedETag(name string, content []byte) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if tag, ok := h.etags[name]; ok {
+		return tag
+	}
+	tag := computeETag(content)
+	h.etags[name] = tag
+	return tag
+}
+
+func computeETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// isClientRoute reports whether a missing path should fall back to the SPA
+// shell (e.g. /customers or /orders/123) rather than returning 404. Unknown
+// API paths never fall back so clients get a real 404.
+func isClientRoute(name string) bool {
+	if name == "api" || strings.HasPrefix(name, "api/") {
+		return false
+	}
+	return path.Ext(name) == ""
+}
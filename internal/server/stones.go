@@ -0,0 +1,177 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/diamonds"
+)
+
+// handleStones lists stones: GET /api/stones?rootApptId=&orderStatus=&query=.
+// With only rootApptId it returns the client view (counts, summary, JSON
+// lines and Center Stone Order Status).
+func (s *Server) handleStones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("rootApptId") != "" && q.Get("orderStatus") == "" && q.Get("query") == "" {
+		client, err := s.diamondsSvc.ClientView(r.Context(), q.Get("rootApptId"))
+		if err != nil {
+			s.writeStoneError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, client)
+		return
+	}
+	list, err := s.diamondsSvc.List(r.Context(), diamonds.Filter{
+		RootApptID:  q.Get("rootApptId"),
+		OrderStatus: q.Get("orderStatus"),
+		Query:       q.Get("query"),
+		Limit:       queryInt(r, "limit", 0),
+		Offset:      queryInt(r, "offset", 0),
+	})
+	if err != nil {
+		s.writeStoneError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"stones": list})
+}
+
+// handleStone returns one stone: GET /api/stones/{stoneId}.
+func (s *Server) handleStone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	id := pathID(r, "/api/stones/")
+	if id == "" {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	st, err := s.diamondsSvc.Get(r.Context(), id)
+	if err != nil {
+		s.writeStoneError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, st)
+}
+
+// handleStonesPropose adds proposed stones: POST /api/stones/propose.
+func (s *Server) handleStonesPropose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload diamonds.ProposeInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.diamondsSvc.Propose(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeStoneError(w, err)
+		return
+	}
+	s.noteStones(r, "propose_stones", "Proposed", res)
+	s.writeJSON(w, http.StatusCreated, res)
+}
+
+// handleStonesApprove approves or declines proposals: POST /api/stones/approve.
+func (s *Server) handleStonesApprove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload diamonds.ApproveInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.diamondsSvc.ApproveOrders(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeStoneError(w, err)
+		return
+	}
+	s.noteStones(r, "approve_stones", "Approved/declined", res)
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+// handleStonesDeliver confirms memo delivery: POST /api/stones/deliver.
+func (s *Server) handleStonesDeliver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload diamonds.DeliveryInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.diamondsSvc.ConfirmDelivery(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeStoneError(w, err)
+		return
+	}
+	s.noteStones(r, "deliver_stones", "Delivered", res)
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+// handleStonesDecision records Purchase/Return and holds: POST /api/stones/decision.
+func (s *Server) handleStonesDecision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload diamonds.DecisionInput
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.diamondsSvc.RecordDecisions(r.Context(), payload, actorFromRequest(r))
+	if err != nil {
+		s.writeStoneError(w, err)
+		return
+	}
+	s.noteStones(r, "decide_stones", "Recorded decisions on", res)
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) noteStones(r *http.Request, action, verb string, res *diamonds.Result) {
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action = action
+		ids := make([]string, len(res.Stones))
+		for i, st := range res.Stones {
+			ids[i] = st.StoneID
+		}
+		e.EntityID = strings.Join(ids, ",")
+		e.Summary = fmt.Sprintf("%s %d stone(s)", verb, len(res.Stones))
+		if len(res.Clients) == 1 {
+			e.RootApptID = res.Clients[0].RootApptID
+			e.Summary += "; " + res.Clients[0].CenterStoneOrderStatus
+		}
+	})
+}
+
+func (s *Server) writeStoneError(w http.ResponseWriter, err error) {
+	var (
+		verr       *diamonds.ValidationError
+		transition *diamonds.TransitionError
+	)
+	switch {
+	case errors.Is(err, diamonds.ErrNotFound), errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &transition):
+		s.writeError(w, http.StatusConflict, err)
+	case errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("stones_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/auth"
+)
+
+// handleUsers lists accounts (GET) or creates/invites one (POST): /api/users.
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.authSvc.ListUsers(r.Context())
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"users": list})
+	case http.MethodPost:
+		var payload auth.CreateUserInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.canonicalRep(r, &payload.RepName); err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		u, reset, err := s.authSvc.CreateUser(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.EntityID, e.Summary = strconv.FormatInt(u.ID, 10), u.Email
+			e.Changes = activity.Diff(nil, u)
+		})
+		s.writeJSON(w, http.StatusCreated, map[string]any{"user": u, "reset": reset})
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handleUser serves GET and PATCH on /api/users/{id} and
+// POST /api/users/{id}/reset-password.
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	rest := pathID(r, "/api/users/")
+	idPart, action, _ := strings.Cut(rest, "/")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch {
+	case action == "reset-password" && r.Method == http.MethodPost:
+		reset, err := s.authSvc.IssueResetToken(r.Context(), id, actorFromRequest(r))
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action, e.EntityID = "issue_password_reset", idPart
+		})
+		s.writeJSON(w, http.StatusCreated, reset)
+	case action != "":
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+	case r.Method == http.MethodGet:
+		u, err := s.authSvc.GetUser(r.Context(), id)
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, u)
+	case r.Method == http.MethodPatch:
+		var payload auth.UpdateUserInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if payload.RepName != nil {
+			if err := s.canonicalRep(r, payload.RepName); err != nil {
+				s.writeUserError(w, err)
+				return
+			}
+		}
+		before, _ := s.authSvc.GetUser(r.Context(), id)
+		u, err := s.authSvc.UpdateUser(r.Context(), id, payload, actorFromRequest(r))
+		if err != nil {
+			s.writeUserError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.EntityID, e.Summary = idPart, u.Email
+			e.Changes = activity.Diff(before, u)
+		})
+		s.writeJSON(w, http.StatusOK, u)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handleMePassword changes the caller's own password: POST /api/me/password.
+func (s *Server) handleMePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	claims, _ := ClaimsFromContext(r.Context())
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+		return
+	}
+	if err := s.authSvc.ChangePassword(r.Context(), userID, payload.CurrentPassword, payload.NewPassword, claims.SessionID); err != nil {
+		s.writeUserError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Action, e.EntityID = "change_password", claims.Subject
+	})
+	s.writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handlePasswordReset redeems an admin-issued reset token: POST /api/auth/reset.
+func (s *Server) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	var payload struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	u, err := s.authSvc.ResetPassword(r.Context(), payload.Token, payload.NewPassword)
+	if err != nil {
+		s.writeUserError(w, err)
+		return
+	}
+	noteActivity(r, func(e *activity.Entry) {
+		e.Actor, e.ActorRole = u.Email, auth.NormalizeRole(u.Role)
+		e.Action, e.EntityID, e.Summary = "reset_password", strconv.FormatInt(u.ID, 10), u.Email
+	})
+	s.writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) writeUserError(w http.ResponseWriter, err error) {
+	var verr *auth.ValidationError
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, auth.ErrEmailTaken):
+		s.writeError(w, http.StatusConflict, err)
+	case errors.Is(err, auth.ErrWrongPassword), errors.Is(err, auth.ErrInvalidResetToken), errors.As(err, &verr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("users_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
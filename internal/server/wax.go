@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/example/vvsapp/internal/activity"
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/orders"
+	"github.com/example/vvsapp/internal/wax"
+)
+
+// handleWaxRequests serves GET (list, with the status vocabulary) and POST
+// (create) on /api/wax. ?pending=1 is the pending-requests dialog.
+func (s *Server) handleWaxRequests(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		list, err := s.waxSvc.List(r.Context(), wax.Filter{
+			Brand:      q.Get("brand"),
+			SO:         q.Get("so"),
+			RootApptID: q.Get("rootApptId"),
+			Status:     q.Get("status"),
+			Pending:    q.Get("pending") == "1" || q.Get("pending") == "true",
+			Limit:      queryInt(r, "limit", 0),
+			Offset:     queryInt(r, "offset", 0),
+		})
+		if err != nil {
+			s.writeWaxError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"requests": list, "statusOptions": s.waxSvc.Statuses()})
+	case http.MethodPost:
+		var payload wax.CreateInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		req, err := s.waxSvc.Create(r.Context(), payload, actorFromRequest(r))
+		if err != nil {
+			s.writeWaxError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.Action = "request_wax"
+			e.EntityID, e.RootApptID = req.WaxRequestID, req.RootApptID
+			e.Summary = fmt.Sprintf("Wax print for %s %s (%s)", req.Brand, req.SONumber, req.Priority)
+		})
+		s.writeJSON(w, http.StatusCreated, req)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// handleWaxRequest serves GET and PATCH on /api/wax/{waxRequestId}.
+func (s *Server) handleWaxRequest(w http.ResponseWriter, r *http.Request) {
+	id := pathID(r, "/api/wax/")
+	if id == "" {
+		s.writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		req, err := s.waxSvc.Get(r.Context(), id)
+		if err != nil {
+			s.writeWaxError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, req)
+	case http.MethodPatch:
+		var payload wax.UpdateInput
+		if err := decodeJSON(r, &payload); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		before, _ := s.waxSvc.Get(r.Context(), id)
+		req, err := s.waxSvc.Update(r.Context(), id, payload, actorFromRequest(r))
+		if err != nil {
+			s.writeWaxError(w, err)
+			return
+		}
+		noteActivity(r, func(e *activity.Entry) {
+			e.EntityID, e.RootApptID = req.WaxRequestID, req.RootApptID
+			e.Summary = fmt.Sprintf("%s %s: %s", req.Brand, req.SONumber, req.Status)
+			e.Changes = activity.Diff(before, req)
+		})
+		s.writeJSON(w, http.StatusOK, req)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (s *Server) writeWaxError(w http.ResponseWriter, err error) {
+	var (
+		verr      *wax.ValidationError
+		orderVerr *orders.ValidationError
+	)
+	switch {
+	case errors.Is(err, wax.ErrNotFound), errors.Is(err, orders.ErrNotFound), errors.Is(err, appointments.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &verr), errors.As(err, &orderVerr):
+		s.writeError(w, http.StatusBadRequest, err)
+	default:
+		s.logger.Error("wax_request_failed", map[string]any{"error": err.Error()})
+		s.writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+	}
+}
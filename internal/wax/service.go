@@ -0,0 +1,543 @@
+package wax
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// ErrNotFound is returned when a wax request does not exist.
+var ErrNotFound = errors.New("wax request not found")
+
+// ValidationError reports input that cannot be recorded.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Priorities a rep can ask for; Normal is the default.
+var Priorities = []string{"Low", "Normal", "High", "Rush"}
+
+// Request mirrors one row of 05_Wax_Requests. DaysUntilDeadline, Overdue
+// and DaysLate are the sheet's metric columns, computed when read.
+type Request struct {
+	WaxRequestID       string `json:"waxRequestId"`
+	RootApptID         string `json:"rootApptId"`
+	SONumber           string `json:"so"`
+	Brand              string `json:"brand"`
+	CustomerName       string `json:"customerName"`
+	AssignedRep        string `json:"assignedRep"`
+	AssistedRep        string `json:"assistedRep"`
+	RequestedBy        string `json:"requestedBy"`
+	Priority           string `json:"priority"`
+	RequestedDate      string `json:"requestedDate"`
+	NeededBy           string `json:"neededBy"`
+	Status             string `json:"status"`
+	AdminDeadline      string `json:"adminDeadline"`
+	EstimatedPrintDate string `json:"estimatedPrintDate"`
+	CompletedPrintDate string `json:"completedPrintDate"`
+	StatusNotes        string `json:"statusNotes"`
+	CreatedAt          string `json:"createdAt"`
+	UpdatedBy          string `json:"updatedBy"`
+	UpdatedAt          string `json:"updatedAt"`
+	// DaysUntilDeadline is Wax Deadline (Admin) − today while the request
+	// is open.
+	DaysUntilDeadline *int `json:"daysUntilDeadline"`
+	Overdue           bool `json:"overdue"`
+	// DaysLate is Completed Print Date − Needed By (Rep).
+	DaysLate *int `json:"daysLate"`
+}
+
+// CreateInput mirrors wax_onRequestSubmit_. The SO must be in the registry;
+// the customer and reps are copied from its root appointment.
+type CreateInput struct {
+	Brand       string `json:"brand"`
+	SO          string `json:"so"`
+	RootApptID  string `json:"rootApptId"`
+	NeededBy    string `json:"neededBy"`
+	Priority    string `json:"priority"`
+	RequestedBy string `json:"requestedBy"`
+	StatusNotes string `json:"statusNotes"`
+}
+
+// UpdateInput carries the admin's edits from the pending dialog. Nil fields
+// are left unchanged; an empty date clears it.
+type UpdateInput struct {
+	Status             *string `json:"status"`
+	AdminDeadline      *string `json:"adminDeadline"`
+	EstimatedPrintDate *string `json:"estimatedPrintDate"`
+	CompletedPrintDate *string `json:"completedPrintDate"`
+	StatusNotes        *string `json:"statusNotes"`
+}
+
+// Filter narrows List results. Empty fields are ignored.
+type Filter struct {
+	Brand      string
+	SO         string
+	RootApptID string
+	Status     string
+	// Pending hides requests in a closed status, as the pending dialog does.
+	Pending bool
+	Limit   int
+	Offset  int
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// Service owns wax print requests and mirrors each order's latest request
+// onto the SO registry.
+type Service struct {
+	db       *sql.DB
+	appts    *appointments.Service
+	orders   *orders.Service
+	statuses []string
+	closed   map[string]bool
+	loc      *time.Location
+	logger   *logging.Logger
+	now      func() time.Time
+	nextID   func(ctx context.Context, q queryer, day time.Time) (string, error)
+}
+
+// NewService constructs a wax service. Dates default to today in loc.
+func NewService(db *sql.DB, appts *appointments.Service, ordersSvc *orders.Service, cfg config.WaxConfig, loc *time.Location, logger *logging.Logger) (*Service, error) {
+	var statuses []string
+	for _, st := range cfg.Statuses {
+		if st = strings.TrimSpace(st); st != "" {
+			statuses = append(statuses, st)
+		}
+	}
+	if len(statuses) == 0 {
+		return nil, errors.New("wax.statuses must list at least one status")
+	}
+	closed := make(map[string]bool, len(cfg.ClosedStatuses))
+	for _, st := range cfg.ClosedStatuses {
+		closed[strings.ToLower(strings.TrimSpace(st))] = true
+	}
+	return &Service{
+		db:       db,
+		appts:    appts,
+		orders:   ordersSvc,
+		statuses: statuses,
+		closed:   closed,
+		loc:      loc,
+		logger:   logger,
+		now:      time.Now,
+		nextID:   nextID,
+	}, nil
+}
+
+const requestColumns = `wax_request_id, root_appt_id, so_number, brand, customer_name, assigned_rep, assisted_rep,
+        requested_by, priority, requested_date, needed_by, status, admin_deadline, estimated_print_date,
+        completed_print_date, status_notes, created_at, updated_by, updated_at`
+
+// Statuses returns the Wax Print Status vocabulary in display order.
+func (s *Service) Statuses() []string {
+	return append([]string(nil), s.statuses...)
+}
+
+// Create files a wax print request for an SO in the first status and
+// mirrors it onto the order.
+func (s *Service) Create(ctx context.Context, in CreateInput, actor string) (*Request, error) {
+	order, err := s.orders.Lookup(ctx, in.Brand, in.SO)
+	if err != nil {
+		return nil, err
+	}
+	if root := strings.TrimSpace(in.RootApptID); root != "" && root != order.RootApptID {
+		return nil, &ValidationError{Field: "rootApptId", Message: fmt.Sprintf("SO %s belongs to %s", order.SOPretty, order.RootApptID)}
+	}
+	appt, err := s.appts.Get(ctx, order.RootApptID)
+	if err != nil {
+		return nil, err
+	}
+	neededBy, err := parseDate("neededBy", in.NeededBy)
+	if err != nil {
+		return nil, err
+	}
+	priority := "Normal"
+	if v := strings.TrimSpace(in.Priority); v != "" {
+		if priority = matchFold(Priorities, v); priority == "" {
+			return nil, &ValidationError{Field: "priority", Message: "must be one of " + strings.Join(Priorities, ", ")}
+		}
+	}
+	requestedBy := strings.TrimSpace(in.RequestedBy)
+	if requestedBy == "" {
+		requestedBy = actor
+	}
+
+	now := s.now()
+	stamp := now.UTC().Format(time.RFC3339)
+	day := now.In(s.loc)
+	req := &Request{
+		RootApptID:    order.RootApptID,
+		SONumber:      order.SOPretty,
+		Brand:         order.Brand,
+		CustomerName:  appt.CustomerName,
+		AssignedRep:   appt.AssignedRep,
+		AssistedRep:   appt.AssistedRep,
+		RequestedBy:   requestedBy,
+		Priority:      priority,
+		RequestedDate: day.Format("2006-01-02"),
+		NeededBy:      neededBy,
+		Status:        s.statuses[0],
+		StatusNotes:   strings.TrimSpace(in.StatusNotes),
+		CreatedAt:     stamp,
+		UpdatedBy:     actor,
+		UpdatedAt:     stamp,
+	}
+
+	// A concurrent request can draw the same WAX ID between our read and
+	// insert; draw again in a fresh transaction.
+	for attempt := 1; ; attempt++ {
+		err = s.insert(ctx, req, day)
+		if !db.IsUniqueViolation(err) || attempt == insertAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("wax_request_created", map[string]any{
+		"wax_request_id": req.WaxRequestID,
+		"so":             req.SONumber,
+		"root_appt_id":   req.RootApptID,
+	})
+	s.withMetrics(req)
+	return req, nil
+}
+
+// insertAttempts bounds how often Create draws a new ID after losing a race.
+const insertAttempts = 5
+
+// insert numbers req, stores it and mirrors it onto the order in one
+// transaction.
+func (s *Service) insert(ctx context.Context, req *Request, day time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin create wax request: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if req.WaxRequestID, err = s.nextID(ctx, tx, day); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO wax_requests(`+requestColumns+`)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.WaxRequestID, req.RootApptID, req.SONumber, req.Brand, req.CustomerName, req.AssignedRep, req.AssistedRep,
+		req.RequestedBy, req.Priority, req.RequestedDate, req.NeededBy, req.Status, req.AdminDeadline,
+		req.EstimatedPrintDate, req.CompletedPrintDate, req.StatusNotes, req.CreatedAt, req.UpdatedBy, req.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert wax request: %w", err)
+	}
+	if err := mirrorToOrder(ctx, tx, req.Brand, req.SONumber, req.CreatedAt); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit create wax request: %w", err)
+	}
+	return nil
+}
+
+// Update applies the admin's edits and re-mirrors the order summary in the
+// same transaction.
+func (s *Service) Update(ctx context.Context, id string, in UpdateInput, actor string) (*Request, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin update wax request: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	req, err := getRequest(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Status != nil {
+		st := matchFold(s.statuses, *in.Status)
+		if st == "" {
+			return nil, &ValidationError{Field: "status", Message: "must be one of " + strings.Join(s.statuses, ", ")}
+		}
+		req.Status = st
+	}
+	for _, d := range []struct {
+		field string
+		in    *string
+		out   *string
+	}{
+		{"adminDeadline", in.AdminDeadline, &req.AdminDeadline},
+		{"estimatedPrintDate", in.EstimatedPrintDate, &req.EstimatedPrintDate},
+		{"completedPrintDate", in.CompletedPrintDate, &req.CompletedPrintDate},
+	} {
+		if d.in == nil {
+			continue
+		}
+		v, err := parseDate(d.field, *d.in)
+		if err != nil {
+			return nil, err
+		}
+		*d.out = v
+	}
+	if in.StatusNotes != nil {
+		req.StatusNotes = strings.TrimSpace(*in.StatusNotes)
+	}
+	req.UpdatedBy = actor
+	req.UpdatedAt = s.now().UTC().Format(time.RFC3339)
+
+	_, err = tx.ExecContext(ctx, `UPDATE wax_requests SET
+            status = ?, admin_deadline = ?, estimated_print_date = ?, completed_print_date = ?,
+            status_notes = ?, updated_by = ?, updated_at = ?
+        WHERE wax_request_id = ?`,
+		req.Status, req.AdminDeadline, req.EstimatedPrintDate, req.CompletedPrintDate,
+		req.StatusNotes, req.UpdatedBy, req.UpdatedAt, req.WaxRequestID)
+	if err != nil {
+		return nil, fmt.Errorf("update wax request %s: %w", req.WaxRequestID, err)
+	}
+	if err := mirrorToOrder(ctx, tx, req.Brand, req.SONumber, req.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit update wax request: %w", err)
+	}
+
+	s.logger.Info("wax_request_updated", map[string]any{
+		"wax_request_id": req.WaxRequestID,
+		"status":         req.Status,
+		"admin_deadline": req.AdminDeadline,
+	})
+	s.withMetrics(req)
+	return req, nil
+}
+
+// Get returns one request.
+func (s *Service) Get(ctx context.Context, id string) (*Request, error) {
+	req, err := getRequest(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	s.withMetrics(req)
+	return req, nil
+}
+
+// List returns requests newest first.
+func (s *Service) List(ctx context.Context, f Filter) ([]Request, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if v := strings.ToUpper(strings.TrimSpace(f.Brand)); v != "" {
+		where = append(where, "brand = ?")
+		args = append(args, v)
+	}
+	if v := orders.SOPretty(f.SO); v != "" {
+		where = append(where, "so_number = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.RootApptID); v != "" {
+		where = append(where, "root_appt_id = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.Status); v != "" {
+		where = append(where, "LOWER(status) = LOWER(?)")
+		args = append(args, v)
+	}
+	if f.Pending && len(s.closed) > 0 {
+		marks := make([]string, 0, len(s.closed))
+		for st := range s.closed {
+			marks = append(marks, "?")
+			args = append(args, st)
+		}
+		where = append(where, "LOWER(TRIM(status)) NOT IN ("+strings.Join(marks, ", ")+")")
+	}
+
+	query := `SELECT ` + requestColumns + ` FROM wax_requests`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY requested_date DESC, wax_request_id DESC LIMIT ? OFFSET ?`
+	args = append(args, clampLimit(f.Limit), max(f.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list wax requests: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Request{}
+	for rows.Next() {
+		req, err := scanRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan wax request: %w", err)
+		}
+		s.withMetrics(req)
+		out = append(out, *req)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate wax requests: %w", err)
+	}
+	return out, nil
+}
+
+// withMetrics fills the metric columns wax_recomputeMetricsForRow_ kept on
+// the sheet, relative to today in the service's location.
+func (s *Service) withMetrics(req *Request) {
+	today := dayOf(s.now().In(s.loc).Format("2006-01-02"))
+	req.DaysUntilDeadline, req.Overdue, req.DaysLate = nil, false, nil
+	if deadline := dayOf(req.AdminDeadline); !deadline.IsZero() && !s.closed[strings.ToLower(strings.TrimSpace(req.Status))] {
+		days := daysBetween(today, deadline)
+		req.DaysUntilDeadline = &days
+		req.Overdue = days < 0
+	}
+	completed, needed := dayOf(req.CompletedPrintDate), dayOf(req.NeededBy)
+	if !completed.IsZero() && !needed.IsZero() {
+		late := daysBetween(needed, completed)
+		req.DaysLate = &late
+	}
+}
+
+// mirrorToOrder copies the order's latest request onto sales_orders (the
+// Master's Wax Print Status, Wax Deadline (Admin) and Wax Request URL). SOs
+// outside the registry, such as MO numbers, have nothing to mirror onto.
+func mirrorToOrder(ctx context.Context, q queryer, brand, so, stamp string) error {
+	key := orders.SOKey(so)
+	if key == "" || strings.HasPrefix(strings.ToUpper(so), "MO") {
+		return nil
+	}
+	var id, status, deadline string
+	err := q.QueryRowContext(ctx, `SELECT wax_request_id, status, admin_deadline FROM wax_requests
+        WHERE brand = ? AND so_number = ?
+        ORDER BY requested_date DESC, wax_request_id DESC LIMIT 1`, brand, orders.SOPretty(so)).Scan(&id, &status, &deadline)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("select latest wax request: %w", err)
+	}
+	_, err = q.ExecContext(ctx, `UPDATE sales_orders SET wax_request_id = ?, wax_status = ?, wax_deadline = ?, updated_at = ?
+        WHERE brand = ? AND so_key = ?`, id, status, deadline, stamp, brand, key)
+	if err != nil {
+		return fmt.Errorf("mirror wax request to %s %s: %w", brand, so, err)
+	}
+	return nil
+}
+
+// nextID issues WAX-YYYYMMDD-### for day, numbering from the highest
+// request already filed that day. Two writers can draw the same ID; the
+// primary key rejects the second and Create retries it.
+func nextID(ctx context.Context, q queryer, day time.Time) (string, error) {
+	prefix := "WAX-" + day.Format("20060102") + "-"
+	rows, err := q.QueryContext(ctx, `SELECT wax_request_id FROM wax_requests WHERE wax_request_id LIKE ?`, prefix+"%")
+	if err != nil {
+		return "", fmt.Errorf("select wax request ids: %w", err)
+	}
+	defer rows.Close()
+	seq := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", fmt.Errorf("scan wax request id: %w", err)
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(id, prefix)); err == nil {
+			seq = max(seq, n)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("iterate wax request ids: %w", err)
+	}
+	return fmt.Sprintf("%s%03d", prefix, seq+1), nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func getRequest(ctx context.Context, q queryer, id string) (*Request, error) {
+	req, err := scanRequest(q.QueryRowContext(ctx, `SELECT `+requestColumns+` FROM wax_requests WHERE wax_request_id = ?`, strings.TrimSpace(id)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select wax request: %w", err)
+	}
+	return req, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRequest(row scanner) (*Request, error) {
+	var r Request
+	err := row.Scan(&r.WaxRequestID, &r.RootApptID, &r.SONumber, &r.Brand, &r.CustomerName, &r.AssignedRep,
+		&r.AssistedRep, &r.RequestedBy, &r.Priority, &r.RequestedDate, &r.NeededBy, &r.Status, &r.AdminDeadline,
+		&r.EstimatedPrintDate, &r.CompletedPrintDate, &r.StatusNotes, &r.CreatedAt, &r.UpdatedBy, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// parseDate accepts YYYY-MM-DD or the dialog's MM/DD/YYYY; blank stays blank.
+func parseDate(field, v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	for _, layout := range []string{"2006-01-02", "1/2/2006"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", &ValidationError{Field: field, Message: "must be YYYY-MM-DD"}
+}
+
+func dayOf(v string) time.Time {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(v))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// matchFold returns the entry of list equal to v ignoring case, or "".
+func matchFold(list []string, v string) string {
+	v = strings.TrimSpace(v)
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return item
+		}
+	}
+	return ""
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
@@ -0,0 +1,119 @@
+package wax
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/example/vvsapp/internal/appointments"
+	"github.com/example/vvsapp/internal/config"
+	"github.com/example/vvsapp/internal/db"
+	"github.com/example/vvsapp/internal/db/dbtest"
+	"github.com/example/vvsapp/internal/logging"
+	"github.com/example/vvsapp/internal/orders"
+)
+
+// newTestService returns a service with SO 77.0001 linked to client R1 and
+// today 2024-05-10.
+func newTestService(t *testing.T) (*Service, *orders.Service) {
+	t.Helper()
+	conn := dbtest.Open(t, db.DriverSQLite)
+	logger := logging.NewWriter("error", io.Discard)
+	appts := appointments.NewService(conn, logger)
+	ordersSvc := orders.NewService(conn, appts, logger)
+	ctx := context.Background()
+	if _, err := appts.Create(ctx, appointments.Appointment{ApptID: "R1", Brand: appointments.BrandVVS,
+		CustomerName: "Jamie", VisitDate: "2024-04-02", AssignedRep: "Alice"}); err != nil {
+		t.Fatalf("create appointment: %v", err)
+	}
+	if _, err := ordersSvc.Assign(ctx, orders.AssignInput{ApptID: "R1", Brand: "VVS", SO: "770001", OdooURL: "odoo.example.com"}); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	svc, err := NewService(conn, appts, ordersSvc, config.WaxConfig{
+		Statuses:       []string{"Wax Requested", "Printing", "Completed"},
+		ClosedStatuses: []string{"Completed"},
+	}, time.UTC, logger)
+	if err != nil {
+		t.Fatalf("wax: %v", err)
+	}
+	svc.now = func() time.Time { return time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC) }
+	return svc, ordersSvc
+}
+
+func ptr(v string) *string {
+	return &v
+}
+
+func TestMirrorToOrder(t *testing.T) {
+	svc, ordersSvc := newTestService(t)
+	ctx := context.Background()
+
+	req, err := svc.Create(ctx, CreateInput{Brand: "VVS", SO: "77.0001", NeededBy: "5/20/2024"}, "alice")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if req.WaxRequestID != "WAX-20240510-001" || req.Status != "Wax Requested" || req.NeededBy != "2024-05-20" ||
+		req.Priority != "Normal" || req.CustomerName != "Jamie" {
+		t.Errorf("request = %+v", req)
+	}
+	order, err := ordersSvc.Lookup(ctx, "VVS", "770001")
+	if err != nil || order.WaxRequestID != req.WaxRequestID || order.WaxStatus != "Wax Requested" || order.WaxDeadline != "" {
+		t.Errorf("order after create = %+v, %v", order, err)
+	}
+
+	upd, err := svc.Update(ctx, req.WaxRequestID, UpdateInput{Status: ptr("printing"), AdminDeadline: ptr("2024-05-12")}, "admin")
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if upd.Status != "Printing" || upd.DaysUntilDeadline == nil || *upd.DaysUntilDeadline != 2 {
+		t.Errorf("updated = %+v", upd)
+	}
+	order, err = ordersSvc.Lookup(ctx, "VVS", "770001")
+	if err != nil || order.WaxStatus != "Printing" || order.WaxDeadline != "2024-05-12" {
+		t.Errorf("order after update = %+v, %v", order, err)
+	}
+
+	var verr *ValidationError
+	if _, err := svc.Update(ctx, req.WaxRequestID, UpdateInput{Status: ptr("Shipped")}, "admin"); !errors.As(err, &verr) {
+		t.Errorf("unknown status = %v, want a validation error", err)
+	}
+	if order, err := ordersSvc.Lookup(ctx, "VVS", "770001"); err != nil || order.WaxStatus != "Printing" {
+		t.Errorf("order after rejected update = %+v, %v", order, err)
+	}
+}
+
+// TestCreateRetriesCollision draws a taken WAX ID once and checks Create
+// draws again rather than failing.
+func TestCreateRetriesCollision(t *testing.T) {
+	svc, ordersSvc := newTestService(t)
+	ctx := context.Background()
+	in := CreateInput{Brand: "VVS", SO: "770001"}
+	if _, err := svc.Create(ctx, in, "alice"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	calls := 0
+	svc.nextID = func(ctx context.Context, q queryer, day time.Time) (string, error) {
+		if calls++; calls == 1 {
+			return "WAX-20240510-001", nil
+		}
+		return nextID(ctx, q, day)
+	}
+	req, err := svc.Create(ctx, in, "bob")
+	if err != nil {
+		t.Fatalf("create after collision: %v", err)
+	}
+	if calls != 2 || req.WaxRequestID != "WAX-20240510-002" {
+		t.Errorf("request = %s after %d draws", req.WaxRequestID, calls)
+	}
+	if order, err := ordersSvc.Lookup(ctx, "VVS", "770001"); err != nil || order.WaxRequestID != "WAX-20240510-002" {
+		t.Errorf("order = %+v, %v", order, err)
+	}
+
+	svc.nextID = func(context.Context, queryer, time.Time) (string, error) { return "WAX-20240510-001", nil }
+	if _, err := svc.Create(ctx, in, "bob"); !db.IsUniqueViolation(err) {
+		t.Errorf("repeated collisions = %v, want the unique violation", err)
+	}
+}
@@ -0,0 +1,10 @@
+// Package web bundles the browser UI and dialog pages into the vvsapp binary.
+package web
+
+import "embed"
+
+// Assets holds the static UI served by internal/server. Only the browser-facing
+// files are embedded; the Apps Script sources alongside them stay out of the binary.
+//
+//go:embed index.html css js dlg_*.html WaxPendingDialog.html
+var Assets embed.FS
@@ -1,5 +1,6 @@
 const healthOverall = document.querySelector('#health-overall');
 const healthDb = document.querySelector('#health-db');
+const healthSchema = document.querySelector('#health-schema');
 const healthTime = document.querySelector('#health-time');
 const healthError = document.querySelector('#health-error');
 const refreshHealthButton = document.querySelector('#refresh-health');
@@ -13,6 +14,7 @@ const API_BASE = '/api';
 function setHealthLoading() {
     healthOverall.textContent = 'Loading…';
     healthDb.textContent = 'Loading…';
+    healthSchema.textContent = 'Loading…';
     healthTime.textContent = 'Loading…';
     healthError.hidden = true;
 }
@@ -27,6 +29,7 @@ async function fetchHealthStatus() {
         const payload = await response.json();
         healthOverall.textContent = payload.status ?? 'unknown';
         healthDb.textContent = payload.db ?? 'unknown';
+        healthSchema.textContent = formatSchema(payload.schema);
         healthTime.textContent = payload.nowIso ?? 'unknown';
     } catch (error) {
         console.error('Health request failed', error);
@@ -34,10 +37,24 @@ async function fetchHealthStatus() {
         healthError.hidden = false;
         healthOverall.textContent = 'error';
         healthDb.textContent = 'error';
+        healthSchema.textContent = 'error';
         healthTime.textContent = '--';
     }
 }
 
+function formatSchema(schema) {
+    if (!schema || schema.state === 'error') {
+        return 'unknown';
+    }
+    if (schema.state === 'ok') {
+        return `v${schema.version} (up to date)`;
+    }
+    if (schema.state === 'pending') {
+        return `v${schema.version} of ${schema.latest} (${schema.pending} pending)`;
+    }
+    return `v${schema.version} (${schema.state}; run vvsapp migrate status)`;
+}
+
 function setLoginMessage(message, type = 'info') {
     if (!message) {
         loginMessage.hidden = true;